@@ -45,6 +45,15 @@ if [ -f /var/lib/bashible/cgroup_config ] && [ "$(cat /var/lib/bashible/cgroup_c
   systemd_cgroup=false
 fi
 
+{{- range .registry.mirrors }}
+  {{- if .ca }}
+mkdir -p /etc/containerd/registry-mirrors
+bb-sync-file /etc/containerd/registry-mirrors/{{ .address }}.crt - containerd-config-file-changed << "EOF"
+{{ .ca }}
+EOF
+  {{- end }}
+{{- end }}
+
 # generated using `containerd config default` by containerd version `containerd containerd.io 1.4.3 269548fa27e0089a8b8278fc4fc781d7f65a939b`
 bb-sync-file /etc/containerd/deckhouse.toml - << EOF
 version = 2
@@ -148,13 +157,24 @@ oom_score = 0
         [plugins."io.containerd.grpc.v1.cri".registry.mirrors."docker.io"]
           endpoint = ["https://registry-1.docker.io"]
         [plugins."io.containerd.grpc.v1.cri".registry.mirrors."{{ .registry.address }}"]
-          endpoint = ["{{ .registry.scheme }}://{{ .registry.address }}"]
+          endpoint = ["{{ .registry.scheme }}://{{ .registry.address }}"{{ range .registry.mirrors }}, "{{ .scheme }}://{{ .address }}"{{ end }}]
       [plugins."io.containerd.grpc.v1.cri".registry.configs]
         [plugins."io.containerd.grpc.v1.cri".registry.configs."{{ .registry.address }}".auth]
           auth = "{{ .registry.auth | default "" }}"
   {{- if eq .registry.scheme "http" }}
         [plugins."io.containerd.grpc.v1.cri".registry.configs."{{ .registry.address }}".tls]
           insecure_skip_verify = true
+  {{- end }}
+  {{- range .registry.mirrors }}
+        [plugins."io.containerd.grpc.v1.cri".registry.configs."{{ .address }}".auth]
+          auth = "{{ .auth | default "" }}"
+    {{- if eq .scheme "http" }}
+        [plugins."io.containerd.grpc.v1.cri".registry.configs."{{ .address }}".tls]
+          insecure_skip_verify = true
+    {{- else if .ca }}
+        [plugins."io.containerd.grpc.v1.cri".registry.configs."{{ .address }}".tls]
+          ca_file = "/etc/containerd/registry-mirrors/{{ .address }}.crt"
+    {{- end }}
   {{- end }}
     [plugins."io.containerd.grpc.v1.cri".image_decryption]
       key_model = ""
@@ -190,6 +190,34 @@ function dynamic_memory_sizing {
     echo -n "${recommended_systemreserved_memory}Gi"
 }
 
+# Changing of these settings requires a drain of the node and a reset of the kubelet state,
+# so we ask for the disruption approval before applying them.
+{{- $disruptiveSettings := dict }}
+{{- $_ := set $disruptiveSettings "cpuManagerPolicy" (dig "kubelet" "cpuManagerPolicy" "None" .nodeGroup) }}
+{{- $_ := set $disruptiveSettings "topologyManager" (dig "kubelet" "topologyManager" (dict) .nodeGroup) }}
+{{- $_ := set $disruptiveSettings "featureGates" (dig "kubelet" "featureGates" (dict) .nodeGroup) }}
+{{- $defaultDisruptiveSettings := dict "cpuManagerPolicy" "None" "topologyManager" (dict) "featureGates" (dict) }}
+kubelet_disruptive_settings={{ $disruptiveSettings | toJson | squote }}
+kubelet_disruptive_settings_path="/var/lib/bashible/kubelet-disruptive-settings"
+
+previous_kubelet_disruptive_settings=""
+if [[ -f "${kubelet_disruptive_settings_path}" ]]; then
+  previous_kubelet_disruptive_settings="$(cat "${kubelet_disruptive_settings_path}")"
+elif [[ -f /var/lib/kubelet/config.yaml ]]; then
+  # The kubelet was configured before the settings were tracked, so it runs with the defaults.
+  previous_kubelet_disruptive_settings={{ $defaultDisruptiveSettings | toJson | squote }}
+fi
+
+if [[ -n "${previous_kubelet_disruptive_settings}" ]] && [[ "${previous_kubelet_disruptive_settings}" != "${kubelet_disruptive_settings}" ]]; then
+  bb-log-info "Kubelet disruptive settings were changed."
+  bb-deckhouse-get-disruptive-update-approval
+  # The kubelet refuses to start if the cpu/memory manager state does not match the configured policy.
+  systemctl stop kubelet
+  rm -f /var/lib/kubelet/cpu_manager_state /var/lib/kubelet/memory_manager_state
+  bb-flag-set kubelet-need-restart
+fi
+echo -n "${kubelet_disruptive_settings}" > "${kubelet_disruptive_settings_path}"
+
 # CIS becnhmark purposes
 tls_params=""
 if [ -f /var/lib/kubelet/pki/kubelet-server-current.pem ]; then
@@ -226,7 +254,11 @@ clusterDomain: {{ .clusterBootstrap.clusterDomain }}
 clusterDNS:
 - {{ .clusterBootstrap.clusterDNSAddress }}
 {{- end }}
+cpuManagerPolicy: {{ dig "kubelet" "cpuManagerPolicy" "None" .nodeGroup | lower }}
 cpuManagerReconcilePeriod: 10s
+{{- $topologyManagerPolicies := dict "None" "none" "BestEffort" "best-effort" "Restricted" "restricted" "SingleNUMANode" "single-numa-node" }}
+topologyManagerPolicy: {{ get $topologyManagerPolicies (dig "kubelet" "topologyManager" "policy" "None" .nodeGroup) }}
+topologyManagerScope: {{ dig "kubelet" "topologyManager" "scope" "Container" .nodeGroup | lower }}
 enableControllerAttachDetach: true
 enableDebuggingHandlers: true
 enableServer: true
@@ -235,25 +267,25 @@ enforceNodeAllocatable:
 eventRecordQPS: 50
 eventBurst: 50
 evictionHard:
-  imagefs.available: $evictionHardThresholdImagefsAvailable
-  imagefs.inodesFree: $evictionHardThresholdImagefsInodesFree
-  memory.available: 1%
-  nodefs.available: $evictionHardThresholdNodefsAvailable
-  nodefs.inodesFree: $evictionHardThresholdNodefsInodesFree
+  imagefs.available: {{ dig "kubelet" "eviction" "hard" "imagefsAvailable" "$evictionHardThresholdImagefsAvailable" .nodeGroup }}
+  imagefs.inodesFree: {{ dig "kubelet" "eviction" "hard" "imagefsInodesFree" "$evictionHardThresholdImagefsInodesFree" .nodeGroup }}
+  memory.available: {{ dig "kubelet" "eviction" "hard" "memoryAvailable" "1%" .nodeGroup }}
+  nodefs.available: {{ dig "kubelet" "eviction" "hard" "nodefsAvailable" "$evictionHardThresholdNodefsAvailable" .nodeGroup }}
+  nodefs.inodesFree: {{ dig "kubelet" "eviction" "hard" "nodefsInodesFree" "$evictionHardThresholdNodefsInodesFree" .nodeGroup }}
 evictionSoft:
-  imagefs.available: $evictionSoftThresholdImagefsAvailable
-  imagefs.inodesFree: $evictionSoftThresholdImagefsInodesFree
-  memory.available: 2%
-  nodefs.available: $evictionSoftThresholdNodefsAvailable
-  nodefs.inodesFree: $evictionSoftThresholdNodefsInodesFree
+  imagefs.available: {{ dig "kubelet" "eviction" "soft" "imagefsAvailable" "$evictionSoftThresholdImagefsAvailable" .nodeGroup }}
+  imagefs.inodesFree: {{ dig "kubelet" "eviction" "soft" "imagefsInodesFree" "$evictionSoftThresholdImagefsInodesFree" .nodeGroup }}
+  memory.available: {{ dig "kubelet" "eviction" "soft" "memoryAvailable" "2%" .nodeGroup }}
+  nodefs.available: {{ dig "kubelet" "eviction" "soft" "nodefsAvailable" "$evictionSoftThresholdNodefsAvailable" .nodeGroup }}
+  nodefs.inodesFree: {{ dig "kubelet" "eviction" "soft" "nodefsInodesFree" "$evictionSoftThresholdNodefsInodesFree" .nodeGroup }}
 evictionSoftGracePeriod:
-  imagefs.available: 1m30s
-  imagefs.inodesFree: 1m30s
-  memory.available: 1m30s
-  nodefs.available: 1m30s
-  nodefs.inodesFree: 1m30s
+  imagefs.available: {{ dig "kubelet" "eviction" "softGracePeriod" "imagefsAvailable" "1m30s" .nodeGroup }}
+  imagefs.inodesFree: {{ dig "kubelet" "eviction" "softGracePeriod" "imagefsInodesFree" "1m30s" .nodeGroup }}
+  memory.available: {{ dig "kubelet" "eviction" "softGracePeriod" "memoryAvailable" "1m30s" .nodeGroup }}
+  nodefs.available: {{ dig "kubelet" "eviction" "softGracePeriod" "nodefsAvailable" "1m30s" .nodeGroup }}
+  nodefs.inodesFree: {{ dig "kubelet" "eviction" "softGracePeriod" "nodefsInodesFree" "1m30s" .nodeGroup }}
 evictionPressureTransitionPeriod: 4m0s
-evictionMaxPodGracePeriod: 90
+evictionMaxPodGracePeriod: {{ dig "kubelet" "eviction" "maxPodGracePeriod" 90 .nodeGroup }}
 evictionMinimumReclaim: null
 failSwapOn: true
 tlsCipherSuites: ["TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256","TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256","TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305","TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384","TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305","TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384","TLS_RSA_WITH_AES_256_GCM_SHA384","TLS_RSA_WITH_AES_128_GCM_SHA256"]
@@ -267,15 +299,24 @@ ${tls_params}
 RotateKubeletServerCertificate default is true, but CIS becnhmark wants it to be explicitly enabled
 https://kubernetes.io/docs/reference/command-line-tools-reference/feature-gates/
 */}}
-featureGates:
+{{- $featureGates := dict }}
 {{- if semverCompare "<1.27" .kubernetesVersion }}
-  ExpandCSIVolumes: true
+  {{- $_ := set $featureGates "ExpandCSIVolumes" true }}
+{{- end }}
+{{- range $gate, $enabled := dig "kubelet" "featureGates" (dict) .nodeGroup }}
+  {{- if not (and (hasPrefix "TopologyManagerPolicy" $gate) (semverCompare "<1.26" $.kubernetesVersion)) }}
+    {{- $_ := set $featureGates $gate $enabled }}
+  {{- end }}
+{{- end }}
+{{- $_ := set $featureGates "RotateKubeletServerCertificate" true }}
+featureGates:
+{{- range $gate, $enabled := $featureGates }}
+  {{ $gate }}: {{ $enabled }}
 {{- end }}
-  RotateKubeletServerCertificate: true
 fileCheckFrequency: 20s
 imageMinimumGCAge: 2m0s
-imageGCHighThresholdPercent: 70
-imageGCLowThresholdPercent: 65
+imageGCHighThresholdPercent: {{ dig "kubelet" "imageGC" "highThresholdPercent" 70 .nodeGroup }}
+imageGCLowThresholdPercent: {{ dig "kubelet" "imageGC" "lowThresholdPercent" 65 .nodeGroup }}
 kubeAPIBurst: 50
 kubeAPIQPS: 50
 hairpinMode: promiscuous-bridge
@@ -47,6 +47,11 @@ if bb-flag? kubelet-need-restart; then
 {{- end }}
 
   bb-flag-unset kubelet-need-restart
+  # Disruption could be approved for kubelet settings changes (step 064_configure_kubelet.sh), it is completed by the restart.
+  # If the reboot is pending, the approval is kept for the reboot step.
+  if ! bb-flag? reboot; then
+    bb-flag-unset disruption
+  fi
 fi
 
 {{- if ne .runType "ImageBuilding" }}
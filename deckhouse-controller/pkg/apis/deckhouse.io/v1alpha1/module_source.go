@@ -64,6 +64,15 @@ type ModuleSourceSpecRegistry struct {
 	Repo      string `json:"repo"`
 	DockerCFG string `json:"dockerCfg"`
 	CA        string `json:"ca"`
+	// Mirrors are tried in order if the registry is unavailable
+	Mirrors []ModuleSourceSpecRegistryMirror `json:"mirrors,omitempty"`
+}
+
+type ModuleSourceSpecRegistryMirror struct {
+	Scheme    string `json:"scheme,omitempty"`
+	Repo      string `json:"repo"`
+	DockerCFG string `json:"dockerCfg,omitempty"`
+	CA        string `json:"ca,omitempty"`
 }
 
 type ModuleSourceStatus struct {
@@ -61,6 +61,27 @@ type ModuleUpdatePolicySpec struct {
 type ModuleUpdatePolicySpecUpdate struct {
 	Mode    string         `json:"mode"`
 	Windows update.Windows `json:"windows"`
+	// Canary spreads applying of a release across clusters
+	Canary *ModuleUpdatePolicySpecCanary `json:"canary,omitempty"`
+	// SoakTime is the minimal time a deployed release must run before the next release is applied,
+	// the next release is applied without waiting if hooks of the module fail during this time
+	SoakTime *Duration `json:"soakTime,omitempty"`
+	// Rollback restores the previous release if the module fails after the deployment
+	Rollback *ModuleUpdatePolicySpecRollback `json:"rollback,omitempty"`
+}
+
+type ModuleUpdatePolicySpecCanary struct {
+	Enabled bool `json:"enabled"`
+	// Waves is the number of groups the clusters are split into by their UUID
+	Waves int `json:"waves"`
+	// Interval is the delay between the waves
+	Interval Duration `json:"interval"`
+}
+
+type ModuleUpdatePolicySpecRollback struct {
+	Enabled bool `json:"enabled"`
+	// GracePeriod is the time after the deployment during which a failed module is rolled back
+	GracePeriod Duration `json:"gracePeriod"`
 }
 
 type ModuleUpdatePolicySpecReleaseSelector struct {
@@ -476,7 +476,7 @@ func (in *ModuleSource) DeepCopyInto(out *ModuleSource) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 	return
 }
@@ -535,7 +535,7 @@ func (in *ModuleSourceList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ModuleSourceSpec) DeepCopyInto(out *ModuleSourceSpec) {
 	*out = *in
-	out.Registry = in.Registry
+	in.Registry.DeepCopyInto(&out.Registry)
 	return
 }
 
@@ -552,6 +552,11 @@ func (in *ModuleSourceSpec) DeepCopy() *ModuleSourceSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ModuleSourceSpecRegistry) DeepCopyInto(out *ModuleSourceSpecRegistry) {
 	*out = *in
+	if in.Mirrors != nil {
+		in, out := &in.Mirrors, &out.Mirrors
+		*out = make([]ModuleSourceSpecRegistryMirror, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -565,6 +570,22 @@ func (in *ModuleSourceSpecRegistry) DeepCopy() *ModuleSourceSpecRegistry {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModuleSourceSpecRegistryMirror) DeepCopyInto(out *ModuleSourceSpecRegistryMirror) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModuleSourceSpecRegistryMirror.
+func (in *ModuleSourceSpecRegistryMirror) DeepCopy() *ModuleSourceSpecRegistryMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(ModuleSourceSpecRegistryMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ModuleSourceStatus) DeepCopyInto(out *ModuleSourceStatus) {
 	*out = *in
@@ -670,6 +691,23 @@ func (in *ModuleUpdatePolicySpec) DeepCopy() *ModuleUpdatePolicySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModuleUpdatePolicySpecCanary) DeepCopyInto(out *ModuleUpdatePolicySpecCanary) {
+	*out = *in
+	out.Interval = in.Interval
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModuleUpdatePolicySpecCanary.
+func (in *ModuleUpdatePolicySpecCanary) DeepCopy() *ModuleUpdatePolicySpecCanary {
+	if in == nil {
+		return nil
+	}
+	out := new(ModuleUpdatePolicySpecCanary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ModuleUpdatePolicySpecReleaseSelector) DeepCopyInto(out *ModuleUpdatePolicySpecReleaseSelector) {
 	*out = *in
@@ -691,10 +729,42 @@ func (in *ModuleUpdatePolicySpecReleaseSelector) DeepCopy() *ModuleUpdatePolicyS
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModuleUpdatePolicySpecRollback) DeepCopyInto(out *ModuleUpdatePolicySpecRollback) {
+	*out = *in
+	out.GracePeriod = in.GracePeriod
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModuleUpdatePolicySpecRollback.
+func (in *ModuleUpdatePolicySpecRollback) DeepCopy() *ModuleUpdatePolicySpecRollback {
+	if in == nil {
+		return nil
+	}
+	out := new(ModuleUpdatePolicySpecRollback)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ModuleUpdatePolicySpecUpdate) DeepCopyInto(out *ModuleUpdatePolicySpecUpdate) {
 	*out = *in
 	out.Windows = in.Windows.DeepCopy()
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(ModuleUpdatePolicySpecCanary)
+		**out = **in
+	}
+	if in.SoakTime != nil {
+		in, out := &in.SoakTime, &out.SoakTime
+		*out = new(Duration)
+		**out = **in
+	}
+	if in.Rollback != nil {
+		in, out := &in.Rollback, &out.Rollback
+		*out = new(ModuleUpdatePolicySpecRollback)
+		**out = **in
+	}
 	return
 }
 
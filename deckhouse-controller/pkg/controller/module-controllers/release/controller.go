@@ -33,8 +33,10 @@ import (
 	"github.com/flant/addon-operator/pkg/utils/logger"
 	"github.com/flant/addon-operator/pkg/values/validation"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -42,14 +44,17 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	coordinationv1 "k8s.io/client-go/listers/coordination/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/deckhouse/deckhouse/deckhouse-controller/pkg/apis/deckhouse.io/v1alpha1"
 	"github.com/deckhouse/deckhouse/deckhouse-controller/pkg/client/clientset/versioned"
+	d8scheme "github.com/deckhouse/deckhouse/deckhouse-controller/pkg/client/clientset/versioned/scheme"
 	d8informers "github.com/deckhouse/deckhouse/deckhouse-controller/pkg/client/informers/externalversions/deckhouse.io/v1alpha1"
 	d8listers "github.com/deckhouse/deckhouse/deckhouse-controller/pkg/client/listers/deckhouse.io/v1alpha1"
 	"github.com/deckhouse/deckhouse/deckhouse-controller/pkg/controller/models"
@@ -57,6 +62,7 @@ import (
 	"github.com/deckhouse/deckhouse/deckhouse-controller/pkg/controller/module-controllers/utils"
 	deckhouseconfig "github.com/deckhouse/deckhouse/go_lib/deckhouse-config"
 	d8http "github.com/deckhouse/deckhouse/go_lib/dependency/http"
+	"github.com/deckhouse/deckhouse/go_lib/hooks/update/notification"
 	docs_builder "github.com/deckhouse/deckhouse/go_lib/module/docs-builder"
 )
 
@@ -89,12 +95,17 @@ type Controller struct {
 	workqueue      workqueue.RateLimitingInterface
 	leaseWorkqueue workqueue.RateLimitingInterface
 
-	logger logger.Logger
+	logger   logger.Logger
+	recorder record.EventRecorder
+
+	// rollbackMetric is exposed while the module is rolled back after the failed deployment
+	rollbackMetric *prometheus.GaugeVec
 
 	// <module-name>: <module-source>
 	sourceModules map[string]string
 
 	modulesValidator   moduleValidator
+	moduleManager      moduleManager
 	externalModulesDir string
 	symlinksDir        string
 
@@ -102,6 +113,10 @@ type Controller struct {
 	delayTimer    *time.Timer
 	restartReason string
 	httpClient    d8http.Client
+
+	// notificationsLock guards the notification queue ConfigMap
+	notificationsLock  sync.Mutex
+	notificationsFlush chan struct{}
 }
 
 const (
@@ -113,6 +128,11 @@ const (
 	sourceReleaseFinalizer = "modules.deckhouse.io/release-exists"
 	manualApprovalRequired = "Waiting for manual approval"
 	waitingForWindow       = "Release is waiting for the update window: %s"
+	waitingForCanary       = "Release is postponed by the canary settings until: %s"
+	waitingForSoakTime     = "Release is waiting for the soak time of the deployed release v%s until: %s"
+	hooksFailed            = "Module hooks failed after the deployment: %s"
+	rolledBack             = "Module failed after the deployment, rolled back to v%s: %s"
+	rolledBackFrom         = "Rolled back from v%s"
 	docsLeaseLabel         = "deckhouse.io/documentation-builder-sync"
 	namespace              = "d8-system"
 )
@@ -124,7 +144,7 @@ func NewController(ks kubernetes.Interface,
 	moduleSourceInformer d8informers.ModuleSourceInformer,
 	moduleUpdatePolicyInformer d8informers.ModuleUpdatePolicyInformer,
 	modulePullOverridesInformer d8informers.ModulePullOverrideInformer,
-	mv moduleValidator,
+	mm moduleManager,
 	httpClient d8http.Client,
 ) *Controller {
 	ratelimiter := workqueue.NewMaxOfRateLimiter(
@@ -146,6 +166,10 @@ func NewController(ks kubernetes.Interface,
 	leaseLister := leaseInformerFactory.Lister()
 	leaseInformer := leaseInformerFactory.Informer()
 
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: ks.CoreV1().Events("")})
+	recorder := eventBroadcaster.NewRecorder(d8scheme.Scheme, corev1.EventSource{Component: "deckhouse-controller"})
+
 	controller := &Controller{
 		kubeclientset:              ks,
 		d8ClientSet:                d8ClientSet,
@@ -163,14 +187,19 @@ func NewController(ks kubernetes.Interface,
 		workqueue:                  workqueue.NewRateLimitingQueue(ratelimiter),
 		leaseWorkqueue:             workqueue.NewRateLimitingQueue(ratelimiter),
 		logger:                     lg,
+		recorder:                   recorder,
+		rollbackMetric:             newRollbackMetric(),
 
 		sourceModules: make(map[string]string),
 
-		modulesValidator:   mv,
+		modulesValidator:   mm,
+		moduleManager:      mm,
 		externalModulesDir: os.Getenv("EXTERNAL_MODULES_DIR"),
 		symlinksDir:        filepath.Join(os.Getenv("EXTERNAL_MODULES_DIR"), "modules"),
 
 		delayTimer: time.NewTimer(3 * time.Second),
+
+		notificationsFlush: make(chan struct{}, 1),
 	}
 
 	// Set up an event handler for when ModuleRelease resources change
@@ -279,6 +308,7 @@ func (c *Controller) Run(ctx context.Context, workers int) {
 	c.logger.Debug("Waiting for ModuleReleaseInformer caches to sync")
 
 	go c.restartLoop(ctx)
+	go c.runNotifications(ctx)
 
 	go c.leaseInformer.Run(ctx.Done())
 	if ok := cache.WaitForCacheSync(ctx.Done(), c.moduleReleasesSynced, c.moduleSourcesSynced,
@@ -372,7 +402,7 @@ func (c *Controller) deleteReconcile(ctx context.Context, roMR *v1alpha1.ModuleR
 		return ctrl.Result{Requeue: true}, err
 	}
 
-	if roMR.Status.Phase == v1alpha1.PhaseDeployed {
+	if roMR.Status.Phase == v1alpha1.PhaseDeployed || hooksFailedAfterDeploy(roMR) {
 		symlinkPath := filepath.Join(c.externalModulesDir, "modules", fmt.Sprintf("%d-%s", roMR.Spec.Weight, roMR.Spec.ModuleName))
 		err := os.RemoveAll(symlinkPath)
 		if err != nil {
@@ -407,10 +437,13 @@ func (c *Controller) createOrUpdateReconcile(ctx context.Context, roMR *v1alpha1
 		if e := c.updateModuleReleaseStatus(ctx, mr); e != nil {
 			return ctrl.Result{Requeue: true}, e
 		}
+		c.notify(ctx, mr, notification.EventAvailable, timeOrNil(mr.Spec.ApplyAfter), fmt.Sprintf("Module %s release v%s is available", mr.Spec.ModuleName, mr.Spec.Version))
 
 		return ctrl.Result{}, nil
 
 	case v1alpha1.PhaseSuperseded, v1alpha1.PhaseSuspended:
+		c.updateRollbackMetric(mr)
+
 		// update labels
 		addLabels(mr, map[string]string{"status": strings.ToLower(mr.Status.Phase)})
 		if err := c.updateModuleRelease(ctx, mr); err != nil {
@@ -419,7 +452,16 @@ func (c *Controller) createOrUpdateReconcile(ctx context.Context, roMR *v1alpha1
 		return ctrl.Result{}, nil
 
 	case v1alpha1.PhaseDeployed:
-		err := c.sendDocumentation(ctx, mr)
+		requeueAfter, err := c.checkDeployedRelease(ctx, mr)
+		if err != nil {
+			return ctrl.Result{Requeue: true}, err
+		}
+		if mr.Status.Phase == v1alpha1.PhaseSuspended {
+			return ctrl.Result{}, nil
+		}
+		c.updateRollbackMetric(mr)
+
+		err = c.sendDocumentation(ctx, mr)
 		if err != nil {
 			return ctrl.Result{Requeue: true}, fmt.Errorf("send documentation: %w", err)
 		}
@@ -449,7 +491,7 @@ func (c *Controller) createOrUpdateReconcile(ctx context.Context, roMR *v1alpha1
 			}
 		}
 
-		return ctrl.Result{}, nil
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	}
 
 	// if ModulePullOverride is set, don't process pending release, to avoid fs override
@@ -560,12 +602,60 @@ func (c *Controller) reconcilePendingRelease(ctx context.Context, mr *v1alpha1.M
 
 			// if policy mode auto
 			if policy.Spec.Update.Mode == "Auto" && !policy.Spec.Update.Windows.IsAllowed(ts) {
-				if e := c.updateModuleReleaseStatusMessage(ctx, release, fmt.Sprintf(waitingForWindow, policy.Spec.Update.Windows.NextAllowedTime(ts))); e != nil {
+				applyTime := policy.Spec.Update.Windows.NextAllowedTime(ts)
+				msg := fmt.Sprintf(waitingForWindow, applyTime)
+				scheduled := release.Status.Message != msg
+				if e := c.updateModuleReleaseStatusMessage(ctx, release, msg); e != nil {
 					return ctrl.Result{Requeue: true}, e
 				}
+				if scheduled {
+					c.notify(ctx, release, notification.EventScheduled, &applyTime,
+						fmt.Sprintf("Module %s release v%s will be applied at: %s", moduleName, release.Spec.Version, applyTime.Format(time.RFC850)))
+				}
 				return ctrl.Result{RequeueAfter: defaultCheckInterval}, nil
 			}
 
+			// if policy mode auto and the release is postponed for this cluster
+			if policy.Spec.Update.Mode == "Auto" && policy.Spec.Update.Canary != nil && policy.Spec.Update.Canary.Enabled {
+				if release.Spec.ApplyAfter == nil {
+					clusterUUID, err := c.getClusterUUID(ctx)
+					if err != nil {
+						return ctrl.Result{Requeue: true}, err
+					}
+
+					if applyAfter := calculateReleaseDelay(release, policy.Spec.Update.Canary, clusterUUID); applyAfter != nil {
+						release = release.DeepCopy()
+						release.Spec.ApplyAfter = &metav1.Time{Time: *applyAfter}
+						if e := c.updateModuleRelease(ctx, release); e != nil {
+							return ctrl.Result{Requeue: true}, e
+						}
+						c.notify(ctx, release, notification.EventScheduled, applyAfter,
+							fmt.Sprintf("Module %s release v%s will be applied at: %s", moduleName, release.Spec.Version, applyAfter.Format(time.RFC850)))
+						// the release is reconciled again after the update
+						return ctrl.Result{}, nil
+					}
+				}
+
+				if release.Spec.ApplyAfter != nil && ts.Before(release.Spec.ApplyAfter.Time) {
+					if e := c.updateModuleReleaseStatusMessage(ctx, release, fmt.Sprintf(waitingForCanary, release.Spec.ApplyAfter.Format(time.RFC3339))); e != nil {
+						return ctrl.Result{Requeue: true}, e
+					}
+					return ctrl.Result{RequeueAfter: defaultCheckInterval}, nil
+				}
+			}
+
+			// the deployed release must run for the soak time before the next one is applied,
+			// the soak time of the release suspended because of the failed hooks is counted from the suspension
+			if pred.currentReleaseIndex >= 0 && policy.Spec.Update.SoakTime != nil && policy.Spec.Update.SoakTime.Duration > 0 {
+				deployed := pred.releases[pred.currentReleaseIndex]
+				if soakEnd := soakTimeEnd(deployed, policy.Spec.Update.SoakTime.Duration); ts.Before(soakEnd) {
+					if e := c.updateModuleReleaseStatusMessage(ctx, release, fmt.Sprintf(waitingForSoakTime, deployed.Spec.Version, soakEnd.Format(time.RFC3339))); e != nil {
+						return ctrl.Result{Requeue: true}, e
+					}
+					return ctrl.Result{RequeueAfter: defaultCheckInterval}, nil
+				}
+			}
+
 			// download desired module version
 			ms, err := c.moduleSourcesLister.Get(mr.GetModuleSource())
 			if err != nil {
@@ -594,19 +684,31 @@ func (c *Controller) reconcilePendingRelease(ctx context.Context, mr *v1alpha1.M
 				if e := c.updateModuleReleaseStatusMessage(ctx, release, "validation failed: "+err.Error()); e != nil {
 					return ctrl.Result{Requeue: true}, e
 				}
+				c.notify(ctx, release, notification.EventFailed, nil, fmt.Sprintf("Module %s release v%s validation failed: %s", moduleName, release.Spec.Version, err))
 
 				return ctrl.Result{}, nil
 			}
 
+			c.notify(ctx, release, notification.EventStarted, nil, fmt.Sprintf("Module %s release v%s deployment is started", moduleName, release.Spec.Version))
 			err = enableModule(c.externalModulesDir, currentModuleSymlink, newModuleSymlink, relativeModulePath)
 			if err != nil {
 				c.logger.Errorf("Module deploy failed: %v", err)
+				// keep the deployed version of the module enabled
+				if pred.currentReleaseIndex >= 0 {
+					deployedRelease := pred.releases[pred.currentReleaseIndex]
+					deployedModuleSymlink := path.Join(c.symlinksDir, fmt.Sprintf("%d-%s", deployedRelease.Spec.Weight, moduleName))
+					if e := enableModule(c.externalModulesDir, newModuleSymlink, deployedModuleSymlink, generateModulePath(moduleName, deployedRelease.Spec.Version.String())); e != nil {
+						c.logger.Errorf("Module %q restore of v%s failed: %v", moduleName, deployedRelease.Spec.Version, e)
+					}
+				}
 				if e := c.suspendModuleVersionForRelease(ctx, release, err); e != nil {
 					return ctrl.Result{Requeue: true}, e
 				}
+
+				return ctrl.Result{}, nil
 			}
-			// after deploying a new release, mark previous one (if any) as superseded
-			if pred.currentReleaseIndex >= 0 {
+			// after deploying a new release, mark previous one (if any) as superseded, the suspended one keeps the reason
+			if pred.currentReleaseIndex >= 0 && !hooksFailedAfterDeploy(pred.releases[pred.currentReleaseIndex]) {
 				release := pred.releases[pred.currentReleaseIndex]
 				release.Status.Phase = v1alpha1.PhaseSuperseded
 				release.Status.Message = ""
@@ -624,6 +726,7 @@ func (c *Controller) reconcilePendingRelease(ctx context.Context, mr *v1alpha1.M
 			if e := c.updateModuleReleaseStatus(ctx, release); e != nil {
 				return ctrl.Result{Requeue: true}, e
 			}
+			c.notify(ctx, release, notification.EventDeployed, nil, fmt.Sprintf("Module %s release v%s is deployed", moduleName, release.Spec.Version))
 		} else {
 			if e := c.updateModuleReleaseStatusMessage(ctx, mr, fmt.Sprintf("Update policy not set. Create a ModuleUpdatePolicy object and label the release '%s=<policy_name>'", UpdatePolicyLabel)); e != nil {
 				return ctrl.Result{Requeue: true}, e
@@ -726,7 +829,106 @@ func (c *Controller) suspendModuleVersionForRelease(ctx context.Context, release
 	release.Status.Message = fmt.Sprintf("Desired version of the module met problems: %s", err)
 	release.Status.TransitionTime = metav1.NewTime(time.Now().UTC())
 
-	return c.updateModuleReleaseStatus(ctx, release)
+	if e := c.updateModuleReleaseStatus(ctx, release); e != nil {
+		return e
+	}
+	c.notify(ctx, release, notification.EventFailed, nil, fmt.Sprintf("Module %s release v%s deployment failed: %s", release.Spec.ModuleName, release.Spec.Version, err))
+
+	return nil
+}
+
+// checkDeployedRelease checks the module of the deployed release during the rollback grace period and the soak time of the update policy.
+// The failed release is rolled back to the previous one during the grace period. During the soak time the failed release
+// is suspended with the reason in its status message.
+// It returns the interval to check the release again, zero if the release is not checked anymore.
+func (c *Controller) checkDeployedRelease(ctx context.Context, release *v1alpha1.ModuleRelease) (time.Duration, error) {
+	policyName, found := release.Labels[UpdatePolicyLabel]
+	if !found {
+		return 0, nil
+	}
+
+	// the release restored by the rollback is not checked to avoid rolling back further
+	if _, found = release.Annotations[rolledBackFromAnnotation]; found {
+		return 0, nil
+	}
+
+	policy, err := c.moduleUpdatePoliciesLister.Get(policyName)
+	if err != nil {
+		return 0, nil
+	}
+
+	now := time.Now().UTC()
+	soakEnd := release.Status.TransitionTime.Time
+	if policy.Spec.Update.SoakTime != nil {
+		soakEnd = soakTimeEnd(release, policy.Spec.Update.SoakTime.Duration)
+	}
+	graceEnd := release.Status.TransitionTime.Time
+	if policy.Spec.Update.Rollback != nil && policy.Spec.Update.Rollback.Enabled {
+		graceEnd = release.Status.TransitionTime.Add(policy.Spec.Update.Rollback.GracePeriod.Duration)
+	}
+
+	checkUntil := soakEnd
+	if graceEnd.After(checkUntil) {
+		checkUntil = graceEnd
+	}
+	if !now.Before(checkUntil) {
+		return 0, nil
+	}
+
+	if failure := c.moduleFailure(release.Spec.ModuleName); failure != nil {
+		c.logger.Errorf("Module %q failed after the deployment of the release %q: %s", release.Spec.ModuleName, release.Name, failure)
+
+		if now.Before(graceEnd) {
+			return 0, c.rollbackRelease(ctx, release, failure)
+		}
+
+		return 0, c.suspendFailedRelease(ctx, release, failure)
+	}
+
+	if left := checkUntil.Sub(now); left < defaultCheckInterval {
+		return left, nil
+	}
+
+	return defaultCheckInterval, nil
+}
+
+// suspendFailedRelease suspends the deployed release whose module hooks failed, the module is kept enabled
+// until the next release is deployed
+func (c *Controller) suspendFailedRelease(ctx context.Context, release *v1alpha1.ModuleRelease, failure error) error {
+	release.Status.Phase = v1alpha1.PhaseSuspended
+	release.Status.Message = fmt.Sprintf(hooksFailed, failure)
+	release.Status.TransitionTime = metav1.NewTime(time.Now().UTC())
+	if err := c.updateModuleReleaseStatus(ctx, release); err != nil {
+		return err
+	}
+
+	c.recorder.Event(release, corev1.EventTypeWarning, reasonSuspended, release.Status.Message)
+	c.notify(ctx, release, notification.EventFailed, nil,
+		fmt.Sprintf("Module %s release v%s is suspended, module hooks failed after the deployment: %s", release.Spec.ModuleName, release.Spec.Version, failure))
+
+	return nil
+}
+
+// hooksFailedAfterDeploy returns true if the deployed release is suspended because hooks of the module failed
+func hooksFailedAfterDeploy(release *v1alpha1.ModuleRelease) bool {
+	return release.Status.Phase == v1alpha1.PhaseSuspended && strings.HasPrefix(release.Status.Message, strings.TrimSuffix(hooksFailed, "%s"))
+}
+
+func (c *Controller) getClusterUUID(ctx context.Context) (string, error) {
+	cm, err := c.kubeclientset.CoreV1().ConfigMaps("kube-system").Get(ctx, "d8-cluster-uuid", metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("get cluster uuid: %w", err)
+	}
+
+	return cm.Data["cluster-uuid"], nil
+}
+
+func timeOrNil(t *metav1.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	return &t.Time
 }
 
 func enableModule(externalModulesDir, oldSymlinkPath, newSymlinkPath, modulePath string) error {
@@ -825,11 +1027,14 @@ func (c *Controller) updateModuleReleaseStatus(ctx context.Context, mrCopy *v1al
 	// NEVER modify objects from the store. It's a read-only, local cache.
 	// You can use DeepCopy() to make a deep copy of original object and modify this copy
 	// Or create a copy manually for better performance
-	_, err := c.d8ClientSet.DeckhouseV1alpha1().ModuleReleases().UpdateStatus(ctx, mrCopy, metav1.UpdateOptions{})
+	updated, err := c.d8ClientSet.DeckhouseV1alpha1().ModuleReleases().UpdateStatus(ctx, mrCopy, metav1.UpdateOptions{})
 	if err != nil {
 		return err
 	}
 
+	// the copy can be updated again in the same reconciliation
+	mrCopy.ResourceVersion = updated.ResourceVersion
+
 	return nil
 }
 
@@ -1151,6 +1356,11 @@ type moduleValidator interface {
 	GetValuesValidator() *validation.ValuesValidator
 }
 
+type moduleManager interface {
+	moduleValidator
+	GetModule(name string) *addonmodules.BasicModule
+}
+
 func (c *Controller) sendDocumentation(ctx context.Context, mr *v1alpha1.ModuleRelease) error {
 	addrs, err := c.getDocsBuilderAddresses(ctx)
 	if err != nil {
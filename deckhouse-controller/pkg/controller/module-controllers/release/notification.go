@@ -0,0 +1,217 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/deckhouse/deckhouse/deckhouse-controller/pkg/apis/deckhouse.io/v1alpha1"
+	"github.com/deckhouse/deckhouse/go_lib/hooks/update/notification"
+)
+
+const (
+	// notificationsConfigMap stores undelivered ModuleRelease notifications between restarts
+	notificationsConfigMap     = "d8-module-release-notifications"
+	notificationsFlushInterval = 30 * time.Second
+)
+
+// notify queues the notification about the ModuleRelease event for the receivers configured in the deckhouse module settings.
+// Errors are logged only, notifications must not affect the release deployment. The notification is sent
+// by the flushNotifications loop, so the reconciliation does not wait for the receivers.
+func (c *Controller) notify(ctx context.Context, release *v1alpha1.ModuleRelease, event notification.Event, applyTime *time.Time, msg string) {
+	receivers, err := c.notificationReceivers()
+	if err != nil {
+		c.logger.Errorf("Get ModuleRelease notification receivers failed: %s", err)
+		return
+	}
+
+	if len(receivers) == 0 {
+		return
+	}
+
+	message := notification.Message{
+		Event:        event,
+		Kind:         notification.KindModuleRelease,
+		Release:      release.Name,
+		Module:       release.Spec.ModuleName,
+		Version:      release.Spec.Version.String(),
+		Requirements: release.Spec.Requirements,
+		Message:      msg,
+	}
+	if applyTime != nil {
+		message.ApplyTime = applyTime.Format(time.RFC3339)
+	}
+
+	c.notificationsLock.Lock()
+	defer c.notificationsLock.Unlock()
+
+	cm, items, err := c.getNotificationQueue(ctx)
+	if err != nil {
+		c.logger.Errorf("Get ModuleRelease notification queue failed: %s", err)
+		return
+	}
+
+	queue := notification.NewQueue(receivers, items, time.Now)
+	queue.Enqueue(message)
+	c.saveNotificationQueue(ctx, cm, queue)
+
+	// wake up the flush loop, it is already woken up if the channel is full
+	select {
+	case c.notificationsFlush <- struct{}{}:
+	default:
+	}
+}
+
+// runNotifications sends queued notifications when they are added and retries undelivered ones every notificationsFlushInterval.
+func (c *Controller) runNotifications(ctx context.Context) {
+	ticker := time.NewTicker(notificationsFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-c.notificationsFlush:
+		}
+
+		c.flushNotifications(ctx)
+	}
+}
+
+// flushNotifications sends due notifications. The queue is locked only to read and to update it,
+// so notify is not blocked by slow receivers.
+func (c *Controller) flushNotifications(ctx context.Context) {
+	receivers, err := c.notificationReceivers()
+	if err != nil {
+		c.logger.Errorf("Get ModuleRelease notification receivers failed: %s", err)
+		return
+	}
+
+	c.notificationsLock.Lock()
+	cm, items, err := c.getNotificationQueue(ctx)
+	if err != nil {
+		c.notificationsLock.Unlock()
+		c.logger.Errorf("Get ModuleRelease notification queue failed: %s", err)
+		return
+	}
+
+	queue := notification.NewQueue(receivers, items, time.Now).WithSecrets(notification.KubernetesSecrets(c.kubeclientset))
+	due := queue.Due()
+	c.saveNotificationQueue(ctx, cm, queue)
+	c.notificationsLock.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+
+	results := queue.Deliver(ctx, due)
+
+	c.notificationsLock.Lock()
+	defer c.notificationsLock.Unlock()
+
+	// the queue is reloaded, notifications could be added while sending
+	cm, items, err = c.getNotificationQueue(ctx)
+	if err != nil {
+		c.logger.Errorf("Get ModuleRelease notification queue failed: %s", err)
+		return
+	}
+
+	queue = notification.NewQueue(receivers, items, time.Now)
+	for _, err = range queue.Complete(results) {
+		c.logger.Errorf("Send ModuleRelease notification failed: %s", err)
+	}
+	c.saveNotificationQueue(ctx, cm, queue)
+}
+
+// saveNotificationQueue stores the queue if it is changed
+func (c *Controller) saveNotificationQueue(ctx context.Context, cm *corev1.ConfigMap, queue *notification.Queue) {
+	if !queue.Changed() {
+		return
+	}
+
+	cm.Data = map[string]string{"queue": queue.Marshal()}
+
+	var err error
+	if cm.ResourceVersion == "" {
+		_, err = c.kubeclientset.CoreV1().ConfigMaps(namespace).Create(ctx, cm, metav1.CreateOptions{})
+	} else {
+		_, err = c.kubeclientset.CoreV1().ConfigMaps(namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		c.logger.Errorf("Save ModuleRelease notification queue failed: %s", err)
+	}
+}
+
+// getNotificationQueue returns the ConfigMap with undelivered notifications, a new one is returned if it does not exist
+func (c *Controller) getNotificationQueue(ctx context.Context) (*corev1.ConfigMap, []notification.Item, error) {
+	cm, err := c.kubeclientset.CoreV1().ConfigMaps(namespace).Get(ctx, notificationsConfigMap, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, nil, err
+		}
+
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      notificationsConfigMap,
+				Namespace: namespace,
+				Labels: map[string]string{
+					"heritage": "deckhouse",
+				},
+			},
+		}, nil, nil
+	}
+
+	items, err := notification.ParseItems(cm.Data["queue"])
+	if err != nil {
+		// a broken queue is dropped, it will be overwritten by the next notification
+		c.logger.Errorf("Parse ModuleRelease notification queue failed: %s", err)
+	}
+
+	return cm, items, nil
+}
+
+// notificationReceivers returns receivers from the update.notification settings of the deckhouse module.
+// The module values are used instead of the ModuleConfig, so the settings have the OpenAPI defaults.
+func (c *Controller) notificationReceivers() ([]notification.Receiver, error) {
+	module := c.moduleManager.GetModule("deckhouse")
+	if module == nil {
+		return nil, nil
+	}
+
+	settings, ok := module.GetValues(false).GetKeySection("update")["notification"]
+	if !ok {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var config notification.Config
+	if err = json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parse update.notification settings: %w", err)
+	}
+
+	return config.ReceiversFor(notification.KindModuleRelease), nil
+}
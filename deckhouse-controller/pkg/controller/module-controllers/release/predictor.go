@@ -17,6 +17,7 @@ package release
 import (
 	"time"
 
+	"github.com/spaolacci/murmur3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/deckhouse/deckhouse/deckhouse-controller/pkg/apis/deckhouse.io/v1alpha1"
@@ -48,6 +49,10 @@ func (rp *releasePredictor) calculateRelease() {
 			rp.currentReleaseIndex = index
 			break
 		}
+		// the module of the release suspended because of the failed hooks is still enabled
+		if hooksFailedAfterDeploy(rl) {
+			rp.currentReleaseIndex = index
+		}
 	}
 
 	for index, rl := range rp.releases {
@@ -78,3 +83,26 @@ func (rp *releasePredictor) calculateRelease() {
 		}
 	}
 }
+
+// calculateReleaseDelay returns the time the release can be applied at according to the canary settings,
+// the wave of the cluster is calculated deterministically by the cluster UUID, so different clusters get the release at different time.
+// Nil is returned if the release can be applied immediately.
+func calculateReleaseDelay(release *v1alpha1.ModuleRelease, canary *v1alpha1.ModuleUpdatePolicySpecCanary, clusterUUID string) *time.Time {
+	if canary == nil || !canary.Enabled || canary.Waves < 2 || canary.Interval.Duration <= 0 {
+		return nil
+	}
+
+	hash := murmur3.Sum64([]byte(clusterUUID + release.Spec.ModuleName + release.Spec.Version.String()))
+	wave := hash % uint64(canary.Waves)
+	if wave == 0 {
+		return nil
+	}
+
+	applyAfter := release.CreationTimestamp.Add(time.Duration(wave) * canary.Interval.Duration).UTC()
+	return &applyAfter
+}
+
+// soakTimeEnd returns the time the deployed release passes the soak time at
+func soakTimeEnd(deployed *v1alpha1.ModuleRelease, soakTime time.Duration) time.Time {
+	return deployed.Status.TransitionTime.Add(soakTime).UTC()
+}
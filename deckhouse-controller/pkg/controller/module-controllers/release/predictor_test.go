@@ -0,0 +1,124 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/deckhouse/deckhouse/deckhouse-controller/pkg/apis/deckhouse.io/v1alpha1"
+)
+
+func newTestRelease(version string, created time.Time) *v1alpha1.ModuleRelease {
+	return &v1alpha1.ModuleRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "echo-v" + version,
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: v1alpha1.ModuleReleaseSpec{
+			ModuleName: "echo",
+			Version:    semver.MustParse(version),
+		},
+	}
+}
+
+func TestCalculateReleaseDelay(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	release := newTestRelease("0.4.2", created)
+	canary := &v1alpha1.ModuleUpdatePolicySpecCanary{
+		Enabled:  true,
+		Waves:    4,
+		Interval: v1alpha1.Duration{Duration: time.Hour},
+	}
+
+	t.Run("canary is disabled", func(t *testing.T) {
+		for _, c := range []*v1alpha1.ModuleUpdatePolicySpecCanary{
+			nil,
+			{Enabled: false, Waves: 4, Interval: canary.Interval},
+			{Enabled: true, Waves: 1, Interval: canary.Interval},
+			{Enabled: true, Waves: 4},
+		} {
+			for i := 0; i < 20; i++ {
+				assert.Nil(t, calculateReleaseDelay(release, c, fmt.Sprintf("cluster-%d", i)))
+			}
+		}
+	})
+
+	t.Run("clusters are spread over the waves", func(t *testing.T) {
+		waves := make(map[time.Duration]int)
+		for i := 0; i < 200; i++ {
+			delay := time.Duration(0)
+			if applyAfter := calculateReleaseDelay(release, canary, fmt.Sprintf("cluster-%d", i)); applyAfter != nil {
+				delay = applyAfter.Sub(created)
+				assert.Equal(t, time.UTC, applyAfter.Location())
+			}
+			waves[delay]++
+		}
+
+		// the wave N is delayed by N intervals after the release creation
+		require.Len(t, waves, 4)
+		for _, delay := range []time.Duration{0, time.Hour, 2 * time.Hour, 3 * time.Hour} {
+			assert.Positive(t, waves[delay], "wave %s is empty", delay)
+		}
+	})
+
+	t.Run("the wave is stable for the cluster and the release", func(t *testing.T) {
+		first := calculateReleaseDelay(release, canary, "cluster-1")
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, first, calculateReleaseDelay(release, canary, "cluster-1"))
+		}
+	})
+
+	t.Run("the wave depends on the release", func(t *testing.T) {
+		var differs bool
+		for i := 0; i < 20 && !differs; i++ {
+			another := newTestRelease(fmt.Sprintf("0.4.%d", 3+i), created)
+			uuid := fmt.Sprintf("cluster-%d", i)
+			differs = !assert.ObjectsAreEqual(calculateReleaseDelay(release, canary, uuid), calculateReleaseDelay(another, canary, uuid))
+		}
+		assert.True(t, differs, "the same cluster must not always be in the same wave")
+	})
+}
+
+func TestSoakTimeEnd(t *testing.T) {
+	deployedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.FixedZone("UTC+3", 3*60*60))
+	release := newTestRelease("0.4.2", deployedAt)
+	release.Status.TransitionTime = metav1.NewTime(deployedAt)
+
+	end := soakTimeEnd(release, 24*time.Hour)
+	assert.Equal(t, time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC), end)
+	assert.Equal(t, time.UTC, end.Location())
+
+	assert.True(t, soakTimeEnd(release, 0).Equal(deployedAt))
+}
+
+func TestHooksFailedAfterDeploy(t *testing.T) {
+	release := newTestRelease("0.4.2", time.Now())
+	release.Status.Phase = v1alpha1.PhaseSuspended
+	release.Status.Message = fmt.Sprintf(rolledBack, "0.4.1", "hook/main: exit status 1")
+	assert.False(t, hooksFailedAfterDeploy(release))
+
+	release.Status.Message = fmt.Sprintf(hooksFailed, "hook/main: exit status 1")
+	assert.True(t, hooksFailedAfterDeploy(release))
+
+	release.Status.Phase = v1alpha1.PhaseDeployed
+	assert.False(t, hooksFailedAfterDeploy(release))
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/deckhouse/deckhouse/deckhouse-controller/pkg/apis/deckhouse.io/v1alpha1"
+	"github.com/deckhouse/deckhouse/go_lib/hooks/update/notification"
+)
+
+const (
+	// rolledBackFromAnnotation is set on the release restored by the rollback, the value is the version of the failed release
+	rolledBackFromAnnotation = "modules.deckhouse.io/rolled-back-from"
+
+	reasonRolledBack = "ModuleReleaseRolledBack"
+	reasonSuspended  = "ModuleReleaseSuspended"
+)
+
+var (
+	rollbackMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "d8_module_release_rolled_back",
+		Help: "The module is rolled back to the previous release after the failed deployment of the release",
+	}, []string{"module", "version", "rollback_version"})
+	registerRollbackMetric sync.Once
+)
+
+// newRollbackMetric returns the metric registered in the default registry,
+// it is registered once because the registry rejects duplicates and the controller can be created several times
+func newRollbackMetric() *prometheus.GaugeVec {
+	registerRollbackMetric.Do(func() {
+		prometheus.MustRegister(rollbackMetric)
+	})
+
+	return rollbackMetric
+}
+
+// updateRollbackMetric exposes the metric while the release restored by the rollback is deployed.
+// The release keeps the annotation, so the metric survives the Deckhouse restart.
+func (c *Controller) updateRollbackMetric(release *v1alpha1.ModuleRelease) {
+	failedVersion, found := release.Annotations[rolledBackFromAnnotation]
+	if !found {
+		return
+	}
+
+	metricLabels := prometheus.Labels{
+		"module":           release.Spec.ModuleName,
+		"version":          failedVersion,
+		"rollback_version": release.Spec.Version.String(),
+	}
+
+	if release.Status.Phase == v1alpha1.PhaseDeployed {
+		c.rollbackMetric.With(metricLabels).Set(1)
+		return
+	}
+
+	c.rollbackMetric.Delete(metricLabels)
+}
+
+// moduleFailure returns the error of the module run or of its hooks
+func (c *Controller) moduleFailure(moduleName string) error {
+	module := c.moduleManager.GetModule(moduleName)
+	if module == nil {
+		return nil
+	}
+
+	if err := module.GetModuleError(); err != nil {
+		return err
+	}
+
+	return module.GetLastHookError()
+}
+
+// rollbackRelease switches the module symlink back to the previous version kept on the disk,
+// suspends the failed release and marks the previous release as deployed.
+// If there is no previous version to roll back to, the failed release is suspended.
+func (c *Controller) rollbackRelease(ctx context.Context, failed *v1alpha1.ModuleRelease, failure error) error {
+	moduleName := failed.Spec.ModuleName
+	now := metav1.NewTime(time.Now().UTC())
+
+	previous, err := c.findRollbackRelease(failed)
+	if err != nil {
+		return err
+	}
+
+	if previous == nil {
+		c.logger.Warnf("Module %q has no previous release to roll back to", moduleName)
+		return c.suspendFailedRelease(ctx, failed, failure)
+	}
+
+	currentModuleSymlink, err := findExistingModuleSymlink(c.symlinksDir, moduleName)
+	if err != nil {
+		return fmt.Errorf("find module %q symlink: %w", moduleName, err)
+	}
+
+	previousModuleSymlink := path.Join(c.symlinksDir, fmt.Sprintf("%d-%s", previous.Spec.Weight, moduleName))
+	err = enableModule(c.externalModulesDir, currentModuleSymlink, previousModuleSymlink, generateModulePath(moduleName, previous.Spec.Version.String()))
+	if err != nil {
+		return fmt.Errorf("roll back module %q to v%s: %w", moduleName, previous.Spec.Version, err)
+	}
+
+	c.logger.Warnf("Module %q is rolled back from v%s to v%s: %s", moduleName, failed.Spec.Version, previous.Spec.Version, failure)
+
+	failed.Status.Phase = v1alpha1.PhaseSuspended
+	failed.Status.Message = fmt.Sprintf(rolledBack, previous.Spec.Version, failure)
+	failed.Status.TransitionTime = now
+	if err = c.updateModuleReleaseStatus(ctx, failed); err != nil {
+		return err
+	}
+
+	previous = previous.DeepCopy()
+	if previous.Annotations == nil {
+		previous.Annotations = make(map[string]string, 1)
+	}
+	previous.Annotations[rolledBackFromAnnotation] = failed.Spec.Version.String()
+	previous, err = c.d8ClientSet.DeckhouseV1alpha1().ModuleReleases().Update(ctx, previous, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+
+	previous.Status.Phase = v1alpha1.PhaseDeployed
+	previous.Status.Message = fmt.Sprintf(rolledBackFrom, failed.Spec.Version)
+	previous.Status.TransitionTime = now
+	if err = c.updateModuleReleaseStatus(ctx, previous); err != nil {
+		return err
+	}
+
+	c.recorder.Event(failed, corev1.EventTypeWarning, reasonRolledBack, failed.Status.Message)
+	c.updateRollbackMetric(previous)
+	c.notify(ctx, failed, notification.EventFailed, nil,
+		fmt.Sprintf("Module %s release v%s failed after the deployment and is rolled back to v%s: %s", moduleName, failed.Spec.Version, previous.Spec.Version, failure))
+
+	c.emitRestart(fmt.Sprintf("module %s is rolled back to v%s", moduleName, previous.Spec.Version))
+
+	return nil
+}
+
+// findRollbackRelease returns the latest superseded release of the module older than the failed one,
+// whose version is still kept on the disk
+func (c *Controller) findRollbackRelease(failed *v1alpha1.ModuleRelease) (*v1alpha1.ModuleRelease, error) {
+	releases, err := c.moduleReleasesLister.List(labels.SelectorFromValidatedSet(map[string]string{"module": failed.Spec.ModuleName}))
+	if err != nil {
+		return nil, err
+	}
+
+	var previous *v1alpha1.ModuleRelease
+	for _, release := range releases {
+		if release.Status.Phase != v1alpha1.PhaseSuperseded || !release.Spec.Version.LessThan(failed.Spec.Version) {
+			continue
+		}
+
+		if previous != nil && !release.Spec.Version.GreaterThan(previous.Spec.Version) {
+			continue
+		}
+
+		modulePath := path.Join(c.externalModulesDir, release.Spec.ModuleName, "v"+release.Spec.Version.String())
+		if _, err = os.Stat(modulePath); err != nil {
+			continue
+		}
+
+		previous = release
+	}
+
+	return previous, nil
+}
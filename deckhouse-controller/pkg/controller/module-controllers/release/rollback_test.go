@@ -0,0 +1,120 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	addonmodules "github.com/flant/addon-operator/pkg/module_manager/models/modules"
+	"github.com/flant/addon-operator/pkg/values/validation"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/deckhouse/deckhouse/deckhouse-controller/pkg/apis/deckhouse.io/v1alpha1"
+	"github.com/deckhouse/deckhouse/deckhouse-controller/pkg/client/clientset/versioned/fake"
+	d8listers "github.com/deckhouse/deckhouse/deckhouse-controller/pkg/client/listers/deckhouse.io/v1alpha1"
+)
+
+type fakeModuleManager struct {
+	modules map[string]*addonmodules.BasicModule
+}
+
+func (m *fakeModuleManager) ValidateModule(_ *addonmodules.BasicModule) error {
+	return nil
+}
+
+func (m *fakeModuleManager) GetValuesValidator() *validation.ValuesValidator {
+	return nil
+}
+
+func (m *fakeModuleManager) GetModule(name string) *addonmodules.BasicModule {
+	return m.modules[name]
+}
+
+func TestNewRollbackMetric(t *testing.T) {
+	// every controller gets the same metric, the second registration must not panic
+	var first, second interface{}
+	assert.NotPanics(t, func() {
+		first = newRollbackMetric()
+		second = newRollbackMetric()
+	})
+	assert.Same(t, first, second)
+}
+
+func TestCheckDeployedReleaseSuspendsFailedRelease(t *testing.T) {
+	ctx := context.Background()
+
+	release := newTestRelease("0.4.2", time.Now())
+	release.Labels = map[string]string{UpdatePolicyLabel: "echo"}
+	release.Status.Phase = v1alpha1.PhaseDeployed
+	release.Status.TransitionTime = metav1.NewTime(time.Now().UTC().Add(-time.Hour))
+
+	// the rollback is not configured, the release is within the soak time
+	policy := &v1alpha1.ModuleUpdatePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "echo"},
+		Spec: v1alpha1.ModuleUpdatePolicySpec{
+			Update: v1alpha1.ModuleUpdatePolicySpecUpdate{
+				Mode:     "Auto",
+				SoakTime: &v1alpha1.Duration{Duration: 24 * time.Hour},
+			},
+		},
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, indexer.Add(policy))
+
+	module := addonmodules.NewBasicModule("echo", "", 900, nil, nil)
+	recorder := record.NewFakeRecorder(10)
+	c := &Controller{
+		d8ClientSet:                fake.NewSimpleClientset(release),
+		moduleUpdatePoliciesLister: d8listers.NewModuleUpdatePolicyLister(indexer),
+		moduleManager:              &fakeModuleManager{modules: map[string]*addonmodules.BasicModule{"echo": module}},
+		logger:                     log.WithField("component", "ModuleReleaseController"),
+		recorder:                   recorder,
+		rollbackMetric:             newRollbackMetric(),
+	}
+
+	// the module works, the release is checked again
+	requeueAfter, err := c.checkDeployedRelease(ctx, release.DeepCopy())
+	require.NoError(t, err)
+	assert.Equal(t, defaultCheckInterval, requeueAfter)
+
+	module.SetError(errors.New("hook/main: exit status 1"))
+	requeueAfter, err = c.checkDeployedRelease(ctx, release.DeepCopy())
+	require.NoError(t, err)
+	assert.Zero(t, requeueAfter)
+
+	suspended, err := c.d8ClientSet.DeckhouseV1alpha1().ModuleReleases().Get(ctx, release.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, v1alpha1.PhaseSuspended, suspended.Status.Phase)
+	assert.Equal(t, fmt.Sprintf(hooksFailed, "hook/main: exit status 1"), suspended.Status.Message)
+	assert.True(t, hooksFailedAfterDeploy(suspended))
+	assert.Len(t, recorder.Events, 1)
+
+	// the suspended release is still the current one for the next release
+	next := newTestRelease("0.4.3", time.Now())
+	next.Status.Phase = v1alpha1.PhasePending
+	pred := newReleasePredictor([]*v1alpha1.ModuleRelease{suspended, next})
+	pred.calculateRelease()
+	assert.Equal(t, 0, pred.currentReleaseIndex)
+	assert.Equal(t, 1, pred.desiredReleaseIndex)
+}
@@ -42,5 +42,18 @@ func GenerateRegistryOptions(ms *v1alpha1.ModuleSource) []cr.Option {
 		opts = append(opts, cr.WithInsecureSchema(true))
 	}
 
+	if len(ms.Spec.Registry.Mirrors) > 0 {
+		mirrors := make([]cr.Mirror, 0, len(ms.Spec.Registry.Mirrors))
+		for _, mirror := range ms.Spec.Registry.Mirrors {
+			mirrors = append(mirrors, cr.Mirror{
+				Repo:      mirror.Repo,
+				DockerCfg: mirror.DockerCFG,
+				CA:        mirror.CA,
+				UseHTTP:   mirror.Scheme == "HTTP",
+			})
+		}
+		opts = append(opts, cr.WithMirrors(ms.Spec.Registry.Repo, mirrors...))
+	}
+
 	return opts
 }
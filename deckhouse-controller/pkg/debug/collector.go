@@ -0,0 +1,464 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/flant/kube-client/client"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+const manifestFile = "manifest.json"
+
+// Options limit the collected data.
+type Options struct {
+	// Sections to collect, all cluster-wide sections are collected if empty and no modules or namespaces are set.
+	Sections []string `json:"sections,omitempty"`
+	// Modules to collect values, objects, logs and events of.
+	Modules []string `json:"modules,omitempty"`
+	// Namespaces to collect objects, logs and events of.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Since limits logs and events to the newer ones, all of them are collected if zero.
+	Since time.Duration `json:"since,omitempty"`
+	// Tail is the number of the last log lines of every container.
+	Tail int64 `json:"tail"`
+}
+
+// Section is a part of the debug archive. Errors are recorded by the collector to the manifest,
+// so a failed file does not prevent collecting the others.
+type Section struct {
+	Name    string
+	Collect func(ctx context.Context, c *Collector)
+}
+
+// Manifest describes the archive content.
+type Manifest struct {
+	CreatedAt time.Time       `json:"createdAt"`
+	Options   Options         `json:"options"`
+	Files     []ManifestFile  `json:"files"`
+	Errors    []ManifestError `json:"errors,omitempty"`
+}
+
+type ManifestFile struct {
+	Name    string `json:"name"`
+	Section string `json:"section"`
+	Size    int    `json:"size"`
+	// Redactions is the number of the sensitive values replaced in the file.
+	Redactions int `json:"redactions,omitempty"`
+}
+
+type ManifestError struct {
+	Section string `json:"section"`
+	File    string `json:"file,omitempty"`
+	Error   string `json:"error"`
+}
+
+// Collector writes the collected data to the gzipped tarball.
+type Collector struct {
+	kubeClient *client.Client
+	// debugGet requests the addon-operator debug server
+	debugGet func(url string) ([]byte, error)
+
+	options  Options
+	now      time.Time
+	tar      *tar.Writer
+	manifest Manifest
+	section  string
+}
+
+func NewCollector(kubeClient *client.Client, debugGet func(url string) ([]byte, error), options Options) *Collector {
+	now := time.Now().UTC()
+
+	return &Collector{
+		kubeClient: kubeClient,
+		debugGet:   debugGet,
+		options:    options,
+		now:        now,
+		manifest: Manifest{
+			CreatedAt: now,
+			Options:   options,
+			Files:     make([]ManifestFile, 0),
+		},
+	}
+}
+
+// Collect runs the sections and writes the archive with the manifest to the writer.
+func (c *Collector) Collect(ctx context.Context, w io.Writer, sections []Section) error {
+	gzipWriter := gzip.NewWriter(w)
+	c.tar = tar.NewWriter(gzipWriter)
+
+	for _, section := range sections {
+		c.section = section.Name
+		section.Collect(ctx, c)
+	}
+
+	c.section = ""
+	data, _ := json.MarshalIndent(c.manifest, "", "  ")
+	if err := c.writeFile(manifestFile, data); err != nil {
+		return err
+	}
+
+	if err := c.tar.Close(); err != nil {
+		return fmt.Errorf("close tar: %w", err)
+	}
+
+	return gzipWriter.Close()
+}
+
+// Manifest returns the manifest of the collected archive.
+func (c *Collector) Manifest() Manifest {
+	return c.manifest
+}
+
+func (c *Collector) fail(file string, err error) {
+	c.manifest.Errors = append(c.manifest.Errors, ManifestError{Section: c.section, File: file, Error: err.Error()})
+}
+
+func (c *Collector) add(file string, data []byte, redactions int) {
+	if err := c.writeFile(file, data); err != nil {
+		c.fail(file, err)
+		return
+	}
+
+	c.manifest.Files = append(c.manifest.Files, ManifestFile{Name: file, Section: c.section, Size: len(data), Redactions: redactions})
+}
+
+func (c *Collector) writeFile(file string, data []byte) error {
+	header := &tar.Header{
+		Name:    file,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: c.now,
+	}
+
+	if err := c.tar.WriteHeader(header); err != nil {
+		return fmt.Errorf("write tar header: %w", err)
+	}
+
+	if _, err := c.tar.Write(data); err != nil {
+		return fmt.Errorf("copy content: %w", err)
+	}
+
+	return nil
+}
+
+// addText redacts and saves the text.
+func (c *Collector) addText(file string, text string) {
+	text, redactions := redactText(text)
+	c.add(file, []byte(text), redactions)
+}
+
+// addObjects redacts and saves the objects as a JSON array.
+func (c *Collector) addObjects(file string, objects []map[string]interface{}) {
+	var redactions int
+	for _, obj := range objects {
+		redactions += redactObject(obj)
+	}
+
+	data, err := json.MarshalIndent(objects, "", "  ")
+	if err != nil {
+		c.fail(file, err)
+		return
+	}
+
+	c.add(file, data, redactions)
+}
+
+// addObject redacts and saves the object.
+func (c *Collector) addObject(file string, obj map[string]interface{}) {
+	redactions := redactObject(obj)
+
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		c.fail(file, err)
+		return
+	}
+
+	c.add(file, data, redactions)
+}
+
+// SaveDebugEndpoint saves the response of the addon-operator debug server, e.g., http://unix/queue/list.text.
+func (c *Collector) SaveDebugEndpoint(file, url string) {
+	data, err := c.debugGet(url)
+	if err != nil {
+		c.fail(file, fmt.Errorf("request %s: %w", url, err))
+		return
+	}
+
+	var obj map[string]interface{}
+	if json.Unmarshal(data, &obj) == nil {
+		c.addObject(file, obj)
+		return
+	}
+
+	c.addText(file, string(data))
+}
+
+// SaveObjects saves objects of the kind, the namespace and the label selector are optional.
+func (c *Collector) SaveObjects(ctx context.Context, file, apiVersion, kind, namespace, selector string) {
+	objects, err := c.listObjects(ctx, apiVersion, kind, namespace, selector)
+	if err != nil {
+		c.fail(file, err)
+		return
+	}
+
+	c.addObjects(file, objects)
+}
+
+type objectKind struct {
+	apiVersion string
+	kind       string
+}
+
+// saveObjectsOfKinds saves objects of several kinds to one file, kinds absent in the cluster are recorded as errors.
+func (c *Collector) saveObjectsOfKinds(ctx context.Context, file string, kinds []objectKind, namespaces []string, selector string) {
+	all := make([]map[string]interface{}, 0)
+	for _, kind := range kinds {
+		for _, namespace := range namespaces {
+			objects, err := c.listObjects(ctx, kind.apiVersion, kind.kind, namespace, selector)
+			if err != nil {
+				c.fail(file, err)
+				continue
+			}
+			all = append(all, objects...)
+		}
+	}
+
+	c.addObjects(file, all)
+}
+
+func (c *Collector) listObjects(ctx context.Context, apiVersion, kind, namespace, selector string) ([]map[string]interface{}, error) {
+	gvr, err := c.kubeClient.GroupVersionResource(apiVersion, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := c.kubeClient.Dynamic().Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("list %s: %w", gvr.Resource, err)
+	}
+
+	objects := make([]map[string]interface{}, 0, len(list.Items))
+	for _, item := range list.Items {
+		objects = append(objects, item.Object)
+	}
+
+	return objects, nil
+}
+
+// SaveEvents saves events of the namespaces (all namespaces if empty) sorted by time.
+func (c *Collector) SaveEvents(ctx context.Context, file string, namespaces []string) {
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	var events []corev1.Event
+	for _, namespace := range namespaces {
+		list, err := c.kubeClient.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			c.fail(file, fmt.Errorf("list events: %w", err))
+			continue
+		}
+
+		for _, event := range list.Items {
+			if c.options.Since > 0 && eventTime(event).Before(c.now.Add(-c.options.Since)) {
+				continue
+			}
+			events = append(events, event)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(events[i]).Before(eventTime(events[j]))
+	})
+
+	objects := make([]map[string]interface{}, 0, len(events))
+	for i := range events {
+		obj, err := toObject(&events[i])
+		if err != nil {
+			c.fail(file, err)
+			return
+		}
+		objects = append(objects, obj)
+	}
+
+	c.addObjects(file, objects)
+}
+
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+func toObject(obj interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var res map[string]interface{}
+	err = json.Unmarshal(data, &res)
+
+	return res, err
+}
+
+// SaveLogs saves logs of the container of pods matching the selector, logs of all containers are saved if the container is empty.
+func (c *Collector) SaveLogs(ctx context.Context, file, namespace, selector, container string) {
+	var buf bytes.Buffer
+	if err := c.writePodsLogs(ctx, &buf, file, namespace, selector, container); err != nil {
+		c.fail(file, err)
+		return
+	}
+
+	c.addText(file, buf.String())
+}
+
+// writePodsLogs writes logs of the pods to the buffer, failed containers are recorded as errors of the file.
+func (c *Collector) writePodsLogs(ctx context.Context, buf *bytes.Buffer, file, namespace, selector, container string) error {
+	pods, err := c.kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("list pods: %w", err)
+	}
+
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("no pods found in the namespace %q by the selector %q", namespace, selector)
+	}
+
+	for _, pod := range pods.Items {
+		for _, podContainer := range pod.Spec.Containers {
+			if container != "" && podContainer.Name != container {
+				continue
+			}
+
+			fmt.Fprintf(buf, "==> %s/%s/%s <==\n", pod.Namespace, pod.Name, podContainer.Name)
+			logs, err := c.podLogs(ctx, pod, podContainer.Name)
+			if err != nil {
+				c.fail(file, fmt.Errorf("get logs of %s/%s/%s: %w", pod.Namespace, pod.Name, podContainer.Name, err))
+				continue
+			}
+			buf.Write(logs)
+			buf.WriteString("\n")
+		}
+	}
+
+	return nil
+}
+
+func (c *Collector) podLogs(ctx context.Context, pod corev1.Pod, container string) ([]byte, error) {
+	options := &corev1.PodLogOptions{Container: container}
+	if c.options.Tail > 0 {
+		options.TailLines = &c.options.Tail
+	}
+	if c.options.Since > 0 {
+		since := int64(c.options.Since.Seconds())
+		options.SinceSeconds = &since
+	}
+
+	return c.kubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, options).DoRaw(ctx)
+}
+
+// SaveExec saves the output of the command executed in the container of the first running pod matching the selector.
+func (c *Collector) SaveExec(ctx context.Context, file, namespace, selector, container string, command []string) {
+	pods, err := c.kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		c.fail(file, fmt.Errorf("list pods: %w", err))
+		return
+	}
+
+	var pod *corev1.Pod
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase == corev1.PodRunning {
+			pod = &pods.Items[i]
+			break
+		}
+	}
+	if pod == nil {
+		c.fail(file, fmt.Errorf("no running pods found in the namespace %q by the selector %q", namespace, selector))
+		return
+	}
+
+	req := c.kubeClient.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(c.kubeClient.RestConfig(), "POST", req.URL())
+	if err != nil {
+		c.fail(file, fmt.Errorf("exec in %s/%s: %w", pod.Namespace, pod.Name, err))
+		return
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		c.fail(file, fmt.Errorf("exec in %s/%s: %w: %s", pod.Namespace, pod.Name, err, stderr.String()))
+		return
+	}
+
+	var obj map[string]interface{}
+	if json.Unmarshal(stdout.Bytes(), &obj) == nil {
+		c.addObject(file, obj)
+		return
+	}
+
+	c.addText(file, stdout.String())
+}
+
+// SaveObject saves the custom data as a JSON object.
+func (c *Collector) SaveObject(file string, obj interface{}) {
+	data, err := toObject(obj)
+	if err != nil {
+		c.fail(file, err)
+		return
+	}
+
+	c.addObject(file, data)
+}
+
+// getObject returns the object by its name
+func (c *Collector) getObject(ctx context.Context, apiVersion, kind, namespace, name string) (*unstructured.Unstructured, error) {
+	gvr, err := c.kubeClient.GroupVersionResource(apiVersion, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.kubeClient.Dynamic().Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/flant/kube-client/fake"
+	"github.com/flant/kube-client/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollect(t *testing.T) {
+	cluster := fake.NewFakeCluster(fake.ClusterVersionV125)
+	require.NoError(t, cluster.Create("d8-system", manifest.MustFromYAML(`
+apiVersion: v1
+kind: Secret
+metadata:
+  name: deckhouse-registry
+  namespace: d8-system
+data:
+  .dockerconfigjson: eyJhdXRocyI6e319
+`)))
+
+	debugGet := func(url string) ([]byte, error) {
+		if url == "http://unix/global/values.json" {
+			return []byte(`{"global":{"modulesImages":{"registry":{"dockercfg":"abc"}},"password":"qwerty"}}`), nil
+		}
+		return nil, errors.New("connection refused")
+	}
+
+	sections := []Section{
+		{
+			Name: "secrets",
+			Collect: func(ctx context.Context, c *Collector) {
+				c.SaveObjects(ctx, "secrets.json", "v1", "Secret", "d8-system", "")
+			},
+		},
+		{
+			Name: "values",
+			Collect: func(_ context.Context, c *Collector) {
+				c.SaveDebugEndpoint("global-values.json", "http://unix/global/values.json")
+				c.SaveDebugEndpoint("queue.txt", "http://unix/queue/list.text")
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	collector := NewCollector(cluster.Client, debugGet, Options{Tail: 100})
+	require.NoError(t, collector.Collect(context.Background(), &buf, sections))
+
+	files := readArchive(t, &buf)
+	require.Contains(t, files, "secrets.json")
+	require.Contains(t, files, "global-values.json")
+	require.Contains(t, files, manifestFile)
+	assert.NotContains(t, files, "queue.txt")
+
+	assert.NotContains(t, files["secrets.json"], "eyJhdXRocyI6e319")
+	assert.Contains(t, files["secrets.json"], `".dockerconfigjson": "REDACTED"`)
+	assert.NotContains(t, files["global-values.json"], "qwerty")
+
+	var m Manifest
+	require.NoError(t, json.Unmarshal([]byte(files[manifestFile]), &m))
+	assert.Equal(t, int64(100), m.Options.Tail)
+	require.Len(t, m.Files, 2)
+	assert.Equal(t, ManifestFile{Name: "secrets.json", Section: "secrets", Size: len(files["secrets.json"]), Redactions: 1}, m.Files[0])
+	assert.Equal(t, "global-values.json", m.Files[1].Name)
+	assert.Equal(t, 1, m.Files[1].Redactions)
+	require.Len(t, m.Errors, 1)
+	assert.Equal(t, ManifestError{
+		Section: "values",
+		File:    "queue.txt",
+		Error:   "request http://unix/queue/list.text: connection refused",
+	}, m.Errors[0])
+}
+
+func TestBuildSections(t *testing.T) {
+	sections, err := BuildSections(Options{})
+	require.NoError(t, err)
+	assert.Equal(t, SectionNames(), sectionNames(sections))
+
+	sections, err = BuildSections(Options{Modules: []string{"prometheus"}, Namespaces: []string{"default"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"queue", "deckhouse-version", "module/prometheus", "namespace/default"}, sectionNames(sections))
+
+	sections, err = BuildSections(Options{Sections: []string{"nodes"}, Modules: []string{"prometheus"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nodes", "module/prometheus"}, sectionNames(sections))
+
+	_, err = BuildSections(Options{Sections: []string{"unknown"}})
+	assert.ErrorContains(t, err, `unknown section "unknown"`)
+}
+
+func sectionNames(sections []Section) []string {
+	names := make([]string, 0, len(sections))
+	for _, section := range sections {
+		names = append(names, section.Name)
+	}
+	return names
+}
+
+func readArchive(t *testing.T, r io.Reader) map[string]string {
+	gzipReader, err := gzip.NewReader(r)
+	require.NoError(t, err)
+
+	files := make(map[string]string)
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		data, err := io.ReadAll(tarReader)
+		require.NoError(t, err)
+		files[header.Name] = string(data)
+	}
+
+	return files
+}
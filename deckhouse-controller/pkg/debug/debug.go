@@ -15,175 +15,55 @@
 package debug
 
 import (
-	"archive/tar"
-	"bytes"
-	"compress/gzip"
+	"context"
 	"fmt"
-	"io"
 	"os"
-	"os/exec"
+	"strings"
 
+	"github.com/flant/kube-client/client"
+	sh_debug "github.com/flant/shell-operator/pkg/debug"
+	log "github.com/sirupsen/logrus"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
-type Command struct {
-	Cmd  string
-	Args []string
-
-	File string
-}
-
-func (c *Command) Save(tarWriter *tar.Writer) error {
-	fileContent, err := exec.Command(c.Cmd, c.Args...).Output()
-	if err != nil {
-		return fmt.Errorf("execute %s %s command: %v", c.Cmd, c.Args, err)
-	}
-
-	header := &tar.Header{
-		Name: c.File,
-		Mode: 0600,
-		Size: int64(len(fileContent)),
-	}
-
-	if err := tarWriter.WriteHeader(header); err != nil {
-		return fmt.Errorf("write tar header: %v", err)
-	}
-
-	reader := bytes.NewReader(fileContent)
-
-	if _, err := io.Copy(tarWriter, reader); err != nil {
-		return fmt.Errorf("copy content: %v", err)
-	}
-
-	return nil
-}
-
-func createTarball() *bytes.Buffer {
-	var buf bytes.Buffer
+func DefineCollectDebugInfoCommand(kpApp *kingpin.Application) {
+	var options Options
+
+	collectDebug := kpApp.Command("collect-debug-info", "Collect debug info from your cluster. The gzipped tarball is written to stdout.")
+	collectDebug.Flag("section", fmt.Sprintf("Cluster-wide section to collect, can be repeated. All sections are collected by default: %s.", strings.Join(SectionNames(), ", "))).
+		StringsVar(&options.Sections)
+	collectDebug.Flag("module", "Collect values, configuration, objects, logs and events of the module, can be repeated. Use snake-case for the module name.").
+		StringsVar(&options.Modules)
+	collectDebug.Flag("namespace", "Collect objects, logs and events of the namespace, can be repeated.").
+		StringsVar(&options.Namespaces)
+	collectDebug.Flag("since", "Collect only logs and events newer than the duration, e.g., 1h.").
+		DurationVar(&options.Since)
+	collectDebug.Flag("tail", "Number of the last log lines to collect of every container.").
+		Default("3000").Int64Var(&options.Tail)
 
-	gzipWriter := gzip.NewWriter(&buf)
-	defer gzipWriter.Close()
+	collectDebug.Action(func(c *kingpin.ParseContext) error {
+		log.SetLevel(log.ErrorLevel)
 
-	tarWriter := tar.NewWriter(gzipWriter)
-	defer tarWriter.Close()
+		sections, err := BuildSections(options)
+		if err != nil {
+			return err
+		}
 
-	debugCommands := []Command{
-		{
-			File: "queue.txt",
-			Cmd:  "deckhouse-controller",
-			Args: []string{"queue", "list"},
-		},
-		{
-			File: "global-values.json",
-			Cmd:  "bash",
-			Args: []string{"-c", `deckhouse-controller global values -o json | jq .`},
-		},
-		{
-			File: "deckhouse-enabled-modules.json",
-			Cmd:  "bash",
-			Args: []string{"-c", "kubectl get modules -o json | jq '.items[]'"},
-		},
-		{
-			File: "events.json",
-			Cmd:  "kubectl",
-			Args: []string{"get", "events", "--sort-by=.metadata.creationTimestamp", "-A", "-o", "json"},
-		},
-		{
-			File: "d8-all.json",
-			Cmd:  "bash",
-			Args: []string{"-c", `for ns in $(kubectl get ns -o go-template='{{range .items}}{{.metadata.name}}{{"\n"}}{{end}}{{"kube-system"}}' -l heritage=deckhouse); do kubectl -n $ns get all -o json; done | jq -s '[.[].items[]]'`},
-		},
-		{
-			File: "node-groups.json",
-			Cmd:  "kubectl",
-			Args: []string{"get", "nodegroups", "-A", "-o", "json"},
-		},
-		{
-			File: "nodes.json",
-			Cmd:  "kubectl",
-			Args: []string{"get", "nodes", "-A", "-o", "json"},
-		},
-		{
-			File: "machines.json",
-			Cmd:  "kubectl",
-			Args: []string{"get", "machines", "-A", "-o", "json"},
-		},
-		{
-			File: "deckhouse-version.json",
-			Cmd:  "bash",
-			Args: []string{"-c", "jq -s add <(kubectl -n d8-system get deployment deckhouse -o json | jq -r '.metadata.annotations | {\"core.deckhouse.io/edition\",\"core.deckhouse.io/version\"}') <(kubectl -n d8-system get deployment deckhouse -o json | jq -r '.spec.template.spec.containers[] | select(.name == \"deckhouse\") | {image}')"},
-		},
-		{
-			File: "deckhouse-releases.json",
-			Cmd:  "kubectl",
-			Args: []string{"get", "deckhousereleases", "-o", "json"},
-		},
-		{
-			File: "deckhouse-logs.json",
-			Cmd:  "kubectl",
-			Args: []string{"logs", "deploy/deckhouse", "--tail", "3000"},
-		},
-		{
-			File: "mcm-logs.txt",
-			Cmd:  "kubectl",
-			Args: []string{"-n", "d8-cloud-instance-manager", "logs", "-l", "app=machine-controller-manager", "--tail", "3000", "-c", "controller"},
-		},
-		{
-			File: "ccm-logs.txt",
-			Cmd:  "bash",
-			Args: []string{"-c", "kubectl -n $(kubectl get ns -o custom-columns=NAME:metadata.name | grep d8-cloud-provider) logs -l app=cloud-controller-manager --tail=3000"},
-		},
-		{
-			File: "cluster-autoscaler-logs.txt",
-			Cmd:  "kubectl",
-			Args: []string{"-n", "d8-cloud-instance-manager", "logs", "-l", "app=cluster-autoscaler", "--tail", "3000", "-c", "cluster-autoscaler"},
-		},
-		{
-			File: "vpa-admission-controller-logs.txt",
-			Cmd:  "kubectl",
-			Args: []string{"-n", "kube-system", "logs", "-l", "app=vpa-admission-controller", "--tail", "3000", "-c", "admission-controller"},
-		},
-		{
-			File: "vpa-recommender-logs.txt",
-			Cmd:  "kubectl",
-			Args: []string{"-n", "kube-system", "logs", "-l", "app=vpa-recommender", "--tail", "3000", "-c", "recommender"},
-		},
-		{
-			File: "vpa-updater-logs.txt",
-			Cmd:  "kubectl",
-			Args: []string{"-n", "kube-system", "logs", "-l", "app=vpa-updater", "--tail", "3000", "-c", "updater"},
-		},
-		{
-			File: "prometheus-logs.txt",
-			Cmd:  "kubectl",
-			Args: []string{"-n", "d8-monitoring", "logs", "-l", "prometheus=main", "--tail", "3000", "-c", "prometheus"},
-		},
-		{
-			File: "terraform-check.json",
-			Cmd:  "kubectl",
-			Args: []string{"exec", "deploy/terraform-state-exporter", "--", "dhctl", "terraform", "check", "--logger-type", "json", "-o", "json"},
-		},
-		{
-			File: "alerts.json",
-			Cmd:  "bash",
-			Args: []string{"-c", `kubectl get clusteralerts.deckhouse.io -o json | jq '.items[]'`},
-		},
-	}
+		kubeClient := client.New()
+		if err = kubeClient.Init(); err != nil {
+			return err
+		}
 
-	for _, cmd := range debugCommands {
-		if err := cmd.Save(tarWriter); err != nil {
-			fmt.Fprint(os.Stderr, err.Error())
+		collector := NewCollector(kubeClient, sh_debug.DefaultClient().Get, options)
+		if err = collector.Collect(context.Background(), os.Stdout, sections); err != nil {
+			return err
 		}
-	}
 
-	return &buf
-}
+		// the archive is written to stdout, so errors are reported to stderr
+		for _, collectErr := range collector.Manifest().Errors {
+			fmt.Fprintf(os.Stderr, "%s: %s: %s\n", collectErr.Section, collectErr.File, collectErr.Error)
+		}
 
-func DefineCollectDebugInfoCommand(kpApp *kingpin.Application) {
-	collectDebug := kpApp.Command("collect-debug-info", "Collect debug info from your cluster.")
-	collectDebug.Action(func(c *kingpin.ParseContext) error {
-		res := createTarball()
-		_, err := io.Copy(os.Stdout, res)
-		return err
+		return nil
 	})
 }
@@ -0,0 +1,143 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"regexp"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+var (
+	privateKeyRe = regexp.MustCompile(`-----BEGIN ([A-Z0-9 ]*)PRIVATE KEY-----[\s\S]*?-----END ([A-Z0-9 ]*)PRIVATE KEY-----`)
+	bearerRe     = regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9._~+/-]+=*`)
+	basicAuthRe  = regexp.MustCompile(`(?i)(authorization["']?\s*[:=]\s*["']?basic\s+)[A-Za-z0-9+/]+=*`)
+	jwtRe        = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	urlAuthRe    = regexp.MustCompile(`(://[^:/@\s"']+:)[^@/\s"']+@`)
+	// "password": "value", password=value, --token value is not matched intentionally to avoid false positives
+	keyValueRe = regexp.MustCompile(`(?i)((?:password|passwd|secret|token|api[_-]?key|private[_-]?key|access[_-]?key|secret[_-]?key)\\?["']?\s*[:=]\s*\\?["']?)([^"'\\\s,;&}]+)`)
+
+	sensitiveKeyRe = regexp.MustCompile(`(?i)(password|passwd|secret|token|apikey|api_key|privatekey|private_key|accesskey|access_key|credentials|kubeconfig|\.key$)`)
+	// keys referencing the sensitive data instead of containing it
+	referenceKeyRe = regexp.MustCompile(`(?i)(name|ref|refs|path|file|ttl|namespace|selector|mode|type|version|expiration|expirationseconds|audience|audiences)$`)
+)
+
+// redactText replaces private keys, tokens and passwords in the text and returns the number of replacements.
+func redactText(text string) (string, int) {
+	var count int
+
+	replace := func(re *regexp.Regexp, repl string) {
+		text = re.ReplaceAllStringFunc(text, func(match string) string {
+			count++
+			return re.ReplaceAllString(match, repl)
+		})
+	}
+
+	replace(privateKeyRe, "-----BEGIN ${1}PRIVATE KEY-----"+redacted+"-----END ${2}PRIVATE KEY-----")
+	replace(jwtRe, redacted)
+	replace(bearerRe, "${1}"+redacted)
+	replace(basicAuthRe, "${1}"+redacted)
+	replace(urlAuthRe, "${1}"+redacted+"@")
+	text = keyValueRe.ReplaceAllStringFunc(text, func(match string) string {
+		groups := keyValueRe.FindStringSubmatch(match)
+		if groups[2] == redacted {
+			return match
+		}
+		count++
+		return groups[1] + redacted
+	})
+
+	return text, count
+}
+
+// redactObject replaces sensitive data in the Kubernetes object in place and returns the number of replacements:
+//   - values of Secrets,
+//   - values of the fields with sensitive names, e.g., password or token,
+//   - values of the environment variables with sensitive names,
+//   - private keys, tokens and passwords in all string fields.
+func redactObject(obj map[string]interface{}) int {
+	var count int
+
+	if kind, _ := obj["kind"].(string); kind == "Secret" {
+		for _, field := range []string{"data", "stringData"} {
+			data, ok := obj[field].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for key := range data {
+				data[key] = redacted
+				count++
+			}
+		}
+	}
+
+	return count + redactValue(obj)
+}
+
+func redactValue(value interface{}) int {
+	var count int
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		// environment variable or a similar name-value pair
+		if name, ok := v["name"].(string); ok && isSensitiveKey(name) {
+			if val, ok := v["value"].(string); ok && val != "" && val != redacted {
+				v["value"] = redacted
+				count++
+			}
+		}
+
+		for key, val := range v {
+			if s, ok := val.(string); ok {
+				if s != "" && s != redacted && isSensitiveKey(key) {
+					v[key] = redacted
+					count++
+					continue
+				}
+
+				var n int
+				v[key], n = redactText(s)
+				count += n
+				continue
+			}
+
+			count += redactValue(val)
+		}
+
+	case []interface{}:
+		for i, item := range v {
+			if s, ok := item.(string); ok {
+				var n int
+				v[i], n = redactText(s)
+				count += n
+				continue
+			}
+
+			count += redactValue(item)
+		}
+	}
+
+	return count
+}
+
+func isSensitiveKey(key string) bool {
+	// annotations and labels keys contain a domain prefix
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		key = key[i+1:]
+	}
+
+	return sensitiveKeyRe.MatchString(key) && !referenceKeyRe.MatchString(key)
+}
@@ -0,0 +1,347 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// workloadKinds are the kinds shown by "kubectl get all"
+var workloadKinds = []objectKind{
+	{"v1", "Pod"},
+	{"v1", "Service"},
+	{"apps/v1", "Deployment"},
+	{"apps/v1", "DaemonSet"},
+	{"apps/v1", "StatefulSet"},
+	{"apps/v1", "ReplicaSet"},
+	{"batch/v1", "Job"},
+	{"batch/v1", "CronJob"},
+}
+
+// controllerKinds are the kinds whose pods are collected for modules
+var controllerKinds = []objectKind{
+	{"apps/v1", "Deployment"},
+	{"apps/v1", "DaemonSet"},
+	{"apps/v1", "StatefulSet"},
+}
+
+// clusterSections are collected by default.
+var clusterSections = []Section{
+	{
+		Name: "queue",
+		Collect: func(_ context.Context, c *Collector) {
+			c.SaveDebugEndpoint("queue.txt", "http://unix/queue/list.text?showEmpty=false")
+		},
+	},
+	{
+		Name: "global-values",
+		Collect: func(_ context.Context, c *Collector) {
+			c.SaveDebugEndpoint("global-values.json", "http://unix/global/values.json")
+		},
+	},
+	{
+		Name: "modules",
+		Collect: func(ctx context.Context, c *Collector) {
+			c.SaveObjects(ctx, "deckhouse-enabled-modules.json", "deckhouse.io/v1alpha1", "Module", "", "")
+		},
+	},
+	{
+		Name: "events",
+		Collect: func(ctx context.Context, c *Collector) {
+			c.SaveEvents(ctx, "events.json", nil)
+		},
+	},
+	{
+		Name: "d8-all",
+		Collect: func(ctx context.Context, c *Collector) {
+			namespaces, err := c.namespaces(ctx, "heritage=deckhouse")
+			if err != nil {
+				c.fail("d8-all.json", err)
+				return
+			}
+
+			c.saveObjectsOfKinds(ctx, "d8-all.json", workloadKinds, append(namespaces, "kube-system"), "")
+		},
+	},
+	{
+		Name: "node-groups",
+		Collect: func(ctx context.Context, c *Collector) {
+			c.SaveObjects(ctx, "node-groups.json", "deckhouse.io/v1", "NodeGroup", "", "")
+		},
+	},
+	{
+		Name: "nodes",
+		Collect: func(ctx context.Context, c *Collector) {
+			c.SaveObjects(ctx, "nodes.json", "v1", "Node", "", "")
+		},
+	},
+	{
+		Name: "machines",
+		Collect: func(ctx context.Context, c *Collector) {
+			c.SaveObjects(ctx, "machines.json", "machine.sapcloud.io/v1alpha1", "Machine", "", "")
+		},
+	},
+	{
+		Name:    "deckhouse-version",
+		Collect: collectDeckhouseVersion,
+	},
+	{
+		Name: "deckhouse-releases",
+		Collect: func(ctx context.Context, c *Collector) {
+			c.SaveObjects(ctx, "deckhouse-releases.json", "deckhouse.io/v1alpha1", "DeckhouseRelease", "", "")
+		},
+	},
+	{
+		Name:    "logs",
+		Collect: collectComponentsLogs,
+	},
+	{
+		Name: "terraform-check",
+		Collect: func(ctx context.Context, c *Collector) {
+			c.SaveExec(ctx, "terraform-check.json", "d8-system", "app=terraform-state-exporter", "exporter",
+				[]string{"dhctl", "terraform", "check", "--logger-type", "json", "-o", "json"})
+		},
+	},
+	{
+		Name: "alerts",
+		Collect: func(ctx context.Context, c *Collector) {
+			c.SaveObjects(ctx, "alerts.json", "deckhouse.io/v1alpha1", "ClusterAlert", "", "")
+		},
+	},
+}
+
+// scopedSections are collected by default if the archive is scoped to modules or namespaces.
+var scopedSections = []string{"queue", "deckhouse-version"}
+
+// SectionNames returns names of the cluster-wide sections.
+func SectionNames() []string {
+	names := make([]string, 0, len(clusterSections))
+	for _, section := range clusterSections {
+		names = append(names, section.Name)
+	}
+
+	return names
+}
+
+// BuildSections returns the sections to collect according to the options.
+func BuildSections(options Options) ([]Section, error) {
+	names := options.Sections
+	if len(names) == 0 {
+		names = SectionNames()
+		if len(options.Modules) > 0 || len(options.Namespaces) > 0 {
+			names = scopedSections
+		}
+	}
+
+	sections := make([]Section, 0, len(names)+len(options.Modules)+len(options.Namespaces))
+	for _, name := range names {
+		section, ok := findSection(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown section %q, known sections: %s", name, strings.Join(SectionNames(), ", "))
+		}
+		sections = append(sections, section)
+	}
+
+	for _, module := range options.Modules {
+		sections = append(sections, moduleSection(module))
+	}
+
+	for _, namespace := range options.Namespaces {
+		sections = append(sections, namespaceSection(namespace))
+	}
+
+	return sections, nil
+}
+
+func findSection(name string) (Section, bool) {
+	for _, section := range clusterSections {
+		if section.Name == name {
+			return section, true
+		}
+	}
+
+	return Section{}, false
+}
+
+// moduleSection collects values, configuration, objects, logs and events of the module.
+// Objects of the module are found by the "module" label set by the helm_lib_module_labels helper.
+func moduleSection(module string) Section {
+	dir := path.Join("modules", module)
+	selector := "module=" + module
+
+	return Section{
+		Name: "module/" + module,
+		Collect: func(ctx context.Context, c *Collector) {
+			c.SaveDebugEndpoint(path.Join(dir, "values.json"), fmt.Sprintf("http://unix/module/%s/values.json?global=false", module))
+			c.saveNamedObject(ctx, path.Join(dir, "module.json"), "deckhouse.io/v1alpha1", "Module", "", module)
+			c.saveNamedObject(ctx, path.Join(dir, "module-config.json"), "deckhouse.io/v1alpha1", "ModuleConfig", "", module)
+			c.saveObjectsOfKinds(ctx, path.Join(dir, "objects.json"), workloadKinds, []string{metav1.NamespaceAll}, selector)
+
+			logsFile := path.Join(dir, "logs.txt")
+			var (
+				buf        bytes.Buffer
+				namespaces = make(map[string]struct{})
+			)
+			for _, kind := range controllerKinds {
+				controllers, err := c.listObjects(ctx, kind.apiVersion, kind.kind, metav1.NamespaceAll, selector)
+				if err != nil {
+					c.fail(logsFile, err)
+					continue
+				}
+
+				for _, obj := range controllers {
+					controller := unstructured.Unstructured{Object: obj}
+					matchLabels, _, _ := unstructured.NestedStringMap(obj, "spec", "selector", "matchLabels")
+					if len(matchLabels) == 0 {
+						continue
+					}
+
+					namespaces[controller.GetNamespace()] = struct{}{}
+					err = c.writePodsLogs(ctx, &buf, logsFile, controller.GetNamespace(), labels.SelectorFromSet(matchLabels).String(), "")
+					if err != nil {
+						c.fail(logsFile, err)
+					}
+				}
+			}
+			c.addText(logsFile, buf.String())
+
+			eventNamespaces := make([]string, 0, len(namespaces))
+			for namespace := range namespaces {
+				eventNamespaces = append(eventNamespaces, namespace)
+			}
+			sort.Strings(eventNamespaces)
+			if len(eventNamespaces) > 0 {
+				c.SaveEvents(ctx, path.Join(dir, "events.json"), eventNamespaces)
+			}
+		},
+	}
+}
+
+// namespaceSection collects objects, logs and events of the namespace.
+func namespaceSection(namespace string) Section {
+	dir := path.Join("namespaces", namespace)
+
+	return Section{
+		Name: "namespace/" + namespace,
+		Collect: func(ctx context.Context, c *Collector) {
+			kinds := append(workloadKinds,
+				objectKind{"v1", "PersistentVolumeClaim"},
+				objectKind{"v1", "ConfigMap"},
+				objectKind{"networking.k8s.io/v1", "Ingress"},
+			)
+			c.saveObjectsOfKinds(ctx, path.Join(dir, "objects.json"), kinds, []string{namespace}, "")
+			c.SaveLogs(ctx, path.Join(dir, "logs.txt"), namespace, "", "")
+			c.SaveEvents(ctx, path.Join(dir, "events.json"), []string{namespace})
+		},
+	}
+}
+
+func collectDeckhouseVersion(ctx context.Context, c *Collector) {
+	const file = "deckhouse-version.json"
+
+	deployment, err := c.kubeClient.AppsV1().Deployments("d8-system").Get(ctx, "deckhouse", metav1.GetOptions{})
+	if err != nil {
+		c.fail(file, err)
+		return
+	}
+
+	version := map[string]string{
+		"core.deckhouse.io/edition": deployment.Annotations["core.deckhouse.io/edition"],
+		"core.deckhouse.io/version": deployment.Annotations["core.deckhouse.io/version"],
+	}
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name == "deckhouse" {
+			version["image"] = container.Image
+		}
+	}
+
+	c.SaveObject(file, version)
+}
+
+func collectComponentsLogs(ctx context.Context, c *Collector) {
+	components := []struct {
+		file      string
+		namespace string
+		selector  string
+		container string
+	}{
+		{"deckhouse-logs.txt", "d8-system", "app=deckhouse", "deckhouse"},
+		{"mcm-logs.txt", "d8-cloud-instance-manager", "app=machine-controller-manager", "controller"},
+		{"cluster-autoscaler-logs.txt", "d8-cloud-instance-manager", "app=cluster-autoscaler", "cluster-autoscaler"},
+		{"vpa-admission-controller-logs.txt", "kube-system", "app=vpa-admission-controller", "admission-controller"},
+		{"vpa-recommender-logs.txt", "kube-system", "app=vpa-recommender", "recommender"},
+		{"vpa-updater-logs.txt", "kube-system", "app=vpa-updater", "updater"},
+		{"prometheus-logs.txt", "d8-monitoring", "prometheus=main", "prometheus"},
+	}
+
+	for _, component := range components {
+		c.SaveLogs(ctx, component.file, component.namespace, component.selector, component.container)
+	}
+
+	// the namespace of the cloud controller manager depends on the provider
+	const ccmFile = "ccm-logs.txt"
+	namespaces, err := c.namespaces(ctx, "")
+	if err != nil {
+		c.fail(ccmFile, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	for _, namespace := range namespaces {
+		if !strings.HasPrefix(namespace, "d8-cloud-provider-") {
+			continue
+		}
+		if err = c.writePodsLogs(ctx, &buf, ccmFile, namespace, "app=cloud-controller-manager", ""); err != nil {
+			c.fail(ccmFile, err)
+		}
+	}
+	if buf.Len() > 0 {
+		c.addText(ccmFile, buf.String())
+	}
+}
+
+func (c *Collector) namespaces(ctx context.Context, selector string) ([]string, error) {
+	list, err := c.kubeClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("list namespaces: %w", err)
+	}
+
+	namespaces := make([]string, 0, len(list.Items))
+	for _, namespace := range list.Items {
+		namespaces = append(namespaces, namespace.Name)
+	}
+
+	return namespaces, nil
+}
+
+// saveNamedObject saves the object by its name.
+func (c *Collector) saveNamedObject(ctx context.Context, file, apiVersion, kind, namespace, name string) {
+	obj, err := c.getObject(ctx, apiVersion, kind, namespace, name)
+	if err != nil {
+		c.fail(file, err)
+		return
+	}
+
+	c.addObject(file, obj.Object)
+}
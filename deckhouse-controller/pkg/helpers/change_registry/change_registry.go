@@ -51,6 +51,7 @@ import (
 const (
 	d8SystemNS = "d8-system"
 	caKey      = "ca"
+	mirrorsKey = "mirrors"
 )
 
 func ChangeRegistry(newRegistry, username, password, caFile, newDeckhouseImageTag string, insecure, dryRun bool) error {
@@ -182,6 +183,8 @@ func modifyPullSecret(ctx context.Context, kubeCl *kclient.KubernetesClient, new
 	deckhouseRegSecret.StringData = newSecretData
 
 	delete(deckhouseRegSecret.Data, caKey)
+	// the mirrors belong to the previous registry
+	delete(deckhouseRegSecret.Data, mirrorsKey)
 
 	return deckhouseRegSecret, nil
 }
@@ -0,0 +1,304 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package effectivepermissions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	kclient "github.com/deckhouse/deckhouse/dhctl/pkg/kubernetes/client"
+)
+
+var (
+	clusterAuthorizationRuleGVR = schema.GroupVersionResource{Group: "deckhouse.io", Version: "v1", Resource: "clusterauthorizationrules"}
+	authorizationRuleGVR        = schema.GroupVersionResource{Group: "deckhouse.io", Version: "v1alpha1", Resource: "authorizationrules"}
+)
+
+const clusterWideTitle = "(cluster-wide)"
+
+// EffectivePermissions prints the permissions granted to the subject by the ClusterAuthorizationRules and AuthorizationRules.
+// If the proposed file is set, it prints the difference made by the rules of the file instead.
+func EffectivePermissions(user string, groups []string, serviceAccount, namespace, proposedFile string) error {
+	subject, err := newSubject(user, groups, serviceAccount)
+	if err != nil {
+		return err
+	}
+
+	var proposed []AuthRule
+	if proposedFile != "" {
+		proposed, err = readProposedRules(proposedFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	kubeCl := kclient.NewKubernetesClient()
+	if err := kubeCl.Init(kclient.AppKubernetesInitParams()); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	cluster, err := loadCluster(ctx, kubeCl)
+	if err != nil {
+		return err
+	}
+
+	rules, err := loadRules(ctx, kubeCl)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	current, warnings := Resolve(cluster, rules, subject, now)
+	printWarnings(os.Stderr, warnings)
+
+	if proposedFile == "" {
+		return printPermissions(os.Stdout, filterNamespace(current, namespace))
+	}
+
+	next, warnings := Resolve(cluster, mergeRules(rules, proposed), subject, now)
+	printWarnings(os.Stderr, warnings)
+
+	return printChanges(os.Stdout, Diff(filterNamespace(current, namespace), filterNamespace(next, namespace)))
+}
+
+func newSubject(user string, groups []string, serviceAccount string) (Subject, error) {
+	if serviceAccount != "" {
+		if user != "" {
+			return Subject{}, errors.New("--user and --service-account cannot be used together")
+		}
+		namespace, name, ok := strings.Cut(serviceAccount, "/")
+		if !ok || namespace == "" || name == "" {
+			return Subject{}, fmt.Errorf("invalid service account %q, <namespace>/<name> is expected", serviceAccount)
+		}
+		subject := NewServiceAccountSubject(namespace, name)
+		subject.Groups = append(subject.Groups, groups...)
+		return subject, nil
+	}
+
+	if user == "" && len(groups) == 0 {
+		return Subject{}, errors.New("one of --user, --group or --service-account is required")
+	}
+
+	subject := Subject{User: user, Groups: groups}
+	if user != "" {
+		subject.Groups = append(subject.Groups, "system:authenticated")
+	}
+	return subject, nil
+}
+
+func loadCluster(ctx context.Context, kubeCl *kclient.KubernetesClient) (*Cluster, error) {
+	cluster := &Cluster{NamespacedResources: make(map[string]bool)}
+
+	clusterRoles, err := kubeCl.RbacV1().ClusterRoles().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list ClusterRoles: %w", err)
+	}
+	cluster.ClusterRoles = clusterRoles.Items
+
+	namespaces, err := kubeCl.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list Namespaces: %w", err)
+	}
+	for _, ns := range namespaces.Items {
+		cluster.Namespaces = append(cluster.Namespaces, Namespace{Name: ns.Name, Labels: ns.Labels})
+	}
+
+	// the partial discovery result is used if some API groups are unavailable
+	resourceLists, err := kubeCl.Discovery().ServerPreferredResources()
+	if err != nil && len(resourceLists) == 0 {
+		return nil, fmt.Errorf("discover API resources: %w", err)
+	}
+	for _, list := range resourceLists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range list.APIResources {
+			cluster.NamespacedResources[baseResource(gv.Group, resource.Name)] = resource.Namespaced
+		}
+	}
+
+	// the namespaces are limited only by the user-authz webhook, it is deployed in the multi-tenancy mode
+	_, err = kubeCl.AppsV1().DaemonSets("d8-user-authz").Get(ctx, "user-authz-webhook", metav1.GetOptions{})
+	switch {
+	case err == nil:
+		cluster.MultiTenancy = true
+	case !apierrors.IsNotFound(err):
+		return nil, fmt.Errorf("get user-authz webhook: %w", err)
+	}
+
+	return cluster, nil
+}
+
+func loadRules(ctx context.Context, kubeCl *kclient.KubernetesClient) ([]AuthRule, error) {
+	rules := make([]AuthRule, 0)
+	for _, gvr := range []schema.GroupVersionResource{clusterAuthorizationRuleGVR, authorizationRuleGVR} {
+		list, err := kubeCl.Dynamic().Resource(gvr).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", gvr.Resource, err)
+		}
+		for i := range list.Items {
+			rule, err := authRuleFromUnstructured(&list.Items[i])
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func readProposedRules(path string) ([]AuthRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rules := make([]AuthRule, 0)
+	decoder := yaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		obj := new(unstructured.Unstructured)
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("decode %s: %w", path, err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+
+		switch obj.GetKind() {
+		case clusterAuthorizationRuleKind:
+			obj.SetNamespace("")
+		case authorizationRuleKind:
+			if obj.GetNamespace() == "" {
+				return nil, fmt.Errorf("%s: AuthorizationRule %s has no namespace", path, obj.GetName())
+			}
+		default:
+			return nil, fmt.Errorf("%s: unsupported kind %q, ClusterAuthorizationRule or AuthorizationRule is expected", path, obj.GetKind())
+		}
+
+		rule, err := authRuleFromUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func authRuleFromUnstructured(obj *unstructured.Unstructured) (AuthRule, error) {
+	rule := AuthRule{Kind: obj.GetKind(), Namespace: obj.GetNamespace(), Name: obj.GetName()}
+
+	spec, _, err := unstructured.NestedMap(obj.Object, "spec")
+	if err != nil {
+		return rule, fmt.Errorf("%s: %w", &rule, err)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, &rule.Spec); err != nil {
+		return rule, fmt.Errorf("%s: %w", &rule, err)
+	}
+	return rule, nil
+}
+
+// mergeRules replaces the existing rules with the proposed ones of the same kind, namespace and name
+func mergeRules(rules, proposed []AuthRule) []AuthRule {
+	result := make([]AuthRule, 0, len(rules)+len(proposed))
+	replaced := make(map[string]bool, len(proposed))
+	for i := range proposed {
+		replaced[proposed[i].key()] = true
+	}
+	for i := range rules {
+		if !replaced[rules[i].key()] {
+			result = append(result, rules[i])
+		}
+	}
+	return append(result, proposed...)
+}
+
+func filterNamespace(permissions Permissions, namespace string) Permissions {
+	if namespace == "" {
+		return permissions
+	}
+	result := make(Permissions)
+	for _, ns := range []string{ClusterWide, namespace} {
+		if resources, ok := permissions[ns]; ok {
+			result[ns] = resources
+		}
+	}
+	return result
+}
+
+func printPermissions(w io.Writer, permissions Permissions) error {
+	list := permissions.List()
+	if len(list) == 0 {
+		_, err := fmt.Fprintln(w, "No permissions are granted by the authorization rules.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tRESOURCE\tVERBS")
+	for _, p := range list {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", namespaceTitle(p.Namespace), p.Resource, strings.Join(p.Verbs, ","))
+	}
+	return tw.Flush()
+}
+
+func printChanges(w io.Writer, changes []Change) error {
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "The proposed rules do not change the effective permissions.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tNAMESPACE\tRESOURCE\tVERBS")
+	for _, c := range changes {
+		sign := "-"
+		if c.Added {
+			sign = "+"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", sign, namespaceTitle(c.Namespace), c.Resource, strings.Join(c.Verbs, ","))
+	}
+	return tw.Flush()
+}
+
+func printWarnings(w io.Writer, warnings []string) {
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "WARNING: %s\n", warning)
+	}
+}
+
+func namespaceTitle(namespace string) string {
+	if namespace == ClusterWide {
+		return clusterWideTitle
+	}
+	return namespace
+}
@@ -0,0 +1,494 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package effectivepermissions
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/deckhouse/deckhouse/go_lib/userauthz"
+)
+
+const (
+	clusterAuthorizationRuleKind = "ClusterAuthorizationRule"
+	authorizationRuleKind        = "AuthorizationRule"
+
+	// ClusterWide is the namespace of the permissions for the cluster-scoped resources
+	// and for the requests to the namespaced resources in all namespaces
+	ClusterWide = ""
+
+	serviceAccountPrefix = "system:serviceaccount:"
+)
+
+type AuthRule struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Spec      AuthRuleSpec
+}
+
+type AuthRuleSpec struct {
+	AccessLevel                   string             `json:"accessLevel"`
+	PortForwarding                bool               `json:"portForwarding"`
+	AllowScale                    bool               `json:"allowScale"`
+	AllowAccessToSystemNamespaces bool               `json:"allowAccessToSystemNamespaces"`
+	LimitNamespaces               []string           `json:"limitNamespaces"`
+	NamespaceSelector             *NamespaceSelector `json:"namespaceSelector"`
+	AdditionalRoles               []rbacv1.RoleRef   `json:"additionalRoles"`
+	Subjects                      []rbacv1.Subject   `json:"subjects"`
+	NotBefore                     string             `json:"notBefore"`
+	ExpiresAt                     string             `json:"expiresAt"`
+}
+
+type NamespaceSelector struct {
+	LabelSelector *metav1.LabelSelector `json:"labelSelector"`
+	MatchAny      bool                  `json:"matchAny"`
+}
+
+func (r *AuthRule) key() string {
+	return r.Kind + "/" + r.Namespace + "/" + r.Name
+}
+
+func (r *AuthRule) String() string {
+	if r.Namespace == "" {
+		return fmt.Sprintf("%s %s", r.Kind, r.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", r.Kind, r.Namespace, r.Name)
+}
+
+// active mirrors the user-authz hooks: the bindings exist only between notBefore and expiresAt
+func (r *AuthRule) active(now time.Time) (bool, error) {
+	for _, field := range []struct {
+		name  string
+		value string
+		check func(t time.Time) bool
+	}{
+		{name: "notBefore", value: r.Spec.NotBefore, check: func(t time.Time) bool { return !now.Before(t) }},
+		{name: "expiresAt", value: r.Spec.ExpiresAt, check: func(t time.Time) bool { return now.Before(t) }},
+	} {
+		if field.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, field.value)
+		if err != nil {
+			return false, fmt.Errorf("%s: invalid %s: %w", r, field.name, err)
+		}
+		if !field.check(t) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Subject is the user whose permissions are resolved
+type Subject struct {
+	User   string
+	Groups []string
+}
+
+// NewServiceAccountSubject returns the subject with the username and the groups of the ServiceAccount
+func NewServiceAccountSubject(namespace, name string) Subject {
+	return Subject{
+		User:   serviceAccountPrefix + namespace + ":" + name,
+		Groups: []string{"system:serviceaccounts", "system:serviceaccounts:" + namespace, "system:authenticated"},
+	}
+}
+
+func (s *Subject) matches(subjects []rbacv1.Subject) bool {
+	for _, subject := range subjects {
+		switch subject.Kind {
+		case rbacv1.UserKind:
+			if subject.Name == s.User {
+				return true
+			}
+		case rbacv1.GroupKind:
+			for _, group := range s.Groups {
+				if subject.Name == group {
+					return true
+				}
+			}
+		case rbacv1.ServiceAccountKind:
+			if serviceAccountPrefix+subject.Namespace+":"+subject.Name == s.User {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Cluster is the state of the cluster used to resolve the rules
+type Cluster struct {
+	ClusterRoles []rbacv1.ClusterRole
+	Namespaces   []Namespace
+	// NamespacedResources contains the known resources in the `<resource>.<group>` form
+	NamespacedResources map[string]bool
+	// MultiTenancy is enabled when the user-authz webhook limits the namespaces
+	MultiTenancy bool
+}
+
+type Namespace struct {
+	Name   string
+	Labels map[string]string
+}
+
+// Permissions are the verbs by the namespace and the resource
+type Permissions map[string]map[string]map[string]struct{}
+
+func (p Permissions) add(namespace, resource string, verbs []string) {
+	if p[namespace] == nil {
+		p[namespace] = make(map[string]map[string]struct{})
+	}
+	if p[namespace][resource] == nil {
+		p[namespace][resource] = make(map[string]struct{})
+	}
+	for _, verb := range verbs {
+		p[namespace][resource][verb] = struct{}{}
+	}
+}
+
+func (p Permissions) allows(namespace, resource, verb string) bool {
+	verbs := p[namespace][resource]
+	if _, ok := verbs[rbacv1.VerbAll]; ok {
+		return true
+	}
+	_, ok := verbs[verb]
+	return ok
+}
+
+// Permission is the row of the report
+type Permission struct {
+	Namespace string
+	Resource  string
+	Verbs     []string
+}
+
+// List returns the permissions sorted by the namespace and the resource, the cluster-wide permissions go first
+func (p Permissions) List() []Permission {
+	result := make([]Permission, 0)
+	for namespace, resources := range p {
+		for resource, verbs := range resources {
+			result = append(result, Permission{Namespace: namespace, Resource: resource, Verbs: verbList(verbs)})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Resource < result[j].Resource
+	})
+	return result
+}
+
+// Change is the row of the diff report
+type Change struct {
+	Permission
+	Added bool
+}
+
+// Diff returns the verbs granted by the proposed permissions only (added) and by the current permissions only (removed)
+func Diff(current, proposed Permissions) []Change {
+	result := make([]Change, 0)
+	for _, c := range []struct {
+		from, to Permissions
+		added    bool
+	}{
+		{from: proposed, to: current, added: true},
+		{from: current, to: proposed, added: false},
+	} {
+		for _, permission := range c.from.List() {
+			verbs := make([]string, 0)
+			for _, verb := range permission.Verbs {
+				if !c.to.allows(permission.Namespace, permission.Resource, verb) {
+					verbs = append(verbs, verb)
+				}
+			}
+			if len(verbs) > 0 {
+				permission.Verbs = verbs
+				result = append(result, Change{Permission: permission, Added: c.added})
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Resource < result[j].Resource
+	})
+	return result
+}
+
+// Resolve returns the permissions granted to the subject by the active rules and the warnings about the skipped rules
+func Resolve(cluster *Cluster, rules []AuthRule, subject Subject, now time.Time) (Permissions, []string) {
+	warnings := make([]string, 0)
+
+	matched := make([]AuthRule, 0)
+	for _, rule := range rules {
+		if !subject.matches(rule.Spec.Subjects) {
+			continue
+		}
+		active, err := rule.active(now)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%v, the rule is skipped", err))
+			continue
+		}
+		if active {
+			matched = append(matched, rule)
+		}
+	}
+
+	roles := make(map[string]*rbacv1.ClusterRole, len(cluster.ClusterRoles))
+	for i := range cluster.ClusterRoles {
+		roles[cluster.ClusterRoles[i].Name] = &cluster.ClusterRoles[i]
+	}
+	customRoles := customClusterRoles(cluster.ClusterRoles)
+
+	filter := newNamespaceFilter(matched, cluster.MultiTenancy)
+
+	result := make(Permissions)
+	for i := range matched {
+		rule := &matched[i]
+		for _, roleName := range ruleRoles(rule, customRoles) {
+			role, ok := roles[roleName]
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("%s: ClusterRole %s is not found", rule, roleName))
+				continue
+			}
+
+			for _, policy := range role.Rules {
+				for _, group := range policy.APIGroups {
+					for _, resource := range policy.Resources {
+						name := resourceName(group, resource, policy.ResourceNames)
+						namespaced, known := cluster.NamespacedResources[baseResource(group, resource)]
+
+						if rule.Kind == authorizationRuleKind {
+							if (namespaced || !known) && filter.allowed(rule.Namespace, cluster.namespaceLabels(rule.Namespace)) {
+								result.add(rule.Namespace, name, policy.Verbs)
+							}
+							continue
+						}
+
+						if !namespaced || !filter.limited() {
+							result.add(ClusterWide, name, policy.Verbs)
+						}
+						if namespaced || !known {
+							for _, ns := range cluster.Namespaces {
+								if filter.allowed(ns.Name, ns.Labels) {
+									result.add(ns.Name, name, policy.Verbs)
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return result, warnings
+}
+
+func (c *Cluster) namespaceLabels(name string) map[string]string {
+	for _, ns := range c.Namespaces {
+		if ns.Name == name {
+			return ns.Labels
+		}
+	}
+	return nil
+}
+
+// ruleRoles returns the ClusterRoles bound by the rule, the same as the user-authz templates render
+func ruleRoles(rule *AuthRule, customRoles map[string][]string) []string {
+	result := make([]string, 0)
+	for _, role := range rule.Spec.AdditionalRoles {
+		result = append(result, role.Name)
+	}
+	if rule.Spec.AccessLevel != "" {
+		result = append(result, userauthz.AccessLevelClusterRole(rule.Spec.AccessLevel))
+		result = append(result, customRoles[rule.Spec.AccessLevel]...)
+	}
+	if rule.Spec.PortForwarding {
+		result = append(result, userauthz.PortForwardClusterRole)
+	}
+	if rule.Spec.AllowScale {
+		result = append(result, userauthz.ScaleClusterRole)
+	}
+	return result
+}
+
+// customClusterRoles returns the annotated ClusterRoles by the access level,
+// a role of the access level is also bound for the higher access levels
+func customClusterRoles(clusterRoles []rbacv1.ClusterRole) map[string][]string {
+	result := make(map[string][]string)
+	for _, role := range clusterRoles {
+		level := role.Annotations["user-authz.deckhouse.io/access-level"]
+		for i, accessLevel := range userauthz.CustomClusterRoleAccessLevels {
+			if accessLevel != level {
+				continue
+			}
+			for _, higher := range userauthz.CustomClusterRoleAccessLevels[i:] {
+				result[higher] = append(result[higher], role.Name)
+			}
+		}
+	}
+	for _, roles := range result {
+		sort.Strings(roles)
+	}
+	return result
+}
+
+// namespaceFilter mirrors the namespace restrictions of the user-authz webhook,
+// the restrictions of all the subject ClusterAuthorizationRules are combined
+type namespaceFilter struct {
+	enabled                       bool
+	allowAccessToSystemNamespaces bool
+	limitNamespaces               []*regexp.Regexp
+	namespaceSelectors            []*NamespaceSelector
+	namespaceFiltersAbsent        bool
+}
+
+func newNamespaceFilter(rules []AuthRule, multiTenancy bool) *namespaceFilter {
+	f := &namespaceFilter{}
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Kind != clusterAuthorizationRuleKind {
+			continue
+		}
+		f.enabled = multiTenancy
+
+		selectorApplied := rule.Spec.NamespaceSelector != nil && rule.Spec.NamespaceSelector.LabelSelector != nil
+		f.namespaceFiltersAbsent = f.namespaceFiltersAbsent || (len(rule.Spec.LimitNamespaces) == 0 && !selectorApplied)
+
+		if rule.Spec.NamespaceSelector == nil {
+			for _, ln := range rule.Spec.LimitNamespaces {
+				r, err := regexp.Compile(userauthz.WrapRegex(ln))
+				if err != nil {
+					continue
+				}
+				f.limitNamespaces = append(f.limitNamespaces, r)
+			}
+			f.allowAccessToSystemNamespaces = f.allowAccessToSystemNamespaces || rule.Spec.AllowAccessToSystemNamespaces
+		} else {
+			f.namespaceSelectors = append(f.namespaceSelectors, rule.Spec.NamespaceSelector)
+		}
+	}
+	return f
+}
+
+// limited reports whether the cluster-wide requests for the namespaced resources are denied
+func (f *namespaceFilter) limited() bool {
+	if !f.enabled {
+		return false
+	}
+
+	for _, selector := range f.namespaceSelectors {
+		if selector.MatchAny {
+			return false
+		}
+	}
+
+	if f.namespaceFiltersAbsent {
+		return !f.allowAccessToSystemNamespaces
+	}
+
+	for _, r := range f.limitNamespaces {
+		switch r.String() {
+		case "^.*$", "^.+$":
+			return !f.allowAccessToSystemNamespaces
+		}
+	}
+
+	return true
+}
+
+func (f *namespaceFilter) allowed(namespace string, nsLabels map[string]string) bool {
+	if !f.limited() {
+		return true
+	}
+
+	allowed := f.namespaceFiltersAbsent
+	if !allowed {
+		for _, r := range f.limitNamespaces {
+			if r.MatchString(namespace) {
+				allowed = true
+				break
+			}
+		}
+	}
+
+	if allowed && !f.allowAccessToSystemNamespaces && userauthz.IsSystemNamespace(namespace) {
+		allowed = false
+	}
+
+	if allowed {
+		return true
+	}
+
+	for _, selector := range f.namespaceSelectors {
+		if selector.LabelSelector == nil {
+			continue
+		}
+		s, err := metav1.LabelSelectorAsSelector(selector.LabelSelector)
+		if err != nil {
+			continue
+		}
+		if s.Matches(labels.Set(nsLabels)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resourceName returns the resource in the kubectl `<resource>.<group>` form
+func resourceName(group, resource string, resourceNames []string) string {
+	name := resource
+	if group != "" {
+		name += "." + group
+	}
+	if len(resourceNames) > 0 {
+		names := append([]string{}, resourceNames...)
+		sort.Strings(names)
+		name += "[" + strings.Join(names, ",") + "]"
+	}
+	return name
+}
+
+// baseResource returns the resource without the subresource to check whether it is namespaced
+func baseResource(group, resource string) string {
+	resource, _, _ = strings.Cut(resource, "/")
+	if group == "" {
+		return resource
+	}
+	return resource + "." + group
+}
+
+// verbList collapses the verbs to `*` if all verbs are granted
+func verbList(verbs map[string]struct{}) []string {
+	if _, ok := verbs[rbacv1.VerbAll]; ok {
+		return []string{rbacv1.VerbAll}
+	}
+	result := make([]string, 0, len(verbs))
+	for verb := range verbs {
+		result = append(result, verb)
+	}
+	sort.Strings(result)
+	return result
+}
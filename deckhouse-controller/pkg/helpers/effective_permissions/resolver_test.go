@@ -0,0 +1,222 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package effectivepermissions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func testCluster(multiTenancy bool) *Cluster {
+	return &Cluster{
+		ClusterRoles: []rbacv1.ClusterRole{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "user-authz:user"},
+				Rules: []rbacv1.PolicyRule{
+					{APIGroups: []string{""}, Resources: []string{"pods", "pods/log"}, Verbs: []string{"get", "list"}},
+					{APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: []string{"get"}},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "user-authz:editor"},
+				Rules: []rbacv1.PolicyRule{
+					{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"*"}},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "user-authz:scale"},
+				Rules: []rbacv1.PolicyRule{
+					{APIGroups: []string{"apps"}, Resources: []string{"deployments/scale"}, Verbs: []string{"patch", "update"}},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "custom-viewer",
+					Annotations: map[string]string{"user-authz.deckhouse.io/access-level": "User"},
+				},
+				Rules: []rbacv1.PolicyRule{
+					{APIGroups: []string{"example.com"}, Resources: []string{"widgets"}, ResourceNames: []string{"b", "a"}, Verbs: []string{"get"}},
+				},
+			},
+		},
+		Namespaces: []Namespace{
+			{Name: "default"},
+			{Name: "dev"},
+			{Name: "prod", Labels: map[string]string{"env": "prod"}},
+			{Name: "kube-system", Labels: map[string]string{"env": "prod"}},
+		},
+		NamespacedResources: map[string]bool{
+			"pods":                true,
+			"nodes":               false,
+			"deployments.apps":    true,
+			"widgets.example.com": true,
+		},
+		MultiTenancy: multiTenancy,
+	}
+}
+
+func car(name string, spec AuthRuleSpec) AuthRule {
+	return AuthRule{Kind: clusterAuthorizationRuleKind, Name: name, Spec: spec}
+}
+
+func ar(namespace, name string, spec AuthRuleSpec) AuthRule {
+	return AuthRule{Kind: authorizationRuleKind, Namespace: namespace, Name: name, Spec: spec}
+}
+
+func jane() []rbacv1.Subject {
+	return []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "jane"}}
+}
+
+func namespaces(permissions Permissions, resource string) []string {
+	result := make([]string, 0)
+	for _, p := range permissions.List() {
+		if p.Resource == resource {
+			result = append(result, p.Namespace)
+		}
+	}
+	return result
+}
+
+func TestResolveSubjects(t *testing.T) {
+	rules := []AuthRule{
+		car("users", AuthRuleSpec{AccessLevel: "User", Subjects: []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "developers"}}}),
+		car("robot", AuthRuleSpec{AccessLevel: "Editor", Subjects: []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "ci", Name: "deployer"}}}),
+	}
+
+	permissions, warnings := Resolve(testCluster(false), rules, Subject{User: "jane"}, now)
+	assert.Empty(t, warnings)
+	assert.Empty(t, permissions)
+
+	permissions, _ = Resolve(testCluster(false), rules, Subject{User: "jane", Groups: []string{"developers"}}, now)
+	assert.ElementsMatch(t, []string{ClusterWide, "default", "dev", "kube-system", "prod"}, namespaces(permissions, "pods"))
+	assert.Equal(t, []string{ClusterWide}, namespaces(permissions, "nodes"))
+	assert.Equal(t, []string{"get", "list"}, verbList(permissions["dev"]["pods"]))
+	assert.Contains(t, permissions[ClusterWide], "widgets.example.com[a,b]")
+
+	permissions, _ = Resolve(testCluster(false), rules, NewServiceAccountSubject("ci", "deployer"), now)
+	assert.Contains(t, permissions["dev"], "deployments.apps")
+	assert.NotContains(t, permissions["dev"], "pods")
+}
+
+func TestResolveNamespaceFilters(t *testing.T) {
+	t.Run("Without multi-tenancy the filters are ignored", func(t *testing.T) {
+		rules := []AuthRule{car("jane", AuthRuleSpec{AccessLevel: "User", LimitNamespaces: []string{"dev"}, Subjects: jane()})}
+		permissions, _ := Resolve(testCluster(false), rules, Subject{User: "jane"}, now)
+		assert.ElementsMatch(t, []string{ClusterWide, "default", "dev", "kube-system", "prod"}, namespaces(permissions, "pods"))
+	})
+
+	t.Run("No filters deny the system namespaces", func(t *testing.T) {
+		rules := []AuthRule{car("jane", AuthRuleSpec{AccessLevel: "User", Subjects: jane()})}
+		permissions, _ := Resolve(testCluster(true), rules, Subject{User: "jane"}, now)
+		assert.Equal(t, []string{"dev", "prod"}, namespaces(permissions, "pods"))
+		assert.Equal(t, []string{ClusterWide}, namespaces(permissions, "nodes"))
+	})
+
+	t.Run("limitNamespaces", func(t *testing.T) {
+		rules := []AuthRule{car("jane", AuthRuleSpec{AccessLevel: "User", LimitNamespaces: []string{"de.*"}, Subjects: jane()})}
+		permissions, _ := Resolve(testCluster(true), rules, Subject{User: "jane"}, now)
+		assert.Equal(t, []string{"dev"}, namespaces(permissions, "pods"))
+	})
+
+	t.Run("namespaceSelector grants the system namespaces", func(t *testing.T) {
+		rules := []AuthRule{car("jane", AuthRuleSpec{
+			AccessLevel:       "User",
+			NamespaceSelector: &NamespaceSelector{LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}},
+			Subjects:          jane(),
+		})}
+		permissions, _ := Resolve(testCluster(true), rules, Subject{User: "jane"}, now)
+		assert.Equal(t, []string{"kube-system", "prod"}, namespaces(permissions, "pods"))
+	})
+
+	t.Run("matchAny", func(t *testing.T) {
+		rules := []AuthRule{car("jane", AuthRuleSpec{AccessLevel: "User", NamespaceSelector: &NamespaceSelector{MatchAny: true}, Subjects: jane()})}
+		permissions, _ := Resolve(testCluster(true), rules, Subject{User: "jane"}, now)
+		assert.Equal(t, []string{ClusterWide, "default", "dev", "kube-system", "prod"}, namespaces(permissions, "pods"))
+	})
+
+	t.Run("AuthorizationRule is limited by the ClusterAuthorizationRules", func(t *testing.T) {
+		rules := []AuthRule{
+			car("jane", AuthRuleSpec{AccessLevel: "User", LimitNamespaces: []string{"dev"}, Subjects: jane()}),
+			ar("dev", "jane", AuthRuleSpec{AccessLevel: "Editor", AllowScale: true, Subjects: jane()}),
+			ar("prod", "jane", AuthRuleSpec{AccessLevel: "Editor", Subjects: jane()}),
+		}
+		permissions, _ := Resolve(testCluster(true), rules, Subject{User: "jane"}, now)
+		assert.Equal(t, []string{"dev"}, namespaces(permissions, "deployments.apps"))
+		assert.Equal(t, []string{"dev"}, namespaces(permissions, "deployments/scale.apps"))
+	})
+}
+
+func TestResolveTemporaryRules(t *testing.T) {
+	rules := []AuthRule{
+		car("expired", AuthRuleSpec{AccessLevel: "User", ExpiresAt: "2024-05-01T11:00:00Z", Subjects: jane()}),
+		car("future", AuthRuleSpec{AccessLevel: "Editor", NotBefore: "2024-05-01T13:00:00Z", Subjects: jane()}),
+		car("invalid", AuthRuleSpec{AccessLevel: "Editor", ExpiresAt: "tomorrow", Subjects: jane()}),
+		car("active", AuthRuleSpec{AllowScale: true, NotBefore: "2024-05-01T11:00:00Z", ExpiresAt: "2024-05-01T13:00:00Z", Subjects: jane()}),
+	}
+
+	permissions, warnings := Resolve(testCluster(false), rules, Subject{User: "jane"}, now)
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "ClusterAuthorizationRule invalid")
+	assert.Equal(t, []string{"deployments/scale.apps"}, resources(permissions[ClusterWide]))
+}
+
+func TestResolveMissingRole(t *testing.T) {
+	rules := []AuthRule{car("jane", AuthRuleSpec{AdditionalRoles: []rbacv1.RoleRef{{Name: "missing"}}, Subjects: jane()})}
+
+	permissions, warnings := Resolve(testCluster(false), rules, Subject{User: "jane"}, now)
+	assert.Empty(t, permissions)
+	assert.Equal(t, []string{"ClusterAuthorizationRule jane: ClusterRole missing is not found"}, warnings)
+}
+
+func TestDiff(t *testing.T) {
+	current := []AuthRule{car("jane", AuthRuleSpec{AccessLevel: "User", LimitNamespaces: []string{"dev"}, Subjects: jane()})}
+	proposed := mergeRules(current, []AuthRule{car("jane", AuthRuleSpec{AccessLevel: "Editor", LimitNamespaces: []string{"prod"}, Subjects: jane()})})
+
+	before, _ := Resolve(testCluster(true), current, Subject{User: "jane"}, now)
+	after, _ := Resolve(testCluster(true), proposed, Subject{User: "jane"}, now)
+
+	changes := Diff(before, after)
+	assert.Contains(t, changes, Change{Permission: Permission{Namespace: "dev", Resource: "pods", Verbs: []string{"get", "list"}}})
+	assert.Contains(t, changes, Change{Permission: Permission{Namespace: "prod", Resource: "deployments.apps", Verbs: []string{"*"}}, Added: true})
+	assert.Contains(t, changes, Change{Permission: Permission{Namespace: ClusterWide, Resource: "nodes", Verbs: []string{"get"}}})
+	assert.NotContains(t, changes, Change{Permission: Permission{Namespace: "prod", Resource: "pods", Verbs: []string{"get", "list"}}})
+
+	assert.Empty(t, Diff(before, before))
+}
+
+func TestDiffWildcardVerbs(t *testing.T) {
+	current := Permissions{}
+	current.add("dev", "pods", []string{"*"})
+	proposed := Permissions{}
+	proposed.add("dev", "pods", []string{"get"})
+
+	assert.Equal(t, []Change{{Permission: Permission{Namespace: "dev", Resource: "pods", Verbs: []string{"*"}}, Added: true}}, Diff(proposed, current))
+	assert.Equal(t, []Change{{Permission: Permission{Namespace: "dev", Resource: "pods", Verbs: []string{"*"}}}}, Diff(current, proposed))
+}
+
+func resources(permissions map[string]map[string]struct{}) []string {
+	result := make([]string, 0, len(permissions))
+	for resource := range permissions {
+		result = append(result, resource)
+	}
+	return result
+}
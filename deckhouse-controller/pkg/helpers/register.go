@@ -19,6 +19,7 @@ import (
 	"gopkg.in/alecthomas/kingpin.v2"
 
 	changeregistry "github.com/deckhouse/deckhouse/deckhouse-controller/pkg/helpers/change_registry"
+	effectivepermissions "github.com/deckhouse/deckhouse/deckhouse-controller/pkg/helpers/effective_permissions"
 	"github.com/deckhouse/deckhouse/deckhouse-controller/pkg/helpers/jwt"
 	dhctlapp "github.com/deckhouse/deckhouse/dhctl/cmd/dhctl/commands"
 )
@@ -53,6 +54,18 @@ func DefineHelperCommands(kpApp *kingpin.Application) {
 		})
 	}
 
+	{
+		effectivePermissionsCommand := helpersCommand.Command("effective-permissions", "Print the permissions granted to the subject by the ClusterAuthorizationRules and AuthorizationRules.")
+		user := effectivePermissionsCommand.Flag("user", "User name.").String()
+		groups := effectivePermissionsCommand.Flag("group", "Group of the user (ex --group admins --group developers).").Strings()
+		serviceAccount := effectivePermissionsCommand.Flag("service-account", "ServiceAccount in the <namespace>/<name> form.").String()
+		namespace := effectivePermissionsCommand.Flag("namespace", "Print only the permissions in the namespace and the cluster-wide ones.").String()
+		proposed := effectivePermissionsCommand.Flag("proposed", "Path to the YAML file with the proposed rules, the difference in the permissions is printed (the rules replace the existing rules with the same name).").ExistingFile()
+		effectivePermissionsCommand.Action(func(c *kingpin.ParseContext) error {
+			return effectivepermissions.EffectivePermissions(*user, *groups, *serviceAccount, *namespace, *proposed)
+		})
+	}
+
 	// dhctl parser for ClusterConfiguration and <Provider-name>ClusterConfiguration secrets
 	dhctlapp.DefineCommandParseClusterConfiguration(kpApp, helpersCommand)
 	dhctlapp.DefineCommandParseCloudDiscoveryData(kpApp, helpersCommand)
@@ -24,6 +24,8 @@ import (
 
 func DefineConvergeCommand(kpApp *kingpin.Application) *kingpin.CmdClause {
 	cmd := kpApp.Command("converge", "Converge kubernetes cluster.")
+	app.DefineLockWaitFlag(cmd)
+	app.DefineStateBackendFlags(cmd)
 	app.DefineSSHFlags(cmd)
 	app.DefineBecomeFlags(cmd)
 	app.DefineKubeFlags(cmd)
@@ -35,7 +37,8 @@ func DefineConvergeCommand(kpApp *kingpin.Application) *kingpin.CmdClause {
 		}
 
 		converger := converge.NewConverger(&converge.Params{
-			SSHClient: sshClient,
+			SSHClient:       sshClient,
+			LockWaitTimeout: app.LockWaitTimeout,
 		})
 		return converger.Converge()
 	})
@@ -15,10 +15,12 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"gopkg.in/alecthomas/kingpin.v2"
 	v1 "k8s.io/api/coordination/v1"
+	"sigs.k8s.io/yaml"
 
 	"github.com/deckhouse/deckhouse/dhctl/pkg/app"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/kubernetes/actions/converge"
@@ -78,3 +80,48 @@ func DefineReleaseConvergeLockCommand(parent *kingpin.CmdClause) *kingpin.CmdCla
 	})
 	return cmd
 }
+
+func DefineConvergeLockStatusCommand(parent *kingpin.CmdClause) *kingpin.CmdClause {
+	cmd := parent.Command("status", "Show the owner of the converge lock and the queue of waiters for it.")
+	app.DefineOutputFlag(cmd)
+	app.DefineSSHFlags(cmd)
+	app.DefineBecomeFlags(cmd)
+	app.DefineKubeFlags(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		sshClient, err := ssh.NewInitClientFromFlags(true)
+		if err != nil {
+			return err
+		}
+
+		kubeCl := client.NewKubernetesClient().WithSSHClient(sshClient)
+		if err := kubeCl.Init(client.AppKubernetesInitParams()); err != nil {
+			return err
+		}
+
+		status, err := client.GetLockStatus(kubeCl, converge.GetLockLeaseConfig("lock-status"))
+		if err != nil {
+			return err
+		}
+
+		var data []byte
+		switch app.OutputFormat {
+		case "yaml":
+			data, err = yaml.Marshal(status)
+			if err != nil {
+				return err
+			}
+		case "json":
+			data, err = json.Marshal(status)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("Unknown output format %s", app.OutputFormat)
+		}
+
+		fmt.Print(string(data))
+		return nil
+	})
+	return cmd
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/deckhouse/deckhouse/dhctl/cmd/dhctl/commands"
 	"github.com/deckhouse/deckhouse/dhctl/cmd/dhctl/commands/bootstrap"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/app"
+	"github.com/deckhouse/deckhouse/dhctl/pkg/events"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/log"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/system/process"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/util/cache"
@@ -84,6 +85,7 @@ func main() {
 	lockCmd := kpApp.Command("lock", "Converge cluster lock")
 	{
 		commands.DefineReleaseConvergeLockCommand(lockCmd)
+		commands.DefineConvergeLockStatusCommand(lockCmd)
 	}
 
 	commands.DefineDestroyCommand(kpApp)
@@ -143,6 +145,13 @@ func main() {
 func runApplication(kpApp *kingpin.Application) {
 	kpApp.Action(func(c *kingpin.ParseContext) error {
 		log.InitLogger(app.LoggerType)
+
+		if err := events.Init(app.EventsOutput); err != nil {
+			return err
+		}
+		if c.SelectedCommand != nil {
+			events.OperationStarted(c.SelectedCommand.FullCommand())
+		}
 		return nil
 	})
 
@@ -156,6 +165,11 @@ func runApplication(kpApp *kingpin.Application) {
 			log.ErrorLn(err)
 			errorCode = 1
 		}
+		if err != nil && tomb.IsInterrupted() {
+			err = events.WithCode(events.InterruptedCode, err)
+		}
+		events.OperationFinished(err)
+		events.Close()
 		tomb.Shutdown(errorCode)
 	}()
 
@@ -47,6 +47,8 @@ var (
 	SanityCheck = false
 	LoggerType  = "pretty"
 	IsDebug     = false
+
+	EventsOutput = ""
 )
 
 func init() {
@@ -75,6 +77,9 @@ func GlobalFlags(cmd *kingpin.Application) {
 		Envar(configEnvName("TMP_DIR")).
 		Default(TmpDirName).
 		StringVar(&TmpDirName)
+	cmd.Flag("events-output", `Write the progress events as JSON lines to the file descriptor (fd://3), the unix socket (unix:///run/dhctl.sock) or the file.`).
+		Envar(configEnvName("EVENTS_OUTPUT")).
+		StringVar(&EventsOutput)
 }
 
 func DefineConfigFlags(cmd *kingpin.CmdClause) {
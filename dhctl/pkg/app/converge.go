@@ -25,6 +25,8 @@ var (
 	ListenAddress = ":9101"
 	CheckInterval = time.Minute
 	OutputFormat  = "yaml"
+
+	LockWaitTimeout time.Duration
 )
 
 func DefineConvergeExporterFlags(cmd *kingpin.CmdClause) {
@@ -45,3 +47,9 @@ func DefineOutputFlag(cmd *kingpin.CmdClause) {
 		Short('o').
 		EnumVar(&OutputFormat, "yaml", "json")
 }
+
+func DefineLockWaitFlag(cmd *kingpin.CmdClause) {
+	cmd.Flag("lock-wait-timeout", "Wait for the converge lock in the queue for the duration instead of failing immediately if the lock is held, e.g., 30m.").
+		Envar(configEnvName("LOCK_WAIT_TIMEOUT")).
+		DurationVar(&LockWaitTimeout)
+}
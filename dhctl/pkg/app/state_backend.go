@@ -0,0 +1,74 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"fmt"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	StateBackendLocal          = "local"
+	StateBackendEncryptedLocal = "encrypted-local"
+	StateBackendS3             = "s3"
+)
+
+var (
+	StateBackend           = StateBackendLocal
+	StateEncryptionKeyFile = ""
+
+	StateS3Endpoint        = ""
+	StateS3Bucket          = ""
+	StateS3Prefix          = "dhctl"
+	StateS3Region          = "us-east-1"
+	StateS3AccessKeyID     = ""
+	StateS3SecretAccessKey = ""
+)
+
+func DefineStateBackendFlags(cmd *kingpin.CmdClause) {
+	cmd.Flag("state-backend", fmt.Sprintf(`Backend to store the terraform state and the bootstrap state. May be:
+	%s - local directory (Default)
+	%s - local directory, the state is encrypted with the --state-encryption-key-file key
+	%s - S3-compatible bucket, the state is locked while dhctl is running
+	`, StateBackendLocal, StateBackendEncryptedLocal, StateBackendS3)).
+		Envar(configEnvName("STATE_BACKEND")).
+		Default(StateBackendLocal).
+		EnumVar(&StateBackend, StateBackendLocal, StateBackendEncryptedLocal, StateBackendS3)
+	cmd.Flag("state-encryption-key-file", "Path to the file with the base64 encoded 32 bytes AES key to encrypt the state. Also encrypts the state in the S3 bucket.").
+		Envar(configEnvName("STATE_ENCRYPTION_KEY_FILE")).
+		StringVar(&StateEncryptionKeyFile)
+
+	cmd.Flag("state-s3-endpoint", "S3 endpoint URL, e.g., https://s3.example.com.").
+		Envar(configEnvName("STATE_S3_ENDPOINT")).
+		StringVar(&StateS3Endpoint)
+	cmd.Flag("state-s3-bucket", "S3 bucket to store the state.").
+		Envar(configEnvName("STATE_S3_BUCKET")).
+		StringVar(&StateS3Bucket)
+	cmd.Flag("state-s3-prefix", "Prefix of the state objects in the S3 bucket.").
+		Envar(configEnvName("STATE_S3_PREFIX")).
+		Default(StateS3Prefix).
+		StringVar(&StateS3Prefix)
+	cmd.Flag("state-s3-region", "S3 region.").
+		Envar(configEnvName("STATE_S3_REGION")).
+		Default(StateS3Region).
+		StringVar(&StateS3Region)
+	cmd.Flag("state-s3-access-key-id", "S3 access key ID. AWS_ACCESS_KEY_ID is used if it is not set.").
+		Envar(configEnvName("STATE_S3_ACCESS_KEY_ID")).
+		StringVar(&StateS3AccessKeyID)
+	cmd.Flag("state-s3-secret-access-key", "S3 secret access key. AWS_SECRET_ACCESS_KEY is used if it is not set.").
+		Envar(configEnvName("STATE_S3_SECRET_ACCESS_KEY")).
+		StringVar(&StateS3SecretAccessKey)
+}
@@ -74,6 +74,8 @@ func DefineCacheFlags(cmd *kingpin.CmdClause) {
 	cmd.Flag("kube-cachestore-name", "Name for cache secret").
 		Envar(configEnvName("CACHE_STORE_KUBE_NAME")).
 		StringVar(&CacheKubeName)
+
+	DefineStateBackendFlags(cmd)
 }
 
 func DefineDropCacheFlags(cmd *kingpin.CmdClause) {
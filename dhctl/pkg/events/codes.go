@@ -0,0 +1,60 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import "errors"
+
+// Code is the stable code of the operation error
+type Code string
+
+const (
+	OperationFailedCode     Code = "operation_failed"
+	TerraformFailedCode     Code = "terraform_failed"
+	NodeBootstrapFailedCode Code = "node_bootstrap_failed"
+	ConvergeLockFailedCode  Code = "converge_lock_failed"
+	InterruptedCode         Code = "interrupted"
+)
+
+type codedError struct {
+	code Code
+	err  error
+}
+
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// WithCode marks the error with the code, the outermost code is reported if the error is wrapped several times
+func WithCode(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &codedError{code: code, err: err}
+}
+
+// ErrorCode returns the code of the error, OperationFailedCode is returned for the errors without the code
+func ErrorCode(err error) Code {
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+
+	return OperationFailedCode
+}
@@ -0,0 +1,209 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package events writes the progress of dhctl operations as JSON lines for the machine consumers.
+// The event types and the error codes are the stable API, new fields may be added to the events.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deckhouse/deckhouse/dhctl/pkg/log"
+)
+
+type Type string
+
+const (
+	OperationStartedType   Type = "operation_started"
+	OperationCompletedType Type = "operation_completed"
+	OperationFailedType    Type = "operation_failed"
+	PhaseStartedType       Type = "phase_started"
+	PhaseCompletedType     Type = "phase_completed"
+	TerraformResourceType  Type = "terraform_resource"
+	NodeBootstrapStepType  Type = "node_bootstrap_step"
+)
+
+const (
+	StatusStarted    = "started"
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+)
+
+type Event struct {
+	Time      time.Time `json:"time"`
+	Type      Type      `json:"type"`
+	Operation string    `json:"operation,omitempty"`
+	// Phase is the phase of the operation the event happened in
+	Phase    string             `json:"phase,omitempty"`
+	Code     Code               `json:"code,omitempty"`
+	Message  string             `json:"message,omitempty"`
+	Resource *TerraformResource `json:"resource,omitempty"`
+	Step     *NodeBootstrapStep `json:"step,omitempty"`
+}
+
+type TerraformResource struct {
+	Address string `json:"address"`
+	// Action is the terraform action, e.g., create, modify, destroy or read
+	Action  string `json:"action"`
+	Status  string `json:"status"`
+	Elapsed string `json:"elapsed,omitempty"`
+}
+
+type NodeBootstrapStep struct {
+	Host    string `json:"host,omitempty"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Attempt int    `json:"attempt,omitempty"`
+}
+
+var global = &emitter{}
+
+type emitter struct {
+	mu        sync.Mutex
+	out       io.WriteCloser
+	operation string
+	phase     string
+}
+
+// Init opens the events output:
+//   - fd://3 is the file descriptor inherited from the parent process;
+//   - unix:///run/dhctl.sock is the unix socket;
+//   - any other value is the path to the file, the events are appended to it.
+//
+// Events are not written if the output is empty.
+func Init(output string) error {
+	if output == "" {
+		return nil
+	}
+
+	var out io.WriteCloser
+	switch {
+	case strings.HasPrefix(output, "fd://"):
+		fd, err := strconv.Atoi(strings.TrimPrefix(output, "fd://"))
+		if err != nil {
+			return fmt.Errorf("invalid events output file descriptor %q: %w", output, err)
+		}
+		out = os.NewFile(uintptr(fd), "events")
+	case strings.HasPrefix(output, "unix://"):
+		conn, err := net.Dial("unix", strings.TrimPrefix(output, "unix://"))
+		if err != nil {
+			return fmt.Errorf("connect to the events socket: %w", err)
+		}
+		out = conn
+	default:
+		file, err := os.OpenFile(output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("open events output: %w", err)
+		}
+		out = file
+	}
+
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	global.out = out
+
+	return nil
+}
+
+// Close closes the events output, the events after it are dropped
+func Close() {
+	global.mu.Lock()
+	defer global.mu.Unlock()
+
+	if global.out != nil {
+		_ = global.out.Close()
+		global.out = nil
+	}
+}
+
+func emit(event Event) {
+	global.mu.Lock()
+	defer global.mu.Unlock()
+
+	if global.out == nil {
+		return
+	}
+
+	event.Time = time.Now().UTC()
+	event.Operation = global.operation
+	if event.Phase == "" {
+		event.Phase = global.phase
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		log.DebugF("Cannot marshal event %s: %v\n", event.Type, err)
+		return
+	}
+
+	if _, err = global.out.Write(append(line, '\n')); err != nil {
+		// the consumer has gone, do not fail the operation because of it
+		log.WarnF("Cannot write event, events are disabled: %v\n", err)
+		_ = global.out.Close()
+		global.out = nil
+	}
+}
+
+func OperationStarted(operation string) {
+	global.mu.Lock()
+	global.operation = operation
+	global.mu.Unlock()
+
+	emit(Event{Type: OperationStartedType})
+}
+
+// OperationFinished emits the completed event or the failed event with the code of the error
+func OperationFinished(err error) {
+	if err == nil {
+		emit(Event{Type: OperationCompletedType})
+		return
+	}
+
+	emit(Event{Type: OperationFailedType, Code: ErrorCode(err), Message: err.Error()})
+}
+
+func PhaseStarted(phase string) {
+	global.mu.Lock()
+	global.phase = phase
+	global.mu.Unlock()
+
+	emit(Event{Type: PhaseStartedType, Phase: phase})
+}
+
+func PhaseCompleted(phase string) {
+	emit(Event{Type: PhaseCompletedType, Phase: phase})
+
+	global.mu.Lock()
+	if global.phase == phase {
+		global.phase = ""
+	}
+	global.mu.Unlock()
+}
+
+func TerraformResourceProgress(resource TerraformResource) {
+	emit(Event{Type: TerraformResourceType, Resource: &resource})
+}
+
+func NodeBootstrapStepProgress(step NodeBootstrapStep) {
+	emit(Event{Type: NodeBootstrapStepType, Step: &step})
+}
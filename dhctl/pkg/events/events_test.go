@@ -0,0 +1,145 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readEvents(t *testing.T, path string) []Event {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var result []Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		result = append(result, event)
+	}
+	require.NoError(t, scanner.Err())
+
+	return result
+}
+
+func TestEmitToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	require.NoError(t, Init(path))
+
+	OperationStarted("bootstrap")
+	PhaseStarted("BaseInfra")
+	TerraformResourceProgress(TerraformResource{Address: "openstack_compute_instance_v2.master", Action: "create", Status: StatusStarted})
+	PhaseCompleted("BaseInfra")
+	NodeBootstrapStepProgress(NodeBootstrapStep{Host: "10.0.0.1", Name: "001_install_kubelet.sh", Status: StatusFailed, Attempt: 1})
+	OperationFinished(fmt.Errorf("bootstrap: %w", WithCode(TerraformFailedCode, errors.New("apply failed"))))
+	Close()
+
+	// dropped after Close
+	OperationFinished(nil)
+
+	result := readEvents(t, path)
+	require.Len(t, result, 6)
+
+	for _, event := range result {
+		require.Equal(t, "bootstrap", event.Operation)
+		require.False(t, event.Time.IsZero())
+	}
+
+	require.Equal(t, OperationStartedType, result[0].Type)
+	require.Equal(t, "", result[0].Phase)
+
+	require.Equal(t, PhaseStartedType, result[1].Type)
+	require.Equal(t, "BaseInfra", result[1].Phase)
+
+	require.Equal(t, TerraformResourceType, result[2].Type)
+	require.Equal(t, "BaseInfra", result[2].Phase)
+	require.Equal(t, "openstack_compute_instance_v2.master", result[2].Resource.Address)
+
+	require.Equal(t, PhaseCompletedType, result[3].Type)
+	require.Equal(t, "BaseInfra", result[3].Phase)
+
+	require.Equal(t, NodeBootstrapStepType, result[4].Type)
+	require.Equal(t, "", result[4].Phase)
+	require.Equal(t, &NodeBootstrapStep{Host: "10.0.0.1", Name: "001_install_kubelet.sh", Status: StatusFailed, Attempt: 1}, result[4].Step)
+
+	require.Equal(t, OperationFailedType, result[5].Type)
+	require.Equal(t, TerraformFailedCode, result[5].Code)
+	require.Equal(t, "bootstrap: apply failed", result[5].Message)
+}
+
+func TestInitInvalidOutput(t *testing.T) {
+	require.Error(t, Init("fd://stdout"))
+	require.Error(t, Init("unix://"+filepath.Join(t.TempDir(), "absent.sock")))
+}
+
+func TestErrorCode(t *testing.T) {
+	require.Nil(t, WithCode(TerraformFailedCode, nil))
+	require.Equal(t, OperationFailedCode, ErrorCode(errors.New("plain")))
+	require.Equal(t, NodeBootstrapFailedCode, ErrorCode(WithCode(NodeBootstrapFailedCode, errors.New("bundle"))))
+
+	// the outermost code wins
+	err := WithCode(InterruptedCode, fmt.Errorf("wrapped: %w", WithCode(ConvergeLockFailedCode, errors.New("lock"))))
+	require.Equal(t, InterruptedCode, ErrorCode(err))
+	require.Equal(t, "wrapped: lock", err.Error())
+}
+
+func TestParseTerraformResource(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected TerraformResource
+		ok       bool
+	}{
+		{
+			line:     "module.master.openstack_compute_instance_v2.master: Creating...",
+			expected: TerraformResource{Address: "module.master.openstack_compute_instance_v2.master", Action: "create", Status: StatusStarted},
+			ok:       true,
+		},
+		{
+			line:     "openstack_networking_network_v2.internal: Still destroying... [10s elapsed]",
+			expected: TerraformResource{Address: "openstack_networking_network_v2.internal", Action: "destroy", Status: StatusInProgress, Elapsed: "10s"},
+			ok:       true,
+		},
+		{
+			line:     "openstack_networking_network_v2.internal: Modifications complete after 1m2s [id=1234]",
+			expected: TerraformResource{Address: "openstack_networking_network_v2.internal", Action: "modify", Status: StatusCompleted, Elapsed: "1m2s"},
+			ok:       true,
+		},
+		{
+			line:     "data.openstack_images_image_v2.image: Read complete after 0s [id=abc]",
+			expected: TerraformResource{Address: "data.openstack_images_image_v2.image", Action: "read", Status: StatusCompleted, Elapsed: "0s"},
+			ok:       true,
+		},
+		{
+			line: "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.line, func(t *testing.T) {
+			resource, ok := ParseTerraformResource(tc.line)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, resource)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	terraformStartedRegexp    = regexp.MustCompile(`^(\S+): (Creating|Modifying|Destroying|Reading)\.\.\.`)
+	terraformInProgressRegexp = regexp.MustCompile(`^(\S+): Still (creating|modifying|destroying|reading)\.\.\. \[(\S+) elapsed]`)
+	terraformCompletedRegexp  = regexp.MustCompile(`^(\S+): (Creation|Modifications|Destruction|Read) complete after (\S+)`)
+
+	terraformActions = map[string]string{
+		"creating":      "create",
+		"creation":      "create",
+		"modifying":     "modify",
+		"modifications": "modify",
+		"destroying":    "destroy",
+		"destruction":   "destroy",
+		"reading":       "read",
+		"read":          "read",
+	}
+)
+
+// ParseTerraformResource parses the resource progress from the terraform apply or destroy output line
+func ParseTerraformResource(line string) (TerraformResource, bool) {
+	if match := terraformStartedRegexp.FindStringSubmatch(line); match != nil {
+		return TerraformResource{Address: match[1], Action: terraformActions[strings.ToLower(match[2])], Status: StatusStarted}, true
+	}
+
+	if match := terraformInProgressRegexp.FindStringSubmatch(line); match != nil {
+		return TerraformResource{Address: match[1], Action: terraformActions[match[2]], Status: StatusInProgress, Elapsed: match[3]}, true
+	}
+
+	if match := terraformCompletedRegexp.FindStringSubmatch(line); match != nil {
+		return TerraformResource{Address: match[1], Action: terraformActions[strings.ToLower(match[2])], Status: StatusCompleted, Elapsed: match[3]}, true
+	}
+
+	return TerraformResource{}, false
+}
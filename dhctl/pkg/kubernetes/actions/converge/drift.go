@@ -0,0 +1,315 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package converge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/deckhouse/deckhouse/dhctl/pkg/config"
+	"github.com/deckhouse/deckhouse/dhctl/pkg/kubernetes/client"
+)
+
+const (
+	KubernetesVersionDrift = "kubernetes_version"
+	ControlPlaneFlagDrift  = "control_plane_flag"
+	MasterReplicasDrift    = "master_replicas"
+	NodeLabelDrift         = "node_label"
+	NodeTaintDrift         = "node_taint"
+
+	nodeGroupLabel = "node.deckhouse.io/group"
+)
+
+// DriftItem is a difference between the desired cluster configuration and the actual cluster state
+type DriftItem struct {
+	Kind string `json:"kind"`
+	// Object is the drifted object, e.g., "Node/worker-0"
+	Object string `json:"object"`
+	// Field is the drifted field of the object, e.g., the control plane component flag or the label key
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+type DriftReport struct {
+	CheckTime time.Time   `json:"checkTime"`
+	Drifts    []DriftItem `json:"drifts"`
+}
+
+// CheckDrift compares the ClusterConfiguration, the provider configuration and the NodeGroup templates
+// with the actual cluster state to catch manual changes made without dhctl
+func CheckDrift(kubeCl *client.KubernetesClient, metaConfig *config.MetaConfig) (*DriftReport, error) {
+	nodes, err := kubeCl.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list nodes: %w", err)
+	}
+
+	report := &DriftReport{CheckTime: time.Now().UTC(), Drifts: make([]DriftItem, 0)}
+
+	drifts, err := kubernetesVersionDrift(metaConfig, nodes.Items)
+	if err != nil {
+		return nil, err
+	}
+	report.Drifts = append(report.Drifts, drifts...)
+
+	drifts, err = controlPlaneDrift(kubeCl, metaConfig)
+	if err != nil {
+		return nil, err
+	}
+	report.Drifts = append(report.Drifts, drifts...)
+
+	report.Drifts = append(report.Drifts, masterReplicasDrift(metaConfig, nodes.Items)...)
+
+	drifts, err = nodeTemplatesDrift(kubeCl, nodes.Items)
+	if err != nil {
+		return nil, err
+	}
+	report.Drifts = append(report.Drifts, drifts...)
+
+	return report, nil
+}
+
+func kubernetesVersionDrift(metaConfig *config.MetaConfig, nodes []corev1.Node) ([]DriftItem, error) {
+	var desired string
+	if err := json.Unmarshal(metaConfig.ClusterConfig["kubernetesVersion"], &desired); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal kubernetes version from ClusterConfiguration: %w", err)
+	}
+	if desired == "Automatic" {
+		desired = config.DefaultKubernetesVersion
+	}
+
+	drifts := make([]DriftItem, 0)
+	for _, node := range nodes {
+		actual := minorVersion(node.Status.NodeInfo.KubeletVersion)
+		if actual == desired {
+			continue
+		}
+
+		drifts = append(drifts, DriftItem{
+			Kind:     KubernetesVersionDrift,
+			Object:   "Node/" + node.Name,
+			Field:    "kubeletVersion",
+			Expected: desired,
+			Actual:   actual,
+		})
+	}
+
+	return drifts, nil
+}
+
+// controlPlaneDrift compares the flags of the control plane static pods with the ClusterConfiguration,
+// flags absent in the pod spec are skipped because their default values are unknown
+func controlPlaneDrift(kubeCl *client.KubernetesClient, metaConfig *config.MetaConfig) ([]DriftItem, error) {
+	serviceSubnet := clusterConfigValue(metaConfig, "serviceSubnetCIDR")
+	expectedFlags := map[string]map[string]string{
+		"kube-apiserver": {
+			"--service-cluster-ip-range": serviceSubnet,
+		},
+		"kube-controller-manager": {
+			"--service-cluster-ip-range": serviceSubnet,
+			"--cluster-cidr":             clusterConfigValue(metaConfig, "podSubnetCIDR"),
+			"--node-cidr-mask-size":      clusterConfigValue(metaConfig, "podSubnetNodeCIDRPrefix"),
+		},
+	}
+
+	pods, err := kubeCl.CoreV1().Pods("kube-system").List(context.TODO(), metav1.ListOptions{LabelSelector: "tier=control-plane"})
+	if err != nil {
+		return nil, fmt.Errorf("list control plane pods: %w", err)
+	}
+
+	drifts := make([]DriftItem, 0)
+	for _, pod := range pods.Items {
+		component := pod.Labels["component"]
+		flags, ok := expectedFlags[component]
+		if !ok {
+			continue
+		}
+
+		actualFlags := make(map[string]string)
+		for _, container := range pod.Spec.Containers {
+			if container.Name != component {
+				continue
+			}
+			for _, arg := range append(container.Command, container.Args...) {
+				if flag, value, found := strings.Cut(arg, "="); found {
+					actualFlags[flag] = value
+				}
+			}
+		}
+
+		for _, flag := range sortedKeys(flags) {
+			actual, found := actualFlags[flag]
+			if !found || flags[flag] == "" || actual == flags[flag] {
+				continue
+			}
+
+			drifts = append(drifts, DriftItem{
+				Kind:     ControlPlaneFlagDrift,
+				Object:   "Pod/" + pod.Name,
+				Field:    flag,
+				Expected: flags[flag],
+				Actual:   actual,
+			})
+		}
+	}
+
+	return drifts, nil
+}
+
+func masterReplicasDrift(metaConfig *config.MetaConfig, nodes []corev1.Node) []DriftItem {
+	if metaConfig.ClusterType != config.CloudClusterType || metaConfig.MasterNodeGroupSpec.Replicas == 0 {
+		return nil
+	}
+
+	actual := 0
+	for _, node := range nodes {
+		if node.Labels[nodeGroupLabel] == MasterNodeGroupName {
+			actual++
+		}
+	}
+
+	if actual == metaConfig.MasterNodeGroupSpec.Replicas {
+		return nil
+	}
+
+	return []DriftItem{{
+		Kind:     MasterReplicasDrift,
+		Object:   "NodeGroup/" + MasterNodeGroupName,
+		Field:    "replicas",
+		Expected: fmt.Sprint(metaConfig.MasterNodeGroupSpec.Replicas),
+		Actual:   fmt.Sprint(actual),
+	}}
+}
+
+// nodeTemplatesDrift checks that the labels and taints from the NodeGroup templates are set on the group nodes
+func nodeTemplatesDrift(kubeCl *client.KubernetesClient, nodes []corev1.Node) ([]DriftItem, error) {
+	nodeGroups, err := kubeCl.Dynamic().Resource(nodeGroupResource).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list NodeGroups: %w", err)
+	}
+
+	nodesByGroup := make(map[string][]corev1.Node)
+	for _, node := range nodes {
+		group := node.Labels[nodeGroupLabel]
+		nodesByGroup[group] = append(nodesByGroup[group], node)
+	}
+
+	drifts := make([]DriftItem, 0)
+	for _, group := range nodeGroups.Items {
+		var template struct {
+			Labels map[string]string `json:"labels"`
+			Taints []corev1.Taint    `json:"taints"`
+		}
+
+		nodeTemplate, found, err := unstructured.NestedMap(group.Object, "spec", "nodeTemplate")
+		if err != nil || !found {
+			continue
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(nodeTemplate, &template); err != nil {
+			return nil, fmt.Errorf("NodeGroup %s node template: %w", group.GetName(), err)
+		}
+
+		for _, node := range nodesByGroup[group.GetName()] {
+			for _, key := range sortedKeys(template.Labels) {
+				actual, found := node.Labels[key]
+				if found && actual == template.Labels[key] {
+					continue
+				}
+
+				drifts = append(drifts, DriftItem{
+					Kind:     NodeLabelDrift,
+					Object:   "Node/" + node.Name,
+					Field:    key,
+					Expected: template.Labels[key],
+					Actual:   actual,
+				})
+			}
+
+			for _, taint := range template.Taints {
+				if hasTaint(node.Spec.Taints, taint) {
+					continue
+				}
+
+				drifts = append(drifts, DriftItem{
+					Kind:     NodeTaintDrift,
+					Object:   "Node/" + node.Name,
+					Field:    fmt.Sprintf("%s:%s", taint.Key, taint.Effect),
+					Expected: taint.Value,
+					Actual:   taintValue(node.Spec.Taints, taint),
+				})
+			}
+		}
+	}
+
+	return drifts, nil
+}
+
+func hasTaint(taints []corev1.Taint, expected corev1.Taint) bool {
+	for _, taint := range taints {
+		if taint.Key == expected.Key && taint.Effect == expected.Effect && taint.Value == expected.Value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func taintValue(taints []corev1.Taint, expected corev1.Taint) string {
+	for _, taint := range taints {
+		if taint.Key == expected.Key && taint.Effect == expected.Effect {
+			return taint.Value
+		}
+	}
+
+	return ""
+}
+
+// minorVersion returns "1.27" for the "v1.27.5" version
+func minorVersion(version string) string {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return version
+	}
+
+	return parts[0] + "." + parts[1]
+}
+
+func clusterConfigValue(metaConfig *config.MetaConfig, key string) string {
+	var value interface{}
+	if err := json.Unmarshal(metaConfig.ClusterConfig[key], &value); err != nil || value == nil {
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/deckhouse/deckhouse/dhctl/pkg/app"
+	"github.com/deckhouse/deckhouse/dhctl/pkg/events"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/kubernetes/client"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/log"
 	statecache "github.com/deckhouse/deckhouse/dhctl/pkg/state/cache"
@@ -62,10 +63,16 @@ func (r *InLockRunner) WithFullUnlock(f bool) *InLockRunner {
 	return r
 }
 
+// WithWaitTimeout makes the runner wait for the lock in the queue if the lock is held
+func (r *InLockRunner) WithWaitTimeout(timeout time.Duration) *InLockRunner {
+	r.lockConfig.WaitTimeout = timeout
+	return r
+}
+
 func (r *InLockRunner) Run(action func() error) error {
 	unlockConverge, err := lockLease(r.kubeCl, r.lockConfig, r.forceLock)
 	if err != nil {
-		return err
+		return events.WithCode(events.ConvergeLockFailedCode, err)
 	}
 	defer unlockConverge(r.fullUnlock)
 	tomb.RegisterOnShutdown("unlock converge", func() {
@@ -80,7 +87,7 @@ func LockConvergeFromLocal(kubeCl *client.KubernetesClient, identity string) (fu
 	lockConfig := GetLockLeaseConfig(localIdentity)
 	unlockConverge, err := lockLease(kubeCl, lockConfig, false)
 	if err != nil {
-		return nil, err
+		return nil, events.WithCode(events.ConvergeLockFailedCode, err)
 	}
 
 	tomb.RegisterOnShutdown("unlock converge", func() {
@@ -0,0 +1,241 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationclientv1 "k8s.io/client-go/kubernetes/typed/coordination/v1"
+)
+
+const (
+	lockQueueLabelKey = "dhctl.deckhouse.io/lease-lock-queue"
+
+	// queueTicketDurationSeconds is a lifetime of the queue ticket without renew,
+	// tickets of the waiters which were killed are ignored after it
+	queueTicketDurationSeconds int32 = 30
+)
+
+// LockQueueEntry is a waiter for the lease lock
+type LockQueueEntry struct {
+	Identity string       `json:"identity"`
+	User     LockUserInfo `json:"user"`
+	QueuedAt time.Time    `json:"queuedAt"`
+}
+
+// acquireInQueue waits for the lock in the queue. The queue is a set of leases (tickets) renewed by the waiters,
+// the waiter with the oldest ticket acquires the lock.
+func (l *LeaseLock) acquireInQueue() (*coordinationv1.Lease, error) {
+	ticket, err := l.enqueue()
+	if err != nil {
+		return nil, fmt.Errorf("enqueue lock waiter: %w", err)
+	}
+	defer l.dequeue(ticket.Name)
+
+	deadline := time.Now().Add(l.config.WaitTimeout)
+	for {
+		ticket, err = l.renewTicket(ticket)
+		if err != nil {
+			return nil, fmt.Errorf("renew lock queue ticket: %w", err)
+		}
+
+		waiters, err := listQueue(l.leasesCl, l.config.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(waiters) > 0 && waiters[0].Name == ticket.Name {
+			lease, err := l.tryAcquire(false)
+			if err == nil {
+				return lease, nil
+			}
+			if !isLockHeldError(err) {
+				return nil, err
+			}
+		}
+
+		if time.Now().After(deadline) {
+			lease, err := l.leasesCl.Get(context.TODO(), l.config.Name, metav1.GetOptions{})
+			if err != nil {
+				lease = nil
+			}
+			info, _ := LockInfo(lease)
+			return nil, fmt.Errorf("%s Timeout %s waiting in the queue is over.\n%s", cannotAcquireLockPrefix, l.config.WaitTimeout, info)
+		}
+
+		log.Infof("Waiting for the lock, position in the queue %d of %d", queuePosition(waiters, ticket.Name), len(waiters))
+		time.Sleep(l.config.RetryWaitDuration)
+	}
+}
+
+// checkQueue returns an error if other waiters are queued for the lock, so the lock does not jump the queue
+func (l *LeaseLock) checkQueue() error {
+	waiters, err := listQueue(l.leasesCl, l.config.Name)
+	if err != nil {
+		return err
+	}
+
+	var queued []string
+	for i := range waiters {
+		if *waiters[i].Spec.HolderIdentity != l.config.Identity {
+			queued = append(queued, *waiters[i].Spec.HolderIdentity)
+		}
+	}
+
+	if len(queued) > 0 {
+		return fmt.Errorf("%s Lock is queued by: %s", cannotAcquireLockPrefix, strings.Join(queued, ", "))
+	}
+
+	return nil
+}
+
+func (l *LeaseLock) enqueue() (*coordinationv1.Lease, error) {
+	userInfo, err := json.Marshal(NewLockUserInfo(l.config.AdditionalUserInfo))
+	if err != nil {
+		userInfo = nil
+	}
+
+	ticketDuration := queueTicketDurationSeconds
+	ticket := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        queueTicketName(l.config.Name, l.config.Identity),
+			Labels:      map[string]string{lockQueueLabelKey: l.config.Name},
+			Annotations: map[string]string{lockUserInfoAnnotKey: string(userInfo)},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &l.config.Identity,
+			AcquireTime:          now(),
+			RenewTime:            now(),
+			LeaseDurationSeconds: &ticketDuration,
+		},
+	}
+
+	created, err := l.leasesCl.Create(context.TODO(), ticket, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		// the ticket is left by the previous run with the same identity, it goes to the end of the queue
+		var existing *coordinationv1.Lease
+		existing, err = l.leasesCl.Get(context.TODO(), ticket.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		existing.Spec = ticket.Spec
+		return l.leasesCl.Update(context.TODO(), existing, metav1.UpdateOptions{})
+	}
+
+	return created, err
+}
+
+func (l *LeaseLock) renewTicket(ticket *coordinationv1.Lease) (*coordinationv1.Lease, error) {
+	ticket.Spec.RenewTime = now()
+	renewed, err := l.leasesCl.Update(context.TODO(), ticket, metav1.UpdateOptions{})
+	if errors.IsNotFound(err) {
+		log.Warnf("Lock queue ticket %s was deleted, enqueue again\n", ticket.Name)
+		return l.enqueue()
+	}
+
+	return renewed, err
+}
+
+func (l *LeaseLock) dequeue(name string) {
+	err := l.leasesCl.Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		log.Errorf("Error while delete lock queue ticket %s: %v", name, err)
+	}
+}
+
+// LockQueue returns the waiters for the lock in the order they acquire it
+func LockQueue(kubeCl *KubernetesClient, config *LeaseLockConfig) ([]LockQueueEntry, error) {
+	waiters, err := listQueue(kubeCl.CoordinationV1().Leases(config.Namespace), config.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]LockQueueEntry, 0, len(waiters))
+	for i := range waiters {
+		_, userInfo := LockInfo(&waiters[i])
+		entries = append(entries, LockQueueEntry{
+			Identity: *waiters[i].Spec.HolderIdentity,
+			User:     *userInfo,
+			QueuedAt: waiters[i].Spec.AcquireTime.Time,
+		})
+	}
+
+	return entries, nil
+}
+
+// listQueue returns alive tickets ordered by the enqueue time
+func listQueue(leasesCl coordinationclientv1.LeaseInterface, lockName string) ([]coordinationv1.Lease, error) {
+	list, err := leasesCl.List(context.TODO(), metav1.ListOptions{LabelSelector: lockQueueLabelKey + "=" + lockName})
+	if err != nil {
+		return nil, fmt.Errorf("list lock queue: %w", err)
+	}
+
+	waiters := make([]coordinationv1.Lease, 0, len(list.Items))
+	for _, ticket := range list.Items {
+		if ticket.Spec.HolderIdentity == nil || ticket.Spec.AcquireTime == nil || ticket.Spec.RenewTime == nil {
+			continue
+		}
+
+		duration := time.Duration(queueTicketDurationSeconds) * time.Second
+		if ticket.Spec.LeaseDurationSeconds != nil {
+			duration = time.Duration(*ticket.Spec.LeaseDurationSeconds) * time.Second
+		}
+		if time.Now().After(ticket.Spec.RenewTime.Add(duration)) {
+			continue
+		}
+
+		waiters = append(waiters, ticket)
+	}
+
+	sort.SliceStable(waiters, func(i, j int) bool {
+		ti, tj := waiters[i].Spec.AcquireTime.Time, waiters[j].Spec.AcquireTime.Time
+		if ti.Equal(tj) {
+			return waiters[i].Name < waiters[j].Name
+		}
+		return ti.Before(tj)
+	})
+
+	return waiters, nil
+}
+
+func queuePosition(waiters []coordinationv1.Lease, name string) int {
+	for i := range waiters {
+		if waiters[i].Name == name {
+			return i + 1
+		}
+	}
+
+	return len(waiters) + 1
+}
+
+// queueTicketName returns a valid object name for any identity
+func queueTicketName(lockName, identity string) string {
+	sum := sha256.Sum256([]byte(identity))
+	return fmt.Sprintf("%s-queue-%x", lockName, sum[:8])
+}
+
+func isLockHeldError(err error) bool {
+	return strings.HasPrefix(err.Error(), cannotAcquireLockPrefix)
+}
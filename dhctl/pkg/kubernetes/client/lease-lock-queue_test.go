@@ -0,0 +1,103 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testLockConfig(identity string, waitTimeout time.Duration) LeaseLockConfig {
+	return LeaseLockConfig{
+		Name:                 "d8-converge-lock",
+		Namespace:            "d8-system",
+		Identity:             identity,
+		LeaseDurationSeconds: 300,
+		RenewEverySeconds:    180,
+		RetryWaitDuration:    10 * time.Millisecond,
+		WaitTimeout:          waitTimeout,
+	}
+}
+
+func TestLeaseLockQueue(t *testing.T) {
+	t.Run("Waiter times out while the lock is held", func(t *testing.T) {
+		kubeCl := NewFakeKubernetesClient()
+
+		holder := NewLeaseLock(kubeCl, testLockConfig("holder", 0))
+		require.NoError(t, holder.Lock(false))
+		defer holder.StopAutoRenew()
+
+		waiter := NewLeaseLock(kubeCl, testLockConfig("waiter", 50*time.Millisecond))
+		err := waiter.Lock(false)
+		require.ErrorContains(t, err, "waiting in the queue is over")
+		require.ErrorContains(t, err, "Locker ID: holder")
+
+		// the ticket is removed after the timeout
+		queue, err := LockQueue(kubeCl, &waiter.config)
+		require.NoError(t, err)
+		require.Empty(t, queue)
+	})
+
+	t.Run("Lock does not jump the queue", func(t *testing.T) {
+		kubeCl := NewFakeKubernetesClient()
+
+		waiter := NewLeaseLock(kubeCl, testLockConfig("waiter", time.Minute))
+		_, err := waiter.enqueue()
+		require.NoError(t, err)
+
+		err = NewLeaseLock(kubeCl, testLockConfig("auto-converger", 0)).Lock(false)
+		require.ErrorContains(t, err, "Lock is queued by: waiter")
+
+		require.NoError(t, waiter.Lock(false))
+		defer waiter.StopAutoRenew()
+
+		lease, err := kubeCl.CoordinationV1().Leases("d8-system").Get(context.TODO(), "d8-converge-lock", metav1.GetOptions{})
+		require.NoError(t, err)
+		require.Equal(t, "waiter", *lease.Spec.HolderIdentity)
+	})
+
+	t.Run("Waiters are ordered by the enqueue time, expired tickets are skipped", func(t *testing.T) {
+		kubeCl := NewFakeKubernetesClient()
+
+		for _, identity := range []string{"first", "second", "dead"} {
+			_, err := NewLeaseLock(kubeCl, testLockConfig(identity, time.Minute)).enqueue()
+			require.NoError(t, err)
+		}
+
+		leasesCl := kubeCl.CoordinationV1().Leases("d8-system")
+		dead, err := leasesCl.Get(context.TODO(), queueTicketName("d8-converge-lock", "dead"), metav1.GetOptions{})
+		require.NoError(t, err)
+		dead.Spec.RenewTime = &metav1.MicroTime{Time: time.Now().Add(-time.Minute)}
+		_, err = leasesCl.Update(context.TODO(), dead, metav1.UpdateOptions{})
+		require.NoError(t, err)
+
+		first, err := leasesCl.Get(context.TODO(), queueTicketName("d8-converge-lock", "first"), metav1.GetOptions{})
+		require.NoError(t, err)
+		first.Spec.AcquireTime = &metav1.MicroTime{Time: time.Now().Add(-time.Second)}
+		_, err = leasesCl.Update(context.TODO(), first, metav1.UpdateOptions{})
+		require.NoError(t, err)
+
+		config := testLockConfig("status", 0)
+		queue, err := LockQueue(kubeCl, &config)
+		require.NoError(t, err)
+		require.Len(t, queue, 2)
+		require.Equal(t, "first", queue[0].Identity)
+		require.Equal(t, "second", queue[1].Identity)
+	})
+}
@@ -21,7 +21,6 @@ import (
 	"math"
 	"os"
 	"os/user"
-	"strings"
 	"sync"
 	"time"
 
@@ -34,7 +33,11 @@ import (
 	"github.com/deckhouse/deckhouse/dhctl/pkg/util/retry"
 )
 
-const lockUserInfoAnnotKey = "dhctl.deckhouse.io/lock-user-info"
+const (
+	lockUserInfoAnnotKey = "dhctl.deckhouse.io/lock-user-info"
+
+	cannotAcquireLockPrefix = "Can't acquire lease lock."
+)
 
 type LeaseLockConfig struct {
 	Name      string
@@ -48,6 +51,9 @@ type LeaseLockConfig struct {
 	OnRenewError func(err error)
 
 	AdditionalUserInfo string
+
+	// WaitTimeout is a time to wait for the lock in the queue, the lock fails immediately if the lock is held and it is zero
+	WaitTimeout time.Duration
 }
 
 // RenewRetries returns a number of possible retries between renew seconds and lease lifetime seconds.
@@ -103,7 +109,22 @@ func (l *LeaseLock) Lock(force bool) error {
 	l.lockLease.Lock()
 	defer l.lockLease.Unlock()
 
-	lease, err := l.tryAcquire(force)
+	var (
+		lease *coordinationv1.Lease
+		err   error
+	)
+
+	switch {
+	case force:
+		lease, err = l.tryAcquire(true)
+	case l.config.WaitTimeout > 0:
+		lease, err = l.acquireInQueue()
+	default:
+		if err = l.checkQueue(); err != nil {
+			return err
+		}
+		lease, err = l.tryAcquire(false)
+	}
 	if err != nil {
 		return err
 	}
@@ -173,10 +194,8 @@ func (l *LeaseLock) startAutoRenew() {
 func (l *LeaseLock) tryAcquire(force bool) (*coordinationv1.Lease, error) {
 	var lease *coordinationv1.Lease
 
-	prefix := "Can't acquire lease lock."
-	cannotRenew := func(err error) bool {
-		return strings.HasPrefix(err.Error(), prefix)
-	}
+	prefix := cannotAcquireLockPrefix
+	cannotRenew := isLockHeldError
 
 	acquireRetries := l.config.RenewRetries()
 	err := retry.NewSilentLoop("acquire lease", acquireRetries, l.config.RetryWaitDuration).BreakIf(cannotRenew).Run(func() error {
@@ -326,6 +345,64 @@ func LockInfo(lease *coordinationv1.Lease) (string, *LockUserInfo) {
 	), &userInfo
 }
 
+// LeaseLockStatus describes the holder of the lock and the waiters for it
+type LeaseLockStatus struct {
+	Locked bool             `json:"locked"`
+	Holder *LockHolder      `json:"holder,omitempty"`
+	Queue  []LockQueueEntry `json:"queue"`
+}
+
+type LockHolder struct {
+	Identity             string       `json:"identity"`
+	User                 LockUserInfo `json:"user"`
+	AcquireTime          time.Time    `json:"acquireTime"`
+	RenewTime            time.Time    `json:"renewTime"`
+	LeaseDurationSeconds int32        `json:"leaseDurationSeconds"`
+	// Expired is true if the holder stopped to renew the lease, the lock can be acquired by another owner
+	Expired bool `json:"expired"`
+}
+
+func GetLockStatus(kubeCl *KubernetesClient, config *LeaseLockConfig) (*LeaseLockStatus, error) {
+	queue, err := LockQueue(kubeCl, config)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &LeaseLockStatus{Queue: queue}
+
+	lease, err := kubeCl.CoordinationV1().Leases(config.Namespace).Get(context.TODO(), config.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, userInfo := LockInfo(lease)
+	holder := &LockHolder{
+		User:                 *userInfo,
+		LeaseDurationSeconds: -1,
+	}
+	if lease.Spec.HolderIdentity != nil {
+		holder.Identity = *lease.Spec.HolderIdentity
+	}
+	if lease.Spec.AcquireTime != nil {
+		holder.AcquireTime = lease.Spec.AcquireTime.Time
+	}
+	if lease.Spec.RenewTime != nil {
+		holder.RenewTime = lease.Spec.RenewTime.Time
+	}
+	if lease.Spec.LeaseDurationSeconds != nil {
+		holder.LeaseDurationSeconds = *lease.Spec.LeaseDurationSeconds
+		holder.Expired = time.Now().After(holder.RenewTime.Add(time.Duration(holder.LeaseDurationSeconds) * time.Second))
+	}
+
+	status.Locked = !holder.Expired
+	status.Holder = holder
+
+	return status, nil
+}
+
 func RemoveLease(kubeCl *KubernetesClient, config *LeaseLockConfig, confirm func(lease *coordinationv1.Lease) error) error {
 	leasesCl := kubeCl.CoordinationV1().Leases(config.Namespace)
 	lease, err := leasesCl.Get(context.TODO(), config.Name, metav1.GetOptions{})
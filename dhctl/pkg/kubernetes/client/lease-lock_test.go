@@ -32,3 +32,29 @@ func TestRenewRetryCount(t *testing.T) {
 		require.Equal(t, 40, lockConf.RenewRetries())
 	})
 }
+
+func TestGetLockStatus(t *testing.T) {
+	kubeCl := NewFakeKubernetesClient()
+	config := testLockConfig("status", 0)
+
+	status, err := GetLockStatus(kubeCl, &config)
+	require.NoError(t, err)
+	require.False(t, status.Locked)
+	require.Nil(t, status.Holder)
+
+	holder := NewLeaseLock(kubeCl, testLockConfig("holder", 0))
+	require.NoError(t, holder.Lock(false))
+	defer holder.StopAutoRenew()
+
+	_, err = NewLeaseLock(kubeCl, testLockConfig("waiter", time.Minute)).enqueue()
+	require.NoError(t, err)
+
+	status, err = GetLockStatus(kubeCl, &config)
+	require.NoError(t, err)
+	require.True(t, status.Locked)
+	require.Equal(t, "holder", status.Holder.Identity)
+	require.False(t, status.Holder.Expired)
+	require.NotEmpty(t, status.Holder.User.Host)
+	require.Len(t, status.Queue, 1)
+	require.Equal(t, "waiter", status.Queue[0].Identity)
+}
@@ -31,6 +31,7 @@ import (
 
 	"github.com/deckhouse/deckhouse/dhctl/pkg/app"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/config"
+	"github.com/deckhouse/deckhouse/dhctl/pkg/events"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/kubernetes/actions/converge"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/kubernetes/actions/deckhouse"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/kubernetes/client"
@@ -111,9 +112,9 @@ func ExecuteBashibleBundle(sshClient *ssh.Client, tmpDir string) error {
 		_, err := bundleCmd.ExecuteBundle(parentDir, bundleDir)
 		if err != nil {
 			if ee, ok := err.(*exec.ExitError); ok {
-				return fmt.Errorf("bundle '%s' error: %v\nstderr: %s", bundleDir, err, string(ee.Stderr))
+				return events.WithCode(events.NodeBootstrapFailedCode, fmt.Errorf("bundle '%s' error: %v\nstderr: %s", bundleDir, err, string(ee.Stderr)))
 			}
-			return fmt.Errorf("bundle '%s' error: %v", bundleDir, err)
+			return events.WithCode(events.NodeBootstrapFailedCode, fmt.Errorf("bundle '%s' error: %v", bundleDir, err))
 		}
 		return nil
 	})
@@ -35,6 +35,9 @@ type Params struct {
 	InitialState phases.DhctlState
 	OnPhaseFunc  phases.OnPhaseFunc
 
+	// LockWaitTimeout is a time to wait for the converge lock in the queue, converge fails immediately if it is zero
+	LockWaitTimeout time.Duration
+
 	*client.KubernetesInitParams
 }
 
@@ -99,7 +102,8 @@ func (c *Converger) Converge() error {
 	if err != nil {
 		return fmt.Errorf("unable to initialize cache %s: %w", cacheIdentity, err)
 	}
-	inLockRunner := converge.NewInLockLocalRunner(kubeCl, "local-converger")
+	inLockRunner := converge.NewInLockLocalRunner(kubeCl, "local-converger").
+		WithWaitTimeout(c.LockWaitTimeout)
 
 	stateCache := cache.Global()
 
@@ -15,8 +15,10 @@
 package operations
 
 import (
+	"encoding/json"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -57,6 +59,9 @@ type ConvergeExporter struct {
 
 	existedEntities *previouslyExistedEntities
 
+	driftMu     sync.RWMutex
+	driftReport *converge.DriftReport
+
 	GaugeMetrics   map[string]*prometheus.GaugeVec
 	CounterMetrics map[string]*prometheus.CounterVec
 }
@@ -152,6 +157,17 @@ func (c *ConvergeExporter) registerMetrics() {
 	prometheus.MustRegister(nodeTemplateStateVec)
 	c.GaugeMetrics["node_template_status"] = nodeTemplateStateVec
 
+	driftVec := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "candi",
+		Subsystem: "converge",
+		Name:      "drift",
+		Help:      "Difference between the desired cluster configuration and the actual cluster state",
+	},
+		[]string{"kind", "object", "field"},
+	)
+	prometheus.MustRegister(driftVec)
+	c.GaugeMetrics["drift"] = driftVec
+
 	errorsVec := prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace: "candi",
 		Subsystem: "converge",
@@ -176,12 +192,14 @@ func (c *ConvergeExporter) Start() {
 
 	http.Handle(c.MetricsPath, promhttp.Handler())
 	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("ok")) })
+	http.HandleFunc("/drift", c.serveDrift)
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		_, _ = w.Write([]byte(`<html>
              <head><title>CandI Converge Exporter</title></head>
              <body>
              <h1>CandI Converge Exporter</h1>
              <p><a href='` + c.MetricsPath + `'>Metrics</a></p>
+             <p><a href='/drift'>Drift</a></p>
              </body>
              </html>`))
 	})
@@ -195,6 +213,7 @@ func (c *ConvergeExporter) Start() {
 
 func (c *ConvergeExporter) convergeLoop(stopCh chan struct{}) {
 	c.recordStatistic(c.getStatistic())
+	c.recordDrift(c.getDrift())
 
 	ticker := time.NewTicker(c.CheckInterval)
 	defer ticker.Stop()
@@ -204,6 +223,7 @@ func (c *ConvergeExporter) convergeLoop(stopCh chan struct{}) {
 		case <-ticker.C:
 			cache.ClearTemporaryDirs()
 			c.recordStatistic(c.getStatistic())
+			c.recordDrift(c.getDrift())
 		case <-stopCh:
 			log.ErrorLn("Stop exporter...")
 			return
@@ -295,3 +315,55 @@ func (c *ConvergeExporter) recordStatistic(statistic *converge.Statistics) {
 	// getStatistic is executed in a loop by timer, so no race condition here
 	c.existedEntities = newExistedEntities
 }
+
+func (c *ConvergeExporter) getDrift() *converge.DriftReport {
+	metaConfig, err := config.ParseConfigInCluster(c.kubeCl)
+	if err != nil {
+		log.ErrorLn(err)
+		c.CounterMetrics["errors"].WithLabelValues().Inc()
+		return nil
+	}
+
+	report, err := converge.CheckDrift(c.kubeCl, metaConfig)
+	if err != nil {
+		log.ErrorLn(err)
+		c.CounterMetrics["errors"].WithLabelValues().Inc()
+		return nil
+	}
+
+	return report
+}
+
+func (c *ConvergeExporter) recordDrift(report *converge.DriftReport) {
+	if report == nil {
+		return
+	}
+
+	// drifts fixed since the previous check disappear from the metric
+	c.GaugeMetrics["drift"].Reset()
+	for _, drift := range report.Drifts {
+		c.GaugeMetrics["drift"].WithLabelValues(drift.Kind, drift.Object, drift.Field).Set(1)
+		log.InfoF("%s: %s drift of %s, expected %q, actual %q\n", drift.Object, drift.Kind, drift.Field, drift.Expected, drift.Actual)
+	}
+
+	c.driftMu.Lock()
+	c.driftReport = report
+	c.driftMu.Unlock()
+}
+
+// serveDrift returns the detailed report of the last drift check
+func (c *ConvergeExporter) serveDrift(w http.ResponseWriter, _ *http.Request) {
+	c.driftMu.RLock()
+	report := c.driftReport
+	c.driftMu.RUnlock()
+
+	if report == nil {
+		http.Error(w, "drift is not checked yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.ErrorF("Error while write drift report: %v\n", err)
+	}
+}
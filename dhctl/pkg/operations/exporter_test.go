@@ -15,13 +15,23 @@
 package operations
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	io_prometheus_client "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
+	"github.com/deckhouse/deckhouse/dhctl/pkg/config"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/kubernetes/actions/converge"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/kubernetes/client"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/log"
@@ -112,3 +122,121 @@ func TestExporterGetStatistic(t *testing.T) {
 		require.Equal(t, exporter.existedEntities.Nodes, map[string]string{"test-0": "test"})
 	})
 }
+
+func TestExporterDrift(t *testing.T) {
+	log.InitLogger("simple")
+
+	nodeGroupGVR := schema.GroupVersionResource{Group: "deckhouse.io", Version: "v1", Resource: "nodegroups"}
+	kubeCl := client.NewFakeKubernetesClientWithListGVR(map[schema.GroupVersionResource]string{
+		nodeGroupGVR: "NodeGroupList",
+	})
+
+	newNode := func(name, group, kubeletVersion string, labels map[string]string, taints ...corev1.Taint) {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"node.deckhouse.io/group": group}},
+			Spec:       corev1.NodeSpec{Taints: taints},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KubeletVersion: kubeletVersion}},
+		}
+		for key, value := range labels {
+			node.Labels[key] = value
+		}
+		_, err := kubeCl.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	newNode("master-0", "master", "v1.27.5", nil)
+	newNode("worker-0", "worker", "v1.27.5", map[string]string{"role": "worker"},
+		corev1.Taint{Key: "dedicated", Value: "worker", Effect: corev1.TaintEffectNoSchedule})
+	newNode("worker-1", "worker", "v1.26.9", map[string]string{"role": "manual"})
+
+	nodeGroup := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "deckhouse.io/v1",
+		"kind":       "NodeGroup",
+		"metadata":   map[string]interface{}{"name": "worker"},
+		"spec": map[string]interface{}{
+			"nodeTemplate": map[string]interface{}{
+				"labels": map[string]interface{}{"role": "worker"},
+				"taints": []interface{}{
+					map[string]interface{}{"key": "dedicated", "value": "worker", "effect": "NoSchedule"},
+				},
+			},
+		},
+	}}
+	_, err := kubeCl.Dynamic().Resource(nodeGroupGVR).Create(context.TODO(), nodeGroup, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	controllerManager := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kube-controller-manager-master-0",
+			Namespace: "kube-system",
+			Labels:    map[string]string{"tier": "control-plane", "component": "kube-controller-manager"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "kube-controller-manager",
+			Command: []string{
+				"kube-controller-manager",
+				"--cluster-cidr=10.111.0.0/16",
+				"--service-cluster-ip-range=10.222.0.0/16",
+			},
+		}}},
+	}
+	_, err = kubeCl.CoreV1().Pods("kube-system").Create(context.TODO(), controllerManager, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	metaConfig := &config.MetaConfig{
+		ClusterType:         config.CloudClusterType,
+		MasterNodeGroupSpec: config.MasterNodeGroupSpec{Replicas: 3},
+		ClusterConfig: map[string]json.RawMessage{
+			"kubernetesVersion": json.RawMessage(`"1.27"`),
+			"serviceSubnetCIDR": json.RawMessage(`"10.222.0.0/16"`),
+			"podSubnetCIDR":     json.RawMessage(`"10.100.0.0/16"`),
+		},
+	}
+
+	report, err := converge.CheckDrift(kubeCl, metaConfig)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []converge.DriftItem{
+		{Kind: converge.KubernetesVersionDrift, Object: "Node/worker-1", Field: "kubeletVersion", Expected: "1.27", Actual: "1.26"},
+		{Kind: converge.ControlPlaneFlagDrift, Object: "Pod/kube-controller-manager-master-0", Field: "--cluster-cidr", Expected: "10.100.0.0/16", Actual: "10.111.0.0/16"},
+		{Kind: converge.MasterReplicasDrift, Object: "NodeGroup/master", Field: "replicas", Expected: "3", Actual: "1"},
+		{Kind: converge.NodeLabelDrift, Object: "Node/worker-1", Field: "role", Expected: "worker", Actual: "manual"},
+		{Kind: converge.NodeTaintDrift, Object: "Node/worker-1", Field: "dedicated:NoSchedule", Expected: "worker", Actual: ""},
+	}, report.Drifts)
+
+	exporter := &ConvergeExporter{
+		kubeCl:          kubeCl,
+		existedEntities: newPreviouslyExistedEntities(),
+		GaugeMetrics:    make(map[string]*prometheus.GaugeVec),
+		CounterMetrics:  make(map[string]*prometheus.CounterVec),
+	}
+	// metrics of the other tests are registered in the default registry
+	exporter.GaugeMetrics["drift"] = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "drift"}, []string{"kind", "object", "field"})
+
+	t.Run("Drift report is not ready before the first check", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		exporter.serveDrift(recorder, httptest.NewRequest(http.MethodGet, "/drift", nil))
+		require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	})
+
+	t.Run("Drifts are exposed as metrics and the JSON report", func(t *testing.T) {
+		exporter.recordDrift(report)
+
+		collected := io_prometheus_client.Metric{}
+		err := exporter.GaugeMetrics["drift"].WithLabelValues(converge.NodeLabelDrift, "Node/worker-1", "role").Write(&collected)
+		require.NoError(t, err)
+		require.Equal(t, float64(1), *collected.Gauge.Value)
+
+		recorder := httptest.NewRecorder()
+		exporter.serveDrift(recorder, httptest.NewRequest(http.MethodGet, "/drift", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var served converge.DriftReport
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &served))
+		require.Len(t, served.Drifts, len(report.Drifts))
+	})
+
+	t.Run("Fixed drifts disappear from metrics", func(t *testing.T) {
+		exporter.recordDrift(&converge.DriftReport{CheckTime: time.Now()})
+		require.Equal(t, 0, testutil.CollectAndCount(exporter.GaugeMetrics["drift"]))
+	})
+}
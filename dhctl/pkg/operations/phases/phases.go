@@ -17,6 +17,8 @@ package phases
 import (
 	"errors"
 	"fmt"
+
+	"github.com/deckhouse/deckhouse/dhctl/pkg/events"
 	dstate "github.com/deckhouse/deckhouse/dhctl/pkg/state"
 )
 
@@ -87,6 +89,11 @@ func (pec *PhasedExecutionContext) StartPhase(phase OperationPhase, isCritical b
 		return false, nil
 	}
 
+	if pec.currentPhase != "" && pec.currentPhase != phase {
+		events.PhaseCompleted(string(pec.currentPhase))
+	}
+	events.PhaseStarted(string(phase))
+
 	pec.currentPhase = phase
 	return pec.callOnPhase(pec.completedPhase, pec.lastState, phase, isCritical)
 }
@@ -106,6 +113,7 @@ func (pec *PhasedExecutionContext) Complete() error {
 	if pec.completedPhase == "" {
 		return nil
 	}
+	events.PhaseCompleted(string(pec.completedPhase))
 	_, err := pec.callOnPhase(pec.completedPhase, pec.lastState, "", false)
 	return err
 }
@@ -0,0 +1,190 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/deckhouse/deckhouse/dhctl/pkg/log"
+	"github.com/deckhouse/deckhouse/dhctl/pkg/state"
+)
+
+// Store is a key-value storage of the state backend
+type Store interface {
+	Get(key string) ([]byte, bool, error)
+	Put(key string, content []byte) error
+	Delete(key string) error
+	List() ([]string, error)
+}
+
+// StateCache keeps the state in the store, optionally encrypted.
+// Terraform works with the local copy of the state in the tmp dir, which is saved to the store after every change.
+type StateCache struct {
+	store  Store
+	cipher *Cipher
+	tmpDir string
+}
+
+func NewStateCache(store Store, cipher *Cipher, tmpDir string) (*StateCache, error) {
+	if err := os.MkdirAll(tmpDir, 0o700); err != nil {
+		return nil, fmt.Errorf("can't create cache directory: %w", err)
+	}
+
+	return &StateCache{store: store, cipher: cipher, tmpDir: tmpDir}, nil
+}
+
+// ReplaceState removes the keys from the store and saves the given state instead
+func (c *StateCache) ReplaceState(initialState map[string][]byte) error {
+	keys, err := c.store.List()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := c.store.Delete(key); err != nil {
+			return fmt.Errorf("unable to remove %s: %w", key, err)
+		}
+	}
+
+	for key, content := range initialState {
+		if err := c.Save(key, content); err != nil {
+			return fmt.Errorf("error writing %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *StateCache) Save(name string, content []byte) error {
+	if c.cipher != nil {
+		var err error
+		content, err = c.cipher.Encrypt(name, content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.store.Put(name, content)
+}
+
+func (c *StateCache) SaveStruct(name string, v interface{}) error {
+	b := new(bytes.Buffer)
+	err := gob.NewEncoder(b).Encode(v)
+	if err != nil {
+		return err
+	}
+
+	return c.Save(name, b.Bytes())
+}
+
+func (c *StateCache) Load(name string) ([]byte, error) {
+	content, found, err := c.store.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("state %s: %w", name, os.ErrNotExist)
+	}
+
+	if c.cipher != nil {
+		return c.cipher.Decrypt(name, content)
+	}
+
+	return content, nil
+}
+
+func (c *StateCache) LoadStruct(name string, v interface{}) error {
+	d, err := c.Load(name)
+	if err != nil {
+		return fmt.Errorf("can't load struct for key %s: %v", name, err)
+	}
+
+	return gob.NewDecoder(bytes.NewBuffer(d)).Decode(v)
+}
+
+func (c *StateCache) Delete(name string) {
+	if err := c.store.Delete(name); err != nil {
+		log.ErrorF("Cannot delete cache %s val %v\n", name, err)
+	}
+}
+
+func (c *StateCache) Clean() {
+	c.CleanWithExceptions()
+}
+
+func (c *StateCache) CleanWithExceptions(excludeKeys ...string) {
+	excludeKeysSet := map[string]struct{}{state.TombstoneKey: {}}
+	for _, k := range excludeKeys {
+		excludeKeysSet[k] = struct{}{}
+	}
+
+	keys, err := c.store.List()
+	if err != nil {
+		log.WarnF("Can't getting keys to remove: %s ...\n", err)
+		return
+	}
+
+	// write tombstone first, it is idempotent
+	if err := c.Save(state.TombstoneKey, []byte("yes")); err != nil {
+		log.WarnF("Can't mark the cache as exhausted: %s ...\n", err)
+	}
+
+	for _, key := range keys {
+		if _, ok := excludeKeysSet[key]; ok {
+			continue
+		}
+		c.Delete(key)
+	}
+}
+
+func (c *StateCache) GetPath(name string) string {
+	return filepath.Join(c.tmpDir, name)
+}
+
+func (c *StateCache) Iterate(iterFunc func(string, []byte) error) error {
+	keys, err := c.store.List()
+	if err != nil {
+		return fmt.Errorf("can't iterate the cache: %w", err)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		content, err := c.Load(key)
+		if err != nil {
+			return err
+		}
+
+		if err := iterFunc(key, content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *StateCache) InCache(name string) (bool, error) {
+	_, found, err := c.store.Get(name)
+	return found, err
+}
+
+func (c *StateCache) NeedIntermediateSave() bool {
+	// terraform writes the state to the local copy, need sync every intermediate states
+	return true
+}
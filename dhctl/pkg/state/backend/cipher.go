@@ -0,0 +1,99 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+)
+
+// encryptedMagic is the header of the encrypted content, it is followed by the nonce and the ciphertext
+var encryptedMagic = []byte("DHCTLENC1")
+
+// Cipher encrypts the state with AES-256-GCM
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipherFromFile reads the base64 encoded 32 bytes key, e.g., generated by `head -c 32 /dev/urandom | base64`
+func NewCipherFromFile(path string) (*Cipher, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read state encryption key: %w", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(content)))
+	if err != nil {
+		return nil, fmt.Errorf("decode state encryption key: %w", err)
+	}
+
+	return NewCipher(key)
+}
+
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("state encryption key must be 32 bytes long, got %d bytes", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt encrypts the content, the name is authenticated to prevent swapping the encrypted values of the keys
+func (c *Cipher) Encrypt(name string, content []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+
+	encrypted := make([]byte, 0, len(encryptedMagic)+len(nonce)+len(content)+c.aead.Overhead())
+	encrypted = append(encrypted, encryptedMagic...)
+	encrypted = append(encrypted, nonce...)
+
+	return c.aead.Seal(encrypted, nonce, content, []byte(name)), nil
+}
+
+func (c *Cipher) Decrypt(name string, content []byte) ([]byte, error) {
+	if !bytes.HasPrefix(content, encryptedMagic) {
+		return nil, fmt.Errorf("state %s is not encrypted", name)
+	}
+
+	content = content[len(encryptedMagic):]
+	if len(content) < c.aead.NonceSize() {
+		return nil, fmt.Errorf("state %s is corrupted", name)
+	}
+
+	nonce, ciphertext := content[:c.aead.NonceSize()], content[c.aead.NonceSize():]
+	decrypted, err := c.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt state %s, probably the encryption key is wrong: %w", name, err)
+	}
+
+	return decrypted, nil
+}
@@ -0,0 +1,468 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// lockKey is the object created while the state is used, it is not a part of the state
+const lockKey = ".lock"
+
+var _ Store = &S3Store{}
+
+type S3Config struct {
+	Endpoint        string
+	Bucket          string
+	Prefix          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// S3Store keeps the state in the S3-compatible bucket, the objects are addressed in the path style.
+// Requests are signed with AWS Signature Version 4.
+// The store must support the conditional writes (the If-None-Match header of PutObject) for the lock,
+// e.g., AWS S3 or MinIO.
+type S3Store struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+
+	// lockToken is the token of the lock object created by this store
+	lockToken string
+}
+
+// S3Lock is the content of the lock object
+type S3Lock struct {
+	Identity string    `json:"identity"`
+	Host     string    `json:"host"`
+	LockedAt time.Time `json:"lockedAt"`
+	// Token is unique for every lock, the owner of the lock checks it
+	Token string `json:"token"`
+}
+
+func NewS3Store(config S3Config) (*S3Store, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, fmt.Errorf("S3 endpoint and bucket are required for the S3 state backend")
+	}
+
+	endpoint, err := url.Parse(strings.TrimSuffix(config.Endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("parse S3 endpoint: %w", err)
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("S3 endpoint must be an URL, e.g., https://s3.example.com")
+	}
+
+	return &S3Store{
+		config:   config,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+func (s *S3Store) Get(key string) ([]byte, bool, error) {
+	resp, err := s.do(http.MethodGet, s.objectKey(key), nil, nil, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, s3Error(resp, "get", key)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("read S3 object %s: %w", key, err)
+	}
+
+	return content, true, nil
+}
+
+func (s *S3Store) Put(key string, content []byte) error {
+	return s.put(key, content, nil)
+}
+
+func (s *S3Store) put(key string, content []byte, headers map[string]string) error {
+	resp, err := s.do(http.MethodPut, s.objectKey(key), nil, headers, content)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp, "put", key)
+	}
+
+	return nil
+}
+
+func (s *S3Store) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, s.objectKey(key), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3Error(resp, "delete", key)
+	}
+
+	return nil
+}
+
+// List returns the state keys, the lock object is skipped
+func (s *S3Store) List() ([]string, error) {
+	prefix := s.objectKey("")
+	keys := make([]string, 0)
+	continuationToken := ""
+
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		resp, err := s.do(http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			err = s3Error(resp, "list", prefix)
+			resp.Body.Close()
+			return nil, err
+		}
+
+		var result struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode S3 objects list: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			key := strings.TrimPrefix(object.Key, prefix)
+			if key == "" || key == lockKey || strings.Contains(key, "/") {
+				continue
+			}
+			keys = append(keys, key)
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+// Lock creates the lock object if it does not exist, so dhctl runs with the same state on different hosts
+// do not overwrite the state of each other. The stale lock of the killed dhctl is removed manually.
+// The lock is refused if the store ignores the If-None-Match header, the lock does not work in such stores.
+func (s *S3Store) Lock(identity string) error {
+	token, err := randomToken()
+	if err != nil {
+		return fmt.Errorf("lock the state: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	content, err := json.Marshal(S3Lock{Identity: identity, Host: host, LockedAt: time.Now().UTC(), Token: token})
+	if err != nil {
+		return err
+	}
+
+	err = s.put(lockKey, content, map[string]string{"If-None-Match": "*"})
+	if err != nil {
+		if isPreconditionFailed(err) {
+			return s.lockedError()
+		}
+		return fmt.Errorf("lock the state: %w", err)
+	}
+
+	// the second conditional write of the same object must fail, otherwise the store does not support the conditional writes
+	err = s.put(lockKey, content, map[string]string{"If-None-Match": "*"})
+	switch {
+	case err == nil:
+		_ = s.Delete(lockKey)
+		return fmt.Errorf("S3 endpoint %s does not support the conditional writes (the If-None-Match header), "+
+			"the state cannot be locked. Use the S3 storage with the conditional writes, e.g., AWS S3 or MinIO", s.endpoint)
+	case !isPreconditionFailed(err):
+		return fmt.Errorf("lock the state: %w", err)
+	}
+
+	// read the lock back to make sure the lock is not overwritten
+	lock, err := s.readLock()
+	if err != nil {
+		return fmt.Errorf("lock the state: %w", err)
+	}
+	if lock.Token != token {
+		return s.lockedError()
+	}
+
+	s.lockToken = token
+	return nil
+}
+
+// Unlock removes the lock object if it is created by this store
+func (s *S3Store) Unlock() error {
+	if s.lockToken == "" {
+		return nil
+	}
+
+	lock, err := s.readLock()
+	if err != nil {
+		return fmt.Errorf("unlock the state: %w", err)
+	}
+	if lock.Token != s.lockToken {
+		return fmt.Errorf("the lock s3://%s/%s is taken over by %q on the host %q, it is not removed",
+			s.config.Bucket, s.objectKey(lockKey), lock.Identity, lock.Host)
+	}
+
+	if err := s.Delete(lockKey); err != nil {
+		return err
+	}
+
+	s.lockToken = ""
+	return nil
+}
+
+func (s *S3Store) readLock() (S3Lock, error) {
+	lock := S3Lock{}
+	content, found, err := s.Get(lockKey)
+	if err != nil || !found {
+		return lock, err
+	}
+
+	if err := json.Unmarshal(content, &lock); err != nil {
+		return lock, fmt.Errorf("decode the lock s3://%s/%s: %w", s.config.Bucket, s.objectKey(lockKey), err)
+	}
+
+	return lock, nil
+}
+
+func (s *S3Store) lockedError() error {
+	lock, _ := s.readLock()
+	return fmt.Errorf("the state is locked by %q on the host %q since %s. Remove the s3://%s/%s object if the lock is stale",
+		lock.Identity, lock.Host, lock.LockedAt.Format(time.RFC3339), s.config.Bucket, s.objectKey(lockKey))
+}
+
+func isPreconditionFailed(err error) bool {
+	var s3Err *S3Error
+	return errors.As(err, &s3Err) && (s3Err.StatusCode == http.StatusPreconditionFailed || s3Err.StatusCode == http.StatusConflict)
+}
+
+func randomToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// objectKey returns the object key of the state key, or the prefix of the state objects for the empty key
+func (s *S3Store) objectKey(key string) string {
+	prefix := strings.Trim(s.config.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	return prefix + key
+}
+
+func (s *S3Store) do(method, key string, query url.Values, headers map[string]string, body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = path.Join("/", s.endpoint.Path, s.config.Bucket)
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	s.sign(req, body, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 request %s %s: %w", method, key, err)
+	}
+
+	return resp, nil
+}
+
+// sign adds the AWS Signature Version 4 headers to the request
+func (s *S3Store) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256Hex(body)
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.config.SessionToken)
+	}
+
+	if s.config.AccessKeyID == "" {
+		// anonymous access
+		return
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := strings.Builder{}
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, s.config.Region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, s.config.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// S3Error is a response of S3 with the unexpected status
+type S3Error struct {
+	StatusCode int
+	Operation  string
+	Key        string
+	Message    string
+}
+
+func (e *S3Error) Error() string {
+	return fmt.Sprintf("S3 %s %s: status %d: %s", e.Operation, e.Key, e.StatusCode, e.Message)
+}
+
+func s3Error(resp *http.Response, operation, key string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	var errorResponse struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	message := strings.TrimSpace(string(body))
+	if err := xml.Unmarshal(body, &errorResponse); err == nil && errorResponse.Code != "" {
+		message = errorResponse.Code + ": " + errorResponse.Message
+	}
+
+	return &S3Error{StatusCode: resp.StatusCode, Operation: operation, Key: key, Message: message}
+}
+
+func canonicalQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+// uriEncode encodes the string as AWS requires: all characters except the unreserved ones are percent-encoded
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -27,8 +28,10 @@ import (
 	"github.com/deckhouse/deckhouse/dhctl/pkg/kubernetes/client"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/log"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/state"
+	"github.com/deckhouse/deckhouse/dhctl/pkg/state/backend"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/util/cache"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/util/stringsutil"
+	"github.com/deckhouse/deckhouse/dhctl/pkg/util/tomb"
 )
 
 var once sync.Once
@@ -37,11 +40,19 @@ var (
 	_ state.Cache = &cache.StateCache{}
 	_ state.Cache = &cache.DummyCache{}
 	_ state.Cache = &client.StateCache{}
+	_ state.Cache = &backend.StateCache{}
 )
 
 var globalCache state.Cache = &cache.DummyCache{}
 
 func choiceCache(identity string, opts CacheOptions) (state.Cache, error) {
+	if app.StateBackend != app.StateBackendLocal {
+		if app.CacheKubeNamespace != "" {
+			return nil, fmt.Errorf("kube-cachestore flags can't be used with the %s state backend", app.StateBackend)
+		}
+		return backendCache(identity, opts)
+	}
+
 	tmpDir := filepath.Join(app.CacheDir, stringsutil.Sha256Encode(identity))
 	log.DebugF("Cache dir %s\n", tmpDir)
 	if err := os.MkdirAll(tmpDir, 0o755); err != nil {
@@ -90,6 +101,95 @@ func choiceCache(identity string, opts CacheOptions) (state.Cache, error) {
 	return k8sCache, nil
 }
 
+// backendCache returns the cache in the encrypted local directory or in the S3 bucket,
+// terraform works with the copy of the state in the tmp dir which is removed on exit
+func backendCache(identity string, opts CacheOptions) (state.Cache, error) {
+	identityHash := stringsutil.Sha256Encode(identity)
+
+	var cipher *backend.Cipher
+	if app.StateEncryptionKeyFile != "" {
+		var err error
+		cipher, err = backend.NewCipherFromFile(app.StateEncryptionKeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var store backend.Store
+	switch app.StateBackend {
+	case app.StateBackendEncryptedLocal:
+		if cipher == nil {
+			return nil, fmt.Errorf("--state-encryption-key-file is required for the %s state backend", app.StateBackend)
+		}
+
+		fileStore, err := backend.NewFileStore(filepath.Join(app.CacheDir, "encrypted", identityHash))
+		if err != nil {
+			return nil, err
+		}
+		store = fileStore
+	case app.StateBackendS3:
+		s3Store, err := backend.NewS3Store(backend.S3Config{
+			Endpoint:        app.StateS3Endpoint,
+			Bucket:          app.StateS3Bucket,
+			Prefix:          path.Join(app.StateS3Prefix, identityHash),
+			Region:          app.StateS3Region,
+			AccessKeyID:     valueOrEnv(app.StateS3AccessKeyID, "AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: valueOrEnv(app.StateS3SecretAccessKey, "AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s3Store.Lock(identity); err != nil {
+			return nil, err
+		}
+		tomb.RegisterOnShutdown("Unlock the state in S3", func() {
+			if err := s3Store.Unlock(); err != nil {
+				log.ErrorF("Cannot unlock the state in S3: %v\n", err)
+			}
+		})
+		store = s3Store
+	default:
+		return nil, fmt.Errorf("unknown state backend %q", app.StateBackend)
+	}
+
+	tmpDir := filepath.Join(app.TmpDirName, "state", identityHash)
+	log.DebugF("State backend %s, tmp dir %s\n", app.StateBackend, tmpDir)
+	tomb.RegisterOnShutdown("Remove the state tmp dir", func() {
+		if !app.IsDebug {
+			_ = os.RemoveAll(tmpDir)
+		}
+	})
+
+	stateCache, err := backend.NewStateCache(store, cipher, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.InitialState != nil {
+		return stateCache, stateCache.ReplaceState(opts.InitialState)
+	}
+
+	hasTombstone, err := stateCache.InCache(state.TombstoneKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if hasTombstone {
+		return nil, fmt.Errorf("Cache exhausted")
+	}
+
+	return stateCache, nil
+}
+
+func valueOrEnv(value, env string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
+
 func initCache(identity string, opts CacheOptions) error {
 	var err error
 	once.Do(func() {
@@ -26,6 +26,7 @@ import (
 	"github.com/alessio/shellescape"
 
 	"github.com/deckhouse/deckhouse/dhctl/pkg/app"
+	"github.com/deckhouse/deckhouse/dhctl/pkg/events"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/log"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/system/ssh/session"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/util/tomb"
@@ -159,22 +160,26 @@ func (u *UploadScript) ExecuteBundle(parentDir, bundleDir string) (stdout []byte
 
 	processLogger := log.GetProcessLogger()
 
-	handler := bundleOutputHandler(bundleCmd, processLogger, &lastStep, &failsCounter)
+	handler := bundleOutputHandler(bundleCmd, processLogger, u.Session.Host(), &lastStep, &failsCounter)
 	err = bundleCmd.WithStdoutHandler(handler).CaptureStdout(nil).Run()
 	if err != nil {
 		if lastStep != "" {
 			processLogger.LogProcessFail()
+			stepEvent(u.Session.Host(), lastStep, events.StatusFailed, failsCounter+1)
 		}
 		err = fmt.Errorf("execute bundle: %v", err)
 	} else {
 		processLogger.LogProcessEnd()
+		if lastStep != "" {
+			stepEvent(u.Session.Host(), lastStep, events.StatusCompleted, failsCounter+1)
+		}
 	}
 	return bundleCmd.StdoutBytes(), err
 }
 
 var stepHeaderRegexp = regexp.MustCompile("^=== Step: /var/lib/bashible/bundle_steps/(.*)$")
 
-func bundleOutputHandler(cmd *Command, processLogger log.ProcessLogger, lastStep *string, failsCounter *int) func(string) {
+func bundleOutputHandler(cmd *Command, processLogger log.ProcessLogger, host string, lastStep *string, failsCounter *int) func(string) {
 	return func(l string) {
 		if l == "===" {
 			return
@@ -194,16 +199,23 @@ func bundleOutputHandler(cmd *Command, processLogger log.ProcessLogger, lastStep
 				}
 
 				processLogger.LogProcessFail()
+				stepEvent(host, *lastStep, events.StatusFailed, *failsCounter)
 				stepName = fmt.Sprintf("%s, retry attempt #%d of 10", stepName, *failsCounter)
 			} else if *lastStep != "" {
 				processLogger.LogProcessEnd()
+				stepEvent(host, *lastStep, events.StatusCompleted, *failsCounter+1)
 				*failsCounter = 0
 			}
 
 			processLogger.LogProcessStart("Run step " + stepName)
+			stepEvent(host, match[1], events.StatusStarted, *failsCounter+1)
 			*lastStep = match[1]
 			return
 		}
 		log.InfoLn(l)
 	}
 }
+
+func stepEvent(host, name, status string, attempt int) {
+	events.NodeBootstrapStepProgress(events.NodeBootstrapStep{Host: host, Name: name, Status: status, Attempt: attempt})
+}
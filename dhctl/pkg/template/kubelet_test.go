@@ -0,0 +1,167 @@
+// Copyright 2024 Flant JSC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const configureKubeletTemplate = "/deckhouse/candi/bashible/common-steps/node-group/064_configure_kubelet.sh.tpl"
+
+func renderConfigureKubelet(t *testing.T, kubernetesVersion, kubelet string) string {
+	t.Helper()
+
+	var data map[string]interface{}
+	err := yaml.Unmarshal([]byte(`
+runType: Normal
+kubernetesVersion: "`+kubernetesVersion+`"
+cri: Containerd
+normal:
+  clusterDomain: cluster.local
+  clusterDNSAddress: 10.222.0.10
+nodeGroup:
+  name: worker
+  kubelet:
+`+kubelet), &data)
+	if err != nil {
+		t.Fatalf("unmarshal data: %v", err)
+	}
+
+	content, err := os.ReadFile(configureKubeletTemplate)
+	if err != nil {
+		t.Fatalf("read template: %v", err)
+	}
+
+	rendered, err := RenderTemplate("064_configure_kubelet.sh.tpl", content, data)
+	if err != nil {
+		t.Fatalf("render template: %v", err)
+	}
+	return rendered.Content.String()
+}
+
+// featureGates returns the featureGates section of the rendered kubelet config.
+func featureGates(t *testing.T, rendered string) []string {
+	t.Helper()
+
+	section := regexp.MustCompile(`(?m)^featureGates:\n((?:  .+\n)+)`).FindStringSubmatch(rendered)
+	if section == nil {
+		t.Fatalf("featureGates are not rendered:\n%s", rendered)
+	}
+	return strings.Split(strings.TrimSuffix(section[1], "\n"), "\n")
+}
+
+func TestConfigureKubeletFeatureGates(t *testing.T) {
+	tests := []struct {
+		name              string
+		kubernetesVersion string
+		kubelet           string
+		expected          []string
+	}{
+		{
+			name:              "Defaults",
+			kubernetesVersion: "1.27",
+			kubelet:           "    {}\n",
+			expected:          []string{"  RotateKubeletServerCertificate: true"},
+		},
+		{
+			name:              "Defaults before 1.27",
+			kubernetesVersion: "1.26",
+			kubelet:           "    {}\n",
+			expected:          []string{"  ExpandCSIVolumes: true", "  RotateKubeletServerCertificate: true"},
+		},
+		{
+			name:              "Gates of the NodeGroup do not duplicate the default ones",
+			kubernetesVersion: "1.26",
+			kubelet: `    featureGates:
+      ExpandCSIVolumes: false
+      MemoryQoS: true
+      RotateKubeletServerCertificate: false
+`,
+			expected: []string{"  ExpandCSIVolumes: false", "  MemoryQoS: true", "  RotateKubeletServerCertificate: true"},
+		},
+		{
+			name:              "Topology manager options are ignored before 1.26",
+			kubernetesVersion: "1.25",
+			kubelet: `    featureGates:
+      TopologyManagerPolicyBetaOptions: true
+`,
+			expected: []string{"  ExpandCSIVolumes: true", "  RotateKubeletServerCertificate: true"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := featureGates(t, renderConfigureKubelet(t, tt.kubernetesVersion, tt.kubelet))
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("expected feature gates:\n%s\ngot:\n%s", strings.Join(tt.expected, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestConfigureKubeletSettings(t *testing.T) {
+	rendered := renderConfigureKubelet(t, "1.27", `    cpuManagerPolicy: Static
+    topologyManager:
+      policy: SingleNUMANode
+      scope: Pod
+    eviction:
+      hard:
+        memoryAvailable: 500Mi
+      maxPodGracePeriod: 30
+    imageGC:
+      highThresholdPercent: 80
+      lowThresholdPercent: 75
+`)
+
+	for _, expected := range []string{
+		"\ncpuManagerPolicy: static\n",
+		"\ntopologyManagerPolicy: single-numa-node\n",
+		"\ntopologyManagerScope: pod\n",
+		"\n  memory.available: 500Mi\n",
+		"\n  nodefs.available: $evictionHardThresholdNodefsAvailable\n",
+		"\nevictionMaxPodGracePeriod: 30\n",
+		"\nimageGCHighThresholdPercent: 80\n",
+		"\nimageGCLowThresholdPercent: 75\n",
+		`kubelet_disruptive_settings='{"cpuManagerPolicy":"Static","featureGates":{},"topologyManager":{"policy":"SingleNUMANode","scope":"Pod"}}'`,
+	} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("expected %q in the rendered template", expected)
+		}
+	}
+}
+
+func TestConfigureKubeletDefaultSettings(t *testing.T) {
+	rendered := renderConfigureKubelet(t, "1.27", "    {}\n")
+
+	for _, expected := range []string{
+		"\ncpuManagerPolicy: none\n",
+		"\ntopologyManagerPolicy: none\n",
+		"\ntopologyManagerScope: container\n",
+		"\nimageGCHighThresholdPercent: 70\n",
+		"\nevictionMaxPodGracePeriod: 90\n",
+		// the kubelet configured before the settings were tracked is compared with the defaults
+		`kubelet_disruptive_settings='{"cpuManagerPolicy":"None","featureGates":{},"topologyManager":{}}'`,
+		`previous_kubelet_disruptive_settings='{"cpuManagerPolicy":"None","featureGates":{},"topologyManager":{}}'`,
+	} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("expected %q in the rendered template", expected)
+		}
+	}
+}
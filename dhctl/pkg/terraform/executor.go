@@ -25,6 +25,7 @@ import (
 	"syscall"
 
 	"github.com/deckhouse/deckhouse/dhctl/pkg/app"
+	"github.com/deckhouse/deckhouse/dhctl/pkg/events"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/log"
 )
 
@@ -115,6 +116,9 @@ func (c *CMDExecutor) Exec(args ...string) (int, error) {
 		s := bufio.NewScanner(stdout)
 		for s.Scan() {
 			log.InfoLn(s.Text())
+			if resource, ok := events.ParseTerraformResource(s.Text()); ok {
+				events.TerraformResourceProgress(resource)
+			}
 		}
 	}()
 
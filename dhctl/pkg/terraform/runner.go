@@ -28,6 +28,7 @@ import (
 
 	"github.com/deckhouse/deckhouse/dhctl/pkg/app"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/config"
+	"github.com/deckhouse/deckhouse/dhctl/pkg/events"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/log"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/state"
 	"github.com/deckhouse/deckhouse/dhctl/pkg/state/cache"
@@ -594,6 +595,10 @@ func (r *Runner) execTerraform(args ...string) (int, error) {
 	exitCode, err := r.terraformExecutor.Exec(args...)
 	log.InfoF("Terraform runner %q process exited.\n", r.step)
 
+	if err != nil && exitCode != terraformHasChangesExitCode {
+		err = events.WithCode(events.TerraformFailedCode, err)
+	}
+
 	return exitCode, err
 }
 
@@ -9,8 +9,10 @@ import (
 	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"time"
 
 	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
 	"github.com/flant/addon-operator/sdk"
@@ -21,6 +23,11 @@ import (
 	"github.com/deckhouse/deckhouse/modules/110-istio/hooks/lib"
 )
 
+const (
+	defaultAuthnKeyRotationPeriod      = 30 * 24 * time.Hour
+	defaultAuthnKeyRotationGracePeriod = 24 * time.Hour
+)
+
 func applyKeypairFilter(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
 	secret := &v1.Secret{}
 	err := sdk.FromUnstructured(obj, secret)
@@ -29,8 +36,13 @@ func applyKeypairFilter(obj *unstructured.Unstructured) (go_hook.FilterResult, e
 	}
 
 	return lib.Keypair{
-		Pub:  string(secret.Data["pub.pem"]),
-		Priv: string(secret.Data["priv.pem"]),
+		Pub:                  string(secret.Data["pub.pem"]),
+		Priv:                 string(secret.Data["priv.pem"]),
+		CreatedAt:            string(secret.Data["created-at"]),
+		NextPub:              string(secret.Data["next-pub.pem"]),
+		NextPriv:             string(secret.Data["next-priv.pem"]),
+		PreviousPub:          string(secret.Data["previous-pub.pem"]),
+		PreviousPubExpiresAt: string(secret.Data["previous-pub-expires-at"]),
 	}, nil
 }
 
@@ -48,9 +60,76 @@ var _ = sdk.RegisterFunc(&go_hook.HookConfig{
 			NamespaceSelector: lib.NsSelector(),
 		},
 	},
+	Schedule: []go_hook.ScheduleConfig{
+		{Name: "rotation", Crontab: "*/15 * * * *"},
+	},
 }, generateKeypair)
 
+func parseRotationDuration(input *go_hook.HookInput, path string, defaultValue time.Duration) (time.Duration, error) {
+	value := input.Values.Get(path)
+	if !value.Exists() {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value.String())
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %s: %v", path, err)
+	}
+	return d, nil
+}
+
+func parseKeypairTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func newKeypair() (lib.Keypair, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return lib.Keypair{}, err
+	}
+
+	privBytes, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return lib.Keypair{}, err
+	}
+	privBlock := &pem.Block{
+		Type:  "ED25519 PRIVATE KEY",
+		Bytes: privBytes,
+	}
+	privPEM := pem.EncodeToMemory(privBlock)
+
+	pubBytes, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return lib.Keypair{}, err
+	}
+	pubBlock := &pem.Block{
+		Type:  "ED25519 PUBLIC KEY",
+		Bytes: pubBytes,
+	}
+	pubPEM := pem.EncodeToMemory(pubBlock)
+
+	return lib.Keypair{
+		Pub:  string(pubPEM),
+		Priv: string(privPEM),
+	}, nil
+}
+
 func generateKeypair(input *go_hook.HookInput) error {
+	period, err := parseRotationDuration(input, "istio.alliance.authnKeyRotation.period", defaultAuthnKeyRotationPeriod)
+	if err != nil {
+		return err
+	}
+	gracePeriod, err := parseRotationDuration(input, "istio.alliance.authnKeyRotation.gracePeriod", defaultAuthnKeyRotationGracePeriod)
+	if err != nil {
+		return err
+	}
+	if period <= 0 {
+		return fmt.Errorf("istio.alliance.authnKeyRotation.period must be positive")
+	}
+
 	var keypair lib.Keypair
 
 	secrets := input.Snapshots["secret"]
@@ -60,40 +139,68 @@ func generateKeypair(input *go_hook.HookInput) error {
 		if !ok {
 			return fmt.Errorf("cannot convert keypair in secret to struct")
 		}
-	} else {
-		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	}
+
+	// The keypair rotated or prepared for the rotation in the previous run is not in the Secret until Helm renders it.
+	var valuesKeypair lib.Keypair
+	if v, ok := input.Values.GetOk("istio.internal.remoteAuthnKeypair"); ok {
+		err = json.Unmarshal([]byte(v.Raw), &valuesKeypair)
 		if err != nil {
-			return err
+			return fmt.Errorf("cannot unmarshal istio.internal.remoteAuthnKeypair: %v", err)
 		}
+	}
+	if valuesKeypair.Priv != "" && valuesKeypair.CreatedAt != "" &&
+		(keypair.Priv == "" || !parseKeypairTime(valuesKeypair.CreatedAt).Before(parseKeypairTime(keypair.CreatedAt))) {
+		keypair = valuesKeypair
+	}
 
-		privBytes, err := x509.MarshalPKCS8PrivateKey(priv)
+	now := time.Now().UTC()
+
+	switch {
+	case keypair.Priv == "":
+		keypair, err = newKeypair()
 		if err != nil {
 			return err
 		}
-		privBlock := &pem.Block{
-			Type:  "ED25519 PRIVATE KEY",
-			Bytes: privBytes,
-		}
-		privPEM := pem.EncodeToMemory(privBlock)
+		keypair.CreatedAt = now.Format(time.RFC3339)
 
-		pubBytes, err := x509.MarshalPKIXPublicKey(pub)
+	case keypair.CreatedAt == "":
+		// the keypair was generated before the rotation was introduced
+		keypair.CreatedAt = now.Format(time.RFC3339)
+	}
+
+	// The next keypair is published the grace period before the rotation and signs nothing until the rotation,
+	// so the remote clusters have fetched its public key by the time the requests are signed with it.
+	rotateAt := parseKeypairTime(keypair.CreatedAt).Add(period)
+	if keypair.NextPriv == "" && !now.Before(rotateAt.Add(-gracePeriod)) {
+		next, err := newKeypair()
 		if err != nil {
 			return err
 		}
-		pubBlock := &pem.Block{
-			Type:  "ED25519 PUBLIC KEY",
-			Bytes: pubBytes,
-		}
-		pubPEM := pem.EncodeToMemory(pubBlock)
+		keypair.NextPub = next.Pub
+		keypair.NextPriv = next.Priv
+		input.LogEntry.Infof("next authn keypair is published, the rotation is at %s", rotateAt.Format(time.RFC3339))
+	}
 
+	if !now.Before(rotateAt) {
 		keypair = lib.Keypair{
-			Pub:  string(pubPEM),
-			Priv: string(privPEM),
+			Pub:       keypair.NextPub,
+			Priv:      keypair.NextPriv,
+			CreatedAt: now.Format(time.RFC3339),
+			// the requests signed before the rotation are still accepted during the grace period
+			PreviousPub:          keypair.Pub,
+			PreviousPubExpiresAt: now.Add(gracePeriod).Format(time.RFC3339),
 		}
+		input.LogEntry.Infof("authn keypair is rotated, the previous public key is published until %s", keypair.PreviousPubExpiresAt)
+	}
+
+	if keypair.PreviousPub != "" && !now.Before(parseKeypairTime(keypair.PreviousPubExpiresAt)) {
+		keypair.PreviousPub = ""
+		keypair.PreviousPubExpiresAt = ""
 	}
+	keypair.ExpiresAt = parseKeypairTime(keypair.CreatedAt).Add(period + gracePeriod).Format(time.RFC3339)
 
-	input.Values.Set("istio.internal.remoteAuthnKeypair.pub", keypair.Pub)
-	input.Values.Set("istio.internal.remoteAuthnKeypair.priv", keypair.Priv)
+	input.Values.Set("istio.internal.remoteAuthnKeypair", keypair)
 
 	return nil
 }
@@ -7,7 +7,9 @@ package ee
 
 import (
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -41,6 +43,14 @@ var _ = Describe("Istio hooks :: alliance_generate_authn_keypair ::", func() {
 			pubBlock, _ := pem.Decode([]byte(pubString))
 			_, err1 := x509.ParsePKIXPublicKey(pubBlock.Bytes)
 			Expect(err1).To(BeNil())
+
+			createdAt, err := time.Parse(time.RFC3339, f.ValuesGet("istio.internal.remoteAuthnKeypair.createdAt").String())
+			Expect(err).To(BeNil())
+			Expect(createdAt).Should(BeTemporally("~", time.Now(), time.Minute))
+			expiresAt, err := time.Parse(time.RFC3339, f.ValuesGet("istio.internal.remoteAuthnKeypair.expiresAt").String())
+			Expect(err).To(BeNil())
+			Expect(expiresAt).Should(Equal(createdAt.Add(31 * 24 * time.Hour)))
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.previousPub").Exists()).To(BeFalse())
 		})
 	})
 
@@ -65,6 +75,181 @@ data:
 
 			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.pub").String()).To(Equal("aaa"))
 			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.priv").String()).To(Equal("bbb"))
+			// the keypair generated before the rotation was introduced is rotated after the period
+			createdAt, err := time.Parse(time.RFC3339, f.ValuesGet("istio.internal.remoteAuthnKeypair.createdAt").String())
+			Expect(err).To(BeNil())
+			Expect(createdAt).Should(BeTemporally("~", time.Now(), time.Minute))
+		})
+	})
+
+	Context("Keypair in the Secret is to be rotated within the grace period", func() {
+		var createdAt = time.Now().Add(-23*time.Hour - 30*time.Minute).UTC().Format(time.RFC3339)
+
+		BeforeEach(func() {
+			f.ValuesSet("istio.alliance.authnKeyRotation.period", "24h")
+			f.ValuesSet("istio.alliance.authnKeyRotation.gracePeriod", "1h")
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: d8-remote-authn-keypair
+  namespace: d8-istio
+data:
+  pub.pem: YWFh # aaa
+  priv.pem: YmJi # bbb
+  created-at: ` + base64.StdEncoding.EncodeToString([]byte(createdAt)) + `
+`))
+			f.RunHook()
+		})
+
+		It("Should publish the next key and keep signing with the current keypair", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.pub").String()).To(Equal("aaa"))
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.priv").String()).To(Equal("bbb"))
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.createdAt").String()).To(Equal(createdAt))
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.nextPub").String()).To(ContainSubstring("ED25519 PUBLIC KEY"))
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.nextPriv").String()).To(ContainSubstring("ED25519 PRIVATE KEY"))
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.previousPub").Exists()).To(BeFalse())
+		})
+
+		Context("Hook runs again before the Secret is updated", func() {
+			var nextPub string
+
+			BeforeEach(func() {
+				nextPub = f.ValuesGet("istio.internal.remoteAuthnKeypair.nextPub").String()
+				f.RunHook()
+			})
+
+			It("Should keep the published next key", func() {
+				Expect(f).To(ExecuteSuccessfully())
+				Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.nextPub").String()).To(Equal(nextPub))
+				Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.priv").String()).To(Equal("bbb"))
+			})
+		})
+	})
+
+	Context("Keypair in the Secret is expired, the next key is published", func() {
+		BeforeEach(func() {
+			f.ValuesSet("istio.alliance.authnKeyRotation.period", "24h")
+			f.ValuesSet("istio.alliance.authnKeyRotation.gracePeriod", "1h")
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: d8-remote-authn-keypair
+  namespace: d8-istio
+data:
+  pub.pem: YWFh # aaa
+  priv.pem: YmJi # bbb
+  created-at: ` + base64.StdEncoding.EncodeToString([]byte(time.Now().Add(-24*time.Hour-time.Minute).UTC().Format(time.RFC3339))) + `
+  next-pub.pem: Y2Nj # ccc
+  next-priv.pem: ZGRk # ddd
+`))
+			f.RunHook()
+		})
+
+		It("Should switch to the next keypair and publish the previous key", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.pub").String()).To(Equal("ccc"))
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.priv").String()).To(Equal("ddd"))
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.nextPub").Exists()).To(BeFalse())
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.nextPriv").Exists()).To(BeFalse())
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.previousPub").String()).To(Equal("aaa"))
+
+			createdAt, err := time.Parse(time.RFC3339, f.ValuesGet("istio.internal.remoteAuthnKeypair.createdAt").String())
+			Expect(err).To(BeNil())
+			Expect(createdAt).Should(BeTemporally("~", time.Now(), time.Minute))
+			previousPubExpiresAt, err := time.Parse(time.RFC3339, f.ValuesGet("istio.internal.remoteAuthnKeypair.previousPubExpiresAt").String())
+			Expect(err).To(BeNil())
+			Expect(previousPubExpiresAt).Should(Equal(createdAt.Add(time.Hour)))
+		})
+	})
+
+	Context("Keypair in the Secret is expired", func() {
+		BeforeEach(func() {
+			f.ValuesSet("istio.alliance.authnKeyRotation.period", "24h")
+			f.ValuesSet("istio.alliance.authnKeyRotation.gracePeriod", "1h")
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: d8-remote-authn-keypair
+  namespace: d8-istio
+data:
+  pub.pem: YWFh # aaa
+  priv.pem: YmJi # bbb
+  created-at: ` + base64.StdEncoding.EncodeToString([]byte(time.Now().Add(-25*time.Hour).UTC().Format(time.RFC3339))) + `
+  previous-pub.pem: Y2Nj # ccc
+  previous-pub-expires-at: ` + base64.StdEncoding.EncodeToString([]byte(time.Now().Add(-time.Hour).UTC().Format(time.RFC3339))) + `
+`))
+			f.RunHook()
+		})
+
+		It("Should rotate the keypair and publish the previous key", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.pub").String()).To(ContainSubstring("ED25519 PUBLIC KEY"))
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.priv").String()).To(ContainSubstring("ED25519 PRIVATE KEY"))
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.previousPub").String()).To(Equal("aaa"))
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.nextPub").Exists()).To(BeFalse())
+
+			createdAt, err := time.Parse(time.RFC3339, f.ValuesGet("istio.internal.remoteAuthnKeypair.createdAt").String())
+			Expect(err).To(BeNil())
+			Expect(createdAt).Should(BeTemporally("~", time.Now(), time.Minute))
+			expiresAt, err := time.Parse(time.RFC3339, f.ValuesGet("istio.internal.remoteAuthnKeypair.expiresAt").String())
+			Expect(err).To(BeNil())
+			Expect(expiresAt).Should(Equal(createdAt.Add(25 * time.Hour)))
+			previousPubExpiresAt, err := time.Parse(time.RFC3339, f.ValuesGet("istio.internal.remoteAuthnKeypair.previousPubExpiresAt").String())
+			Expect(err).To(BeNil())
+			Expect(previousPubExpiresAt).Should(Equal(createdAt.Add(time.Hour)))
+		})
+
+		Context("Hook runs again before the Secret is updated", func() {
+			BeforeEach(func() {
+				f.RunHook()
+			})
+
+			It("Should keep the rotated keypair", func() {
+				Expect(f).To(ExecuteSuccessfully())
+				Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.previousPub").String()).To(Equal("aaa"))
+				Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.pub").String()).To(ContainSubstring("ED25519 PUBLIC KEY"))
+			})
+		})
+	})
+
+	Context("Grace period of the previous key is over", func() {
+		var createdAt = time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: d8-remote-authn-keypair
+  namespace: d8-istio
+data:
+  pub.pem: YWFh # aaa
+  priv.pem: YmJi # bbb
+  created-at: ` + base64.StdEncoding.EncodeToString([]byte(createdAt)) + `
+  previous-pub.pem: Y2Nj # ccc
+  previous-pub-expires-at: ` + base64.StdEncoding.EncodeToString([]byte(time.Now().Add(-time.Hour).UTC().Format(time.RFC3339))) + `
+`))
+			f.RunHook()
+		})
+
+		It("Should stop publishing the previous key", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.pub").String()).To(Equal("aaa"))
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.createdAt").String()).To(Equal(createdAt))
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.previousPub").Exists()).To(BeFalse())
+			Expect(f.ValuesGet("istio.internal.remoteAuthnKeypair.previousPubExpiresAt").Exists()).To(BeFalse())
 		})
 	})
 })
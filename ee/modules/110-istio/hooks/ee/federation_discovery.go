@@ -26,6 +26,8 @@ import (
 var (
 	federationMetricsGroup = "federation_discovery"
 	federationMetricName   = "d8_istio_federation_metadata_endpoints_fetch_error_count"
+
+	federationAuthnKeyExpiredMetricName = "d8_istio_federation_remote_authn_key_expired"
 )
 
 type IstioFederationDiscoveryCrdInfo struct {
@@ -47,6 +49,14 @@ func (i *IstioFederationDiscoveryCrdInfo) SetMetricMetadataEndpointError(mc go_h
 	mc.Set(federationMetricName, isError, labels, metrics.WithGroup(federationMetricsGroup))
 }
 
+func (i *IstioFederationDiscoveryCrdInfo) SetMetricAuthnKeyExpired(mc go_hook.MetricsCollector, isExpired float64) {
+	labels := map[string]string{
+		"federation_name": i.Name,
+	}
+
+	mc.Set(federationAuthnKeyExpiredMetricName, isExpired, labels, metrics.WithGroup(federationMetricsGroup))
+}
+
 func (i *IstioFederationDiscoveryCrdInfo) PatchMetadataCache(pc *object_patch.PatchCollector, scope string, meta interface{}) error {
 	patch := map[string]interface{}{
 		"status": map[string]interface{}{
@@ -194,6 +204,15 @@ func federationDiscovery(input *go_hook.HookInput, dc dependency.Container) erro
 			continue
 		}
 		federationInfo.SetMetricMetadataEndpointError(input.MetricsCollector, federationInfo.PublicMetadataEndpoint, 0)
+		// the clusters without the keypair rotation don't publish the expiration
+		if publicMetadata.AuthnKeyPubExpiresAt != "" {
+			if publicMetadata.AuthnKeyPubExpired(time.Now()) {
+				input.LogEntry.Warnf("public metadata endpoint %s for IstioFederation %s presents the authn key expired at %s", federationInfo.PublicMetadataEndpoint, federationInfo.Name, publicMetadata.AuthnKeyPubExpiresAt)
+				federationInfo.SetMetricAuthnKeyExpired(input.MetricsCollector, 1)
+			} else {
+				federationInfo.SetMetricAuthnKeyExpired(input.MetricsCollector, 0)
+			}
+		}
 		err = federationInfo.PatchMetadataCache(input.PatchCollector, "public", publicMetadata)
 		if err != nil {
 			return err
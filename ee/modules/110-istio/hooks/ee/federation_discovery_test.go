@@ -712,4 +712,81 @@ status: {}
 			}))
 		})
 	})
+
+	Context("Federations with the rotated authn keys", func() {
+		BeforeEach(func() {
+			f.ValuesSet(`istio.federation.enabled`, true)
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: IstioFederation
+metadata:
+  name: expired-key
+spec:
+  trustDomain: "e.k"
+  metadataEndpoint: "https://expired-key/metadata/"
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: IstioFederation
+metadata:
+  name: rotated-key
+spec:
+  trustDomain: "r.k"
+  metadataEndpoint: "https://rotated-key/metadata/"
+`))
+			expiresAt := map[string]string{
+				"expired-key": time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+				"rotated-key": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+			}
+			dependency.TestDC.HTTPClient.DoMock.
+				Set(func(req *http.Request) (rp1 *http.Response, err error) {
+					host := strings.Split(req.Host, ":")[0]
+					response := `{"ingressGateways": [], "publicServices": []}`
+					if req.URL.Path == "/metadata/public/public.json" {
+						response = `{
+						  "clusterUUID": "` + host + `-uuid",
+						  "authnKeyPub": "` + host + `-authn",
+						  "authnKeyPubExpiresAt": "` + expiresAt[host] + `",
+						  "previousAuthnKeyPub": "` + host + `-previous-authn",
+						  "previousAuthnKeyPubExpiresAt": "` + expiresAt[host] + `",
+						  "rootCA": "` + host + `-root-ca"
+						}`
+					}
+					return &http.Response{
+						Header:     map[string][]string{"Content-Type": {"application/json"}},
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewBufferString(response)),
+					}, nil
+				})
+			f.RunHook()
+		})
+
+		It("Hook must report the expired key", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(string(f.LogrusOutput.Contents())).To(ContainSubstring("public metadata endpoint https://expired-key/metadata/public/public.json for IstioFederation expired-key presents the authn key expired at"))
+
+			Expect(f.KubernetesGlobalResource("IstioFederation", "rotated-key").Field("status.metadataCache.public.previousAuthnKeyPub").String()).To(Equal("rotated-key-previous-authn"))
+
+			m := f.MetricsCollector.CollectedMetrics()
+			Expect(m).To(HaveLen(7))
+			Expect(m[2]).To(BeEquivalentTo(operation.MetricOperation{
+				Name:   federationAuthnKeyExpiredMetricName,
+				Group:  federationMetricsGroup,
+				Action: "set",
+				Value:  pointer.Float64(1.0),
+				Labels: map[string]string{
+					"federation_name": "expired-key",
+				},
+			}))
+			Expect(m[5]).To(BeEquivalentTo(operation.MetricOperation{
+				Name:   federationAuthnKeyExpiredMetricName,
+				Group:  federationMetricsGroup,
+				Action: "set",
+				Value:  pointer.Float64(0.0),
+				Labels: map[string]string{
+					"federation_name": "rotated-key",
+				},
+			}))
+		})
+	})
 })
@@ -5,9 +5,29 @@ Licensed under the Deckhouse Platform Enterprise Edition (EE) license. See https
 
 package crd
 
+import "time"
+
 // Warning! This struct is duplicated in images/metadata-exporter
 type AlliancePublicMetadata struct {
 	ClusterUUID string `json:"clusterUUID"`
 	AuthnKeyPub string `json:"authnKeyPub"`
 	RootCA      string `json:"rootCA"`
+
+	AuthnKeyPubExpiresAt         string `json:"authnKeyPubExpiresAt,omitempty"`
+	NextAuthnKeyPub              string `json:"nextAuthnKeyPub,omitempty"`
+	PreviousAuthnKeyPub          string `json:"previousAuthnKeyPub,omitempty"`
+	PreviousAuthnKeyPubExpiresAt string `json:"previousAuthnKeyPubExpiresAt,omitempty"`
+}
+
+// AuthnKeyPubExpired reports if the remote cluster still presents the key after its expiration,
+// e.g., the keypair is not rotated in time.
+func (m *AlliancePublicMetadata) AuthnKeyPubExpired(now time.Time) bool {
+	if m.AuthnKeyPubExpiresAt == "" {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, m.AuthnKeyPubExpiresAt)
+	if err != nil {
+		return false
+	}
+	return !now.Before(expiresAt)
 }
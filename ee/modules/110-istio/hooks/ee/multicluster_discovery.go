@@ -25,6 +25,8 @@ import (
 var (
 	multiclusterMetricsGroup = "multicluster_discovery"
 	multiclusterMetricName   = "d8_istio_multicluster_metadata_endpoints_fetch_error_count"
+
+	multiclusterAuthnKeyExpiredMetricName = "d8_istio_multicluster_remote_authn_key_expired"
 )
 
 type IstioMulticlusterDiscoveryCrdInfo struct {
@@ -44,6 +46,14 @@ func (i *IstioMulticlusterDiscoveryCrdInfo) SetMetricMetadataEndpointError(mc go
 	mc.Set(multiclusterMetricName, isError, labels, metrics.WithGroup(multiclusterMetricsGroup))
 }
 
+func (i *IstioMulticlusterDiscoveryCrdInfo) SetMetricAuthnKeyExpired(mc go_hook.MetricsCollector, isExpired float64) {
+	labels := map[string]string{
+		"multicluster_name": i.Name,
+	}
+
+	mc.Set(multiclusterAuthnKeyExpiredMetricName, isExpired, labels, metrics.WithGroup(multiclusterMetricsGroup))
+}
+
 func (i *IstioMulticlusterDiscoveryCrdInfo) PatchMetadataCache(pc *object_patch.PatchCollector, scope string, meta interface{}) error {
 	patch := map[string]interface{}{
 		"status": map[string]interface{}{
@@ -138,6 +148,15 @@ func multiclusterDiscovery(input *go_hook.HookInput, dc dependency.Container) er
 			continue
 		}
 		multiclusterInfo.SetMetricMetadataEndpointError(input.MetricsCollector, multiclusterInfo.PublicMetadataEndpoint, 0)
+		// the clusters without the keypair rotation don't publish the expiration
+		if publicMetadata.AuthnKeyPubExpiresAt != "" {
+			if publicMetadata.AuthnKeyPubExpired(time.Now()) {
+				input.LogEntry.Warnf("public metadata endpoint %s for IstioMulticluster %s presents the authn key expired at %s", multiclusterInfo.PublicMetadataEndpoint, multiclusterInfo.Name, publicMetadata.AuthnKeyPubExpiresAt)
+				multiclusterInfo.SetMetricAuthnKeyExpired(input.MetricsCollector, 1)
+			} else {
+				multiclusterInfo.SetMetricAuthnKeyExpired(input.MetricsCollector, 0)
+			}
+		}
 		err = multiclusterInfo.PatchMetadataCache(input.PatchCollector, "public", publicMetadata)
 		if err != nil {
 			return err
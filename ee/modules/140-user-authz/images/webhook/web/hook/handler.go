@@ -44,9 +44,10 @@ type Handler struct {
 
 	kubeclient kubernetes.Interface
 
+	mu sync.RWMutex
 	//        [user type] [user name]
-	mu        sync.RWMutex
-	directory map[string]map[string]DirectoryEntry
+	directory        map[string]map[string]DirectoryEntry
+	systemNamespaces []*regexp.Regexp
 }
 
 func NewHandler(logger *log.Logger, discoveryCache cache.Cache) (*Handler, error) {
@@ -120,12 +121,9 @@ func (h *Handler) authorizeNamespacedRequest(request *WebhookRequest, entry *Dir
 
 	if !request.Status.Denied && !entry.AllowAccessToSystemNamespaces {
 		// check if the target namespace is a system one and restricted
-		for _, pattern := range systemNamespacesRegex {
-			if pattern.MatchString(request.Spec.ResourceAttributes.Namespace) {
-				request.Status.Denied = true
-				request.Status.Reason = noNamespaceAccessReason
-				break
-			}
+		if h.isSystemNamespace(request.Spec.ResourceAttributes.Namespace) {
+			request.Status.Denied = true
+			request.Status.Reason = noNamespaceAccessReason
 		}
 	}
 
@@ -253,6 +251,12 @@ func (h *Handler) renewDirectories() {
 		return
 	}
 
+	systemNamespaces, err := compileSystemNamespaces(config.SystemNamespaces)
+	if err != nil {
+		h.logger.Printf("cannot apply the config %s: %v", configPath, err)
+		return
+	}
+
 	directory := map[string]map[string]DirectoryEntry{
 		"User":           make(map[string]DirectoryEntry),
 		"Group":          make(map[string]DirectoryEntry),
@@ -303,6 +307,7 @@ func (h *Handler) renewDirectories() {
 	defer h.mu.Unlock()
 
 	h.directory = directory
+	h.systemNamespaces = systemNamespaces
 	h.logger.Println("configuration was reloaded successfully")
 }
 
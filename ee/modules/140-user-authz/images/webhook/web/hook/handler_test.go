@@ -600,9 +600,15 @@ func TestAuthorizeRequest(t *testing.T) {
 				MatchAny: true,
 			}
 
+			systemNamespaces, err := compileSystemNamespaces([]string{"kube-.*", "d8-.*", "default"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
 			handler := &Handler{
-				logger:     log.New(io.Discard, "", 0),
-				kubeclient: fake.NewSimpleClientset(testCase.Namespaces...),
+				logger:           log.New(io.Discard, "", 0),
+				systemNamespaces: systemNamespaces,
+				kubeclient:       fake.NewSimpleClientset(testCase.Namespaces...),
 				cache: &dummyCache{
 					data: map[string]map[string]bool{
 						"test/v1": {
@@ -737,3 +743,25 @@ func TestWrapRegexpTest(t *testing.T) {
 		})
 	}
 }
+
+func TestCompileSystemNamespaces(t *testing.T) {
+	if _, err := compileSystemNamespaces(nil); err == nil {
+		t.Fatal("the config without the system namespaces must not be applied")
+	}
+
+	if _, err := compileSystemNamespaces([]string{"kube-("}); err == nil {
+		t.Fatal("the invalid pattern must not be applied")
+	}
+
+	systemNamespaces, err := compileSystemNamespaces([]string{"kube-.*", "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := &Handler{systemNamespaces: systemNamespaces}
+	for namespace, expected := range map[string]bool{"kube-system": true, "default": true, "default-dev": false, "dev": false} {
+		if handler.isSystemNamespace(namespace) != expected {
+			t.Fatalf("isSystemNamespace(%q) != %v", namespace, expected)
+		}
+	}
+}
@@ -5,22 +5,38 @@ Licensed under the Deckhouse Platform Enterprise Edition (EE) license. See https
 
 package hook
 
-import "regexp"
+import (
+	"fmt"
+	"regexp"
+)
 
-var systemNamespaces = []string{
-	"kube-.*",
-	"d8-.*",
-	"default",
-	// legacy
-	"antiopa",
-	"loghouse",
+// compileSystemNamespaces compiles the system namespaces patterns of the config,
+// the module sets them in the userAuthz.internal.systemNamespaces value
+func compileSystemNamespaces(patterns []string) ([]*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("no system namespaces in the config")
+	}
+
+	result := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		r, err := regexp.Compile(wrapRegex(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid system namespace pattern %q: %v", pattern, err)
+		}
+		result = append(result, r)
+	}
+
+	return result, nil
 }
 
-var systemNamespacesRegex []*regexp.Regexp
+func (h *Handler) isSystemNamespace(namespace string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 
-func init() {
-	for _, systemNamespace := range systemNamespaces {
-		r, _ := regexp.Compile("^" + systemNamespace + "$")
-		systemNamespacesRegex = append(systemNamespacesRegex, r)
+	for _, pattern := range h.systemNamespaces {
+		if pattern.MatchString(namespace) {
+			return true
+		}
 	}
+	return false
 }
@@ -50,6 +50,7 @@ type UserAuthzConfig struct {
 			} `json:"subjects"`
 		} `json:"spec,omitempty"`
 	} `json:"crds"`
+	SystemNamespaces []string `json:"systemNamespaces"`
 }
 
 // WebhookRequest is a replica of the SubjectAccessReview Kubernetes kind with only important fields
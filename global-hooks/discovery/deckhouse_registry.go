@@ -16,6 +16,7 @@ package hooks
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 
 	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
@@ -35,6 +36,16 @@ type registrySecret struct {
 	Path              string
 	Scheme            string
 	CA                string
+	Mirrors           []registryMirror
+}
+
+// registryMirror is an item of the JSON list in the "mirrors" field of the secret,
+// the mirror serves the same path as the registry
+type registryMirror struct {
+	Address   string `json:"address"`
+	Scheme    string `json:"scheme,omitempty"`
+	CA        string `json:"ca,omitempty"`
+	DockerCfg string `json:"dockerCfg,omitempty"`
 }
 
 var _ = sdk.RegisterFunc(&go_hook.HookConfig{
@@ -70,12 +81,20 @@ func applyD8RegistrySecretFilter(obj *unstructured.Unstructured) (go_hook.Filter
 		scheme = []byte("https")
 	}
 
+	var mirrors []registryMirror
+	if mirrorsRaw, ok := secret.Data["mirrors"]; ok && len(mirrorsRaw) > 0 {
+		if err = json.Unmarshal(mirrorsRaw, &mirrors); err != nil {
+			return nil, fmt.Errorf("unmarshal mirrors of the 'deckhouse-registry' secret: %w", err)
+		}
+	}
+
 	return &registrySecret{
 		RegistryDockercfg: secret.Data[".dockerconfigjson"],
 		Address:           string(secret.Data["address"]),
 		Path:              string(secret.Data["path"]),
 		Scheme:            string(scheme),
 		CA:                string(secret.Data["ca"]),
+		Mirrors:           mirrors,
 	}, nil
 }
 
@@ -106,5 +125,25 @@ func discoveryDeckhouseRegistry(input *go_hook.HookInput) error {
 	input.Values.Set("global.modulesImages.registry.CA", registrySecretRaw.CA)
 	input.Values.Set("global.modulesImages.registry.address", registrySecretRaw.Address)
 	input.Values.Set("global.modulesImages.registry.path", registrySecretRaw.Path)
+
+	mirrors := make([]map[string]string, 0, len(registrySecretRaw.Mirrors))
+	for _, mirror := range registrySecretRaw.Mirrors {
+		if mirror.Address == "" {
+			return fmt.Errorf("address field not found in the mirror of the 'deckhouse-registry' secret")
+		}
+
+		scheme := mirror.Scheme
+		if scheme == "" {
+			scheme = "https"
+		}
+
+		mirrors = append(mirrors, map[string]string{
+			"address":   mirror.Address,
+			"scheme":    scheme,
+			"CA":        mirror.CA,
+			"dockercfg": mirror.DockerCfg,
+		})
+	}
+	input.Values.Set("global.modulesImages.registry.mirrors", mirrors)
 	return nil
 }
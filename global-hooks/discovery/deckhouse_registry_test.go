@@ -66,6 +66,22 @@ data:
   path: L2RlY2tob3VzZQ==            # /deckhouse
 `
 
+		stateDeckhouseRegistrySecretWithMirrors = `
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: deckhouse-registry
+  namespace: d8-system
+type: kubernetes.io/dockerconfigjson
+data:
+  .dockerconfigjson: eHl6Cg==
+  address: cmVnaXN0cnkudGVzdC5jb20= # registry.test.com
+  path: L2RlY2tob3VzZQ==            # /deckhouse
+  # [{"address":"mirror.test.com","dockerCfg":"eHl6Cg=="},{"address":"mirror2.test.com:5000","scheme":"http"}]
+  mirrors: W3siYWRkcmVzcyI6Im1pcnJvci50ZXN0LmNvbSIsImRvY2tlckNmZyI6ImVIbDZDZz09In0seyJhZGRyZXNzIjoibWlycm9yMi50ZXN0LmNvbTo1MDAwIiwic2NoZW1lIjoiaHR0cCJ9XQ==
+`
+
 		stateDeckhouseRegistrySecretWithoutAddress = `
 ---
 apiVersion: v1
@@ -140,6 +156,21 @@ data:
 		})
 	})
 
+	Context("Secret with mirrors is created", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(stateDeckhouseRegistrySecretWithMirrors))
+			f.RunHook()
+		})
+
+		It("Mirrors must be set", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.ValuesGet("global.modulesImages.registry.mirrors").String()).To(MatchJSON(`[
+{"address":"mirror.test.com","scheme":"https","CA":"","dockercfg":"eHl6Cg=="},
+{"address":"mirror2.test.com:5000","scheme":"http","CA":"","dockercfg":""}
+]`))
+		})
+	})
+
 	Context("Secret without Address is created", func() {
 		BeforeEach(func() {
 			f.BindingContexts.Set(f.KubeStateSet(stateDeckhouseRegistrySecretWithoutAddress))
@@ -117,6 +117,78 @@ func (c *Chain) ConvertToLatest(fromVersion int, settings map[string]interface{}
 	}
 }
 
+// ConvertTo converts settings from one version to another. Conversion to a previous version
+// is possible only if all conversions between versions have reverse functions.
+func (c *Chain) ConvertTo(fromVersion int, toVersion int, settings map[string]interface{}) (map[string]interface{}, error) {
+	currentSettings, err := SettingsFromMap(settings)
+	if err != nil {
+		return nil, fmt.Errorf("bad input settings: %v", err)
+	}
+
+	if !c.IsKnownVersion(fromVersion) {
+		return nil, fmt.Errorf("version %d is unknown", fromVersion)
+	}
+	if !c.IsKnownVersion(toVersion) {
+		return nil, fmt.Errorf("version %d is unknown", toVersion)
+	}
+
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	maxTries := len(c.conversions)
+	tries := 0
+	currentVersion := fromVersion
+	for currentVersion != toVersion {
+		var newVer int
+		var newSettings *Settings
+
+		if toVersion > currentVersion {
+			conv := c.conversions[currentVersion]
+			if conv == nil {
+				return nil, fmt.Errorf("convert from %d to %d: conversion chain interrupt: no conversion from %d", fromVersion, toVersion, currentVersion)
+			}
+			newVer = conv.Target
+			newSettings, err = conv.Convert(currentSettings)
+		} else {
+			conv := c.conversionToVersion(currentVersion)
+			if conv == nil {
+				return nil, fmt.Errorf("convert from %d to %d: conversion chain interrupt: no conversion to %d", fromVersion, toVersion, currentVersion)
+			}
+			newVer = conv.Source
+			newSettings, err = conv.ConvertBack(currentSettings)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("convert from %d to %d: conversion chain error for %d: %v", fromVersion, toVersion, currentVersion, err)
+		}
+
+		currentVersion = newVer
+		currentSettings = newSettings
+
+		// Prevent looped conversions.
+		tries++
+		if tries > maxTries {
+			return nil, fmt.Errorf("convert from %d to %d: conversion chain too long or looped", fromVersion, toVersion)
+		}
+	}
+
+	// Run JSON marshal-unmarshal for result settings to be compatible with ConvertToLatest.
+	newMap, err := currentSettings.Map()
+	if err != nil {
+		return nil, fmt.Errorf("convert from %d to %d: map error: %v", fromVersion, toVersion, err)
+	}
+	return newMap, nil
+}
+
+// conversionToVersion returns a conversion with the target version. It should be called under lock.
+func (c *Chain) conversionToVersion(targetVersion int) *Conversion {
+	for _, conv := range c.conversions {
+		if conv.Target == targetVersion {
+			return conv
+		}
+	}
+	return nil
+}
+
 func (c *Chain) Conversion(srcVersion int) *Conversion {
 	c.m.RLock()
 	defer c.m.RUnlock()
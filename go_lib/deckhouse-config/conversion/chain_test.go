@@ -72,3 +72,40 @@ func TestConvertConfigValuesToLatestReturnConsistentResults(t *testing.T) {
 		g.Expect(convertedV1[k]).Should(BeIdenticalTo(convertedV2[k]), "types should be identical")
 	}
 }
+
+func TestConvertConfigValuesToPreviousVersion(t *testing.T) {
+	g := NewWithT(t)
+
+	const modName = "test-mod-reversible"
+	RegisterReversibleFunc(modName, 1, 2,
+		func(settings *Settings) error {
+			return settings.Set("param2", settings.Get("param1").String())
+		},
+		func(settings *Settings) error {
+			return settings.Delete("param2")
+		},
+	)
+	RegisterFunc(modName, 2, 3, func(settings *Settings) error {
+		return settings.Delete("param1")
+	})
+
+	chain := Registry().Chain(modName)
+
+	// Forward conversion to the intermediate version.
+	newVals, err := chain.ConvertTo(1, 2, map[string]interface{}{"param1": "val1"})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(newVals).Should(Equal(map[string]interface{}{"param1": "val1", "param2": "val1"}))
+
+	// Reverse conversion.
+	newVals, err = chain.ConvertTo(2, 1, newVals)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(newVals).Should(Equal(map[string]interface{}{"param1": "val1"}))
+
+	// Conversion from 2 to 3 has no reverse function.
+	_, err = chain.ConvertTo(3, 1, map[string]interface{}{"param2": "val1"})
+	g.Expect(err).Should(HaveOccurred())
+	g.Expect(err.Error()).Should(ContainSubstring("not reversible"))
+
+	_, err = chain.ConvertTo(2, 4, map[string]interface{}{})
+	g.Expect(err).Should(HaveOccurred(), "should not convert to unknown version")
+}
@@ -16,12 +16,17 @@ limitations under the License.
 
 package conversion
 
+import "fmt"
+
 type ConversionFunc func(settings *Settings) error
 
 type Conversion struct {
 	Source     int
 	Target     int
 	Conversion ConversionFunc
+	// Reverse converts settings from the Target version back to the Source version.
+	// It is optional, conversions without it can't be used to downgrade settings.
+	Reverse ConversionFunc
 }
 
 func (c *Conversion) Convert(settings *Settings) (*Settings, error) {
@@ -37,6 +42,26 @@ func (c *Conversion) Convert(settings *Settings) (*Settings, error) {
 	return newValues, nil
 }
 
+// ConvertBack converts settings from the Target version to the Source version.
+func (c *Conversion) ConvertBack(settings *Settings) (*Settings, error) {
+	if c.Reverse == nil {
+		return nil, fmt.Errorf("conversion from %d to %d is not reversible", c.Source, c.Target)
+	}
+
+	// Copy values to prevent accidental mutating on error.
+	newValues := SettingsFromBytes(settings.Bytes())
+	err := c.Reverse(newValues)
+	if err != nil {
+		return nil, err
+	}
+	return newValues, nil
+}
+
+// IsReversible returns whether the conversion has a reverse function.
+func (c *Conversion) IsReversible() bool {
+	return c.Reverse != nil
+}
+
 func NewConversion(srcVersion int, targetVersion int, conversionFunc ConversionFunc) *Conversion {
 	return &Conversion{
 		Source:     srcVersion,
@@ -44,3 +69,12 @@ func NewConversion(srcVersion int, targetVersion int, conversionFunc ConversionF
 		Conversion: conversionFunc,
 	}
 }
+
+func NewReversibleConversion(srcVersion int, targetVersion int, conversionFunc ConversionFunc, reverseFunc ConversionFunc) *Conversion {
+	return &Conversion{
+		Source:     srcVersion,
+		Target:     targetVersion,
+		Conversion: conversionFunc,
+		Reverse:    reverseFunc,
+	}
+}
@@ -54,6 +54,14 @@ func RegisterFunc(moduleName string, srcVersion int, targetVersion int, conversi
 	return true
 }
 
+// RegisterReversibleFunc adds a function as a Conversion with a reverse function to Registry.
+// The reverse function is used to convert settings back to the previous version, e.g. on Deckhouse rollback.
+// Returns true to use with "var _ =".
+func RegisterReversibleFunc(moduleName string, srcVersion int, targetVersion int, conversionFunc ConversionFunc, reverseFunc ConversionFunc) bool {
+	Registry().Add(moduleName, NewReversibleConversion(srcVersion, targetVersion, conversionFunc, reverseFunc))
+	return true
+}
+
 type ConvRegistry struct {
 	// module name -> module chain
 	chains map[string]*Chain
@@ -17,6 +17,7 @@ limitations under the License.
 package deckhouse_config
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -146,6 +147,50 @@ func (c *ConfigValidator) ConvertToLatest(cfg *v1alpha1.ModuleConfig) Validation
 	return result
 }
 
+// ConvertTo checks if ModuleConfig resource is well-formed and converts spec.settings to the specified version.
+// It is used to check if settings can be represented in a previous version, e.g. before Deckhouse rollback.
+func (c *ConfigValidator) ConvertTo(cfg *v1alpha1.ModuleConfig, version int) ValidationResult {
+	result := c.validateCR(cfg)
+	if result.HasError() || !hasVersionedSettings(cfg) {
+		return result
+	}
+
+	result.Settings = cfg.Spec.Settings
+	chain := conversion.Registry().Chain(cfg.GetName())
+	newSettings, err := chain.ConvertTo(cfg.Spec.Version, version, cfg.Spec.Settings)
+	if err != nil {
+		result.Error = fmt.Sprintf("spec.settings conversion from version %d to %d: %v", cfg.Spec.Version, version, err)
+		return result
+	}
+	result.Settings = newSettings
+	result.Version = version
+
+	return result
+}
+
+// CheckConvertibleTo checks if spec.settings of ModuleConfigs can be represented in the target versions,
+// e.g. the latest versions of settings in the Deckhouse release to roll back to.
+// targetVersions is a map of ModuleConfig names to versions, ModuleConfigs not in the map are not checked.
+// Returns errors for ModuleConfigs that can't be converted.
+func (c *ConfigValidator) CheckConvertibleTo(cfgs []*v1alpha1.ModuleConfig, targetVersions map[string]int) map[string]error {
+	errs := make(map[string]error)
+
+	for _, cfg := range cfgs {
+		targetVersion, has := targetVersions[cfg.GetName()]
+		// Settings in older versions are supported by the target release as is.
+		if !has || !hasVersionedSettings(cfg) || cfg.Spec.Version <= targetVersion {
+			continue
+		}
+
+		result := c.ConvertTo(cfg, targetVersion)
+		if result.HasError() {
+			errs[cfg.GetName()] = errors.New(result.Error)
+		}
+	}
+
+	return errs
+}
+
 // Validate checks ModuleConfig resource:
 // - check if resource is well-formed
 // - runs conversions for spec.settings is needed
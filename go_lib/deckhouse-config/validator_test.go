@@ -273,3 +273,69 @@ func modCfgFromYAML(manifest string) (*v1alpha1.ModuleConfig, error) {
 
 	return &obj, nil
 }
+
+func TestValidatorCheckConvertibleTo(t *testing.T) {
+	g := NewWithT(t)
+
+	conversion.RegisterReversibleFunc("module-reversible", 1, 2,
+		func(settings *conversion.Settings) error {
+			return settings.Set("newParam", true)
+		},
+		func(settings *conversion.Settings) error {
+			return settings.Delete("newParam")
+		},
+	)
+	conversion.RegisterFunc("module-irreversible", 1, 2, func(settings *conversion.Settings) error {
+		return settings.Delete("oldParam")
+	})
+
+	var cfgs []*v1alpha1.ModuleConfig
+	for _, manifest := range []string{`
+apiVersion: deckhouse.io/v1alpha1
+kind: ModuleConfig
+metadata:
+  name: module-reversible
+spec:
+  version: 2
+  settings:
+    newParam: true
+`, `
+apiVersion: deckhouse.io/v1alpha1
+kind: ModuleConfig
+metadata:
+  name: module-irreversible
+spec:
+  version: 2
+  settings:
+    param: value
+`, `
+apiVersion: deckhouse.io/v1alpha1
+kind: ModuleConfig
+metadata:
+  name: module-one
+spec:
+  enabled: true
+`} {
+		cfg, err := modCfgFromYAML(manifest)
+		g.Expect(err).ShouldNot(HaveOccurred(), "should parse manifest: %s", manifest)
+		cfgs = append(cfgs, cfg)
+	}
+
+	v := NewConfigValidator(nil)
+
+	res := v.ConvertTo(cfgs[0], 1)
+	g.Expect(res.HasError()).Should(BeFalse(), "should convert to previous version, got error: %s", res.Error)
+	g.Expect(res.Version).Should(Equal(1))
+	g.Expect(res.Settings).ShouldNot(HaveKey("newParam"))
+
+	errs := v.CheckConvertibleTo(cfgs, map[string]int{
+		"module-reversible":   1,
+		"module-irreversible": 1,
+		"module-one":          1,
+	})
+	g.Expect(errs).Should(HaveLen(1))
+	g.Expect(errs).Should(HaveKey("module-irreversible"))
+
+	errs = v.CheckConvertibleTo(cfgs, map[string]int{"module-irreversible": 2})
+	g.Expect(errs).Should(BeEmpty(), "should not check configs in the target version")
+}
@@ -57,6 +57,7 @@ type client struct {
 
 // NewClient creates container registry client using `repo` as prefix for tags passed to methods. If insecure flag is set to true, then no cert validation is performed.
 // Repo example: "cr.example.com/ns/app"
+// If mirrors are set, requests fail over to them when the registry is unavailable, see WithMirrors.
 func NewClient(repo string, options ...Option) (Client, error) {
 	timeout := defaultTimeout
 	// make possible to rewrite timeout in runtime
@@ -75,6 +76,19 @@ func NewClient(repo string, options ...Option) (Client, error) {
 		opt(opts)
 	}
 
+	if len(opts.mirrors) > 0 {
+		return newFailoverClient(repo, opts)
+	}
+
+	r, err := newClient(repo, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func newClient(repo string, opts *registryOptions) (*client, error) {
 	r := &client{
 		registryURL: repo,
 		options:     opts,
@@ -243,6 +257,8 @@ type registryOptions struct {
 	dockerCfg   string
 	userAgent   string
 	timeout     time.Duration
+	mirrorsBase string
+	mirrors     []Mirror
 }
 
 type Option func(options *registryOptions)
@@ -0,0 +1,205 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+const (
+	// unhealthyCooldown is the period the failed registry is tried only after the healthy ones
+	unhealthyCooldown = time.Minute
+)
+
+// Mirror is a registry used if the primary registry and the previous mirrors are unavailable
+type Mirror struct {
+	// Repo replaces the base of the client repo, e.g., "mirror.example.com/deckhouse"
+	Repo string
+	// DockerCfg is a docker config base64, the mirror is accessed anonymously if it is empty
+	DockerCfg string
+	CA        string
+	UseHTTP   bool
+}
+
+// WithMirrors tries the mirrors in order if the primary registry is unavailable.
+// The base is the prefix of the client repo, which is replaced with the mirror repo:
+// the "registry.example.com/modules/echo" repo with the "registry.example.com/modules" base
+// is "mirror.example.com/modules/echo" for the "mirror.example.com/modules" mirror.
+func WithMirrors(base string, mirrors ...Mirror) Option {
+	return func(options *registryOptions) {
+		options.mirrorsBase = base
+		options.mirrors = mirrors
+	}
+}
+
+// failoverClient sends requests to the first available registry,
+// registries failed recently are tried after the healthy ones
+type failoverClient struct {
+	clients []*client
+}
+
+func newFailoverClient(repo string, opts *registryOptions) (Client, error) {
+	base := strings.TrimSuffix(opts.mirrorsBase, "/")
+	if !strings.HasPrefix(repo, base) {
+		return nil, fmt.Errorf("repo %q does not start with the %q mirrors base", repo, base)
+	}
+	suffix := strings.TrimPrefix(repo, base)
+
+	primary, err := newClient(repo, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	clients := make([]*client, 0, len(opts.mirrors)+1)
+	clients = append(clients, primary)
+	for _, mirror := range opts.mirrors {
+		mirrorOpts := &registryOptions{
+			ca:          mirror.CA,
+			useHTTP:     mirror.UseHTTP,
+			withoutAuth: mirror.DockerCfg == "",
+			dockerCfg:   mirror.DockerCfg,
+			userAgent:   opts.userAgent,
+			timeout:     opts.timeout,
+		}
+
+		mirrorClient, err := newClient(path.Join(mirror.Repo, suffix), mirrorOpts)
+		if err != nil {
+			return nil, fmt.Errorf("mirror %q: %w", mirror.Repo, err)
+		}
+		clients = append(clients, mirrorClient)
+	}
+
+	return &failoverClient{clients: clients}, nil
+}
+
+func (f *failoverClient) Image(tag string) (v1.Image, error) {
+	var image v1.Image
+	err := f.do(func(c *client) error {
+		var err error
+		image, err = c.Image(tag)
+		return err
+	})
+
+	return image, err
+}
+
+func (f *failoverClient) Digest(tag string) (string, error) {
+	var digest string
+	err := f.do(func(c *client) error {
+		var err error
+		digest, err = c.Digest(tag)
+		return err
+	})
+
+	return digest, err
+}
+
+func (f *failoverClient) ListTags() ([]string, error) {
+	var tags []string
+	err := f.do(func(c *client) error {
+		var err error
+		tags, err = c.ListTags()
+		return err
+	})
+
+	return tags, err
+}
+
+// do calls the registries until one of them responds.
+// A response with a client error, e.g., the tag is not found, is returned as is, the other registries are not called.
+func (f *failoverClient) do(call func(c *client) error) error {
+	errs := make([]error, 0, len(f.clients))
+	for _, c := range registriesHealth.order(f.clients) {
+		err := call(c)
+		if err == nil || !isUnavailable(err) {
+			registriesHealth.succeeded(c.registryURL)
+			return err
+		}
+
+		registriesHealth.failed(c.registryURL)
+		errs = append(errs, fmt.Errorf("%s: %w", c.registryURL, err))
+	}
+
+	return fmt.Errorf("all registries are unavailable: %w", errors.Join(errs...))
+}
+
+// isUnavailable returns false if the registry responded with a client error
+func isUnavailable(err error) bool {
+	var transportErr *transport.Error
+	if !errors.As(err, &transportErr) {
+		return true
+	}
+
+	return transportErr.StatusCode >= http.StatusInternalServerError || transportErr.StatusCode == http.StatusTooManyRequests
+}
+
+var registriesHealth = &healthTracker{failures: make(map[string]time.Time)}
+
+// healthTracker keeps the time of the last failure of the registry repos,
+// it is shared by all clients because the clients are created for every request
+type healthTracker struct {
+	mu       sync.Mutex
+	failures map[string]time.Time
+}
+
+func (h *healthTracker) failed(repo string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.failures[repo] = time.Now()
+}
+
+func (h *healthTracker) succeeded(repo string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.failures, repo)
+}
+
+// order returns the healthy clients first keeping the configured order
+func (h *healthTracker) order(clients []*client) []*client {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// forget the failures after the cooldown, so the removed registries do not stay in the map
+	for repo, failedAt := range h.failures {
+		if time.Since(failedAt) >= unhealthyCooldown {
+			delete(h.failures, repo)
+		}
+	}
+
+	healthy := make([]*client, 0, len(clients))
+	unhealthy := make([]*client, 0)
+	for _, c := range clients {
+		if _, found := h.failures[c.registryURL]; found {
+			unhealthy = append(unhealthy, c)
+			continue
+		}
+		healthy = append(healthy, c)
+	}
+
+	return append(healthy, unhealthy...)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cr
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailoverClient(t *testing.T) {
+	// the primary registry is down
+	primary := httptest.NewServer(registry.New())
+	primaryHost := strings.TrimPrefix(primary.URL, "http://")
+	primary.Close()
+
+	mirror := httptest.NewServer(registry.New())
+	defer mirror.Close()
+	mirrorHost := strings.TrimPrefix(mirror.URL, "http://")
+
+	image, err := random.Image(64, 1)
+	require.NoError(t, err)
+	ref, err := name.ParseReference(mirrorHost+"/modules/echo:v1.0.0", name.Insecure)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, image))
+
+	newClient := func() Client {
+		c, err := NewClient(primaryHost+"/modules/echo",
+			WithDisabledAuth(),
+			WithInsecureSchema(true),
+			WithTimeout(5*time.Second),
+			WithMirrors(primaryHost+"/modules", Mirror{Repo: mirrorHost + "/modules", UseHTTP: true}),
+		)
+		require.NoError(t, err)
+		return c
+	}
+
+	t.Run("fail over to the mirror", func(t *testing.T) {
+		tags, err := newClient().ListTags()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1.0.0"}, tags)
+
+		expected, err := image.Digest()
+		require.NoError(t, err)
+		digest, err := newClient().Digest("v1.0.0")
+		require.NoError(t, err)
+		assert.Equal(t, expected.String(), digest)
+	})
+
+	t.Run("unavailable registry is tried last", func(t *testing.T) {
+		clients := newClient().(*failoverClient).clients
+		ordered := registriesHealth.order(clients)
+		assert.Equal(t, mirrorHost+"/modules/echo", ordered[0].registryURL)
+		assert.Equal(t, primaryHost+"/modules/echo", ordered[1].registryURL)
+	})
+
+	t.Run("not found tag is not a failure", func(t *testing.T) {
+		_, err := newClient().Digest("v2.0.0")
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "all registries are unavailable")
+	})
+
+	t.Run("all registries are unavailable", func(t *testing.T) {
+		mirror.Close()
+
+		_, err := newClient().ListTags()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "all registries are unavailable")
+	})
+
+	t.Run("repo out of the mirrors base", func(t *testing.T) {
+		_, err := NewClient("registry.example.com/deckhouse", WithDisabledAuth(),
+			WithMirrors("registry.example.com/modules", Mirror{Repo: "mirror.example.com/modules"}))
+		assert.Error(t, err)
+	})
+}
+
+func TestHealthTrackerPrunesFailures(t *testing.T) {
+	h := &healthTracker{failures: map[string]time.Time{
+		"removed.example.com/modules/echo": time.Now().Add(-2 * unhealthyCooldown),
+		"mirror.example.com/modules/echo":  time.Now(),
+	}}
+
+	clients := []*client{{registryURL: "mirror.example.com/modules/echo"}, {registryURL: "registry.example.com/modules/echo"}}
+	ordered := h.order(clients)
+	assert.Equal(t, "registry.example.com/modules/echo", ordered[0].registryURL)
+	assert.Equal(t, map[string]time.Time{"mirror.example.com/modules/echo": h.failures["mirror.example.com/modules/echo"]}, h.failures)
+}
@@ -87,6 +87,9 @@ type Helper struct {
 
 	// OnPodDeletedOrEvicted is called when a pod is evicted/deleted; for printing progress output
 	OnPodDeletedOrEvicted func(pod *corev1.Pod, usingEviction bool)
+
+	// Progress records eviction attempts and blocking PodDisruptionBudgets if it is set
+	Progress *Progress
 }
 
 type waitForDeleteParams struct {
@@ -311,6 +314,7 @@ func (d *Helper) evictPods(pods []corev1.Pod, evictionGroupVersion schema.GroupV
 				}
 
 				err := d.EvictPod(activePod, evictionGroupVersion)
+				d.recordEvictionAttempt(activePod, err)
 				if err == nil {
 					break
 				} else if apierrors.IsNotFound(err) {
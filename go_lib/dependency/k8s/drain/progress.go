@@ -0,0 +1,148 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drain
+
+import (
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PodEvictionProgress contains eviction attempts of a single pod made during the drain.
+type PodEvictionProgress struct {
+	Namespace string
+	Name      string
+
+	Attempts  int
+	LastError string
+	// BlockingPDBs are names of PodDisruptionBudgets which do not allow to evict the pod.
+	BlockingPDBs []string
+	Evicted      bool
+}
+
+// Blocked returns true if the last eviction attempt was rejected because of PodDisruptionBudgets.
+func (p PodEvictionProgress) Blocked() bool {
+	return !p.Evicted && len(p.BlockingPDBs) > 0
+}
+
+// Progress accumulates eviction attempts of pods on the drained node. It is safe for concurrent use.
+type Progress struct {
+	mu   sync.Mutex
+	pods map[string]*PodEvictionProgress
+}
+
+func NewProgress() *Progress {
+	return &Progress{pods: make(map[string]*PodEvictionProgress)}
+}
+
+// Pods returns eviction progress of all pods sorted by namespace and name.
+func (p *Progress) Pods() []PodEvictionProgress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	res := make([]PodEvictionProgress, 0, len(p.pods))
+	for _, pod := range p.pods {
+		res = append(res, *pod)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Namespace != res[j].Namespace {
+			return res[i].Namespace < res[j].Namespace
+		}
+		return res[i].Name < res[j].Name
+	})
+
+	return res
+}
+
+// BlockedPods returns pods whose eviction is blocked by PodDisruptionBudgets.
+func (p *Progress) BlockedPods() []PodEvictionProgress {
+	var res []PodEvictionProgress
+	for _, pod := range p.Pods() {
+		if pod.Blocked() {
+			res = append(res, pod)
+		}
+	}
+
+	return res
+}
+
+func (p *Progress) record(pod corev1.Pod, err error, blockingPDBs []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := pod.Namespace + "/" + pod.Name
+	podProgress, ok := p.pods[key]
+	if !ok {
+		podProgress = &PodEvictionProgress{Namespace: pod.Namespace, Name: pod.Name}
+		p.pods[key] = podProgress
+	}
+
+	podProgress.Attempts++
+	podProgress.BlockingPDBs = blockingPDBs
+	podProgress.Evicted = err == nil || apierrors.IsNotFound(err)
+	podProgress.LastError = ""
+	if err != nil {
+		podProgress.LastError = err.Error()
+	}
+}
+
+// recordEvictionAttempt stores the result of the eviction attempt if the progress recording is enabled.
+func (d *Helper) recordEvictionAttempt(pod corev1.Pod, err error) {
+	if d.Progress == nil {
+		return
+	}
+
+	var blockingPDBs []string
+	// the eviction API responds with 429 if the eviction violates a PodDisruptionBudget
+	if apierrors.IsTooManyRequests(err) {
+		blockingPDBs = d.getBlockingPDBs(pod)
+	}
+
+	d.Progress.record(pod, err, blockingPDBs)
+}
+
+// getBlockingPDBs returns PodDisruptionBudgets selecting the pod which do not allow disruptions at the moment.
+// Errors are ignored because this information is used for diagnostics only.
+func (d *Helper) getBlockingPDBs(pod corev1.Pod) []string {
+	pdbs, err := d.Client.PolicyV1().PodDisruptionBudgets(pod.Namespace).List(d.getContext(), metav1.ListOptions{})
+	if err != nil {
+		return nil
+	}
+
+	var res []string
+	for _, pdb := range pdbs.Items {
+		if pdb.Status.DisruptionsAllowed > 0 {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+
+		res = append(res, pdb.Name)
+	}
+
+	sort.Strings(res)
+
+	return res
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	d8http "github.com/deckhouse/deckhouse/go_lib/dependency/http"
+)
+
+// Event is a stage of the release lifecycle.
+type Event string
+
+const (
+	// EventAvailable is sent when a new release appears in the cluster.
+	EventAvailable Event = "Available"
+	// EventScheduled is sent when the time of the release deployment is known.
+	EventScheduled Event = "Scheduled"
+	// EventStarted is sent when the release deployment is started.
+	EventStarted Event = "Started"
+	// EventDeployed is sent when the release is deployed.
+	EventDeployed Event = "Deployed"
+	// EventFailed is sent when the release deployment is failed.
+	EventFailed Event = "Failed"
+	// EventSuspended is sent when the release is suspended.
+	EventSuspended Event = "Suspended"
+)
+
+const (
+	KindDeckhouseRelease = "DeckhouseRelease"
+	KindModuleRelease    = "ModuleRelease"
+)
+
+// Headers of the notification request.
+const (
+	HeaderEvent     = "X-Deckhouse-Event"
+	HeaderDelivery  = "X-Deckhouse-Delivery"
+	HeaderTimestamp = "X-Deckhouse-Timestamp"
+	HeaderSignature = "X-Deckhouse-Signature"
+)
+
+// legacyReceiverName is the name of the receiver configured by the notification.webhook parameter.
+const legacyReceiverName = "webhook"
+
+// SecretNamespace is the namespace of the Secrets with the receiver credentials.
+const SecretNamespace = "d8-system"
+
+// Keys of the Secret with the receiver credentials.
+const (
+	SecretKeySigningKey  = "signingKey"
+	SecretKeyUsername    = "username"
+	SecretKeyPassword    = "password"
+	SecretKeyBearerToken = "bearerToken"
+)
+
+// SendTimeout limits the time of a single delivery attempt.
+const SendTimeout = 10 * time.Second
+
+// Config is the update.notification section of the deckhouse module settings.
+type Config struct {
+	// Webhook, TLSSkipVerify and Auth configure the receiver of the Scheduled events of Deckhouse releases.
+	// They are kept for compatibility, receivers should be used instead.
+	Webhook       string     `json:"webhook,omitempty"`
+	TLSSkipVerify bool       `json:"tlsSkipVerify,omitempty"`
+	Auth          *Auth      `json:"auth,omitempty"`
+	Receivers     []Receiver `json:"receivers,omitempty"`
+}
+
+// ReceiversFor returns receivers of the notifications about releases of the kind.
+func (c *Config) ReceiversFor(kind string) []Receiver {
+	if c == nil {
+		return nil
+	}
+
+	receivers := make([]Receiver, 0, len(c.Receivers)+1)
+	if c.Webhook != "" && kind == KindDeckhouseRelease {
+		receivers = append(receivers, Receiver{
+			Name:          legacyReceiverName,
+			Webhook:       c.Webhook,
+			Events:        []Event{EventScheduled},
+			TLSSkipVerify: c.TLSSkipVerify,
+			Auth:          c.Auth,
+		})
+	}
+
+	return append(receivers, c.Receivers...)
+}
+
+type Receiver struct {
+	Name    string `json:"name"`
+	Webhook string `json:"webhook"`
+	// Events the receiver is subscribed to, all events are sent if empty.
+	Events        []Event `json:"events,omitempty"`
+	TLSSkipVerify bool    `json:"tlsSkipVerify,omitempty"`
+	// SecretRef is the Secret in the SecretNamespace with the credentials of the receiver.
+	SecretRef *SecretRef `json:"secretRef,omitempty"`
+
+	// Auth and SigningKey are filled from the Secret before sending.
+	Auth *Auth `json:"-"`
+	// SigningKey is the key of the HMAC-SHA256 signature of the request.
+	SigningKey string `json:"-"`
+}
+
+type SecretRef struct {
+	Name string `json:"name"`
+}
+
+// SecretGetter returns the data of the Secret in the SecretNamespace.
+type SecretGetter func(ctx context.Context, name string) (map[string][]byte, error)
+
+// KubernetesSecrets returns the getter of the Secrets from the cluster.
+func KubernetesSecrets(client kubernetes.Interface) SecretGetter {
+	return func(ctx context.Context, name string) (map[string][]byte, error) {
+		secret, err := client.CoreV1().Secrets(SecretNamespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		return secret.Data, nil
+	}
+}
+
+// withCredentials returns the receiver with the signing key and the auth settings from its Secret.
+func (r Receiver) withCredentials(ctx context.Context, getSecret SecretGetter) (Receiver, error) {
+	if r.SecretRef == nil || r.SecretRef.Name == "" {
+		return r, nil
+	}
+
+	if getSecret == nil {
+		return r, fmt.Errorf("secret %s/%s is not available", SecretNamespace, r.SecretRef.Name)
+	}
+
+	data, err := getSecret(ctx, r.SecretRef.Name)
+	if err != nil {
+		return r, fmt.Errorf("get secret %s/%s: %w", SecretNamespace, r.SecretRef.Name, err)
+	}
+
+	r.SigningKey = string(data[SecretKeySigningKey])
+
+	switch {
+	case len(data[SecretKeyBearerToken]) > 0:
+		token := string(data[SecretKeyBearerToken])
+		r.Auth = &Auth{Token: &token}
+	case len(data[SecretKeyUsername]) > 0:
+		r.Auth = &Auth{Basic: &BasicAuth{Username: string(data[SecretKeyUsername]), Password: string(data[SecretKeyPassword])}}
+	}
+
+	return r, nil
+}
+
+// Subscribed returns true if the receiver accepts the event.
+func (r Receiver) Subscribed(event Event) bool {
+	if len(r.Events) == 0 {
+		return true
+	}
+
+	for _, e := range r.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Auth struct {
+	Basic *BasicAuth `json:"basic,omitempty"`
+	Token *string    `json:"bearerToken,omitempty"`
+}
+
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func (a *Auth) Fill(req *http.Request) {
+	if a == nil {
+		return
+	}
+	if a.Basic != nil {
+		req.SetBasicAuth(a.Basic.Username, a.Basic.Password)
+		return
+	}
+	if a.Token != nil {
+		req.Header.Set("Authorization", "Bearer "+*a.Token)
+	}
+}
+
+// Message is the body of the notification request.
+type Message struct {
+	Event     Event     `json:"event"`
+	Kind      string    `json:"kind"`
+	Release   string    `json:"release"`
+	Module    string    `json:"module,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+
+	Version       string            `json:"version"`
+	Requirements  map[string]string `json:"requirements,omitempty"`
+	ChangelogLink string            `json:"changelogLink"`
+	ApplyTime     string            `json:"applyTime,omitempty"`
+
+	Message string `json:"message"`
+}
+
+// Send posts the message to the receiver. Responses with a non-2xx status code are considered as failures.
+func Send(ctx context.Context, receiver Receiver, delivery string, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, receiver.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(msg.Event))
+	req.Header.Set(HeaderDelivery, delivery)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if receiver.SigningKey != "" {
+		req.Header.Set(HeaderSignature, Sign(receiver.SigningKey, timestamp, body))
+	}
+	receiver.Auth.Fill(req)
+
+	options := []d8http.Option{d8http.WithTimeout(SendTimeout)}
+	if receiver.TLSSkipVerify {
+		options = append(options, d8http.WithInsecureSkipVerify())
+	}
+
+	resp, err := d8http.NewClient(options...).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Sign returns the signature of the request body: "sha256=" followed by the hex-encoded HMAC-SHA256
+// of the timestamp header value and the body joined with a dot.
+// The timestamp is signed to let receivers reject replayed requests.
+func Sign(key, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigReceiversFor(t *testing.T) {
+	config := &Config{
+		Webhook: "https://legacy.example.com",
+		Receivers: []Receiver{
+			{Name: "chat", Webhook: "https://chat.example.com", Events: []Event{EventDeployed, EventFailed}},
+		},
+	}
+
+	receivers := config.ReceiversFor(KindDeckhouseRelease)
+	require.Len(t, receivers, 2)
+	assert.Equal(t, "webhook", receivers[0].Name)
+	assert.True(t, receivers[0].Subscribed(EventScheduled))
+	assert.False(t, receivers[0].Subscribed(EventDeployed))
+
+	// the legacy webhook receives only Deckhouse release notifications
+	receivers = config.ReceiversFor(KindModuleRelease)
+	require.Len(t, receivers, 1)
+	assert.Equal(t, "chat", receivers[0].Name)
+	assert.True(t, receivers[0].Subscribed(EventFailed))
+	assert.False(t, receivers[0].Subscribed(EventAvailable))
+
+	assert.True(t, Receiver{}.Subscribed(EventSuspended))
+}
+
+func TestSendSignature(t *testing.T) {
+	var (
+		body    []byte
+		headers http.Header
+	)
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		headers = r.Header
+	}))
+	defer svr.Close()
+
+	receiver := Receiver{Name: "signed", Webhook: svr.URL, SigningKey: "secret"}
+	err := Send(context.Background(), receiver, "delivery-id", Message{Event: EventStarted, Kind: KindDeckhouseRelease, Release: "v1.56.0", Version: "1.56"})
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body), `"event":"Started"`)
+	assert.Equal(t, "Started", headers.Get(HeaderEvent))
+	assert.Equal(t, "delivery-id", headers.Get(HeaderDelivery))
+	assert.Equal(t, Sign("secret", headers.Get(HeaderTimestamp), body), headers.Get(HeaderSignature))
+
+	receiver.SigningKey = ""
+	err = Send(context.Background(), receiver, "delivery-id", Message{Event: EventStarted})
+	require.NoError(t, err)
+	assert.Empty(t, headers.Get(HeaderSignature))
+}
+
+func TestSendFailedStatus(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer svr.Close()
+
+	err := Send(context.Background(), Receiver{Name: "down", Webhook: svr.URL}, "delivery-id", Message{Event: EventStarted})
+	assert.EqualError(t, err, "unexpected response status: 503 Service Unavailable")
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163", Sign("secret", "1700000000", []byte("{}")))
+	assert.NotEqual(t, Sign("secret", "1700000000", []byte("{}")), Sign("secret", "1700000001", []byte("{}")))
+	assert.NotEqual(t, Sign("secret", "1700000000", []byte("{}")), Sign("another", "1700000000", []byte("{}")))
+}
+
+func TestQueue(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	receivers := []Receiver{
+		{Name: "all"},
+		{Name: "deployed", Events: []Event{EventDeployed}},
+	}
+
+	q := NewQueue(receivers, nil, func() time.Time { return now })
+
+	var (
+		mu   sync.Mutex
+		sent []string
+	)
+	q.send = func(_ context.Context, receiver Receiver, _ string, msg Message) error {
+		if receiver.Name == "all" {
+			return errors.New("connection refused")
+		}
+		mu.Lock()
+		sent = append(sent, receiver.Name+":"+string(msg.Event))
+		mu.Unlock()
+		return nil
+	}
+
+	q.Enqueue(Message{Event: EventStarted, Release: "v1.56.0"})
+	q.Enqueue(Message{Event: EventDeployed, Release: "v1.56.0"})
+	assert.True(t, q.Changed())
+	assert.Equal(t, 3, q.Len())
+
+	errs := q.Flush(context.Background())
+	assert.Len(t, errs, 2)
+	assert.Equal(t, []string{"deployed:Deployed"}, sent)
+	assert.Equal(t, 2, q.Len())
+	assert.True(t, q.Pending(func(msg Message) bool { return msg.Event == EventStarted }))
+
+	// the queue survives the restart
+	items, err := ParseItems(q.Marshal())
+	require.NoError(t, err)
+	q = NewQueue(receivers, items, func() time.Time { return now })
+	q.send = func(_ context.Context, receiver Receiver, _ string, msg Message) error {
+		mu.Lock()
+		sent = append(sent, receiver.Name+":"+string(msg.Event))
+		mu.Unlock()
+		return nil
+	}
+
+	// backoff is not passed
+	now = now.Add(10 * time.Second)
+	assert.Empty(t, q.Flush(context.Background()))
+	assert.Equal(t, 2, q.Len())
+
+	now = now.Add(time.Minute)
+	assert.Empty(t, q.Flush(context.Background()))
+	assert.Equal(t, 0, q.Len())
+	assert.Equal(t, "deployed:Deployed", sent[0])
+	assert.ElementsMatch(t, []string{"deployed:Deployed", "all:Started", "all:Deployed"}, sent)
+}
+
+func TestQueueDropsMessages(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	q := NewQueue([]Receiver{{Name: "down"}}, nil, func() time.Time { return now })
+	q.send = func(context.Context, Receiver, string, Message) error {
+		return errors.New("connection refused")
+	}
+
+	q.Enqueue(Message{Event: EventStarted, Release: "v1.56.0"})
+	for i := 0; i < MaxAttempts; i++ {
+		q.Flush(context.Background())
+		now = now.Add(maxBackoff)
+	}
+	assert.Equal(t, 0, q.Len())
+
+	// messages of removed receivers are dropped
+	items := []Item{{Receiver: "removed", Message: Message{Event: EventStarted}, NextAttempt: now.Add(time.Hour)}}
+	q = NewQueue([]Receiver{{Name: "down"}}, items, func() time.Time { return now })
+	assert.False(t, q.Pending(func(Message) bool { return true }))
+	assert.Empty(t, q.Flush(context.Background()))
+	assert.Equal(t, 0, q.Len())
+	assert.True(t, q.Changed())
+}
+
+func TestQueueCompleteAfterReload(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	receivers := []Receiver{{Name: "all"}}
+
+	q := NewQueue(receivers, nil, func() time.Time { return now })
+	q.send = func(context.Context, Receiver, string, Message) error { return nil }
+	q.Enqueue(Message{Event: EventStarted, Release: "v1.56.0"})
+
+	due := q.Due()
+	require.Len(t, due, 1)
+	results := q.Deliver(context.Background(), due)
+	require.Contains(t, results, due[0].Item.ID)
+	// Deliver does not change the queue
+	assert.Equal(t, 1, q.Len())
+
+	// the message enqueued while sending is kept
+	items, err := ParseItems(q.Marshal())
+	require.NoError(t, err)
+	reloaded := NewQueue(receivers, items, func() time.Time { return now })
+	reloaded.Enqueue(Message{Event: EventDeployed, Release: "v1.56.0"})
+
+	assert.Empty(t, reloaded.Complete(results))
+	assert.Equal(t, 1, reloaded.Len())
+	assert.True(t, reloaded.Pending(func(msg Message) bool { return msg.Event == EventDeployed }))
+}
+
+func TestQueueSecrets(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	receivers := []Receiver{
+		{Name: "token", SecretRef: &SecretRef{Name: "token"}},
+		{Name: "basic", SecretRef: &SecretRef{Name: "basic"}},
+		{Name: "missing", SecretRef: &SecretRef{Name: "missing"}},
+	}
+	secrets := map[string]map[string][]byte{
+		"token": {SecretKeySigningKey: []byte("key"), SecretKeyBearerToken: []byte("token")},
+		"basic": {SecretKeyUsername: []byte("user"), SecretKeyPassword: []byte("pass")},
+	}
+
+	var (
+		mu   sync.Mutex
+		sent = make(map[string]Receiver)
+	)
+	q := NewQueue(receivers, nil, func() time.Time { return now }).WithSecrets(func(_ context.Context, name string) (map[string][]byte, error) {
+		data, ok := secrets[name]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return data, nil
+	})
+	q.send = func(_ context.Context, receiver Receiver, _ string, _ Message) error {
+		mu.Lock()
+		sent[receiver.Name] = receiver
+		mu.Unlock()
+		return nil
+	}
+
+	q.Enqueue(Message{Event: EventStarted, Release: "v1.56.0"})
+	errs := q.Flush(context.Background())
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), `get secret d8-system/missing: not found`)
+	assert.Equal(t, 1, q.Len())
+
+	require.Contains(t, sent, "token")
+	assert.Equal(t, "key", sent["token"].SigningKey)
+	assert.Equal(t, "token", *sent["token"].Auth.Token)
+
+	require.Contains(t, sent, "basic")
+	assert.Empty(t, sent["basic"].SigningKey)
+	assert.Equal(t, &BasicAuth{Username: "user", Password: "pass"}, sent["basic"].Auth.Basic)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, backoff(1))
+	assert.Equal(t, time.Minute, backoff(2))
+	assert.Equal(t, 8*time.Minute, backoff(5))
+	assert.Equal(t, time.Hour, backoff(MaxAttempts))
+}
@@ -0,0 +1,293 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxAttempts is the number of delivery attempts after which the message is dropped.
+	MaxAttempts = 12
+	// MaxItems limits the queue size, the queue is stored in a ConfigMap.
+	MaxItems = 100
+
+	initialBackoff = 30 * time.Second
+	maxBackoff     = time.Hour
+)
+
+// Item is a message waiting for delivery to the receiver.
+type Item struct {
+	ID          string    `json:"id"`
+	Receiver    string    `json:"receiver"`
+	Message     Message   `json:"message"`
+	Attempts    int       `json:"attempts,omitempty"`
+	NextAttempt time.Time `json:"nextAttempt"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+// Delivery is the item to send and its receiver.
+type Delivery struct {
+	Item     Item
+	Receiver Receiver
+}
+
+// Queue keeps undelivered messages between runs, so receiver outages do not drop them.
+// Messages are retried with exponential backoff until MaxAttempts is reached.
+type Queue struct {
+	receivers []Receiver
+	items     []Item
+	changed   bool
+	now       func() time.Time
+	getSecret SecretGetter
+	send      func(ctx context.Context, receiver Receiver, delivery string, msg Message) error
+}
+
+// NewQueue returns the queue of the items loaded from the storage.
+func NewQueue(receivers []Receiver, items []Item, now func() time.Time) *Queue {
+	return &Queue{
+		receivers: receivers,
+		items:     items,
+		now:       now,
+		send:      Send,
+	}
+}
+
+func (q *Queue) receiver(name string) (Receiver, bool) {
+	for _, receiver := range q.receivers {
+		if receiver.Name == name {
+			return receiver, true
+		}
+	}
+
+	return Receiver{}, false
+}
+
+// WithSecrets sets the getter of the Secrets referenced by the receivers.
+func (q *Queue) WithSecrets(getSecret SecretGetter) *Queue {
+	q.getSecret = getSecret
+	return q
+}
+
+// ParseItems parses items serialized by Queue.Marshal.
+func ParseItems(data string) ([]Item, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	var items []Item
+	if err := json.Unmarshal([]byte(data), &items); err != nil {
+		return nil, fmt.Errorf("parse notification queue: %w", err)
+	}
+
+	return items, nil
+}
+
+// Marshal serializes the queue items to store them.
+func (q *Queue) Marshal() string {
+	if len(q.items) == 0 {
+		return "[]"
+	}
+
+	data, _ := json.Marshal(q.items)
+
+	return string(data)
+}
+
+// Changed returns true if the queue has to be stored again.
+func (q *Queue) Changed() bool {
+	return q.changed
+}
+
+// Len returns the number of undelivered messages.
+func (q *Queue) Len() int {
+	return len(q.items)
+}
+
+// Enqueue adds the message for every receiver subscribed to its event.
+func (q *Queue) Enqueue(msg Message) {
+	now := q.now()
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = now
+	}
+
+	for _, receiver := range q.receivers {
+		if !receiver.Subscribed(msg.Event) {
+			continue
+		}
+
+		q.items = append(q.items, Item{
+			ID:          deliveryID(receiver.Name, msg),
+			Receiver:    receiver.Name,
+			Message:     msg,
+			NextAttempt: now,
+		})
+		q.changed = true
+	}
+
+	if len(q.items) > MaxItems {
+		q.items = q.items[len(q.items)-MaxItems:]
+	}
+}
+
+// Pending returns true if a message matching the filter is not delivered yet.
+func (q *Queue) Pending(filter func(msg Message) bool) bool {
+	for _, item := range q.items {
+		if _, ok := q.receiver(item.Receiver); ok && filter(item.Message) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Flush sends due messages and returns delivery errors, see Due, Deliver and Complete.
+func (q *Queue) Flush(ctx context.Context) []error {
+	return q.Complete(q.Deliver(ctx, q.Due()))
+}
+
+// Due returns messages to send now. Messages of removed receivers are dropped.
+func (q *Queue) Due() []Delivery {
+	var deliveries []Delivery
+
+	now := q.now()
+	items := q.items[:0]
+
+	for _, item := range q.items {
+		receiver, ok := q.receiver(item.Receiver)
+		if !ok {
+			q.changed = true
+			continue
+		}
+
+		if !now.Before(item.NextAttempt) {
+			deliveries = append(deliveries, Delivery{Item: item, Receiver: receiver})
+		}
+		items = append(items, item)
+	}
+
+	q.items = items
+
+	return deliveries
+}
+
+// Deliver sends the messages concurrently, every attempt is limited by SendTimeout. It does not change the queue,
+// so it can be called without holding the lock of the queue storage. The result contains the error of every
+// delivery by the item ID, nil if the message is delivered.
+func (q *Queue) Deliver(ctx context.Context, deliveries []Delivery) map[string]error {
+	results := make(map[string]error, len(deliveries))
+	if len(deliveries) == 0 {
+		return results
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery Delivery) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, SendTimeout)
+			defer cancel()
+
+			receiver, err := delivery.Receiver.withCredentials(ctx, q.getSecret)
+			if err == nil {
+				err = q.send(ctx, receiver, delivery.Item.ID, delivery.Item.Message)
+			}
+
+			mu.Lock()
+			results[delivery.Item.ID] = err
+			mu.Unlock()
+		}(delivery)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// Complete applies the results of Deliver and returns delivery errors. Delivered messages are removed, failed messages
+// are kept for the next attempt, messages exceeded MaxAttempts are dropped. Items missing in the results are not changed.
+func (q *Queue) Complete(results map[string]error) []error {
+	var errs []error
+
+	now := q.now()
+	items := q.items[:0]
+
+	for _, item := range q.items {
+		err, sent := results[item.ID]
+		if !sent {
+			items = append(items, item)
+			continue
+		}
+
+		q.changed = true
+
+		if err == nil {
+			continue
+		}
+
+		item.Attempts++
+		item.LastError = err.Error()
+		if item.Attempts >= MaxAttempts {
+			errs = append(errs, fmt.Errorf("send %s notification about %s to %q failed %d times, dropping it: %w",
+				item.Message.Event, item.Message.Release, item.Receiver, item.Attempts, err))
+			continue
+		}
+
+		item.NextAttempt = now.Add(backoff(item.Attempts))
+		items = append(items, item)
+		errs = append(errs, fmt.Errorf("send %s notification about %s to %q: %w", item.Message.Event, item.Message.Release, item.Receiver, err))
+	}
+
+	q.items = items
+
+	return errs
+}
+
+func backoff(attempts int) time.Duration {
+	d := initialBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+
+	if d > maxBackoff {
+		return maxBackoff
+	}
+
+	return d
+}
+
+// deliveryID is the same for all attempts to send the message, so receivers can deduplicate them.
+func deliveryID(receiver string, msg Message) string {
+	hash := sha256.New()
+	for _, value := range []string{receiver, string(msg.Event), msg.Kind, msg.Release, msg.Timestamp.Format(time.RFC3339Nano)} {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:32]
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package userauthz keeps the conventions of the user-authz module shared by its hooks, its webhook
+// and the deckhouse-controller helpers.
+package userauthz
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// The access levels of the ClusterAuthorizationRule from the lowest to the highest
+const (
+	AccessLevelUser           = "User"
+	AccessLevelPrivilegedUser = "PrivilegedUser"
+	AccessLevelEditor         = "Editor"
+	AccessLevelAdmin          = "Admin"
+	AccessLevelClusterEditor  = "ClusterEditor"
+	AccessLevelClusterAdmin   = "ClusterAdmin"
+	AccessLevelSuperAdmin     = "SuperAdmin"
+)
+
+// The ClusterRoles of the module templates
+const (
+	clusterRolePrefix = "user-authz:"
+
+	PortForwardClusterRole = clusterRolePrefix + "port-forward"
+	ScaleClusterRole       = clusterRolePrefix + "scale"
+)
+
+var (
+	// AccessLevels are ordered from the lowest to the highest, every access level has its ClusterRole
+	AccessLevels = append(append([]string{}, CustomClusterRoleAccessLevels...), AccessLevelSuperAdmin)
+	// CustomClusterRoleAccessLevels are the values of the user-authz.deckhouse.io/access-level annotation of the custom ClusterRoles,
+	// the custom ClusterRole of the access level is also bound for the higher access levels
+	CustomClusterRoleAccessLevels = []string{
+		AccessLevelUser,
+		AccessLevelPrivilegedUser,
+		AccessLevelEditor,
+		AccessLevelAdmin,
+		AccessLevelClusterEditor,
+		AccessLevelClusterAdmin,
+	}
+)
+
+// SystemNamespaces are the patterns of the namespaces accessible by the rules
+// only with the allowAccessToSystemNamespaces option. The webhook gets them in its config.
+var SystemNamespaces = []string{
+	"kube-.*",
+	"d8-.*",
+	"default",
+	// legacy
+	"antiopa",
+	"loghouse",
+}
+
+var systemNamespacesRegex = func() []*regexp.Regexp {
+	result := make([]*regexp.Regexp, 0, len(SystemNamespaces))
+	for _, pattern := range SystemNamespaces {
+		result = append(result, regexp.MustCompile(WrapRegex(pattern)))
+	}
+	return result
+}()
+
+// IsSystemNamespace reports whether the namespace matches one of the SystemNamespaces
+func IsSystemNamespace(namespace string) bool {
+	for _, r := range systemNamespacesRegex {
+		if r.MatchString(namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// WrapRegex makes the limitNamespaces pattern match the whole namespace name
+func WrapRegex(pattern string) string {
+	if !strings.HasPrefix(pattern, "^") {
+		pattern = "^" + pattern
+	}
+	if !strings.HasSuffix(pattern, "$") {
+		pattern += "$"
+	}
+	return pattern
+}
+
+// AccessLevelClusterRole returns the ClusterRole of the access level,
+// the templates convert the access level with the sprig kebabcase function
+func AccessLevelClusterRole(accessLevel string) string {
+	return clusterRolePrefix + kebabCase(accessLevel)
+}
+
+func kebabCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package userauthz
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const modulePath = "../../modules/140-user-authz"
+
+func TestIsSystemNamespace(t *testing.T) {
+	for _, namespace := range []string{"kube-system", "d8-system", "default", "antiopa", "loghouse"} {
+		assert.True(t, IsSystemNamespace(namespace), namespace)
+	}
+	for _, namespace := range []string{"dev", "default-dev", "my-kube-system", "d8", "loghouse2"} {
+		assert.False(t, IsSystemNamespace(namespace), namespace)
+	}
+}
+
+// TestClusterRoles checks the names against the module templates and the access levels against the CRD
+func TestClusterRoles(t *testing.T) {
+	templates, err := filepath.Glob(filepath.Join(modulePath, "templates", "*.yaml"))
+	require.NoError(t, err)
+
+	var content strings.Builder
+	for _, template := range templates {
+		data, err := os.ReadFile(template)
+		require.NoError(t, err)
+		content.Write(data)
+	}
+
+	roles := []string{PortForwardClusterRole, ScaleClusterRole}
+	for _, accessLevel := range AccessLevels {
+		roles = append(roles, AccessLevelClusterRole(accessLevel))
+	}
+	for _, role := range roles {
+		assert.Contains(t, content.String(), "name: "+role+"\n")
+	}
+	assert.Equal(t, "user-authz:privileged-user", AccessLevelClusterRole(AccessLevelPrivilegedUser))
+
+	crd, err := os.ReadFile(filepath.Join(modulePath, "crds", "clusterauthorizationrule.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(crd), "enum: ["+strings.Join(AccessLevels, ",")+"]")
+}
@@ -443,6 +443,24 @@ func isHTTP(input *go_hook.HookInput) bool {
 	return registryScheme == "http"
 }
 
+// getMirrors returns mirrors of the registry, a mirror serves the same path as the registry
+func getMirrors(input *go_hook.HookInput) []cr.Mirror {
+	registryPath := input.Values.Get("global.modulesImages.registry.path").String()
+
+	mirrorsValues := input.Values.Get("global.modulesImages.registry.mirrors").Array()
+	mirrors := make([]cr.Mirror, 0, len(mirrorsValues))
+	for _, mirror := range mirrorsValues {
+		mirrors = append(mirrors, cr.Mirror{
+			Repo:      mirror.Get("address").String() + registryPath,
+			DockerCfg: mirror.Get("dockercfg").String(),
+			CA:        mirror.Get("CA").String(),
+			UseHTTP:   mirror.Get("scheme").String() == "http",
+		})
+	}
+
+	return mirrors
+}
+
 type DeckhouseReleaseChecker struct {
 	registryClient cr.Client
 	logger         *logrus.Entry
@@ -576,7 +594,8 @@ func NewDeckhouseReleaseChecker(input *go_hook.HookInput, dc dependency.Containe
 	dockerCfg := input.Values.Get("global.modulesImages.registry.dockercfg").String()
 	clusterUUID := input.Values.Get("global.discovery.clusterUUID").String()
 	// registry.deckhouse.io/deckhouse/ce/release-channel:$release-channel
-	regCli, err := dc.GetRegistryClient(path.Join(repo, "release-channel"), cr.WithAuth(dockerCfg), cr.WithCA(getCA(input)), cr.WithInsecureSchema(isHTTP(input)), cr.WithUserAgent(clusterUUID))
+	regCli, err := dc.GetRegistryClient(path.Join(repo, "release-channel"), cr.WithAuth(dockerCfg), cr.WithCA(getCA(input)), cr.WithInsecureSchema(isHTTP(input)), cr.WithUserAgent(clusterUUID),
+		cr.WithMirrors(repo, getMirrors(input)...))
 	if err != nil {
 		return nil, err
 	}
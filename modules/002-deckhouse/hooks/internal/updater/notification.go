@@ -17,119 +17,114 @@ limitations under the License.
 package updater
 
 import (
-	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"time"
 
 	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
-	"github.com/tidwall/gjson"
 
+	"github.com/deckhouse/deckhouse/go_lib/hooks/update/notification"
 	"github.com/deckhouse/deckhouse/modules/002-deckhouse/hooks/internal/apis/v1alpha1"
 )
 
-type NotificationConfig struct {
-	WebhookURL              string
-	SkipTLSVerify           bool
-	MinimalNotificationTime v1alpha1.Duration
-	Auth                    *Auth `json:"auth,omitempty"`
-}
-
-type Auth struct {
-	Basic *BasicAuth `json:"basic,omitempty"`
-	Token *string    `json:"bearerToken,omitempty"`
-}
+const (
+	// maxNotificationDelay limits the time the release waits for the delivery of the Scheduled notification.
+	// The delivery is retried after the release is deployed.
+	maxNotificationDelay = 15 * time.Minute
+	// deployFailedTimeout is the time the Deckhouse pod has to become ready after the release deployment.
+	deployFailedTimeout = 30 * time.Minute
+)
 
-type BasicAuth struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+type NotificationConfig struct {
+	notification.Config
+	MinimalNotificationTime v1alpha1.Duration `json:"minimalNotificationTime"`
 }
 
-func (a *Auth) Fill(req *http.Request) {
-	if a == nil {
-		return
-	}
-	if a.Basic != nil {
-		req.SetBasicAuth(a.Basic.Username, a.Basic.Password)
-		return
-	}
-	if a.Token != nil {
-		req.Header.Set("Authorization", "Bearer "+*a.Token)
-	}
-}
+type (
+	Auth      = notification.Auth
+	BasicAuth = notification.BasicAuth
+)
 
 func ParseNotificationConfigFromValues(input *go_hook.HookInput) (*NotificationConfig, error) {
-	webhook, ok := input.Values.GetOk("deckhouse.update.notification.webhook")
-	if !ok {
-		webhook = gjson.Result{}
-	}
+	config := &NotificationConfig{}
 
-	var minimalTime v1alpha1.Duration
-	t, ok := input.Values.GetOk("deckhouse.update.notification.minimalNotificationTime")
+	n, ok := input.Values.GetOk("deckhouse.update.notification")
 	if ok {
-		err := json.Unmarshal([]byte(t.Raw), &minimalTime)
+		err := json.Unmarshal([]byte(n.Raw), config)
 		if err != nil {
-			return nil, fmt.Errorf("parsing minimalNotificationTime: %v", err)
+			return nil, fmt.Errorf("parsing notification settings: %v", err)
 		}
 	}
 
-	skipTLSVertify := input.Values.Get("deckhouse.update.notification.tlsSkipVerify").Bool()
+	return config, nil
+}
 
-	var auth *Auth
-	a, ok := input.Values.GetOk("deckhouse.update.notification.auth")
-	if ok {
-		auth = &Auth{}
-		err := json.Unmarshal([]byte(a.Raw), auth)
-		if err != nil {
-			return nil, fmt.Errorf("parsing auth: %v", err)
-		}
+func (du *DeckhouseUpdater) notify(event notification.Event, release *DeckhouseRelease, applyTime *time.Time, msg string) {
+	message := notification.Message{
+		Event:         event,
+		Kind:          notification.KindDeckhouseRelease,
+		Release:       release.Name,
+		Version:       fmt.Sprintf("%d.%d", release.Version.Major(), release.Version.Minor()),
+		Requirements:  release.Requirements,
+		ChangelogLink: release.ChangelogLink,
+		Message:       msg,
+	}
+	if applyTime != nil {
+		message.ApplyTime = applyTime.Format(time.RFC3339)
 	}
 
-	return &NotificationConfig{
-		WebhookURL:              webhook.String(),
-		SkipTLSVerify:           skipTLSVertify,
-		MinimalNotificationTime: minimalTime,
-		Auth:                    auth,
-	}, nil
+	du.notifications.Enqueue(message)
 }
 
-func sendWebhookNotification(config *NotificationConfig, data webhookData) error {
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: config.SkipTLSVerify},
+// FlushNotifications sends queued notifications. Undelivered notifications are stored in the release data ConfigMap
+// to retry them in the next runs.
+func (du *DeckhouseUpdater) FlushNotifications() {
+	for _, err := range du.notifications.Flush(context.Background()) {
+		du.input.LogEntry.Errorf("Send deckhouse release notification failed: %s", err)
 	}
-	client := &http.Client{
-		Transport: tr,
-		Timeout:   10 * time.Second,
+
+	if du.notifications.Changed() {
+		du.createReleaseDataCM()
 	}
+}
 
-	buf := bytes.NewBuffer(nil)
-	_ = json.NewEncoder(buf).Encode(data)
+// notificationPending returns true if the receivers have not got the notification about the release event yet.
+// Notifications older than maxNotificationDelay are not waited for, so an unavailable receiver does not block the update.
+func (du *DeckhouseUpdater) notificationPending(event notification.Event, release *DeckhouseRelease) bool {
+	return du.notifications.Pending(func(msg notification.Message) bool {
+		return msg.Event == event && msg.Release == release.Name && du.now.Sub(msg.Timestamp) < maxNotificationDelay
+	})
+}
 
-	req, err := http.NewRequest(http.MethodPost, config.WebhookURL, buf)
-	if err != nil {
-		return err
+// NotifyDeployFailed sends the Failed notification if the Deckhouse container of the deployed release crashed
+// or the pod is not ready for deployFailedTimeout. The notification is sent once per deployment.
+func (du *DeckhouseUpdater) NotifyDeployFailed(crash string) {
+	if !du.releaseData.IsUpdating || du.deckhousePodIsReady || du.releaseData.FailureNotified {
+		return
 	}
-	req.Header.Add("Content-Type", "application/json")
-	config.Auth.Fill(req)
 
-	for i := 0; i < 3; i++ {
-		_, err = client.Do(req)
-		if err == nil {
-			return nil
+	var release *DeckhouseRelease
+	for i := range du.releases {
+		if du.releases[i].Status.Phase == v1alpha1.PhaseDeployed {
+			release = &du.releases[i]
 		}
-		time.Sleep(3 * time.Second)
+	}
+	if release == nil {
+		return
 	}
 
-	return err
-}
-
-type webhookData struct {
-	Version       string            `json:"version"`
-	Requirements  map[string]string `json:"requirements,omitempty"`
-	ChangelogLink string            `json:"changelogLink"`
-	ApplyTime     string            `json:"applyTime,omitempty"`
+	var reason string
+	switch {
+	case crash != "":
+		reason = crash
+	case !release.Status.TransitionTime.IsZero() && du.now.Sub(release.Status.TransitionTime) > deployFailedTimeout:
+		reason = fmt.Sprintf("the Deckhouse pod is not ready for more than %s", deployFailedTimeout)
+	default:
+		return
+	}
 
-	Message string `json:"message"`
+	du.notify(notification.EventFailed, release, nil, fmt.Sprintf("Deckhouse Release %s deployment failed: %s", release.Version.Original(), reason))
+	du.releaseData.FailureNotified = true
+	du.createReleaseDataCM()
 }
@@ -22,6 +22,8 @@ import (
 
 	"github.com/Masterminds/semver/v3"
 
+	"github.com/deckhouse/deckhouse/go_lib/hooks/update/notification"
+
 	"github.com/deckhouse/deckhouse/modules/002-deckhouse/hooks/internal/apis/v1alpha1"
 )
 
@@ -72,6 +74,8 @@ func (sp StatusPatch) MarshalJSON() ([]byte, error) {
 }
 
 type DeckhouseReleaseData struct {
-	IsUpdating bool
-	Notified   bool
+	IsUpdating        bool
+	Notified          bool
+	FailureNotified   bool
+	NotificationQueue []notification.Item
 }
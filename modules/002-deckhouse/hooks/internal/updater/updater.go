@@ -36,6 +36,7 @@ import (
 
 	"github.com/deckhouse/deckhouse/go_lib/dependency/requirements"
 	"github.com/deckhouse/deckhouse/go_lib/hooks/update"
+	"github.com/deckhouse/deckhouse/go_lib/hooks/update/notification"
 	"github.com/deckhouse/deckhouse/modules/002-deckhouse/hooks/internal/apis/v1alpha1"
 )
 
@@ -67,9 +68,12 @@ type DeckhouseUpdater struct {
 
 	releaseData        DeckhouseReleaseData
 	notificationConfig *NotificationConfig
+	notifications      *notification.Queue
+	// updateFinished is set when the Deckhouse pod becomes ready after the release deployment
+	updateFinished bool
 }
 
-func NewDeckhouseUpdater(input *go_hook.HookInput, mode string, data DeckhouseReleaseData, podIsReady, isBootstrapping bool) (*DeckhouseUpdater, error) {
+func NewDeckhouseUpdater(input *go_hook.HookInput, mode string, data DeckhouseReleaseData, podIsReady, isBootstrapping bool, getSecret notification.SecretGetter) (*DeckhouseUpdater, error) {
 	nConfig, err := ParseNotificationConfigFromValues(input)
 	if err != nil {
 		return nil, fmt.Errorf("parsing notification config: %v", err)
@@ -91,6 +95,9 @@ func NewDeckhouseUpdater(input *go_hook.HookInput, mode string, data DeckhouseRe
 		deckhouseIsBootstrapping:    isBootstrapping,
 		releaseData:                 data,
 		notificationConfig:          nConfig,
+		notifications: notification.NewQueue(nConfig.ReceiversFor(notification.KindDeckhouseRelease), data.NotificationQueue, func() time.Time {
+			return now
+		}).WithSecrets(getSecret),
 	}, nil
 }
 
@@ -111,6 +118,12 @@ func (du *DeckhouseUpdater) checkPatchReleaseConditions(predictedRelease *Deckho
 
 func (du *DeckhouseUpdater) checkReleaseNotification(predictedRelease *DeckhouseRelease, updateWindows update.Windows) bool {
 	if du.releaseData.Notified {
+		// the release is not deployed until the receivers get the notification or maxNotificationDelay passes
+		if du.notificationPending(notification.EventScheduled, predictedRelease) {
+			du.input.LogEntry.Infof("Release %s is waiting for the notification to be delivered", predictedRelease.Name)
+			return false
+		}
+
 		return true
 	}
 
@@ -129,21 +142,8 @@ func (du *DeckhouseUpdater) checkReleaseNotification(predictedRelease *Deckhouse
 
 	version := fmt.Sprintf("%d.%d", predictedRelease.Version.Major(), predictedRelease.Version.Minor())
 	msg := fmt.Sprintf("New Deckhouse Release %s is available. Release will be applied at: %s", version, releaseApplyTime.Format(time.RFC850))
-	if du.notificationConfig.WebhookURL != "" {
-		data := webhookData{
-			Version:       fmt.Sprintf("%d.%d", predictedRelease.Version.Major(), predictedRelease.Version.Minor()),
-			Requirements:  predictedRelease.Requirements,
-			ChangelogLink: predictedRelease.ChangelogLink,
-			ApplyTime:     releaseApplyTime.Format(time.RFC3339),
-			Message:       msg,
-		}
-
-		err := sendWebhookNotification(du.notificationConfig, data)
-		if err != nil {
-			du.input.LogEntry.Errorf("Send deckhouse release notification failed: %s", err)
-			return false
-		}
-	}
+	du.notify(notification.EventScheduled, predictedRelease, &releaseApplyTime, msg)
+	du.FlushNotifications()
 
 	du.changeNotifiedFlag(true)
 	if applyTimeChanged {
@@ -161,7 +161,7 @@ func (du *DeckhouseUpdater) checkReleaseNotification(predictedRelease *Deckhouse
 		return false
 	}
 
-	return true
+	return !du.notificationPending(notification.EventScheduled, predictedRelease)
 }
 
 // for minor release (version change) we check more conditions
@@ -350,6 +350,7 @@ func (du *DeckhouseUpdater) runReleaseDeploy(predictedRelease, currentRelease *D
 
 	du.ChangeUpdatingFlag(true)
 	du.changeNotifiedFlag(false)
+	du.notify(notification.EventStarted, predictedRelease, nil, fmt.Sprintf("Deckhouse Release %s deployment is started", predictedRelease.Version.Original()))
 
 	// patch deckhouse deployment is faster than set internal values and then upgrade by helm
 	// we can set "deckhouse.internal.currentReleaseImageName" value but lets left it this way
@@ -548,6 +549,7 @@ func (du *DeckhouseUpdater) patchInitialStatus(release DeckhouseRelease) Deckhou
 	release.Status.Approved = true
 
 	du.updateStatus(&release, "", v1alpha1.PhasePending)
+	du.notify(notification.EventAvailable, &release, release.ApplyAfter, fmt.Sprintf("New Deckhouse Release %s is available", release.Version.Original()))
 
 	return release
 }
@@ -577,6 +579,7 @@ func (du *DeckhouseUpdater) patchSuspendedStatus(release DeckhouseRelease) Deckh
 
 	du.input.PatchCollector.MergePatch(annotationsPatch, "deckhouse.io/v1alpha1", "DeckhouseRelease", "", release.Name)
 	du.updateStatus(&release, "", v1alpha1.PhaseSuspended)
+	du.notify(notification.EventSuspended, &release, nil, fmt.Sprintf("Deckhouse Release %s is suspended", release.Version.Original()))
 
 	return release
 }
@@ -630,6 +633,14 @@ func (du *DeckhouseUpdater) FetchAndPrepareReleases(snap []go_hook.FilterResult)
 	sort.Sort(ByVersion(releases))
 
 	du.releases = releases
+
+	if du.updateFinished {
+		for i := range du.releases {
+			if du.releases[i].Status.Phase == v1alpha1.PhaseDeployed {
+				du.notify(notification.EventDeployed, &du.releases[i], nil, fmt.Sprintf("Deckhouse Release %s is deployed", du.releases[i].Version.Original()))
+			}
+		}
+	}
 }
 
 func (du *DeckhouseUpdater) checkReleaseRequirements(rl *DeckhouseRelease) bool {
@@ -671,7 +682,10 @@ func (du *DeckhouseUpdater) ChangeUpdatingFlag(fl bool) {
 		return
 	}
 
+	// the Deckhouse pod is ready after the deployment of the new release
+	du.updateFinished = !fl
 	du.releaseData.IsUpdating = fl
+	du.releaseData.FailureNotified = false
 	du.createReleaseDataCM()
 }
 
@@ -702,6 +716,10 @@ func (du *DeckhouseUpdater) createReleaseDataCM() {
 			"isUpdating": strconv.FormatBool(du.releaseData.IsUpdating),
 			// notification about next release is sent, flag will be reset when new release is deployed
 			"notified": strconv.FormatBool(du.releaseData.Notified),
+			// notification about the failed deployment of the current release is sent
+			"failureNotified": strconv.FormatBool(du.releaseData.FailureNotified),
+			// undelivered notifications, they are retried in the next runs
+			"notificationQueue": du.notifications.Marshal(),
 		},
 	}
 
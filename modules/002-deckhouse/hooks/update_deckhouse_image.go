@@ -35,6 +35,7 @@ import (
 	"github.com/deckhouse/deckhouse/go_lib/dependency"
 	"github.com/deckhouse/deckhouse/go_lib/dependency/cr"
 	"github.com/deckhouse/deckhouse/go_lib/hooks/update"
+	"github.com/deckhouse/deckhouse/go_lib/hooks/update/notification"
 	"github.com/deckhouse/deckhouse/modules/002-deckhouse/hooks/internal/apis/v1alpha1"
 	"github.com/deckhouse/deckhouse/modules/002-deckhouse/hooks/internal/updater"
 )
@@ -111,6 +112,8 @@ type deckhousePodInfo struct {
 	Image     string `json:"image"`
 	ImageID   string `json:"imageID"`
 	Ready     bool   `json:"ready"`
+	// Crash describes the last abnormal termination of the container
+	Crash string `json:"crash,omitempty"`
 }
 
 const (
@@ -143,10 +146,17 @@ func updateDeckhouse(input *go_hook.HookInput, dc dependency.Container) error {
 	approvalMode := input.Values.Get("deckhouse.update.mode").String()
 	// if values key does not exist, then cluster is just bootstrapping
 	clusterBootstrapping := !input.Values.Exists("global.clusterIsBootstrapped")
-	deckhouseUpdater, err := updater.NewDeckhouseUpdater(input, approvalMode, releaseData, deckhousePod.Ready, clusterBootstrapping)
+	var getSecret notification.SecretGetter
+	if client, err := dc.GetK8sClient(); err == nil {
+		getSecret = notification.KubernetesSecrets(client)
+	} else {
+		input.LogEntry.Errorf("Kubernetes client init failed, notification receivers secrets are not available: %s", err)
+	}
+	deckhouseUpdater, err := updater.NewDeckhouseUpdater(input, approvalMode, releaseData, deckhousePod.Ready, clusterBootstrapping, getSecret)
 	if err != nil {
 		return fmt.Errorf("initializing deckhouse updater: %v", err)
 	}
+	defer deckhouseUpdater.FlushNotifications()
 
 	if deckhousePod.Ready {
 		input.MetricsCollector.Expire(metricUpdatingGroup)
@@ -166,6 +176,8 @@ func updateDeckhouse(input *go_hook.HookInput, dc dependency.Container) error {
 		return nil
 	}
 
+	deckhouseUpdater.NotifyDeployFailed(deckhousePod.Crash)
+
 	// predict next patch for Deploy
 	deckhouseUpdater.PredictNextRelease()
 
@@ -281,9 +293,10 @@ func filterDeckhouseRelease(unstructured *unstructured.Unstructured) (go_hook.Fi
 		ChangelogLink: release.Spec.ChangelogLink,
 		Disruptions:   release.Spec.Disruptions,
 		Status: v1alpha1.DeckhouseReleaseStatus{
-			Phase:    release.Status.Phase,
-			Approved: release.Status.Approved,
-			Message:  release.Status.Message,
+			Phase:          release.Status.Phase,
+			Approved:       release.Status.Approved,
+			TransitionTime: release.Status.TransitionTime,
+			Message:        release.Status.Message,
 		},
 		ManuallyApproved: releaseApproved,
 		AnnotationFlags:  annotationFlags,
@@ -298,7 +311,7 @@ func filterReleaseDataCM(unstructured *unstructured.Unstructured) (go_hook.Filte
 		return nil, err
 	}
 
-	var isUpdating, notified bool
+	var isUpdating, notified, failureNotified bool
 
 	if v, ok := cm.Data["isUpdating"]; ok {
 		if v == "true" {
@@ -312,9 +325,20 @@ func filterReleaseDataCM(unstructured *unstructured.Unstructured) (go_hook.Filte
 		}
 	}
 
+	if v, ok := cm.Data["failureNotified"]; ok {
+		if v == "true" {
+			failureNotified = true
+		}
+	}
+
+	// a broken queue is dropped, it must not block the update
+	queue, _ := notification.ParseItems(cm.Data["notificationQueue"])
+
 	return updater.DeckhouseReleaseData{
-		IsUpdating: isUpdating,
-		Notified:   notified,
+		IsUpdating:        isUpdating,
+		Notified:          notified,
+		FailureNotified:   failureNotified,
+		NotificationQueue: queue,
 	}, nil
 }
 
@@ -336,11 +360,18 @@ func filterDeckhousePod(unstructured *unstructured.Unstructured) (go_hook.Filter
 		imageName = pod.Spec.Containers[0].Image
 	}
 
-	var ready bool
+	var (
+		ready bool
+		crash string
+	)
 
 	if len(pod.Status.ContainerStatuses) > 0 {
 		imageID = pod.Status.ContainerStatuses[0].ImageID
 		ready = pod.Status.ContainerStatuses[0].Ready
+		// Deckhouse restarts itself with the zero exit code, other codes mean it crashed
+		if terminated := pod.Status.ContainerStatuses[0].LastTerminationState.Terminated; terminated != nil && terminated.ExitCode != 0 {
+			crash = fmt.Sprintf("the Deckhouse container terminated with the exit code %d (%s)", terminated.ExitCode, terminated.Reason)
+		}
 	}
 
 	return deckhousePodInfo{
@@ -349,6 +380,7 @@ func filterDeckhousePod(unstructured *unstructured.Unstructured) (go_hook.Filter
 		Name:      pod.Name,
 		Namespace: pod.Namespace,
 		Ready:     ready,
+		Crash:     crash,
 	}, nil
 }
 
@@ -379,7 +411,13 @@ func tagUpdate(input *go_hook.HookInput, dc dependency.Container, deckhousePod *
 
 	dockerCfg := input.Values.Get("global.modulesImages.registry.dockercfg").String()
 
-	regClient, err := dc.GetRegistryClient(repo, cr.WithCA(getCA(input)), cr.WithInsecureSchema(isHTTP(input)), cr.WithAuth(dockerCfg))
+	opts := []cr.Option{cr.WithCA(getCA(input)), cr.WithInsecureSchema(isHTTP(input)), cr.WithAuth(dockerCfg)}
+	// the image of a dev build can be pulled from another registry
+	if base := input.Values.Get("global.modulesImages.registry.base").String(); strings.HasPrefix(repo, base) {
+		opts = append(opts, cr.WithMirrors(base, getMirrors(input)...))
+	}
+
+	regClient, err := dc.GetRegistryClient(repo, opts...)
 	if err != nil {
 		input.LogEntry.Errorf("Registry (%s) client init failed: %s", repo, err)
 		return nil
@@ -17,19 +17,25 @@ limitations under the License.
 package hooks
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
 
 	"github.com/deckhouse/deckhouse/go_lib/dependency"
 	"github.com/deckhouse/deckhouse/go_lib/dependency/cr"
 	"github.com/deckhouse/deckhouse/go_lib/dependency/requirements"
+	"github.com/deckhouse/deckhouse/go_lib/hooks/update/notification"
 	"github.com/deckhouse/deckhouse/modules/002-deckhouse/hooks/internal/updater"
 	. "github.com/deckhouse/deckhouse/testing/hooks"
 )
@@ -823,6 +829,230 @@ metadata:
 		})
 	})
 
+	Context("Notification: receivers", func() {
+		var (
+			mu         sync.Mutex
+			events     []string
+			signatures []string
+		)
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, r.Header.Get(notification.HeaderEvent))
+			if r.Header.Get(notification.HeaderSignature) == notification.Sign("secret", r.Header.Get(notification.HeaderTimestamp), body) {
+				signatures = append(signatures, "valid")
+			}
+		}))
+		AfterEach(func() {
+			defer svr.Close()
+		})
+
+		BeforeEach(func() {
+			events, signatures = nil, nil
+			f.ValuesSetFromYaml("deckhouse.update.notification.receivers", []byte(fmt.Sprintf(`[{"name": "all", "webhook": %q, "secretRef": {"name": "notification-all"}}]`, svr.URL)))
+			f.KubeStateSet(deckhousePodYaml + deckhouseReleases)
+			// the hook reads the Secret with the static client
+			secrets := f.BindingContextController.FakeCluster().Client.CoreV1().Secrets(notification.SecretNamespace)
+			_ = secrets.Delete(context.Background(), "notification-all", metav1.DeleteOptions{})
+			_, err := secrets.Create(context.Background(), &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "notification-all", Namespace: notification.SecretNamespace},
+				Data:       map[string][]byte{notification.SecretKeySigningKey: []byte("secret")},
+			}, metav1.CreateOptions{})
+			Expect(err).ShouldNot(HaveOccurred())
+			f.BindingContexts.Set(f.GenerateScheduleContext("*/15 * * * * *"))
+
+			f.RunHook()
+		})
+
+		It("Should send signed notifications about the release lifecycle", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			r126 := f.KubernetesGlobalResource("DeckhouseRelease", "v1.26.0")
+			Expect(r126.Field("status.phase").String()).To(Equal("Deployed"))
+			// notifications are sent concurrently
+			Expect(events).To(ConsistOf("Available", "Scheduled", "Started"))
+			Expect(signatures).To(HaveLen(3))
+			cm := f.KubernetesResource("ConfigMap", "d8-system", "d8-release-data")
+			Expect(cm.Field("data.notificationQueue").String()).To(Equal("[]"))
+		})
+	})
+
+	Context("Notification: receiver is unavailable", func() {
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		AfterEach(func() {
+			defer svr.Close()
+		})
+
+		BeforeEach(func() {
+			f.ValuesSetFromYaml("deckhouse.update.notification.receivers", []byte(fmt.Sprintf(`[{"name": "down", "webhook": %q, "events": ["Scheduled"]}]`, svr.URL)))
+			f.KubeStateSet(deckhousePodYaml + deckhouseReleases)
+			f.BindingContexts.Set(f.GenerateScheduleContext("*/15 * * * * *"))
+
+			f.RunHook()
+		})
+
+		It("Should keep the notification in the queue and postpone the release", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			r126 := f.KubernetesGlobalResource("DeckhouseRelease", "v1.26.0")
+			Expect(r126.Field("status.phase").String()).To(Equal("Pending"))
+			cm := f.KubernetesResource("ConfigMap", "d8-system", "d8-release-data")
+			Expect(cm.Field("data.notified").Bool()).To(BeTrue())
+			items, err := notification.ParseItems(cm.Field("data.notificationQueue").String())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(items).To(HaveLen(1))
+			Expect(items[0].Receiver).To(Equal("down"))
+			Expect(items[0].Message.Event).To(Equal(notification.EventScheduled))
+			Expect(items[0].Attempts).To(Equal(1))
+			Expect(items[0].LastError).To(Equal("unexpected response status: 503 Service Unavailable"))
+		})
+	})
+
+	Context("Notification: release is deployed", func() {
+		var httpBody string
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			httpBody = string(data)
+		}))
+		AfterEach(func() {
+			defer svr.Close()
+		})
+
+		BeforeEach(func() {
+			f.ValuesSetFromYaml("deckhouse.update.notification.receivers", []byte(fmt.Sprintf(`[{"name": "deployed", "webhook": %q, "events": ["Deployed"]}]`, svr.URL)))
+			f.KubeStateSet(deckhousePodYaml + `
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: DeckhouseRelease
+metadata:
+  name: v1.26.0
+spec:
+  version: "v1.26.0"
+status:
+  phase: Deployed
+---
+apiVersion: v1
+data:
+  isUpdating: "true"
+  notified: "false"
+kind: ConfigMap
+metadata:
+  name: d8-release-data
+  namespace: d8-system
+`)
+			f.BindingContexts.Set(f.GenerateScheduleContext("*/15 * * * * *"))
+			f.RunHook()
+		})
+
+		It("Should send the Deployed notification", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(httpBody).To(ContainSubstring(`"event":"Deployed"`))
+			Expect(httpBody).To(ContainSubstring(`"release":"v1.26.0"`))
+			cm := f.KubernetesResource("ConfigMap", "d8-system", "d8-release-data")
+			Expect(cm.Field("data.isUpdating").Bool()).To(BeFalse())
+		})
+	})
+
+	Context("Notification: Scheduled notification is not delivered for a long time", func() {
+		BeforeEach(func() {
+			f.ValuesSetFromYaml("deckhouse.update.notification.receivers", []byte(`[{"name": "down", "webhook": "https://down.example.com", "events": ["Scheduled"]}]`))
+			f.KubeStateSet(deckhousePodYaml + deckhouseReleases + `
+---
+apiVersion: v1
+data:
+  isUpdating: "false"
+  notified: "true"
+  notificationQueue: '[{"id":"scheduled","receiver":"down","message":{"event":"Scheduled","kind":"DeckhouseRelease","release":"v1.26.0","timestamp":"2021-01-01T13:00:00Z","version":"1.26","changelogLink":"","message":""},"attempts":4,"nextAttempt":"2021-01-01T14:00:00Z"}]'
+kind: ConfigMap
+metadata:
+  name: d8-release-data
+  namespace: d8-system
+`)
+			f.BindingContexts.Set(f.GenerateScheduleContext("*/15 * * * * *"))
+			f.RunHook()
+		})
+
+		It("Should deploy the release and keep retrying the notification", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			r126 := f.KubernetesGlobalResource("DeckhouseRelease", "v1.26.0")
+			Expect(r126.Field("status.phase").String()).To(Equal("Deployed"))
+			cm := f.KubernetesResource("ConfigMap", "d8-system", "d8-release-data")
+			items, err := notification.ParseItems(cm.Field("data.notificationQueue").String())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(items).To(HaveLen(1))
+			Expect(items[0].ID).To(Equal("scheduled"))
+		})
+	})
+
+	Context("Notification: Deckhouse pod crashed after the release deployment", func() {
+		const crashedState = `      lastState:
+        terminated:
+          exitCode: 2
+          reason: Error
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: DeckhouseRelease
+metadata:
+  name: v1.26.0
+spec:
+  version: "v1.26.0"
+status:
+  phase: Deployed
+---
+apiVersion: v1
+data:
+  isUpdating: "true"
+  notified: "false"
+  failureNotified: "%s"
+kind: ConfigMap
+metadata:
+  name: d8-release-data
+  namespace: d8-system
+`
+
+		var httpBody string
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			httpBody = string(data)
+		}))
+		AfterEach(func() {
+			defer svr.Close()
+		})
+
+		BeforeEach(func() {
+			httpBody = ""
+			f.ValuesSetFromYaml("deckhouse.update.notification.receivers", []byte(fmt.Sprintf(`[{"name": "failed", "webhook": %q, "events": ["Failed"]}]`, svr.URL)))
+			f.KubeStateSet(deckhouseDeployment + deckhouseNotReadyPod + fmt.Sprintf(crashedState, "false"))
+			f.BindingContexts.Set(f.GenerateScheduleContext("*/15 * * * * *"))
+			f.RunHook()
+		})
+
+		It("Should send the Failed notification", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(httpBody).To(ContainSubstring(`"event":"Failed"`))
+			Expect(httpBody).To(ContainSubstring(`"release":"v1.26.0"`))
+			Expect(httpBody).To(ContainSubstring(`the Deckhouse container terminated with the exit code 2 (Error)`))
+			cm := f.KubernetesResource("ConfigMap", "d8-system", "d8-release-data")
+			Expect(cm.Field("data.isUpdating").Bool()).To(BeTrue())
+			Expect(cm.Field("data.failureNotified").Bool()).To(BeTrue())
+		})
+
+		Context("Failed notification is already sent", func() {
+			BeforeEach(func() {
+				httpBody = ""
+				f.KubeStateSet(deckhouseDeployment + deckhouseNotReadyPod + fmt.Sprintf(crashedState, "true"))
+				f.BindingContexts.Set(f.GenerateScheduleContext("*/15 * * * * *"))
+				f.RunHook()
+			})
+
+			It("Should not send it again", func() {
+				Expect(f).To(ExecuteSuccessfully())
+				Expect(httpBody).To(BeEmpty())
+			})
+		})
+	})
+
 	Context("release with apply-now annotation out of window", func() {
 		BeforeEach(func() {
 			f.ValuesSetFromYaml("deckhouse.update.windows", []byte(`[{"from": "8:00", "to": "10:00"}]`))
@@ -71,6 +71,7 @@ func cleanupReleases(input *go_hook.HookInput) error {
 
 	moduleReleases := make(map[string][]deprecatedRelease, 0)
 	outdatedModuleReleases := make(map[string][]deprecatedRelease, 0)
+	deployedVersions := make(map[string]*semver.Version)
 
 	// TODO(nabokihms): Instead of subscribing to Kubernetes objects,
 	//   make it available through global values like `enabledModules`
@@ -85,6 +86,9 @@ func cleanupReleases(input *go_hook.HookInput) error {
 		if rel.Phase == v1alpha1.PhaseSuperseded || rel.Phase == v1alpha1.PhaseSuspended {
 			outdatedModuleReleases[rel.Module] = append(outdatedModuleReleases[rel.Module], rel)
 		}
+		if rel.Phase == v1alpha1.PhaseDeployed {
+			deployedVersions[rel.Module] = rel.Version
+		}
 	}
 
 	// for absent modules - delete all ModuleRelease resources
@@ -100,11 +104,25 @@ func cleanupReleases(input *go_hook.HookInput) error {
 	}
 
 	// delete outdated release, keep only last 3
-	for _, releases := range outdatedModuleReleases {
+	for moduleName, releases := range outdatedModuleReleases {
 		sort.Sort(sort.Reverse(byVersion[deprecatedRelease](releases)))
 
+		// the previous deployed release is kept for the rollback of the failed deployment
+		rollbackIndex := -1
+		if deployed, ok := deployedVersions[moduleName]; ok {
+			for i, release := range releases {
+				if release.Phase == v1alpha1.PhaseSuperseded && release.Version.LessThan(deployed) {
+					rollbackIndex = i
+					break
+				}
+			}
+		}
+
 		if len(releases) > keepReleaseCount {
 			for i := keepReleaseCount; i < len(releases); i++ {
+				if i == rollbackIndex {
+					continue
+				}
 				input.LogEntry.Infof("Cleanup release %q because it's outdated", releases[i].Name)
 				deleteModuleRelease(input, externalModulesDir, releases[i])
 			}
@@ -105,6 +105,43 @@ status:
 		})
 	})
 
+	Context("Cluster has suspended releases newer than the deployed one", func() {
+		BeforeEach(func() {
+			state := generateOutdated("echoserver", "v0.0.1")
+			for i := 3; i < 7; i++ {
+				state += "\n" + generateSuspended("echoserver", "v0.0."+strconv.Itoa(i))
+			}
+
+			f.KubeStateSet(state + `
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: Module
+metadata:
+  name: echoserver
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: ModuleRelease
+metadata:
+  name: echoserver-v0.0.2
+spec:
+  moduleName: echoserver
+  version: 0.0.2
+status:
+  phase: Deployed
+`)
+
+			f.BindingContexts.Set(f.GenerateScheduleContext("13 3 * * *"))
+			f.RunHook()
+		})
+
+		It("Should keep the previous release for the rollback", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.KubernetesGlobalResource("ModuleRelease", "echoserver-v0.0.3").Exists()).To(BeFalse())
+			Expect(f.KubernetesGlobalResource("ModuleRelease", "echoserver-v0.0.4").Exists()).To(BeTrue())
+			Expect(f.KubernetesGlobalResource("ModuleRelease", "echoserver-v0.0.1").Exists()).To(BeTrue())
+		})
+	})
+
 	Context("Cluster has releases from absent module", func() {
 		BeforeEach(func() {
 			f.KubeStateSet(`
@@ -166,3 +203,20 @@ status:
 func generateOutdated(moduleName, moduleVersion string) string {
 	return fmt.Sprintf(outdatedTemplate, moduleName, moduleVersion)
 }
+
+const suspendedTemplate = `
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: ModuleRelease
+metadata:
+  name: %[1]s-%[2]s
+spec:
+  moduleName: %[1]s
+  version: %[2]s
+status:
+  phase: Suspended
+`
+
+func generateSuspended(moduleName, moduleVersion string) string {
+	return fmt.Sprintf(suspendedTemplate, moduleName, moduleVersion)
+}
@@ -59,6 +59,22 @@ func filterSource(obj *unstructured.Unstructured) (go_hook.FilterResult, error)
 	return newms, nil
 }
 
+// filterPolicyUpdate keeps the update settings of the policy which are not taken from the Deckhouse update settings
+func filterPolicyUpdate(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	var mup v1alpha1.ModuleUpdatePolicy
+
+	err := sdk.FromUnstructured(obj, &mup)
+	if err != nil {
+		return nil, err
+	}
+
+	return v1alpha1.ModuleUpdatePolicySpecUpdate{
+		Canary:   mup.Spec.Update.Canary,
+		SoakTime: mup.Spec.Update.SoakTime,
+		Rollback: mup.Spec.Update.Rollback,
+	}, nil
+}
+
 type deckhouseDiscoveryData struct {
 	ReleaseChannel string
 	UpdateSettings *v1alpha1.ModuleUpdatePolicySpecUpdate
@@ -111,6 +127,17 @@ var _ = sdk.RegisterFunc(&go_hook.HookConfig{
 			},
 			FilterFunc: filterSource,
 		},
+		{
+			Name:                         "policies",
+			ApiVersion:                   "deckhouse.io/v1alpha1",
+			Kind:                         "ModuleUpdatePolicy",
+			ExecuteHookOnEvents:          pointer.Bool(false),
+			ExecuteHookOnSynchronization: pointer.Bool(false),
+			NameSelector: &types.NameSelector{
+				MatchNames: []string{"deckhouse"},
+			},
+			FilterFunc: filterPolicyUpdate,
+		},
 		{
 			Name:       "deckhouse-secret",
 			ApiVersion: "v1",
@@ -149,6 +176,14 @@ func createDeckhouseModuleSourceAndPolicy(input *go_hook.HookInput) error {
 		}
 	}
 
+	// the Deckhouse update settings have no canary, soak time and rollback, keep the ones set by the user
+	if len(input.Snapshots["policies"]) > 0 {
+		policyUpdate := input.Snapshots["policies"][0].(v1alpha1.ModuleUpdatePolicySpecUpdate)
+		us.Canary = policyUpdate.Canary
+		us.SoakTime = policyUpdate.SoakTime
+		us.Rollback = policyUpdate.Rollback
+	}
+
 	// get scheme from values
 	scheme := strings.ToUpper(input.Values.Get("global.modulesImages.registry.scheme").String())
 	switch scheme {
@@ -15,13 +15,65 @@
 package hooks
 
 import (
+	"encoding/json"
+	"os"
+
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/validate"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
 
 	. "github.com/deckhouse/deckhouse/testing/hooks"
 )
 
+// validateModuleUpdatePolicy validates the object against the schema of the ModuleUpdatePolicy CRD
+func validateModuleUpdatePolicy(obj map[string]interface{}) error {
+	content, err := os.ReadFile("/deckhouse/deckhouse-controller/crds/module-update-policy.yaml")
+	if err != nil {
+		return err
+	}
+
+	var crd struct {
+		Spec struct {
+			Versions []struct {
+				Schema struct {
+					OpenAPIV3Schema json.RawMessage `json:"openAPIV3Schema"`
+				} `json:"schema"`
+			} `json:"versions"`
+		} `json:"spec"`
+	}
+	if err = yaml.Unmarshal(content, &crd); err != nil {
+		return err
+	}
+
+	var sc spec.Schema
+	if err = json.Unmarshal(crd.Spec.Versions[0].Schema.OpenAPIV3Schema, &sc); err != nil {
+		return err
+	}
+
+	return validate.AgainstSchema(&sc, dropNulls(obj), strfmt.Default)
+}
+
+// dropNulls removes the null values like the API server does for the fields which are not nullable
+func dropNulls(obj map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		switch value := v.(type) {
+		case nil:
+			continue
+		case map[string]interface{}:
+			res[k] = dropNulls(value)
+		default:
+			res[k] = value
+		}
+	}
+
+	return res
+}
+
 var _ = Describe("Modules :: external module manager :: hooks :: create deckhouse module source ::", func() {
 	initValues := `
 global:
@@ -394,6 +446,62 @@ data:
 			Expect(mup.Field("spec.releaseChannel").String()).To(Equal("Alpha"))
 			Expect(mup.Field("spec.update.mode").String()).To(Equal("Auto"))
 			Expect(mup.Field("spec.update.windows").String()).To(Equal(""))
+			// the CRD defaults are applied to the unset fields
+			Expect(mup.Field("spec.update.canary").Exists()).To(BeFalse())
+			Expect(mup.Field("spec.update.soakTime").Exists()).To(BeFalse())
+			Expect(mup.Field("spec.update.rollback").Exists()).To(BeFalse())
+			Expect(validateModuleUpdatePolicy(mup)).To(Succeed())
+		})
+	})
+
+	Context("With existing MUP with canary, soak time and rollback", func() {
+		existingResources := `
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: deckhouse-discovery
+  namespace: d8-system
+data:
+  releaseChannel: QWxwaGE= # Alpha
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: ModuleUpdatePolicy
+metadata:
+  name: deckhouse
+spec:
+  releaseChannel: Stable
+  moduleReleaseSelector:
+    labelSelector:
+      matchLabels:
+        source: deckhouse
+  update:
+    mode: Manual
+    canary:
+      enabled: true
+      waves: 3
+      interval: 2h
+    soakTime: 1h
+    rollback:
+      enabled: true
+      gracePeriod: 30m
+`
+
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(existingResources))
+			f.RunHook()
+		})
+
+		It("Should keep the canary, soak time and rollback settings", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			mup := f.KubernetesGlobalResource("ModuleUpdatePolicy", "deckhouse")
+			Expect(mup.Field("spec.releaseChannel").String()).To(Equal("Alpha"))
+			Expect(mup.Field("spec.update.mode").String()).To(Equal("Auto"))
+			Expect(mup.Field("spec.update.canary").String()).To(MatchJSON(`{"enabled":true,"waves":3,"interval":"2h0m0s"}`))
+			Expect(mup.Field("spec.update.soakTime").String()).To(Equal("1h0m0s"))
+			Expect(mup.Field("spec.update.rollback").String()).To(MatchJSON(`{"enabled":true,"gracePeriod":"30m0s"}`))
+			Expect(validateModuleUpdatePolicy(mup)).To(Succeed())
 		})
 	})
 })
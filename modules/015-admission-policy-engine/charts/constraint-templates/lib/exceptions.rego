@@ -0,0 +1,79 @@
+package lib.exceptions
+
+# The reviewed object is exempted from the constraint if it matches one of the PolicyException targets,
+# it is used by the constraints verifying a single check of the policy
+exempted {
+  exception := input.parameters.exceptions[_]
+  target_matches(exception)
+}
+
+# The reviewed object is exempted from the check of the policy if it matches one of the PolicyException targets waiving the check,
+# it is used by the constraints verifying several checks of the policy
+exempted_from(check) {
+  exception := input.parameters.exceptions[_]
+  exception.checks[_] == check
+  target_matches(exception)
+}
+
+target_matches(exception) {
+  exception.namespace == object_namespace
+  exception.kind == input.review.kind.kind
+  name_matches(exception)
+  labels_match(exception)
+}
+
+object_namespace = namespace {
+  namespace := input.review.object.metadata.namespace
+} else = namespace {
+  namespace := input.review.namespace
+}
+
+name_matches(exception) {
+  not exception.name
+}
+
+name_matches(exception) {
+  exception.name == input.review.object.metadata.name
+}
+
+labels_match(exception) {
+  not exception.labelSelector
+}
+
+labels_match(exception) {
+  labels := object.get(input.review.object.metadata, "labels", {})
+  mismatched_labels := [key | value := exception.labelSelector.matchLabels[key]; not labels[key] == value]
+  count(mismatched_labels) == 0
+  mismatched_expressions := [e | e := exception.labelSelector.matchExpressions[_]; not expression_matches(e, labels)]
+  count(mismatched_expressions) == 0
+}
+
+expression_matches(e, labels) {
+  e.operator == "In"
+  labels[e.key] == e.values[_]
+}
+
+expression_matches(e, labels) {
+  e.operator == "NotIn"
+  not labels[e.key]
+}
+
+expression_matches(e, labels) {
+  e.operator == "NotIn"
+  value := labels[e.key]
+  not value_in(value, e.values)
+}
+
+expression_matches(e, labels) {
+  e.operator == "Exists"
+  labels[e.key]
+}
+
+expression_matches(e, labels) {
+  e.operator == "DoesNotExist"
+  not labels[e.key]
+}
+
+value_in(value, values) {
+  value == values[_]
+}
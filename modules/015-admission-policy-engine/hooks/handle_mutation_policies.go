@@ -0,0 +1,232 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/clarketm/json"
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
+	"github.com/flant/addon-operator/sdk"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	v1alpha1 "github.com/deckhouse/deckhouse/modules/015-admission-policy-engine/hooks/internal/apis"
+)
+
+const mutationPolicyDryRunMode = "DryRun"
+
+// maxMutatorNameLength keeps the mutator names valid DNS labels
+const maxMutatorNameLength = 63
+
+var (
+	// plainPathKey is the key that can be used in the gatekeeper mutation location without quotes
+	plainPathKey = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+	// invalidNameChars are replaced in the mutator names, e.g., the "/" of the "nvidia.com/gpu" resource
+	invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+)
+
+var _ = sdk.RegisterFunc(&go_hook.HookConfig{
+	Queue: "/modules/admission-policy-engine/mutation_policies",
+	Kubernetes: []go_hook.KubernetesConfig{
+		{
+			Name:       "mutation-policies",
+			ApiVersion: "deckhouse.io/v1alpha1",
+			Kind:       "MutationPolicy",
+			FilterFunc: filterMP,
+		},
+	},
+}, handleMP)
+
+// handleMP converts the MutationPolicies to the gatekeeper mutators.
+// The policies in the DryRun mode get no mutators, they are rendered as the D8MutationDryRun constraints.
+func handleMP(input *go_hook.HookInput) error {
+	result := make([]*mutationPolicy, 0)
+
+	snap := input.Snapshots["mutation-policies"]
+
+	for _, sn := range snap {
+		mp := sn.(*mutationPolicy)
+		mp.Mutators = make([]mutator, 0)
+		if mp.Spec.Mode != mutationPolicyDryRunMode {
+			mp.Mutators = buildMutators(mp.Metadata.Name, &mp.Spec)
+		}
+		result = append(result, mp)
+	}
+
+	data, _ := json.Marshal(result)
+
+	input.Values.Set("admissionPolicyEngine.internal.mutationPolicies", json.RawMessage(data))
+
+	return nil
+}
+
+func buildMutators(name string, spec *v1alpha1.MutationPolicySpec) []mutator {
+	mutators := make([]mutator, 0)
+	mutations := &spec.Mutations
+
+	// the init containers get the same defaults as the containers
+	containerFields := []struct {
+		field  string
+		prefix string
+	}{
+		{field: "containers", prefix: ""},
+		{field: "initContainers", prefix: "init-"},
+	}
+
+	for _, c := range containerFields {
+		containers := fmt.Sprintf("spec.%s[name:*]", c.field)
+
+		if mutations.ImagePullPolicy != "" {
+			mutators = append(mutators, mutator{
+				Kind:     "Assign",
+				Name:     mutatorName(name, c.prefix+"image-pull-policy", containers+".imagePullPolicy"),
+				Location: containers + ".imagePullPolicy",
+				Parameters: map[string]interface{}{
+					"assign": map[string]interface{}{"value": mutations.ImagePullPolicy},
+				},
+			})
+		}
+
+		for _, res := range []struct {
+			kind   string
+			values map[string]string
+		}{
+			{kind: "requests", values: mutations.Resources.Requests},
+			{kind: "limits", values: mutations.Resources.Limits},
+		} {
+			for _, resource := range sortedKeys(res.values) {
+				location := fmt.Sprintf("%s.resources.%s.%s", containers, res.kind, pathKey(resource))
+				mutators = append(mutators, mutator{
+					Kind:     "Assign",
+					Name:     mutatorName(name, c.prefix+res.kind+"-"+resource, location),
+					Location: location,
+					Parameters: map[string]interface{}{
+						"pathTests": []interface{}{
+							map[string]interface{}{"subPath": location, "condition": "MustNotExist"},
+						},
+						"assign": map[string]interface{}{"value": res.values[resource]},
+					},
+				})
+			}
+		}
+
+		if len(mutations.DropCapabilities) > 0 {
+			mutators = append(mutators, mutator{
+				Kind:     "ModifySet",
+				Name:     mutatorName(name, c.prefix+"drop-capabilities", containers+".securityContext.capabilities.drop"),
+				Location: containers + ".securityContext.capabilities.drop",
+				Parameters: map[string]interface{}{
+					"operation": "merge",
+					"values":    map[string]interface{}{"fromList": mutations.DropCapabilities},
+				},
+			})
+		}
+	}
+
+	if mutations.SeccompProfile != nil {
+		location := "spec.securityContext.seccompProfile"
+		mutators = append(mutators, mutator{
+			Kind:     "Assign",
+			Name:     mutatorName(name, "seccomp-profile", location),
+			Location: location,
+			Parameters: map[string]interface{}{
+				"pathTests": []interface{}{
+					map[string]interface{}{"subPath": location, "condition": "MustNotExist"},
+				},
+				"assign": map[string]interface{}{"value": mutations.SeccompProfile},
+			},
+		})
+	}
+
+	// AssignMetadata never changes the existing labels
+	for _, key := range sortedKeys(mutations.Labels) {
+		location := "metadata.labels." + pathKey(key)
+		mutators = append(mutators, mutator{
+			Kind:     "AssignMetadata",
+			Name:     mutatorName(name, "label", location),
+			Location: location,
+			Parameters: map[string]interface{}{
+				"assign": map[string]interface{}{"value": mutations.Labels[key]},
+			},
+		})
+	}
+
+	return mutators
+}
+
+// mutatorName returns the name of the policy mutation with the hash of the policy name and the mutation location.
+// The names without the hash collide, e.g., the "init-requests-cpu" mutation of the "defaults" policy
+// and the "requests-cpu" mutation of the "defaults-init" policy.
+func mutatorName(policy, mutation, location string) string {
+	sum := sha256.Sum256([]byte(policy + "\n" + location))
+	suffix := "-" + hex.EncodeToString(sum[:4])
+
+	name := invalidNameChars.ReplaceAllString(strings.ToLower(policy+"-"+mutation), "-")
+	if len(name) > maxMutatorNameLength-len(suffix) {
+		name = name[:maxMutatorNameLength-len(suffix)]
+	}
+
+	return strings.Trim(name, "-") + suffix
+}
+
+func pathKey(key string) string {
+	if plainPathKey.MatchString(key) {
+		return key
+	}
+	return fmt.Sprintf("%q", key)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func filterMP(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	var mp mutationPolicy
+
+	err := sdk.FromUnstructured(obj, &mp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mp, nil
+}
+
+type mutationPolicy struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec v1alpha1.MutationPolicySpec `json:"spec"`
+	// Mutators are rendered as the gatekeeper mutation objects
+	Mutators []mutator `json:"mutators"`
+}
+
+type mutator struct {
+	Kind       string                 `json:"kind"`
+	Name       string                 `json:"name"`
+	Location   string                 `json:"location"`
+	Parameters map[string]interface{} `json:"parameters"`
+}
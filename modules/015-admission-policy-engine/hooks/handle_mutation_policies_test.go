@@ -0,0 +1,144 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	v1alpha1 "github.com/deckhouse/deckhouse/modules/015-admission-policy-engine/hooks/internal/apis"
+	. "github.com/deckhouse/deckhouse/testing/hooks"
+)
+
+var _ = Describe("Modules :: admission-policy-engine :: hooks :: handle mutation policies", func() {
+	f := HookExecutionConfigInit(
+		`{"admissionPolicyEngine": {"internal": {"bootstrapped": true} } }`,
+		`{"admissionPolicyEngine":{}}`,
+	)
+	f.RegisterCRD("deckhouse.io", "v1alpha1", "MutationPolicy", false)
+
+	Context("Mutation policy is set", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(testMutationPolicy))
+			f.RunHook()
+		})
+		It("should generate the mutators", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.ValuesGet("admissionPolicyEngine.internal.mutationPolicies").Array()).To(HaveLen(1))
+			Expect(f.ValuesGet("admissionPolicyEngine.internal.mutationPolicies.0.mutators").String()).To(MatchJSON(`[
+				{"kind": "Assign", "name": "defaults-image-pull-policy-619458bd", "location": "spec.containers[name:*].imagePullPolicy",
+				 "parameters": {"assign": {"value": "Always"}}},
+				{"kind": "Assign", "name": "defaults-requests-cpu-338fdafd", "location": "spec.containers[name:*].resources.requests.cpu",
+				 "parameters": {"assign": {"value": "100m"}, "pathTests": [{"subPath": "spec.containers[name:*].resources.requests.cpu", "condition": "MustNotExist"}]}},
+				{"kind": "Assign", "name": "defaults-limits-ephemeral-storage-00fe403c", "location": "spec.containers[name:*].resources.limits.\"ephemeral-storage\"",
+				 "parameters": {"assign": {"value": "1Gi"}, "pathTests": [{"subPath": "spec.containers[name:*].resources.limits.\"ephemeral-storage\"", "condition": "MustNotExist"}]}},
+				{"kind": "ModifySet", "name": "defaults-drop-capabilities-4e9ca661", "location": "spec.containers[name:*].securityContext.capabilities.drop",
+				 "parameters": {"operation": "merge", "values": {"fromList": ["ALL"]}}},
+				{"kind": "Assign", "name": "defaults-init-image-pull-policy-655bd1eb", "location": "spec.initContainers[name:*].imagePullPolicy",
+				 "parameters": {"assign": {"value": "Always"}}},
+				{"kind": "Assign", "name": "defaults-init-requests-cpu-1be5159c", "location": "spec.initContainers[name:*].resources.requests.cpu",
+				 "parameters": {"assign": {"value": "100m"}, "pathTests": [{"subPath": "spec.initContainers[name:*].resources.requests.cpu", "condition": "MustNotExist"}]}},
+				{"kind": "Assign", "name": "defaults-init-limits-ephemeral-storage-a051c368", "location": "spec.initContainers[name:*].resources.limits.\"ephemeral-storage\"",
+				 "parameters": {"assign": {"value": "1Gi"}, "pathTests": [{"subPath": "spec.initContainers[name:*].resources.limits.\"ephemeral-storage\"", "condition": "MustNotExist"}]}},
+				{"kind": "ModifySet", "name": "defaults-init-drop-capabilities-907a8fd3", "location": "spec.initContainers[name:*].securityContext.capabilities.drop",
+				 "parameters": {"operation": "merge", "values": {"fromList": ["ALL"]}}},
+				{"kind": "Assign", "name": "defaults-seccomp-profile-6f92bf3e", "location": "spec.securityContext.seccompProfile",
+				 "parameters": {"assign": {"value": {"type": "RuntimeDefault"}}, "pathTests": [{"subPath": "spec.securityContext.seccompProfile", "condition": "MustNotExist"}]}},
+				{"kind": "AssignMetadata", "name": "defaults-label-df809498", "location": "metadata.labels.\"app.kubernetes.io/part-of\"",
+				 "parameters": {"assign": {"value": "shop"}}},
+				{"kind": "AssignMetadata", "name": "defaults-label-46c034c8", "location": "metadata.labels.team",
+				 "parameters": {"assign": {"value": "backend"}}}
+			]`))
+		})
+	})
+
+	Context("Mutation policy in the DryRun mode", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(testDryRunMutationPolicy))
+			f.RunHook()
+		})
+		It("should not generate the mutators", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.ValuesGet("admissionPolicyEngine.internal.mutationPolicies.0.spec.mode").String()).To(Equal("DryRun"))
+			Expect(f.ValuesGet("admissionPolicyEngine.internal.mutationPolicies.0.mutators").Array()).To(BeEmpty())
+		})
+	})
+
+	Context("Mutation names", func() {
+		It("should not collide and should be valid object names", func() {
+			spec := &v1alpha1.MutationPolicySpec{}
+			spec.Mutations.ImagePullPolicy = "Always"
+			spec.Mutations.Resources.Limits = map[string]string{"nvidia.com/gpu": "1", "hugepages-2Mi": "100Mi"}
+
+			names := make(map[string]struct{})
+			for _, policy := range []string{"defaults", "defaults-init", strings.Repeat("long-policy-name", 8)} {
+				for _, m := range buildMutators(policy, spec) {
+					Expect(m.Name).To(MatchRegexp(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`))
+					Expect(names).NotTo(HaveKey(m.Name))
+					names[m.Name] = struct{}{}
+				}
+			}
+			Expect(names).To(HaveLen(18))
+		})
+	})
+})
+
+var testMutationPolicy = `
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: MutationPolicy
+metadata:
+  name: defaults
+spec:
+  mode: Apply
+  mutations:
+    imagePullPolicy: Always
+    resources:
+      requests:
+        cpu: 100m
+      limits:
+        ephemeral-storage: 1Gi
+    seccompProfile:
+      type: RuntimeDefault
+    dropCapabilities:
+    - ALL
+    labels:
+      team: backend
+      app.kubernetes.io/part-of: shop
+  match:
+    namespaceSelector:
+      matchNames:
+        - default
+`
+
+var testDryRunMutationPolicy = `
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: MutationPolicy
+metadata:
+  name: defaults
+spec:
+  mode: DryRun
+  mutations:
+    imagePullPolicy: Always
+  match:
+    namespaceSelector:
+      matchNames:
+        - default
+`
@@ -26,7 +26,8 @@ import (
 )
 
 var _ = sdk.RegisterFunc(&go_hook.HookConfig{
-	Queue: "/modules/admission-policy-engine/operation_policies",
+	Queue:    "/modules/admission-policy-engine/operation_policies",
+	Schedule: []go_hook.ScheduleConfig{policyExceptionsSchedule},
 	Kubernetes: []go_hook.KubernetesConfig{
 		{
 			Name:       "operation-policies",
@@ -34,6 +35,7 @@ var _ = sdk.RegisterFunc(&go_hook.HookConfig{
 			Kind:       "OperationPolicy",
 			FilterFunc: filterOP,
 		},
+		policyExceptionsBinding,
 	},
 }, handleOP)
 
@@ -41,9 +43,11 @@ func handleOP(input *go_hook.HookInput) error {
 	result := make([]*operationPolicy, 0)
 
 	snap := input.Snapshots["operation-policies"]
+	exceptions := collectPolicyExceptions(input, operationPolicyKind, operationPolicyChecks)
 
 	for _, sn := range snap {
 		op := sn.(*operationPolicy)
+		op.Exceptions = exceptions[op.Metadata.Name]
 		result = append(result, op)
 	}
 
@@ -70,4 +74,6 @@ type operationPolicy struct {
 		Name string `json:"name"`
 	} `json:"metadata"`
 	Spec v1alpha1.OperationPolicySpec `json:"spec"`
+	// Exceptions are the targets of the policy exceptions by the constraint kind
+	Exceptions map[string][]exceptionTarget `json:"exceptions,omitempty"`
 }
@@ -30,6 +30,7 @@ var _ = Describe("Modules :: admission-policy-engine :: hooks :: handle operatio
 	)
 	f.RegisterCRD("templates.gatekeeper.sh", "v1", "ConstraintTemplate", false)
 	f.RegisterCRD("deckhouse.io", "v1alpha1", "OperationPolicy", false)
+	f.RegisterCRD("deckhouse.io", "v1alpha1", "PolicyException", true)
 
 	Context("Operation policy is set", func() {
 		BeforeEach(func() {
@@ -41,6 +42,21 @@ var _ = Describe("Modules :: admission-policy-engine :: hooks :: handle operatio
 			Expect(f.ValuesGet("admissionPolicyEngine.internal.operationPolicies").Array()).To(HaveLen(1))
 		})
 	})
+
+	Context("Operation policy with exception", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(testOperationPolicy + testOperationPolicyException))
+			f.RunHook()
+		})
+		It("should render the exception into the policy", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.ValuesGet("admissionPolicyEngine.internal.operationPolicies.0.exceptions").String()).To(MatchJSON(`{
+				"D8AllowedRepos": [
+					{"namespace": "legacy", "kind": "Pod", "name": "legacy-app", "checks": ["allowedRepos"]}
+				]
+			}`))
+		})
+	})
 })
 
 var testOperationPolicy = `
@@ -91,3 +107,22 @@ spec:
       matchNames:
         - default
 `
+
+var testOperationPolicyException = `
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: PolicyException
+metadata:
+  name: legacy-app
+  namespace: legacy
+spec:
+  policy:
+    kind: OperationPolicy
+    name: foo
+  checks:
+  - allowedRepos
+  targets:
+  - kind: Pod
+    name: legacy-app
+  expiresAt: "2150-10-10T10:10:10Z"
+`
@@ -28,7 +28,8 @@ import (
 )
 
 var _ = sdk.RegisterFunc(&go_hook.HookConfig{
-	Queue: "/modules/admission-policy-engine/security_policies",
+	Queue:    "/modules/admission-policy-engine/security_policies",
+	Schedule: []go_hook.ScheduleConfig{policyExceptionsSchedule},
 	Kubernetes: []go_hook.KubernetesConfig{
 		{
 			Name:       "security-policies",
@@ -36,6 +37,7 @@ var _ = sdk.RegisterFunc(&go_hook.HookConfig{
 			Kind:       "SecurityPolicy",
 			FilterFunc: filterSP,
 		},
+		policyExceptionsBinding,
 	},
 }, handleSP)
 
@@ -43,12 +45,14 @@ func handleSP(input *go_hook.HookInput) error {
 	result := make([]*securityPolicy, 0)
 
 	snap := input.Snapshots["security-policies"]
+	exceptions := collectPolicyExceptions(input, securityPolicyKind, securityPolicyChecks)
 
 	for _, sn := range snap {
 		sp := sn.(*securityPolicy)
 		// set observed status
 		input.PatchCollector.Filter(set_cr_statuses.SetObservedStatus(sn, filterSP), "deckhouse.io/v1alpha1", "securitypolicy", "", sp.Metadata.Name, object_patch.WithSubresource("/status"), object_patch.IgnoreHookError())
 		sp.preprocesSecurityPolicy()
+		sp.Exceptions = exceptions[sp.Metadata.Name]
 		result = append(result, sp)
 	}
 
@@ -130,4 +134,6 @@ type securityPolicy struct {
 		Name string `json:"name"`
 	} `json:"metadata"`
 	Spec v1alpha1.SecurityPolicySpec `json:"spec"`
+	// Exceptions are the targets of the policy exceptions by the constraint kind
+	Exceptions map[string][]exceptionTarget `json:"exceptions,omitempty"`
 }
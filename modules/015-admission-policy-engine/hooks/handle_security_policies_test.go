@@ -37,6 +37,7 @@ var _ = Describe("Modules :: admission-policy-engine :: hooks :: handle security
 	)
 	f.RegisterCRD("templates.gatekeeper.sh", "v1", "ConstraintTemplate", false)
 	f.RegisterCRD("deckhouse.io", "v1alpha1", "SecurityPolicy", false)
+	f.RegisterCRD("deckhouse.io", "v1alpha1", "PolicyException", true)
 
 	err := os.Setenv("TEST_CONDITIONS_CALC_NOW_TIME", nowTime)
 	if err != nil {
@@ -172,6 +173,45 @@ var _ = Describe("Modules :: admission-policy-engine :: hooks :: handle security
 			Expect(f.KubernetesGlobalResource("SecurityPolicy", "foo").Field("status").String()).To(MatchJSON(expectedStatus))
 		})
 	})
+
+	Context("Security Policy with exceptions", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(testSecurityPolicy + testSecurityPolicyExceptions))
+			f.RunHook()
+		})
+		It("should render active exceptions and report expired ones", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.ValuesGet("admissionPolicyEngine.internal.securityPolicies.0.exceptions").String()).To(MatchJSON(`{
+				"D8HostNetwork": [
+					{
+						"namespace": "legacy",
+						"kind": "Pod",
+						"labelSelector": {"matchLabels": {"app": "legacy-app"}},
+						"checks": ["allowHostNetwork", "allowedHostPorts"]
+					}
+				],
+				"D8HostProcesses": [
+					{
+						"namespace": "legacy",
+						"kind": "Pod",
+						"labelSelector": {"matchLabels": {"app": "legacy-app"}},
+						"checks": ["allowHostPID"]
+					}
+				]
+			}`))
+
+			m := f.MetricsCollector.CollectedMetrics()
+			Expect(m).To(HaveLen(2))
+			Expect(m[0].Action).To(Equal("expire"))
+			Expect(m[1].Name).To(Equal("d8_admission_policy_engine_policy_exception_expired"))
+			Expect(m[1].Labels).To(Equal(map[string]string{
+				"namespace":   "legacy",
+				"name":        "expired",
+				"policy_kind": "SecurityPolicy",
+				"policy":      "foo",
+			}))
+		})
+	})
 })
 
 var testSecurityPolicy = `
@@ -247,3 +287,58 @@ spec:
       - '*'
 
 `
+
+var testSecurityPolicyExceptions = `
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: PolicyException
+metadata:
+  name: legacy-app
+  namespace: legacy
+spec:
+  policy:
+    kind: SecurityPolicy
+    name: foo
+  checks:
+  - allowHostNetwork
+  - allowedHostPorts
+  - allowHostPID
+  targets:
+  - kind: Pod
+    labelSelector:
+      matchLabels:
+        app: legacy-app
+  expiresAt: "2150-10-10T10:10:10Z"
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: PolicyException
+metadata:
+  name: expired
+  namespace: legacy
+spec:
+  policy:
+    kind: SecurityPolicy
+    name: foo
+  checks:
+  - allowPrivileged
+  targets:
+  - kind: Pod
+    name: legacy-app
+  expiresAt: "2020-02-02T22:22:22Z"
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: PolicyException
+metadata:
+  name: operation
+  namespace: legacy
+spec:
+  policy:
+    kind: OperationPolicy
+    name: foo
+  checks:
+  - allowedRepos
+  targets:
+  - kind: Pod
+    name: legacy-app
+  expiresAt: "2150-10-10T10:10:10Z"
+`
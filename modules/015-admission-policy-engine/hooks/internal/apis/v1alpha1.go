@@ -138,6 +138,7 @@ type OperationPolicySpec struct {
 			MinReplicas int `json:"minReplicas,omitempty"`
 			MaxReplicas int `json:"maxReplicas,omitempty"`
 		} `json:"replicaLimits,omitempty"`
+		VerifyImageSignatures []ImageSignatureRule `json:"verifyImageSignatures,omitempty"`
 	} `json:"policies"`
 	Match struct {
 		NamespaceSelector NamespaceSelector    `json:"namespaceSelector,omitempty"`
@@ -145,7 +146,102 @@ type OperationPolicySpec struct {
 	} `json:"match"`
 }
 
+type MutationPolicy struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard object's metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec defines the default values set to the matched objects.
+	Spec MutationPolicySpec `json:"spec"`
+
+	// Most recently observed status of the policy.
+	// Populated by the system.
+
+	Status PolicyStatus `json:"status,omitempty"`
+}
+
+type MutationPolicySpec struct {
+	// Mode is Apply or DryRun, the DryRun policy only reports the objects that would be changed.
+	Mode      string `json:"mode"`
+	Mutations struct {
+		Resources struct {
+			Requests map[string]string `json:"requests,omitempty"`
+			Limits   map[string]string `json:"limits,omitempty"`
+		} `json:"resources,omitempty"`
+		ImagePullPolicy  string            `json:"imagePullPolicy,omitempty"`
+		SeccompProfile   *SeccompProfile   `json:"seccompProfile,omitempty"`
+		DropCapabilities []string          `json:"dropCapabilities,omitempty"`
+		Labels           map[string]string `json:"labels,omitempty"`
+	} `json:"mutations"`
+	Match struct {
+		NamespaceSelector NamespaceSelector    `json:"namespaceSelector,omitempty"`
+		LabelSelector     metav1.LabelSelector `json:"labelSelector,omitempty"`
+	} `json:"match"`
+}
+
+type SeccompProfile struct {
+	Type             string `json:"type"`
+	LocalhostProfile string `json:"localhostProfile,omitempty"`
+}
+
+type ImageSignatureRule struct {
+	Reference  string   `json:"reference"`
+	PublicKeys []string `json:"publicKeys,omitempty"`
+	Keyless    []struct {
+		Issuer  string `json:"issuer"`
+		Subject string `json:"subject"`
+	} `json:"keyless,omitempty"`
+}
+
 type PolicyStatus struct {
+	// Violations is the audit report of the policy, it is written by the constraint exporter
+	Violations *PolicyViolations `json:"violations,omitempty"`
+}
+
+type PolicyViolations struct {
+	TotalViolations int `json:"totalViolations"`
+	TopNamespaces   []struct {
+		Namespace  string `json:"namespace"`
+		Violations int    `json:"violations"`
+	} `json:"topNamespaces,omitempty"`
+	Objects []struct {
+		Constraint string `json:"constraint"`
+		Kind       string `json:"kind"`
+		Name       string `json:"name"`
+		Namespace  string `json:"namespace,omitempty"`
+		Message    string `json:"message"`
+	} `json:"objects,omitempty"`
+}
+
+type PolicyException struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard object's metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec defines the checks of the policy waived for the objects.
+	Spec PolicyExceptionSpec `json:"spec"`
+}
+
+type PolicyExceptionSpec struct {
+	Policy struct {
+		// Kind is SecurityPolicy or OperationPolicy.
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	} `json:"policy"`
+	// Checks are the names of the policy fields, e.g., allowHostNetwork.
+	Checks    []string                `json:"checks"`
+	Targets   []PolicyExceptionTarget `json:"targets"`
+	ExpiresAt metav1.Time             `json:"expiresAt"`
+}
+
+type PolicyExceptionTarget struct {
+	Kind          string                `json:"kind"`
+	Name          string                `json:"name,omitempty"`
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
 }
 
 type NamespaceSelector struct {
@@ -0,0 +1,197 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"sort"
+	"time"
+
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook/metrics"
+	"github.com/flant/addon-operator/sdk"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	v1alpha1 "github.com/deckhouse/deckhouse/modules/015-admission-policy-engine/hooks/internal/apis"
+)
+
+const (
+	securityPolicyKind  = "SecurityPolicy"
+	operationPolicyKind = "OperationPolicy"
+
+	policyExceptionsSnapshot = "policy-exceptions"
+)
+
+// securityPolicyChecks maps the fields of the SecurityPolicy to the gatekeeper constraints checking them
+var securityPolicyChecks = map[string]string{
+	"allowPrivileged":          "D8PrivilegedContainer",
+	"allowPrivilegeEscalation": "D8AllowPrivilegeEscalation",
+	"allowHostPID":             "D8HostProcesses",
+	"allowHostIPC":             "D8HostProcesses",
+	"allowHostNetwork":         "D8HostNetwork",
+	"allowedHostPorts":         "D8HostNetwork",
+	"readOnlyRootFilesystem":   "D8ReadOnlyRootFilesystem",
+	"allowedFlexVolumes":       "D8AllowedFlexVolumes",
+	"allowedVolumes":           "D8AllowedVolumeTypes",
+	"allowedHostPaths":         "D8AllowedHostPaths",
+	"allowedCapabilities":      "D8AllowedCapabilities",
+	"requiredDropCapabilities": "D8AllowedCapabilities",
+	"allowedAppArmor":          "D8AppArmor",
+	"allowedProcMount":         "D8AllowedProcMount",
+	"fsGroup":                  "D8AllowedUsers",
+	"runAsUser":                "D8AllowedUsers",
+	"runAsGroup":               "D8AllowedUsers",
+	"supplementalGroups":       "D8AllowedUsers",
+	"seLinux":                  "D8SeLinux",
+	"allowedUnsafeSysctls":     "D8AllowedSysctls",
+	"forbiddenSysctls":         "D8AllowedSysctls",
+	"seccompProfiles":          "D8AllowedSeccompProfiles",
+}
+
+// operationPolicyChecks maps the fields of the OperationPolicy to the gatekeeper constraints checking them
+var operationPolicyChecks = map[string]string{
+	"allowedRepos":              "D8AllowedRepos",
+	"requiredResources":         "D8RequiredResources",
+	"disallowedImageTags":       "D8DisallowedTags",
+	"requiredLabels":            "D8RequiredLabels",
+	"requiredAnnotations":       "D8RequiredAnnotations",
+	"requiredProbes":            "D8RequiredProbes",
+	"maxRevisionHistoryLimit":   "D8RevisionHistoryLimit",
+	"imagePullPolicy":           "D8ImagePullPolicy",
+	"priorityClassNames":        "D8PriorityClass",
+	"checkHostNetworkDNSPolicy": "D8DNSPolicy",
+	"checkContainerDuplicates":  "D8ContainerDuplicates",
+	"replicaLimits":             "D8ReplicaLimits",
+	"verifyImageSignatures":     "D8VerifyImageSignatures",
+}
+
+var policyExceptionsBinding = go_hook.KubernetesConfig{
+	Name:       policyExceptionsSnapshot,
+	ApiVersion: "deckhouse.io/v1alpha1",
+	Kind:       "PolicyException",
+	FilterFunc: filterPolicyException,
+}
+
+// the expired exceptions are excluded from the constraints on schedule
+var policyExceptionsSchedule = go_hook.ScheduleConfig{
+	Name:    "policy-exceptions-expiration",
+	Crontab: "*/5 * * * *",
+}
+
+type policyException struct {
+	Name      string                       `json:"name"`
+	Namespace string                       `json:"namespace"`
+	Spec      v1alpha1.PolicyExceptionSpec `json:"spec"`
+}
+
+func filterPolicyException(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	var pe v1alpha1.PolicyException
+
+	err := sdk.FromUnstructured(obj, &pe)
+	if err != nil {
+		return nil, err
+	}
+
+	return &policyException{
+		Name:      pe.Name,
+		Namespace: pe.Namespace,
+		Spec:      pe.Spec,
+	}, nil
+}
+
+// exceptionTarget is the object exempted from the constraint, it is matched by the rego library of the constraint templates.
+// Checks are the waived checks of the policy, the constraints verifying several checks skip only the waived ones.
+type exceptionTarget struct {
+	Namespace     string                `json:"namespace"`
+	Kind          string                `json:"kind"`
+	Name          string                `json:"name,omitempty"`
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	Checks        []string              `json:"checks"`
+}
+
+// collectPolicyExceptions returns the targets of the active exceptions by the policy name and the constraint kind.
+// Expired exceptions are skipped and reported with the metric.
+func collectPolicyExceptions(input *go_hook.HookInput, policyKind string, checks map[string]string) map[string]map[string][]exceptionTarget {
+	metricGroup := "d8_admission_policy_engine_policy_exceptions_" + policyKind
+	input.MetricsCollector.Expire(metricGroup)
+
+	exceptions := make([]*policyException, 0)
+	for _, sn := range input.Snapshots[policyExceptionsSnapshot] {
+		pe := sn.(*policyException)
+		if pe.Spec.Policy.Kind == policyKind {
+			exceptions = append(exceptions, pe)
+		}
+	}
+
+	sort.Slice(exceptions, func(i, j int) bool {
+		if exceptions[i].Namespace != exceptions[j].Namespace {
+			return exceptions[i].Namespace < exceptions[j].Namespace
+		}
+		return exceptions[i].Name < exceptions[j].Name
+	})
+
+	now := time.Now()
+	result := make(map[string]map[string][]exceptionTarget)
+
+	for _, pe := range exceptions {
+		if pe.Spec.ExpiresAt.Time.Before(now) {
+			input.MetricsCollector.Set("d8_admission_policy_engine_policy_exception_expired", 1, map[string]string{
+				"namespace":   pe.Namespace,
+				"name":        pe.Name,
+				"policy_kind": policyKind,
+				"policy":      pe.Spec.Policy.Name,
+			}, metrics.WithGroup(metricGroup))
+			continue
+		}
+
+		// the waived checks by the constraint kind
+		constraints := make(map[string][]string)
+		for _, check := range pe.Spec.Checks {
+			kind, ok := checks[check]
+			if !ok {
+				input.LogEntry.Warnf("PolicyException %s/%s: check %q is unknown for %s", pe.Namespace, pe.Name, check, policyKind)
+				continue
+			}
+			constraints[kind] = append(constraints[kind], check)
+		}
+
+		if len(constraints) == 0 {
+			continue
+		}
+
+		policyExceptions, ok := result[pe.Spec.Policy.Name]
+		if !ok {
+			policyExceptions = make(map[string][]exceptionTarget)
+			result[pe.Spec.Policy.Name] = policyExceptions
+		}
+
+		for kind, waived := range constraints {
+			sort.Strings(waived)
+			for _, target := range pe.Spec.Targets {
+				policyExceptions[kind] = append(policyExceptions[kind], exceptionTarget{
+					Namespace:     pe.Namespace,
+					Kind:          target.Kind,
+					Name:          target.Name,
+					LabelSelector: target.LabelSelector,
+					Checks:        waived,
+				})
+			}
+		}
+	}
+
+	return result
+}
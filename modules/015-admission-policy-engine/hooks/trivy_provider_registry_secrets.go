@@ -106,40 +106,75 @@ func registrySecretToAuthnConfig(secret *corev1.Secret) (*dockerConfig, error) {
 	return config, nil
 }
 
+// handleTrivyProviderSecrets collects the registry credentials for the trivy provider scanning the images
+// and for the image signature provider downloading the signatures
 func handleTrivyProviderSecrets(input *go_hook.HookInput, dc dependency.Container) error {
-	if !input.Values.Get("admissionPolicyEngine.denyVulnerableImages.enabled").Bool() {
+	denyVulnerableImages := input.Values.Get("admissionPolicyEngine.denyVulnerableImages.enabled").Bool()
+	verifyImageSignatures := imageSignatureVerificationEnabled(input)
+
+	if !denyVulnerableImages && !verifyImageSignatures {
 		return nil
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*20)
 	defer cancel()
 
+	k8sClient, err := dc.GetK8sClient()
+	if err != nil {
+		return fmt.Errorf("can't get k8s client for retrieving registry secrets: %w", err)
+	}
+
+	if denyVulnerableImages {
+		resultCfg, err := collectRegistryAuths(ctx, input, k8sClient, "admissionPolicyEngine.denyVulnerableImages.registrySecrets")
+		if err != nil {
+			return err
+		}
+		input.Values.Set("admissionPolicyEngine.internal.denyVulnerableImages.dockerConfigJson", resultCfg)
+	}
+
+	if verifyImageSignatures {
+		resultCfg, err := collectRegistryAuths(ctx, input, k8sClient, "admissionPolicyEngine.imageSignatureVerification.registrySecrets")
+		if err != nil {
+			return err
+		}
+		input.Values.Set("admissionPolicyEngine.internal.imageSignatureVerification.dockerConfigJson", resultCfg)
+	}
+
+	return nil
+}
+
+// imageSignatureVerificationEnabled returns true if any OperationPolicy requires the image signatures
+func imageSignatureVerificationEnabled(input *go_hook.HookInput) bool {
+	for _, op := range input.Values.Get("admissionPolicyEngine.internal.operationPolicies").Array() {
+		if op.Get("spec.policies.verifyImageSignatures").Exists() {
+			return true
+		}
+	}
+	return false
+}
+
+// collectRegistryAuths merges the deckhouse-registry secret with the registry secrets from the module values
+func collectRegistryAuths(ctx context.Context, input *go_hook.HookInput, k8sClient k8s.Client, registrySecretsPath string) (dockerConfig, error) {
 	resultCfg := dockerConfig{Auths: make(map[string]authn.AuthConfig)}
 	for _, authSnap := range input.Snapshots["trivy_provider_secrets"] {
 		err := convertSnapToAuthnConfig(authSnap, &resultCfg)
 		if err != nil && !errors.Is(err, ErrNilSnapshot) {
-			return err
+			return resultCfg, err
 		}
 	}
 
-	k8sClient, err := dc.GetK8sClient()
-	if err != nil {
-		return fmt.Errorf("can't get k8s client for retrieving registry secrets: %w", err)
-	}
-
-	registrySecretsValues := input.Values.Get("admissionPolicyEngine.denyVulnerableImages.registrySecrets").Array()
-	for _, registrySecretValue := range registrySecretsValues {
+	for _, registrySecretValue := range input.Values.Get(registrySecretsPath).Array() {
 		registrySecret, err := registrySecretValueToAuthnConfig(ctx, registrySecretValue, k8sClient)
 		if err != nil {
-			return fmt.Errorf("can't get registry secret from module values: %w", err)
+			return resultCfg, fmt.Errorf("can't get registry secret from module values: %w", err)
 		}
 		err = convertSnapToAuthnConfig(registrySecret.Auths, &resultCfg)
 		if err != nil && !errors.Is(err, ErrNilSnapshot) {
-			return err
+			return resultCfg, err
 		}
 	}
-	input.Values.Set("admissionPolicyEngine.internal.denyVulnerableImages.dockerConfigJson", resultCfg)
-	return nil
+
+	return resultCfg, nil
 }
 
 var ErrNilSnapshot = errors.New("nil snapshot")
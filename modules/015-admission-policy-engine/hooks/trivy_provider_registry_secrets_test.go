@@ -28,7 +28,7 @@ import (
 )
 
 var _ = Describe("Modules :: admission-policy-engine :: hooks :: handle trivy provider registry secrets", func() {
-	f := HookExecutionConfigInit(`{"admissionPolicyEngine":{"internal":{"denyVulnerableImages": {}}}}`, ``)
+	f := HookExecutionConfigInit(`{"admissionPolicyEngine":{"internal":{"denyVulnerableImages": {}, "imageSignatureVerification": {}}}}`, ``)
 
 	BeforeEach(func() {
 		f.BindingContexts.Set(f.KubeStateSet(testDenyVulnerableImagesSecrets))
@@ -70,6 +70,30 @@ var _ = Describe("Modules :: admission-policy-engine :: hooks :: handle trivy pr
 			Expect(f.ValuesGet("admissionPolicyEngine.internal.denyVulnerableImages.dockerConfigJson").String()).To(MatchJSON(testDenyVulnerableImagesSecretsValues))
 		})
 	})
+
+	Context("Registry secrets data is stored in values for the image signature verification", func() {
+		BeforeEach(func() {
+			_, err := f.KubeClient().CoreV1().Secrets(testDenyVulnerableImagesSecret.GetNamespace()).Create(context.Background(), testDenyVulnerableImagesSecret, metav1.CreateOptions{})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			f.ValuesSetFromYaml("admissionPolicyEngine.internal.operationPolicies", []byte(`[{"metadata":{"name":"signed"},"spec":{"policies":{"verifyImageSignatures":[{"reference":"registry.test-3.com/*","publicKeys":["key"]}]}}}]`))
+			f.ValuesSetFromYaml("admissionPolicyEngine.imageSignatureVerification.registrySecrets", []byte(`[{"name": "test-2", "namespace": "default"}]`))
+			f.RunHook()
+		})
+
+		AfterEach(func() {
+			err := f.KubeClient().CoreV1().Secrets(testDenyVulnerableImagesSecret.GetNamespace()).Delete(context.Background(), testDenyVulnerableImagesSecret.GetName(), metav1.DeleteOptions{})
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+		It("Executes successfully", func() {
+			Expect(f).To(ExecuteSuccessfully())
+		})
+
+		It("should store data in values", func() {
+			Expect(f.ValuesGet("admissionPolicyEngine.internal.imageSignatureVerification.dockerConfigJson").String()).To(MatchJSON(testDenyVulnerableImagesSecretsValues))
+			Expect(f.ValuesGet("admissionPolicyEngine.internal.denyVulnerableImages.dockerConfigJson").String()).To(Equal(""))
+		})
+	})
 })
 var (
 	testDenyVulnerableImagesSecret = &corev1.Secret{
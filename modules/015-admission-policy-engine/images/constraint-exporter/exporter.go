@@ -81,6 +81,8 @@ func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
 	ch <- gatekeeper.Up
 	ch <- gatekeeper.ConstraintViolation
 	ch <- gatekeeper.ConstraintInformation
+	ch <- gatekeeper.PolicyViolation
+	ch <- gatekeeper.PolicyNamespaceViolation
 }
 
 func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
@@ -148,7 +150,15 @@ func (e *Exporter) fetchConstraints(clientGVR controllerClient.Client) ([]gateke
 	constraintInformationMetrics := gatekeeper.ExportConstraintInformation(constraints)
 	allMetrics = append(allMetrics, constraintInformationMetrics...)
 
+	policyReports := gatekeeper.AggregatePolicyViolations(constraints, policyViolationsLimit)
+	allMetrics = append(allMetrics, gatekeeper.ExportPolicyViolations(policyReports)...)
+
 	e.metrics = allMetrics
 
+	err = gatekeeper.UpdatePolicyStatuses(clientGVR, policyReports)
+	if err != nil {
+		klog.Warningf("Update policy statuses failed: %+v\n", err)
+	}
+
 	return constraints, nil
 }
@@ -38,6 +38,8 @@ var (
 	interval      time.Duration
 
 	trackObjectsCMName string
+
+	policyViolationsLimit int
 )
 
 func init() {
@@ -48,6 +50,8 @@ func init() {
 	flag.DurationVar(&interval, "server.interval", 30*time.Second,
 		"Kubernetes API server polling interval")
 	flag.StringVar(&trackObjectsCMName, "track-objects-configmap", "constraint-exporter", "ConfigMap for export tracking resource kinds")
+	flag.IntVar(&policyViolationsLimit, "policy-status.violations-limit", 20,
+		"Max number of the violating objects in the status of the SecurityPolicy and the OperationPolicy")
 }
 
 var (
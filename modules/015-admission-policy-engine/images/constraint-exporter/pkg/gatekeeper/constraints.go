@@ -137,6 +137,9 @@ func GetConstraints(cClient controllerClient.Client, client *kubernetes.Clientse
 
 				case f("security.deckhouse.io/security-policy"):
 					constraint.Meta.SourceType = "SecurityPolicy"
+
+				case f("security.deckhouse.io/mutation-policy"):
+					constraint.Meta.SourceType = "MutationPolicy"
 				}
 
 				constraints = append(constraints, constraint)
@@ -40,6 +40,16 @@ var (
 		"Some general information of all constraints",
 		[]string{"kind", "name", "enforcementAction", "totalViolations"}, nil,
 	)
+	PolicyViolation = prometheus.NewDesc(
+		prometheus.BuildFQName(prefix, "", "policy_violations"),
+		"Audit violations of all constraints of the SecurityPolicy or the OperationPolicy",
+		[]string{"policy_kind", "policy"}, nil,
+	)
+	PolicyNamespaceViolation = prometheus.NewDesc(
+		prometheus.BuildFQName(prefix, "", "policy_namespace_violations"),
+		"Audit violations of the SecurityPolicy or the OperationPolicy in the top offending namespaces",
+		[]string{"policy_kind", "policy", "namespace"}, nil,
+	)
 )
 
 func ExportViolations(constraints []Constraint) []prometheus.Metric {
@@ -61,3 +71,14 @@ func ExportConstraintInformation(constraints []Constraint) []prometheus.Metric {
 	}
 	return m
 }
+
+func ExportPolicyViolations(reports map[Policy]*PolicyViolations) []prometheus.Metric {
+	m := make([]prometheus.Metric, 0)
+	for policy, report := range reports {
+		m = append(m, prometheus.MustNewConstMetric(PolicyViolation, prometheus.GaugeValue, float64(report.TotalViolations), policy.Kind, policy.Name))
+		for _, ns := range report.TopNamespaces {
+			m = append(m, prometheus.MustNewConstMetric(PolicyNamespaceViolation, prometheus.GaugeValue, float64(ns.Violations), policy.Kind, policy.Name, ns.Namespace))
+		}
+	}
+	return m
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatekeeper
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	controllerClient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const topNamespacesCount = 5
+
+// Policy is the SecurityPolicy, the OperationPolicy or the MutationPolicy the constraints are rendered from
+type Policy struct {
+	Kind string
+	Name string
+}
+
+// PolicyViolations is the audit report of the policy, it is written to the status of the policy
+type PolicyViolations struct {
+	// TotalViolations is the sum of the audit violations of all constraints of the policy
+	TotalViolations int                   `json:"totalViolations"`
+	TopNamespaces   []NamespaceViolations `json:"topNamespaces,omitempty"`
+	Objects         []ViolatingObject     `json:"objects,omitempty"`
+}
+
+type NamespaceViolations struct {
+	Namespace  string `json:"namespace"`
+	Violations int    `json:"violations"`
+}
+
+type ViolatingObject struct {
+	Constraint string `json:"constraint"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	Message    string `json:"message"`
+}
+
+var policyResources = map[string]schema.GroupVersionResource{
+	"SecurityPolicy":  {Group: "deckhouse.io", Version: "v1alpha1", Resource: "securitypolicies"},
+	"OperationPolicy": {Group: "deckhouse.io", Version: "v1alpha1", Resource: "operationpolicies"},
+	// the MutationPolicy gets the changes reported by the constraints of the DryRun mode
+	"MutationPolicy": {Group: "deckhouse.io", Version: "v1alpha1", Resource: "mutationpolicies"},
+}
+
+// AggregatePolicyViolations groups the audit results of the constraints by the policies.
+// Gatekeeper keeps a limited number of violations in the constraint status, so the namespaces and the objects
+// are calculated from them while the total is the exact number.
+func AggregatePolicyViolations(constraints []Constraint, objectsLimit int) map[Policy]*PolicyViolations {
+	result := make(map[Policy]*PolicyViolations)
+	namespaces := make(map[Policy]map[string]int)
+
+	for _, c := range constraints {
+		if _, ok := policyResources[c.Meta.SourceType]; !ok {
+			continue
+		}
+
+		policy := Policy{Kind: c.Meta.SourceType, Name: c.Meta.Name}
+		report, ok := result[policy]
+		if !ok {
+			report = &PolicyViolations{}
+			result[policy] = report
+			namespaces[policy] = make(map[string]int)
+		}
+
+		report.TotalViolations += int(c.Status.TotalViolations)
+		for _, v := range c.Status.Violations {
+			if v.Namespace != "" {
+				namespaces[policy][v.Namespace]++
+			}
+			report.Objects = append(report.Objects, ViolatingObject{
+				Constraint: c.Meta.Kind,
+				Kind:       v.Kind,
+				Name:       v.Name,
+				Namespace:  v.Namespace,
+				Message:    v.Message,
+			})
+		}
+	}
+
+	for policy, report := range result {
+		sort.SliceStable(report.Objects, func(i, j int) bool {
+			a, b := report.Objects[i], report.Objects[j]
+			if a.Namespace != b.Namespace {
+				return a.Namespace < b.Namespace
+			}
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			return a.Constraint < b.Constraint
+		})
+		if len(report.Objects) > objectsLimit {
+			report.Objects = report.Objects[:objectsLimit]
+		}
+
+		for ns, count := range namespaces[policy] {
+			report.TopNamespaces = append(report.TopNamespaces, NamespaceViolations{Namespace: ns, Violations: count})
+		}
+		sort.Slice(report.TopNamespaces, func(i, j int) bool {
+			a, b := report.TopNamespaces[i], report.TopNamespaces[j]
+			if a.Violations != b.Violations {
+				return a.Violations > b.Violations
+			}
+			return a.Namespace < b.Namespace
+		})
+		if len(report.TopNamespaces) > topNamespacesCount {
+			report.TopNamespaces = report.TopNamespaces[:topNamespacesCount]
+		}
+	}
+
+	return result
+}
+
+// UpdatePolicyStatuses writes the violations to the status of every policy, the policies without constraints get the empty report
+func UpdatePolicyStatuses(cClient controllerClient.Client, reports map[Policy]*PolicyViolations) error {
+	for kind, gvr := range policyResources {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvr.GroupVersion().WithKind(kind + "List"))
+
+		err := cClient.List(context.TODO(), list)
+		if err != nil {
+			return err
+		}
+
+		for i := range list.Items {
+			item := &list.Items[i]
+
+			report, ok := reports[Policy{Kind: kind, Name: item.GetName()}]
+			if !ok {
+				report = &PolicyViolations{}
+			}
+
+			if policyStatusEqual(item, report) {
+				continue
+			}
+
+			// the empty lists are marshaled as null to remove the stale ones with the merge patch
+			patch, err := json.Marshal(map[string]interface{}{
+				"status": map[string]interface{}{
+					"violations": map[string]interface{}{
+						"totalViolations": report.TotalViolations,
+						"topNamespaces":   report.TopNamespaces,
+						"objects":         report.Objects,
+					},
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			err = cClient.Status().Patch(context.TODO(), item, controllerClient.RawPatch(types.MergePatchType, patch))
+			if err != nil {
+				klog.Warningf("Update status of %s %s failed: %+v\n", kind, item.GetName(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func policyStatusEqual(item *unstructured.Unstructured, report *PolicyViolations) bool {
+	current, found, err := unstructured.NestedMap(item.Object, "status", "violations")
+	if err != nil || !found {
+		return false
+	}
+
+	var currentReport PolicyViolations
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(current, &currentReport)
+	if err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(&currentReport, report)
+}
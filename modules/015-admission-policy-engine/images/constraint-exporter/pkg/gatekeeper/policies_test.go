@@ -0,0 +1,131 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatekeeper
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestAggregatePolicyViolations(t *testing.T) {
+	constraints := []Constraint{
+		{
+			Meta: ConstraintMeta{Kind: "D8HostNetwork", Name: "genpolicy", SourceType: "SecurityPolicy"},
+			Status: ConstraintStatus{
+				TotalViolations: 3,
+				Violations: []*Violation{
+					{Kind: "Pod", Name: "b", Namespace: "ns1", Message: "host network"},
+					{Kind: "Pod", Name: "a", Namespace: "ns1", Message: "host network"},
+					{Kind: "Pod", Name: "c", Namespace: "ns2", Message: "host network"},
+				},
+			},
+		},
+		{
+			Meta: ConstraintMeta{Kind: "D8PrivilegedContainer", Name: "genpolicy", SourceType: "SecurityPolicy"},
+			Status: ConstraintStatus{
+				TotalViolations: 1,
+				Violations: []*Violation{
+					{Kind: "Pod", Name: "d", Namespace: "ns2", Message: "privileged"},
+				},
+			},
+		},
+		{
+			Meta:   ConstraintMeta{Kind: "D8RequiredLabels", Name: "genpolicy", SourceType: "OperationPolicy"},
+			Status: ConstraintStatus{},
+		},
+		{
+			Meta: ConstraintMeta{Kind: "D8PrivilegedContainer", Name: "d8-pod-security-baseline-deny-default", SourceType: "PSS"},
+			Status: ConstraintStatus{
+				TotalViolations: 1,
+				Violations:      []*Violation{{Kind: "Pod", Name: "e", Namespace: "ns3", Message: "privileged"}},
+			},
+		},
+	}
+
+	reports := AggregatePolicyViolations(constraints, 3)
+	require.Len(t, reports, 2)
+
+	security := reports[Policy{Kind: "SecurityPolicy", Name: "genpolicy"}]
+	require.NotNil(t, security)
+	assert.Equal(t, 4, security.TotalViolations)
+	assert.Equal(t, []NamespaceViolations{{Namespace: "ns1", Violations: 2}, {Namespace: "ns2", Violations: 2}}, security.TopNamespaces)
+	assert.Equal(t, []ViolatingObject{
+		{Constraint: "D8HostNetwork", Kind: "Pod", Name: "a", Namespace: "ns1", Message: "host network"},
+		{Constraint: "D8HostNetwork", Kind: "Pod", Name: "b", Namespace: "ns1", Message: "host network"},
+		{Constraint: "D8HostNetwork", Kind: "Pod", Name: "c", Namespace: "ns2", Message: "host network"},
+	}, security.Objects)
+
+	operation := reports[Policy{Kind: "OperationPolicy", Name: "genpolicy"}]
+	require.NotNil(t, operation)
+	assert.Equal(t, &PolicyViolations{}, operation)
+}
+
+func TestAggregatePolicyViolationsTopNamespaces(t *testing.T) {
+	violations := make([]*Violation, 0)
+	for i := 0; i < 7; i++ {
+		for j := 0; j <= i; j++ {
+			violations = append(violations, &Violation{Kind: "Pod", Name: fmt.Sprintf("pod-%d", j), Namespace: fmt.Sprintf("ns-%d", i)})
+		}
+	}
+
+	reports := AggregatePolicyViolations([]Constraint{{
+		Meta:   ConstraintMeta{Kind: "D8RequiredLabels", Name: "labels", SourceType: "OperationPolicy"},
+		Status: ConstraintStatus{TotalViolations: float64(len(violations)), Violations: violations},
+	}}, 20)
+
+	report := reports[Policy{Kind: "OperationPolicy", Name: "labels"}]
+	require.NotNil(t, report)
+	assert.Equal(t, 28, report.TotalViolations)
+	assert.Len(t, report.Objects, 20)
+	assert.Equal(t, []NamespaceViolations{
+		{Namespace: "ns-6", Violations: 7},
+		{Namespace: "ns-5", Violations: 6},
+		{Namespace: "ns-4", Violations: 5},
+		{Namespace: "ns-3", Violations: 4},
+		{Namespace: "ns-2", Violations: 3},
+	}, report.TopNamespaces)
+}
+
+func TestPolicyStatusEqual(t *testing.T) {
+	report := &PolicyViolations{
+		TotalViolations: 1,
+		TopNamespaces:   []NamespaceViolations{{Namespace: "ns1", Violations: 1}},
+		Objects:         []ViolatingObject{{Constraint: "D8HostNetwork", Kind: "Pod", Name: "a", Namespace: "ns1", Message: "host network"}},
+	}
+
+	item := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"violations": map[string]interface{}{
+				"totalViolations": int64(1),
+				"topNamespaces": []interface{}{
+					map[string]interface{}{"namespace": "ns1", "violations": int64(1)},
+				},
+				"objects": []interface{}{
+					map[string]interface{}{"constraint": "D8HostNetwork", "kind": "Pod", "name": "a", "namespace": "ns1", "message": "host network"},
+				},
+			},
+		},
+	}}
+
+	assert.True(t, policyStatusEqual(item, report))
+	assert.False(t, policyStatusEqual(item, &PolicyViolations{}))
+	assert.False(t, policyStatusEqual(&unstructured.Unstructured{Object: map[string]interface{}{}}, &PolicyViolations{}))
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package externaldata contains the gatekeeper external data provider API types.
+// They are copied to avoid importing the gatekeeper frameworks with the rego engine.
+package externaldata
+
+const APIVersion = "externaldata.gatekeeper.sh/v1alpha1"
+
+type ProviderRequest struct {
+	APIVersion string  `json:"apiVersion,omitempty"`
+	Kind       string  `json:"kind,omitempty"`
+	Request    Request `json:"request,omitempty"`
+}
+
+type Request struct {
+	Keys []string `json:"keys,omitempty"`
+}
+
+type ProviderResponse struct {
+	APIVersion string   `json:"apiVersion,omitempty"`
+	Kind       string   `json:"kind,omitempty"`
+	Response   Response `json:"response,omitempty"`
+}
+
+type Response struct {
+	Idempotent  bool   `json:"idempotent,omitempty"`
+	Items       []Item `json:"items,omitempty"`
+	SystemError string `json:"systemError,omitempty"`
+}
+
+type Item struct {
+	Key   string      `json:"key,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"image-signature-provider/verifier"
+	"image-signature-provider/web"
+)
+
+func main() {
+	var (
+		keyFile        string
+		certFile       string
+		clientCAFile   string
+		configFile     = "/etc/image-signature-provider/config.json"
+		host           = "0.0.0.0"
+		port           = 8443
+		timeoutSeconds = 6
+		cacheTTL       = time.Hour
+	)
+	flag.StringVar(&keyFile, "key-file", keyFile, "Path to file containing TLS certificate key.")
+	flag.StringVar(&certFile, "cert-file", certFile, "Path to file containing TLS certificate.")
+	flag.StringVar(&clientCAFile, "client-ca-file", clientCAFile, "Path to client CA certificate (gatekeeper CA).")
+	flag.StringVar(&configFile, "config-file", configFile, "Path to file containing verification rules of the OperationPolicies.")
+	flag.StringVar(&host, "host", host, "Host for the server to listen on.")
+	flag.IntVar(&port, "port", port, "Port for the server to listen on.")
+	flag.IntVar(&timeoutSeconds, "timeout", timeoutSeconds, "Verification timeout in seconds.")
+	flag.DurationVar(&cacheTTL, "cache-ttl", cacheTTL, "How long the verified image digests are cached.")
+	klog.InitFlags(nil)
+	flag.Parse()
+
+	tlsConfig, err := newTLSConfig(clientCAFile)
+	if err != nil {
+		klog.Fatal(err)
+	}
+
+	v := verifier.NewVerifier(verifier.NewConfigLoader(configFile), cacheTTL)
+	handler := web.NewHandler(v, time.Duration(timeoutSeconds)*time.Second)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/validate", handler.HandleRequest())
+
+	server := &http.Server{
+		Addr:              net.JoinHostPort(host, strconv.Itoa(port)),
+		Handler:           mux,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	klog.Info("Starting server...")
+	if err = server.ListenAndServeTLS(certFile, keyFile); err != nil {
+		klog.Fatal(err)
+	}
+}
+
+func newTLSConfig(caCertFile string) (*tls.Config, error) {
+	if caCertFile == "" {
+		return nil, nil
+	}
+
+	caCert, err := os.ReadFile(caCertFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load Gatekeeper's CA certificate %s: %w", caCertFile, err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(caCert)
+
+	return &tls.Config{
+		ClientCAs:  clientCAs,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		MinVersion: tls.VersionTLS13,
+	}, nil
+}
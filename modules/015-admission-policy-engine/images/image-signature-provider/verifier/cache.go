@@ -0,0 +1,81 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verifier
+
+import (
+	"sync"
+	"time"
+)
+
+const maxCacheEntries = 10000
+
+// cache keeps the successful verifications by the image digest, the failed ones are checked again on every request
+// because the image can be signed later
+type cache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]time.Time
+}
+
+func newCache(ttl time.Duration) *cache {
+	return &cache{
+		ttl:     ttl,
+		entries: make(map[string]time.Time),
+	}
+}
+
+func (c *cache) verified(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiration, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+
+	if time.Now().After(expiration) {
+		delete(c.entries, key)
+		return false
+	}
+
+	return true
+}
+
+func (c *cache) add(key string) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxCacheEntries {
+		now := time.Now()
+		for k, expiration := range c.entries {
+			if now.After(expiration) {
+				delete(c.entries, k)
+			}
+		}
+		// all entries are fresh, start from scratch instead of growing unbounded
+		if len(c.entries) >= maxCacheEntries {
+			c.entries = make(map[string]time.Time)
+		}
+	}
+
+	c.entries[key] = time.Now().Add(c.ttl)
+}
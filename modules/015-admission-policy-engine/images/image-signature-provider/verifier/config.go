@@ -0,0 +1,120 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config is rendered from the verifyImageSignatures rules of the OperationPolicies
+type Config struct {
+	// Policies are the rules by the OperationPolicy name
+	Policies map[string][]Rule `json:"policies"`
+	Keyless  KeylessConfig     `json:"keyless"`
+}
+
+type Rule struct {
+	// Reference is the glob pattern of the image repository, `*` matches any sequence of characters
+	Reference  string     `json:"reference"`
+	PublicKeys []string   `json:"publicKeys,omitempty"`
+	Keyless    []Identity `json:"keyless,omitempty"`
+}
+
+// Identity is the signer of the keyless signature, the subject supports the glob patterns
+type Identity struct {
+	Issuer  string `json:"issuer"`
+	Subject string `json:"subject"`
+}
+
+// KeylessConfig contains the trusted roots for the keyless signatures
+type KeylessConfig struct {
+	FulcioRootCertificates string `json:"fulcioRootCertificates,omitempty"`
+	RekorPublicKey         string `json:"rekorPublicKey,omitempty"`
+}
+
+// matchingRules returns the rules of the policy for the repository, the image must satisfy all of them
+func (c *Config) matchingRules(policy, repository string) []Rule {
+	result := make([]Rule, 0)
+	for _, rule := range c.Policies[policy] {
+		if globMatch(rule.Reference, repository) {
+			result = append(result, rule)
+		}
+	}
+	return result
+}
+
+func globMatch(pattern, value string) bool {
+	parts := strings.Split(pattern, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+
+	re, err := regexp.Compile("^" + strings.Join(parts, ".*") + "$")
+	if err != nil {
+		return false
+	}
+
+	return re.MatchString(value)
+}
+
+// ConfigLoader rereads the config file when the mounted ConfigMap is updated
+type ConfigLoader struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	config  *Config
+}
+
+func NewConfigLoader(path string) *ConfigLoader {
+	return &ConfigLoader{path: path}
+}
+
+func (l *ConfigLoader) Load() (*Config, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("stat config file: %w", err)
+	}
+
+	if l.config != nil && info.ModTime().Equal(l.modTime) {
+		return l.config, nil
+	}
+
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	config := new(Config)
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("unmarshal config file: %w", err)
+	}
+
+	l.config = config
+	l.modTime = info.ModTime()
+
+	return config, nil
+}
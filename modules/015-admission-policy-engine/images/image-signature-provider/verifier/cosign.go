@@ -0,0 +1,363 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verifier
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// Annotations of the cosign signature layers
+const (
+	signatureAnnotation   = "dev.cosignproject.cosign/signature"
+	certificateAnnotation = "dev.sigstore.cosign/certificate"
+	chainAnnotation       = "dev.sigstore.cosign/chain"
+	bundleAnnotation      = "dev.sigstore.cosign/bundle"
+)
+
+const maxPayloadSize = 1 << 20
+
+var (
+	// Fulcio certificate extensions with the OIDC issuer, the raw string and the DER encoded one
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+
+	errNoSignatures = errors.New("image is not signed")
+)
+
+type signature struct {
+	payload     []byte
+	signature   []byte
+	certificate *x509.Certificate
+	chain       []*x509.Certificate
+	bundle      *rekorBundle
+}
+
+// simpleSigning is the payload signed by cosign
+type simpleSigning struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+type rekorBundle struct {
+	SignedEntryTimestamp []byte       `json:"SignedEntryTimestamp"`
+	Payload              rekorPayload `json:"Payload"`
+}
+
+// rekorPayload fields are ordered by name to marshal the canonical JSON signed by rekor
+type rekorPayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+type hashedRekord struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content string `json:"content"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// fetchSignatures downloads the signatures from the cosign signature tag of the image digest
+func fetchSignatures(repo name.Repository, digest v1.Hash, options ...remote.Option) ([]signature, error) {
+	tag := repo.Tag(fmt.Sprintf("%s-%s.sig", digest.Algorithm, digest.Hex))
+
+	img, err := remote.Image(tag, options...)
+	if err != nil {
+		var terr *transport.Error
+		if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+			return nil, errNoSignatures
+		}
+		return nil, fmt.Errorf("get signatures %s: %w", tag, err)
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("get signatures manifest: %w", err)
+	}
+
+	signatures := make([]signature, 0, len(manifest.Layers))
+	for _, desc := range manifest.Layers {
+		encoded, ok := desc.Annotations[signatureAnnotation]
+		if !ok {
+			continue
+		}
+
+		sig, err := parseSignature(img, desc, encoded)
+		if err != nil {
+			return nil, err
+		}
+		signatures = append(signatures, sig)
+	}
+
+	if len(signatures) == 0 {
+		return nil, errNoSignatures
+	}
+
+	return signatures, nil
+}
+
+func parseSignature(img v1.Image, desc v1.Descriptor, encoded string) (signature, error) {
+	var sig signature
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return sig, fmt.Errorf("decode signature: %w", err)
+	}
+	sig.signature = raw
+
+	layer, err := img.LayerByDigest(desc.Digest)
+	if err != nil {
+		return sig, fmt.Errorf("get signature layer %s: %w", desc.Digest, err)
+	}
+
+	rc, err := layer.Compressed()
+	if err != nil {
+		return sig, fmt.Errorf("read signature layer %s: %w", desc.Digest, err)
+	}
+	defer rc.Close()
+
+	sig.payload, err = io.ReadAll(io.LimitReader(rc, maxPayloadSize))
+	if err != nil {
+		return sig, fmt.Errorf("read signature layer %s: %w", desc.Digest, err)
+	}
+
+	if certPEM, ok := desc.Annotations[certificateAnnotation]; ok {
+		certs, err := parseCertificates(certPEM)
+		if err != nil {
+			return sig, fmt.Errorf("parse signature certificate: %w", err)
+		}
+		if len(certs) == 0 {
+			return sig, errors.New("signature certificate is empty")
+		}
+		sig.certificate = certs[0]
+
+		sig.chain, err = parseCertificates(desc.Annotations[chainAnnotation])
+		if err != nil {
+			return sig, fmt.Errorf("parse signature certificate chain: %w", err)
+		}
+	}
+
+	if bundle, ok := desc.Annotations[bundleAnnotation]; ok {
+		sig.bundle = new(rekorBundle)
+		if err := json.Unmarshal([]byte(bundle), sig.bundle); err != nil {
+			return sig, fmt.Errorf("unmarshal signature bundle: %w", err)
+		}
+	}
+
+	return sig, nil
+}
+
+// verifyPayload checks that the signed payload belongs to the image digest
+func (s *signature) verifyPayload(digest v1.Hash) error {
+	var payload simpleSigning
+	if err := json.Unmarshal(s.payload, &payload); err != nil {
+		return fmt.Errorf("unmarshal signature payload: %w", err)
+	}
+
+	if payload.Critical.Image.DockerManifestDigest != digest.String() {
+		return fmt.Errorf("signature is issued for the other digest %s", payload.Critical.Image.DockerManifestDigest)
+	}
+
+	return nil
+}
+
+func (s *signature) verifyWithKey(key crypto.PublicKey) error {
+	hash := sha256.Sum256(s.payload)
+
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, hash[:], s.signature) {
+			return errors.New("invalid ECDSA signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], s.signature); err != nil {
+			return fmt.Errorf("invalid RSA signature: %w", err)
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, s.payload, s.signature) {
+			return errors.New("invalid ED25519 signature")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+
+	return nil
+}
+
+// verifyKeyless checks the certificate issued by Fulcio at the time the signature was recorded in the rekor log
+// and returns the OIDC issuer and the subjects of the signer
+func (s *signature) verifyKeyless(roots *x509.CertPool, rekorKey crypto.PublicKey) (string, []string, error) {
+	if s.certificate == nil {
+		return "", nil, errors.New("signature has no certificate")
+	}
+	if s.bundle == nil {
+		return "", nil, errors.New("signature has no transparency log bundle")
+	}
+
+	if err := s.bundle.verify(rekorKey, s.payload, s.signature); err != nil {
+		return "", nil, err
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range s.chain {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := s.certificate.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   time.Unix(s.bundle.Payload.IntegratedTime, 0),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("verify signature certificate: %w", err)
+	}
+
+	if err := s.verifyWithKey(s.certificate.PublicKey); err != nil {
+		return "", nil, err
+	}
+
+	subjects := append([]string{}, s.certificate.EmailAddresses...)
+	for _, uri := range s.certificate.URIs {
+		subjects = append(subjects, uri.String())
+	}
+
+	return certificateIssuer(s.certificate), subjects, nil
+}
+
+// verify checks the signed entry timestamp of the rekor log and that the log entry is created for the signature
+func (b *rekorBundle) verify(rekorKey crypto.PublicKey, payload, sig []byte) error {
+	canonical, err := json.Marshal(b.Payload)
+	if err != nil {
+		return err
+	}
+
+	key, ok := rekorKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported rekor public key type %T", rekorKey)
+	}
+
+	hash := sha256.Sum256(canonical)
+	if !ecdsa.VerifyASN1(key, hash[:], b.SignedEntryTimestamp) {
+		return errors.New("invalid signed entry timestamp of the transparency log bundle")
+	}
+
+	body, err := base64.StdEncoding.DecodeString(b.Payload.Body)
+	if err != nil {
+		return fmt.Errorf("decode transparency log entry: %w", err)
+	}
+
+	var entry hashedRekord
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return fmt.Errorf("unmarshal transparency log entry: %w", err)
+	}
+
+	if entry.Kind != "hashedrekord" {
+		return fmt.Errorf("unsupported transparency log entry kind %q", entry.Kind)
+	}
+
+	payloadHash := sha256.Sum256(payload)
+	if entry.Spec.Data.Hash.Algorithm != "sha256" || entry.Spec.Data.Hash.Value != hex.EncodeToString(payloadHash[:]) {
+		return errors.New("transparency log entry is created for the other payload")
+	}
+
+	if entry.Spec.Signature.Content != base64.StdEncoding.EncodeToString(sig) {
+		return errors.New("transparency log entry is created for the other signature")
+	}
+
+	return nil
+}
+
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err == nil {
+				return issuer
+			}
+		case ext.Id.Equal(oidIssuerV1):
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+func parsePublicKey(data string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func parseCertificates(data string) ([]*x509.Certificate, error) {
+	rest := bytes.TrimSpace([]byte(data))
+	certs := make([]*x509.Certificate, 0)
+
+	for len(rest) > 0 {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, errors.New("certificate is not PEM encoded")
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+		rest = bytes.TrimSpace(rest)
+	}
+
+	return certs, nil
+}
@@ -0,0 +1,266 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verifier
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"k8s.io/klog/v2"
+
+	"image-signature-provider/externaldata"
+)
+
+const (
+	verifiedMessage = "image signature is verified"
+	noRulesMessage  = "no signature verification rules for the image"
+)
+
+type Verifier struct {
+	config   *ConfigLoader
+	cache    *cache
+	keychain authn.Keychain
+	// options are added to the registry requests, they are used in the tests
+	options []remote.Option
+}
+
+func NewVerifier(config *ConfigLoader, cacheTTL time.Duration) *Verifier {
+	return &Verifier{
+		config:   config,
+		cache:    newCache(cacheTTL),
+		keychain: authn.DefaultKeychain,
+	}
+}
+
+// Validate verifies the images of the gatekeeper external data request.
+// The keys have the `<OperationPolicy name>/<image>` format, the policy name cannot contain slashes.
+func (v *Verifier) Validate(ctx context.Context, data []byte) externaldata.Response {
+	var providerRequest externaldata.ProviderRequest
+	if err := json.Unmarshal(data, &providerRequest); err != nil {
+		return externaldata.Response{SystemError: fmt.Sprintf("unable to unmarshal data to ProviderRequest: %v", err)}
+	}
+
+	config, err := v.config.Load()
+	if err != nil {
+		klog.Errorf("Load config: %v", err)
+		return externaldata.Response{SystemError: err.Error()}
+	}
+
+	trust, err := newKeylessTrust(config.Keyless)
+	if err != nil {
+		klog.Errorf("Load keyless trusted roots: %v", err)
+		return externaldata.Response{SystemError: err.Error()}
+	}
+
+	items := make([]externaldata.Item, 0, len(providerRequest.Request.Keys))
+	for _, key := range providerRequest.Request.Keys {
+		item := externaldata.Item{Key: key}
+
+		policy, image, ok := strings.Cut(key, "/")
+		if !ok {
+			item.Error = "invalid key, <policy>/<image> is expected"
+			items = append(items, item)
+			continue
+		}
+
+		message, err := v.verifyImage(ctx, config, trust, policy, image)
+		if err != nil {
+			klog.Infof("Image %s of the policy %s is rejected: %v", image, policy, err)
+			item.Error = err.Error()
+		} else {
+			item.Value = message
+		}
+		items = append(items, item)
+	}
+
+	return externaldata.Response{Items: items}
+}
+
+func (v *Verifier) verifyImage(ctx context.Context, config *Config, trust *keylessTrust, policy, image string) (string, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", fmt.Errorf("parse image reference: %w", err)
+	}
+
+	rules := config.matchingRules(policy, ref.Context().Name())
+	if len(rules) == 0 {
+		return noRulesMessage, nil
+	}
+
+	options := append([]remote.Option{remote.WithContext(ctx), remote.WithAuthFromKeychain(v.keychain)}, v.options...)
+
+	digest, err := resolveDigest(ref, options...)
+	if err != nil {
+		return "", err
+	}
+
+	cacheKey := verificationCacheKey(digest, rules, config.Keyless)
+	if v.cache.verified(cacheKey) {
+		return verifiedMessage, nil
+	}
+
+	signatures, err := fetchSignatures(ref.Context(), digest, options...)
+	if err != nil {
+		return "", err
+	}
+
+	for _, rule := range rules {
+		if err := verifyRule(rule, signatures, digest, trust); err != nil {
+			return "", err
+		}
+	}
+
+	v.cache.add(cacheKey)
+
+	return verifiedMessage, nil
+}
+
+func resolveDigest(ref name.Reference, options ...remote.Option) (v1.Hash, error) {
+	if d, ok := ref.(name.Digest); ok {
+		return v1.NewHash(d.DigestStr())
+	}
+
+	desc, err := remote.Head(ref, options...)
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("resolve image digest: %w", err)
+	}
+
+	return desc.Digest, nil
+}
+
+// verifyRule succeeds if one of the signatures is made by the trusted key or the trusted keyless identity of the rule
+func verifyRule(rule Rule, signatures []signature, digest v1.Hash, trust *keylessTrust) error {
+	keys := make([]crypto.PublicKey, 0, len(rule.PublicKeys))
+	for _, data := range rule.PublicKeys {
+		key, err := parsePublicKey(data)
+		if err != nil {
+			return fmt.Errorf("parse public key of the rule %q: %w", rule.Reference, err)
+		}
+		keys = append(keys, key)
+	}
+
+	var lastErr error
+	for i := range signatures {
+		sig := &signatures[i]
+
+		if err := sig.verifyPayload(digest); err != nil {
+			lastErr = err
+			continue
+		}
+
+		for _, key := range keys {
+			err := sig.verifyWithKey(key)
+			if err == nil {
+				return nil
+			}
+			lastErr = err
+		}
+
+		if len(rule.Keyless) == 0 || sig.certificate == nil {
+			continue
+		}
+
+		if trust == nil {
+			lastErr = errors.New("keyless verification is not configured")
+			continue
+		}
+
+		issuer, subjects, err := sig.verifyKeyless(trust.roots, trust.rekorKey)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if identityMatches(rule.Keyless, issuer, subjects) {
+			return nil
+		}
+		lastErr = fmt.Errorf("signer %v issued by %s is not trusted", subjects, issuer)
+	}
+
+	message := fmt.Sprintf("image is not signed by the trusted keys or identities of the rule %q", rule.Reference)
+	if lastErr != nil {
+		message += ": " + lastErr.Error()
+	}
+
+	return errors.New(message)
+}
+
+func identityMatches(identities []Identity, issuer string, subjects []string) bool {
+	for _, identity := range identities {
+		if identity.Issuer != issuer {
+			continue
+		}
+		for _, subject := range subjects {
+			if globMatch(identity.Subject, subject) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type keylessTrust struct {
+	roots    *x509.CertPool
+	rekorKey crypto.PublicKey
+}
+
+// newKeylessTrust returns nil if the trusted roots are not configured
+func newKeylessTrust(config KeylessConfig) (*keylessTrust, error) {
+	if config.FulcioRootCertificates == "" || config.RekorPublicKey == "" {
+		return nil, nil
+	}
+
+	certs, err := parseCertificates(config.FulcioRootCertificates)
+	if err != nil {
+		return nil, fmt.Errorf("parse Fulcio root certificates: %w", err)
+	}
+
+	roots := x509.NewCertPool()
+	for _, cert := range certs {
+		roots.AddCert(cert)
+	}
+
+	rekorKey, err := parsePublicKey(config.RekorPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("parse Rekor public key: %w", err)
+	}
+
+	return &keylessTrust{roots: roots, rekorKey: rekorKey}, nil
+}
+
+// verificationCacheKey changes with the rules, so the cached results are not used after the trusted keys are changed
+func verificationCacheKey(digest v1.Hash, rules []Rule, keyless KeylessConfig) string {
+	data, _ := json.Marshal(struct {
+		Rules   []Rule
+		Keyless KeylessConfig
+	}{rules, keyless})
+
+	hash := sha256.Sum256(data)
+	return digest.String() + "/" + hex.EncodeToString(hash[:])
+}
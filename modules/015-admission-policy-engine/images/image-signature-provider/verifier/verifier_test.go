@@ -0,0 +1,340 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verifier
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"image-signature-provider/externaldata"
+)
+
+const testIssuer = "https://token.actions.githubusercontent.com"
+
+type testRegistry struct {
+	host string
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	server := httptest.NewServer(registry.New())
+	t.Cleanup(server.Close)
+
+	return &testRegistry{host: strings.TrimPrefix(server.URL, "http://")}
+}
+
+// pushImage pushes the random image and returns its digest
+func (r *testRegistry) pushImage(t *testing.T, repo string) v1.Hash {
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+
+	ref, err := name.ParseReference(fmt.Sprintf("%s/%s:latest", r.host, repo))
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	return digest
+}
+
+// pushSignature pushes the cosign signature of the digest with the additional annotations
+func (r *testRegistry) pushSignature(t *testing.T, repo string, digest v1.Hash, sign func(payload []byte) (string, map[string]string)) {
+	payload := []byte(fmt.Sprintf(
+		`{"critical":{"identity":{"docker-reference":"%s/%s"},"image":{"docker-manifest-digest":"%s"},"type":"cosign container image signature"},"optional":null}`,
+		r.host, repo, digest,
+	))
+
+	sig, annotations := sign(payload)
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[signatureAnnotation] = sig
+
+	img, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       static.NewLayer(payload, "application/vnd.dev.cosign.simplesigning.v1+json"),
+		Annotations: annotations,
+	})
+	require.NoError(t, err)
+	img = mutate.MediaType(img, types.OCIManifestSchema1)
+
+	tag, err := name.NewTag(fmt.Sprintf("%s/%s:%s-%s.sig", r.host, repo, digest.Algorithm, digest.Hex))
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(tag, img))
+}
+
+func signWithKey(t *testing.T, key *ecdsa.PrivateKey) func([]byte) (string, map[string]string) {
+	return func(payload []byte) (string, map[string]string) {
+		hash := sha256.Sum256(payload)
+		sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+		require.NoError(t, err)
+		return base64.StdEncoding.EncodeToString(sig), nil
+	}
+}
+
+func publicKeyPEM(t *testing.T, key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func certificatePEM(der []byte) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func newTestVerifier(t *testing.T, config *Config) *Verifier {
+	data, err := json.Marshal(config)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	return NewVerifier(NewConfigLoader(path), time.Hour)
+}
+
+func validate(t *testing.T, v *Verifier, keys ...string) []externaldata.Item {
+	data, err := json.Marshal(externaldata.ProviderRequest{Request: externaldata.Request{Keys: keys}})
+	require.NoError(t, err)
+
+	response := v.Validate(context.Background(), data)
+	require.Empty(t, response.SystemError)
+	require.Len(t, response.Items, len(keys))
+
+	return response.Items
+}
+
+func TestVerifyWithPublicKey(t *testing.T) {
+	reg := newTestRegistry(t)
+	trustedKey, otherKey := newKey(t), newKey(t)
+
+	signedDigest := reg.pushImage(t, "team/signed")
+	reg.pushSignature(t, "team/signed", signedDigest, signWithKey(t, trustedKey))
+
+	reg.pushImage(t, "team/unsigned")
+
+	otherDigest := reg.pushImage(t, "team/other")
+	reg.pushSignature(t, "team/other", otherDigest, signWithKey(t, otherKey))
+
+	reg.pushImage(t, "public/app")
+
+	v := newTestVerifier(t, &Config{Policies: map[string][]Rule{
+		"signed-images": {{Reference: reg.host + "/team/*", PublicKeys: []string{publicKeyPEM(t, &trustedKey.PublicKey)}}},
+	}})
+
+	items := validate(t, v,
+		"signed-images/"+reg.host+"/team/signed:latest",
+		"signed-images/"+reg.host+"/team/signed@"+signedDigest.String(),
+		"signed-images/"+reg.host+"/team/unsigned:latest",
+		"signed-images/"+reg.host+"/team/other:latest",
+		"signed-images/"+reg.host+"/public/app:latest",
+		"unknown-policy/"+reg.host+"/team/unsigned:latest",
+	)
+
+	assert.Equal(t, verifiedMessage, items[0].Value)
+	assert.Empty(t, items[0].Error)
+	assert.Equal(t, verifiedMessage, items[1].Value)
+	assert.Equal(t, "image is not signed", items[2].Error)
+	assert.Contains(t, items[3].Error, "image is not signed by the trusted keys or identities of the rule")
+	assert.Equal(t, noRulesMessage, items[4].Value)
+	assert.Equal(t, noRulesMessage, items[5].Value)
+
+	assert.Len(t, v.cache.entries, 1, "the verified digest must be cached")
+}
+
+func TestVerifyKeyless(t *testing.T) {
+	reg := newTestRegistry(t)
+
+	rootKey, rekorKey, signingKey := newKey(t), newKey(t), newKey(t)
+	now := time.Now()
+
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "sigstore"},
+		NotBefore:             now.Add(-3 * time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	require.NoError(t, err)
+	root, err := x509.ParseCertificate(rootDER)
+	require.NoError(t, err)
+
+	issuerValue, err := asn1.MarshalWithParams(testIssuer, "utf8")
+	require.NoError(t, err)
+
+	// the certificate is valid for ten minutes only, it is verified at the time of the transparency log entry
+	leafTemplate := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       now.Add(-2 * time.Hour),
+		NotAfter:        now.Add(-2*time.Hour + 10*time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		EmailAddresses:  []string{"release@example.com"},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuerValue}},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, root, &signingKey.PublicKey, rootKey)
+	require.NoError(t, err)
+
+	signKeyless := func(payload []byte) (string, map[string]string) {
+		hash := sha256.Sum256(payload)
+		sig, err := ecdsa.SignASN1(rand.Reader, signingKey, hash[:])
+		require.NoError(t, err)
+		encodedSig := base64.StdEncoding.EncodeToString(sig)
+
+		body, err := json.Marshal(map[string]interface{}{
+			"apiVersion": "0.0.1",
+			"kind":       "hashedrekord",
+			"spec": map[string]interface{}{
+				"data":      map[string]interface{}{"hash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(hash[:])}},
+				"signature": map[string]interface{}{"content": encodedSig},
+			},
+		})
+		require.NoError(t, err)
+
+		bundle := rekorBundle{Payload: rekorPayload{
+			Body:           base64.StdEncoding.EncodeToString(body),
+			IntegratedTime: now.Add(-2*time.Hour + 5*time.Minute).Unix(),
+			LogID:          "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d",
+			LogIndex:       42,
+		}}
+		canonical, err := json.Marshal(bundle.Payload)
+		require.NoError(t, err)
+		canonicalHash := sha256.Sum256(canonical)
+		bundle.SignedEntryTimestamp, err = ecdsa.SignASN1(rand.Reader, rekorKey, canonicalHash[:])
+		require.NoError(t, err)
+
+		bundleJSON, err := json.Marshal(bundle)
+		require.NoError(t, err)
+
+		return encodedSig, map[string]string{
+			certificateAnnotation: certificatePEM(leafDER),
+			chainAnnotation:       certificatePEM(rootDER),
+			bundleAnnotation:      string(bundleJSON),
+		}
+	}
+
+	digest := reg.pushImage(t, "team/keyless")
+	reg.pushSignature(t, "team/keyless", digest, signKeyless)
+
+	keyless := KeylessConfig{
+		FulcioRootCertificates: certificatePEM(rootDER),
+		RekorPublicKey:         publicKeyPEM(t, &rekorKey.PublicKey),
+	}
+	image := reg.host + "/team/keyless:latest"
+
+	t.Run("trusted identity", func(t *testing.T) {
+		v := newTestVerifier(t, &Config{
+			Policies: map[string][]Rule{"keyless": {{
+				Reference: reg.host + "/team/*",
+				Keyless:   []Identity{{Issuer: testIssuer, Subject: "*@example.com"}},
+			}}},
+			Keyless: keyless,
+		})
+
+		items := validate(t, v, "keyless/"+image)
+		assert.Empty(t, items[0].Error)
+		assert.Equal(t, verifiedMessage, items[0].Value)
+	})
+
+	t.Run("untrusted identity", func(t *testing.T) {
+		v := newTestVerifier(t, &Config{
+			Policies: map[string][]Rule{"keyless": {{
+				Reference: reg.host + "/team/*",
+				Keyless:   []Identity{{Issuer: testIssuer, Subject: "admin@example.com"}},
+			}}},
+			Keyless: keyless,
+		})
+
+		items := validate(t, v, "keyless/"+image)
+		assert.Contains(t, items[0].Error, "signer [release@example.com] issued by "+testIssuer+" is not trusted")
+	})
+
+	t.Run("other rekor key", func(t *testing.T) {
+		v := newTestVerifier(t, &Config{
+			Policies: map[string][]Rule{"keyless": {{
+				Reference: reg.host + "/team/*",
+				Keyless:   []Identity{{Issuer: testIssuer, Subject: "*@example.com"}},
+			}}},
+			Keyless: KeylessConfig{
+				FulcioRootCertificates: certificatePEM(rootDER),
+				RekorPublicKey:         publicKeyPEM(t, &newKey(t).PublicKey),
+			},
+		})
+
+		items := validate(t, v, "keyless/"+image)
+		assert.Contains(t, items[0].Error, "invalid signed entry timestamp")
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		v := newTestVerifier(t, &Config{
+			Policies: map[string][]Rule{"keyless": {{
+				Reference: reg.host + "/team/*",
+				Keyless:   []Identity{{Issuer: testIssuer, Subject: "*@example.com"}},
+			}}},
+		})
+
+		items := validate(t, v, "keyless/"+image)
+		assert.Contains(t, items[0].Error, "keyless verification is not configured")
+	})
+}
+
+func TestGlobMatch(t *testing.T) {
+	assert.True(t, globMatch("registry.example.com/*", "registry.example.com/team/app"))
+	assert.True(t, globMatch("*/team/app", "registry.example.com/team/app"))
+	assert.True(t, globMatch("registry.example.com/team/app", "registry.example.com/team/app"))
+	assert.False(t, globMatch("registry.example.com/team/app", "registry.example.com/team/app2"))
+	assert.False(t, globMatch("registry.example.com/*", "registry.example.org/team/app"))
+	assert.False(t, globMatch("registry.example.com/team.app", "registry.example.com/teamxapp"))
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"image-signature-provider/externaldata"
+)
+
+type validator interface {
+	Validate(ctx context.Context, data []byte) externaldata.Response
+}
+
+type Handler struct {
+	v       validator
+	timeout time.Duration
+}
+
+func NewHandler(v validator, timeout time.Duration) *Handler {
+	return &Handler{
+		v:       v,
+		timeout: timeout,
+	}
+}
+
+func (h *Handler) HandleRequest() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			h.sendResponse(errHelper("only POST requests are allowed"), w)
+			return
+		}
+
+		requestBody, err := io.ReadAll(r.Body)
+		if err != nil {
+			h.sendResponse(errHelper(fmt.Sprintf("unable to read request body: %v", err)), w)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+		defer cancel()
+
+		h.sendResponse(h.v.Validate(ctx, requestBody), w)
+	}
+}
+
+func (h *Handler) sendResponse(response externaldata.Response, w http.ResponseWriter) {
+	providerResponse := externaldata.ProviderResponse{
+		APIVersion: externaldata.APIVersion,
+		Kind:       "ProviderResponse",
+		Response:   response,
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(providerResponse); err != nil {
+		klog.Errorf("Can't write response to gatekeeper: %v", err)
+	}
+}
+
+func errHelper(errMsg string) externaldata.Response {
+	return externaldata.Response{SystemError: errMsg}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template_tests
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/deckhouse/deckhouse/testing/helm"
+)
+
+var _ = Describe("Module :: admissionPolicyEngine :: helm template :: mutation policies", func() {
+	f := SetupHelmConfig(`{admissionPolicyEngine: {podSecurityStandards: {}, internal: {"bootstrapped": true, "podSecurityStandards": {"enforcementActions": ["deny"]}, "mutationPolicies": [
+{
+	"metadata":{"name":"defaults"},
+	"spec":{
+		"mode":"Apply",
+		"mutations":{"imagePullPolicy":"Always","labels":{"team":"backend"}},
+		"match":{"namespaceSelector":{"matchNames":["default"]}}},
+	"mutators":[
+		{"kind":"Assign","name":"defaults-image-pull-policy-619458bd","location":"spec.containers[name:*].imagePullPolicy","parameters":{"assign":{"value":"Always"}}},
+		{"kind":"AssignMetadata","name":"defaults-label-46c034c8","location":"metadata.labels.team","parameters":{"assign":{"value":"backend"}}}
+	]},
+{
+	"metadata":{"name":"preview"},
+	"spec":{
+		"mode":"DryRun",
+		"mutations":{"imagePullPolicy":"Always"},
+		"match":{"namespaceSelector":{"matchNames":["default"]}}},
+	"mutators":[]}],
+		"trackedConstraintResources": [{"apiGroups":[""],"resources":["pods"]}],
+		"trackedMutateResources": [{"apiGroups":[""],"resources":["pods"]}],
+		"webhook": {ca: YjY0ZW5jX3N0cmluZwo=, crt: YjY0ZW5jX3N0cmluZwo=, key: YjY0ZW5jX3N0cmluZwo=}}}}`)
+
+	Context("Cluster with mutation policies", func() {
+		BeforeEach(func() {
+			f.ValuesSetFromYaml("global", globalValues)
+			f.ValuesSet("global.modulesImages", GetModulesImages())
+			f.HelmRender()
+		})
+
+		It("Everything must render properly", func() {
+			Expect(f.RenderError).ShouldNot(HaveOccurred())
+
+			assign := f.KubernetesGlobalResource("Assign", "defaults-image-pull-policy-619458bd")
+			Expect(assign.Exists()).To(BeTrue())
+			Expect(assign.Field("spec.location").String()).To(Equal("spec.containers[name:*].imagePullPolicy"))
+			Expect(assign.Field("spec.applyTo").String()).To(MatchJSON(`[{"groups":[""],"kinds":["Pod"],"versions":["v1"]}]`))
+			Expect(assign.Field("spec.match.namespaces").String()).To(MatchJSON(`["default"]`))
+
+			assignMetadata := f.KubernetesGlobalResource("AssignMetadata", "defaults-label-46c034c8")
+			Expect(assignMetadata.Exists()).To(BeTrue())
+			Expect(assignMetadata.Field("spec.applyTo").Exists()).To(BeFalse())
+
+			Expect(f.KubernetesGlobalResource("D8MutationDryRun", "defaults").Exists()).To(BeFalse())
+
+			dryRun := f.KubernetesGlobalResource("D8MutationDryRun", "preview")
+			Expect(dryRun.Exists()).To(BeTrue())
+			Expect(dryRun.Field("spec.enforcementAction").String()).To(Equal("dryrun"))
+			Expect(dryRun.Field("spec.parameters.mutations").String()).To(MatchJSON(`{"imagePullPolicy":"Always"}`))
+		})
+	})
+})
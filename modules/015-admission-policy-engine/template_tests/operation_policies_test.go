@@ -59,11 +59,15 @@ var _ = Describe("Module :: admissionPolicyEngine :: helm template :: operation
 			"replicaLimits":{
 					"minReplicas":1,
 					"maxReplicas":10
-			}
+			},
+			"verifyImageSignatures":[
+				{"reference":"registry.example.com/*","publicKeys":["-----BEGIN PUBLIC KEY-----"]}
+			]
 		},
 		"match":{"namespaceSelector":{"matchNames":["default"]}}}}],
 		"trackedConstraintResources": [{"apiGroups":[""],"resources":["pods"]},{"apiGroups":["extensions","networking.k8s.io"],"resources":["ingresses"]}],
 		"trackedMutateResources": [{"apiGroups":[""],"resources":["pods"]},{"apiGroups":["extensions","networking.k8s.io"],"resources":["ingresses"]}],
+		"imageSignatureVerification": {"webhook": {ca: YjY0ZW5jX3N0cmluZwo=, crt: YjY0ZW5jX3N0cmluZwo=, key: YjY0ZW5jX3N0cmluZwo=}, "dockerConfigJson": {"auths": {}}},
 		"webhook": {ca: YjY0ZW5jX3N0cmluZwo=, crt: YjY0ZW5jX3N0cmluZwo=, key: YjY0ZW5jX3N0cmluZwo=}}}}`)
 
 	Context("Cluster with operation policies", func() {
@@ -88,7 +92,14 @@ var _ = Describe("Module :: admissionPolicyEngine :: helm template :: operation
 			Expect(f.KubernetesGlobalResource("D8RequiredAnnotations", testPolicyName).Exists()).To(BeTrue())
 			Expect(f.KubernetesGlobalResource("D8ContainerDuplicates", testPolicyName).Exists()).To(BeTrue())
 			Expect(f.KubernetesGlobalResource("D8ReplicaLimits", testPolicyName).Exists()).To(BeTrue())
+			Expect(f.KubernetesGlobalResource("D8VerifyImageSignatures", testPolicyName).Exists()).To(BeTrue())
+
+			Expect(f.KubernetesGlobalResource("D8VerifyImageSignatures", testPolicyName).Field("spec.parameters.policy").String()).To(Equal(testPolicyName))
+			Expect(f.KubernetesGlobalResource("Provider", "image-signature-provider").Exists()).To(BeTrue())
+			Expect(f.KubernetesResource("Deployment", "d8-admission-policy-engine", "image-signature-provider").Exists()).To(BeTrue())
 
+			config := f.KubernetesResource("ConfigMap", "d8-admission-policy-engine", "image-signature-provider").Field(`data.config\.json`).String()
+			Expect(config).To(MatchJSON(`{"keyless":{},"policies":{"genpolicy":[{"reference":"registry.example.com/*","publicKeys":["-----BEGIN PUBLIC KEY-----"]}]}}`))
 		})
 	})
 })
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	. "github.com/onsi/ginkgo"
@@ -78,6 +79,58 @@ admissionPolicyEngine:
 	})
 })
 
+var _ = Describe("Module :: admissionPolicyEngine :: constraint templates ::", func() {
+	const chartDir = "../charts/constraint-templates"
+
+	It("Policy exceptions must be the same as in the lib directory", func() {
+		templates, err := filepath.Glob(filepath.Join(chartDir, "templates", "*", "*.yaml"))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(templates).NotTo(BeEmpty())
+
+		for part, file := range map[string]string{"schema": "exceptions-schema.yaml", "lib": "exceptions.rego"} {
+			expected, err := os.ReadFile(filepath.Join(chartDir, "lib", file))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			for _, template := range templates {
+				content, err := os.ReadFile(template)
+				Expect(err).ShouldNot(HaveOccurred())
+				if !strings.Contains(string(content), "data.lib.exceptions.exempted") {
+					continue
+				}
+
+				Expect(placeholderContent(string(content), part)).To(Equal(string(expected)),
+					"%s is outdated, run `make generate` to copy the policy exceptions %s", template, part)
+			}
+		}
+	})
+})
+
+// placeholderContent returns the unindented lines between the placeholder comments
+func placeholderContent(content, part string) string {
+	startMarker := fmt.Sprintf("# start policy exceptions %s placeholder", part)
+	endMarker := fmt.Sprintf("# end policy exceptions %s placeholder", part)
+
+	var (
+		lines  []string
+		indent string
+		inside bool
+	)
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == startMarker:
+			inside = true
+			indent = line[:len(line)-len(strings.TrimLeft(line, " "))]
+		case trimmed == endMarker:
+			return strings.Join(lines, "\n") + "\n"
+		case inside:
+			lines = append(lines, strings.TrimPrefix(line, indent))
+		}
+	}
+
+	return ""
+}
+
 func gatorAvailable() (string, bool) {
 	gatorPath, err := exec.LookPath("gator")
 	if err != nil {
@@ -27,6 +27,10 @@ var _ = Describe("Module :: admissionPolicyEngine :: helm template :: security p
 	f := SetupHelmConfig(`{admissionPolicyEngine: {podSecurityStandards: {}, internal: {"bootstrapped": true, "podSecurityStandards": {"enforcementActions": ["deny"]}, "securityPolicies": [
 {
 	"metadata":{"name":"genpolicy"},
+	"exceptions":{
+		"D8HostNetwork":[{"namespace":"legacy","kind":"Pod","labelSelector":{"matchLabels":{"app":"legacy-app"}}}],
+		"D8PrivilegedContainer":[{"namespace":"legacy","kind":"Pod","name":"legacy-app"}]
+	},
 	"spec":{
 		"policies":{
 				"allowHostIPC": true,
@@ -80,5 +84,19 @@ var _ = Describe("Module :: admissionPolicyEngine :: helm template :: security p
 			Expect(f.KubernetesGlobalResource("D8SeLinux", testPolicyName).Exists()).To(BeTrue())
 			Expect(f.KubernetesGlobalResource("D8AppArmor", testPolicyName).Exists()).To(BeTrue())
 		})
+
+		It("Policy exceptions must be rendered into the constraints", func() {
+			Expect(f.RenderError).ShouldNot(HaveOccurred())
+
+			hostNetwork := f.KubernetesGlobalResource("D8HostNetwork", testPolicyName)
+			Expect(hostNetwork.Field("spec.parameters.exceptions").String()).To(MatchJSON(`[{"namespace":"legacy","kind":"Pod","labelSelector":{"matchLabels":{"app":"legacy-app"}}}]`))
+			Expect(hostNetwork.Field("spec.parameters.allowHostNetwork").Bool()).To(BeFalse())
+
+			privileged := f.KubernetesGlobalResource("D8PrivilegedContainer", testPolicyName)
+			Expect(privileged.Field("spec.parameters.exceptions").String()).To(MatchJSON(`[{"namespace":"legacy","kind":"Pod","name":"legacy-app"}]`))
+
+			Expect(f.KubernetesGlobalResource("D8AllowPrivilegeEscalation", testPolicyName).Field("spec.parameters").Exists()).To(BeFalse())
+			Expect(f.KubernetesGlobalResource("D8AllowedHostPaths", testPolicyName).Field("spec.parameters.exceptions").Exists()).To(BeFalse())
+		})
 	})
 })
@@ -21,6 +21,26 @@
     {{- end }}
 {{- end }}
 
+{{- define "constraint_exceptions" }}
+    {{- $cr := index . 0 }}
+    {{- $kind := index . 1 }}
+
+    {{- if and $cr.exceptions (hasKey $cr.exceptions $kind) }}
+    exceptions:
+      {{- index $cr.exceptions $kind | toYaml | nindent 6 }}
+    {{- end }}
+{{- end }}
+
+{{- define "constraint_exceptions_parameters" }}
+    {{- $cr := index . 0 }}
+    {{- $kind := index . 1 }}
+
+    {{- if and $cr.exceptions (hasKey $cr.exceptions $kind) }}
+  parameters:
+      {{- include "constraint_exceptions" (list $cr $kind) }}
+    {{- end }}
+{{- end }}
+
 {{- define "pod_security_standard_baseline" }}
   {{- $context := index . 0 }}
   {{- $policyCRDName := index . 1 }}
@@ -112,3 +132,17 @@ spec:
   {{- end }}
   {{- print "" }}
 {{- end }}
+
+{{- define "image.signature.provider.enabled" }}
+  {{- $context := . }}
+  {{- $enabled := false }}
+  {{- range $cr := $context.Values.admissionPolicyEngine.internal.operationPolicies }}
+    {{- if hasKey $cr.spec.policies "verifyImageSignatures" }}
+      {{- $enabled = true }}
+    {{- end }}
+  {{- end }}
+  {{- if $enabled }}
+    {{- print "true" }}
+  {{- end }}
+  {{- print "" }}
+{{- end }}
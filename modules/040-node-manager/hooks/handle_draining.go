@@ -18,11 +18,14 @@ package hooks
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook/metrics"
 	"github.com/flant/addon-operator/sdk"
 	"github.com/flant/shell-operator/pkg/kube/object_patch"
 	"github.com/sirupsen/logrus"
@@ -35,11 +38,19 @@ import (
 
 	"github.com/deckhouse/deckhouse/go_lib/dependency"
 	"github.com/deckhouse/deckhouse/go_lib/dependency/k8s/drain"
+	"github.com/deckhouse/deckhouse/modules/040-node-manager/hooks/internal/conditions"
+	ngv1 "github.com/deckhouse/deckhouse/modules/040-node-manager/hooks/internal/v1"
 )
 
 const (
 	drainingAnnotationKey = "update.node.deckhouse.io/draining"
 	drainedAnnotationKey  = "update.node.deckhouse.io/drained"
+
+	// the drain helper gives up evicting pods after this timeout, the drain is retried on the next hook run
+	drainAttemptTimeout    = 5 * time.Minute
+	drainInProgressMessage = "The node is being drained."
+	// maximum length of the Node condition message
+	drainConditionMessageLimit = 1024
 )
 
 var _ = sdk.RegisterFunc(&go_hook.HookConfig{
@@ -62,6 +73,15 @@ var _ = sdk.RegisterFunc(&go_hook.HookConfig{
 			},
 			FilterFunc: drainFilter,
 		},
+		{
+			Name:                         "ngs",
+			WaitForSynchronization:       pointer.Bool(true),
+			ExecuteHookOnSynchronization: pointer.Bool(false),
+			ExecuteHookOnEvents:          pointer.Bool(false),
+			ApiVersion:                   "deckhouse.io/v1",
+			Kind:                         "NodeGroup",
+			FilterFunc:                   drainFilterNodeGroup,
+		},
 	},
 	Settings: &go_hook.HookConfigSettings{
 		ExecutionMinInterval: 30 * time.Second,
@@ -98,17 +118,53 @@ func drainFilter(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
 		}
 	}
 
+	var drainCondition *corev1.NodeCondition
+	for _, c := range node.Status.Conditions {
+		if c.Type == conditions.NodeConditionTypeDraining {
+			drainCondition = c.DeepCopy()
+			break
+		}
+	}
+
 	return drainingNode{
 		Name:           node.Name,
+		NodeGroup:      node.Labels["node.deckhouse.io/group"],
 		DrainingSource: drainingSource,
 		DrainedSource:  drainedSource,
 		Unschedulable:  node.Spec.Unschedulable,
+		DrainCondition: drainCondition,
+	}, nil
+}
+
+func drainFilterNodeGroup(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	var ng ngv1.NodeGroup
+
+	err := sdk.FromUnstructured(obj, &ng)
+	if err != nil {
+		return nil, err
+	}
+
+	var timeout time.Duration
+	if ng.Spec.Disruptions.DrainTimeout != "" {
+		timeout, err = time.ParseDuration(ng.Spec.Disruptions.DrainTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse spec.disruptions.drainTimeout of NodeGroup %s: %v", ng.Name, err)
+		}
+	}
+
+	return drainNodeGroup{
+		Name:         ng.Name,
+		DrainTimeout: timeout,
 	}, nil
 }
 
 // Drain nodes: If node is marked for draining – drain it!
 // all nodes in one node group drain concurrently. If we need to limit this behavior - put here some queue implementation
+// The drain progress is reported in the Draining condition of the node. If the drain is not completed
+// within the drainTimeout of the NodeGroup, the node is marked as stuck and the drain is not retried anymore.
 func handleDraining(input *go_hook.HookInput, dc dependency.Container) error {
+	input.MetricsCollector.Expire("node_drain")
+
 	k8sCli, err := dc.GetK8sClient()
 	if err != nil {
 		return err
@@ -125,6 +181,14 @@ func handleDraining(input *go_hook.HookInput, dc dependency.Container) error {
 	drainHelper := drain.NewDrainer(k8sCli, errOut)
 	drainHelper.Ctx = context.Background()
 
+	drainTimeouts := make(map[string]time.Duration)
+	for _, s := range input.Snapshots["ngs"] {
+		ng := s.(drainNodeGroup)
+		drainTimeouts[ng.Name] = ng.DrainTimeout
+	}
+
+	now := time.Now()
+
 	var wg = &sync.WaitGroup{}
 	drainingNodesC := make(chan drainedNodeRes, 1)
 
@@ -136,6 +200,32 @@ func handleDraining(input *go_hook.HookInput, dc dependency.Container) error {
 			if !dNode.Unschedulable && dNode.DrainedSource == "user" {
 				input.PatchCollector.MergePatch(removeDrainedAnnotation, "v1", "Node", "", dNode.Name)
 			}
+			// If the 'draining' annotation was removed before the drain completion, the drain is canceled
+			if dNode.drainStartedAt() != nil {
+				setNodeDrainCondition(input.PatchCollector, dNode.Name, corev1.ConditionFalse, conditions.DrainReasonCanceled, "", now)
+			}
+			continue
+		}
+
+		startedAt := now
+		if t := dNode.drainStartedAt(); t != nil {
+			startedAt = *t
+		}
+
+		if dNode.isStuck() {
+			setDrainMetrics(input, dNode, startedAt, now, true)
+			continue
+		}
+
+		drainTimeout := drainTimeouts[dNode.NodeGroup]
+		if drainTimeout > 0 && now.Sub(startedAt) >= drainTimeout {
+			input.LogEntry.Errorf("node %q was not drained within %s, the drain is stuck", dNode.Name, drainTimeout)
+			message := fmt.Sprintf("The node was not drained within %s.", drainTimeout)
+			if dNode.DrainCondition.Message != "" {
+				message += " " + dNode.DrainCondition.Message
+			}
+			setNodeDrainCondition(input.PatchCollector, dNode.Name, corev1.ConditionTrue, conditions.DrainReasonStuck, message, startedAt)
+			setDrainMetrics(input, dNode, startedAt, now, true)
 			continue
 		}
 
@@ -144,6 +234,11 @@ func handleDraining(input *go_hook.HookInput, dc dependency.Container) error {
 			input.PatchCollector.MergePatch(removeDrainedAnnotation, "v1", "Node", "", dNode.Name)
 		}
 
+		// Report the started drain, the condition of the running drain is updated after every attempt
+		if dNode.drainStartedAt() == nil {
+			setNodeDrainCondition(input.PatchCollector, dNode.Name, corev1.ConditionTrue, conditions.DrainReasonInProgress, drainInProgressMessage, startedAt)
+		}
+
 		cordonNode := &corev1.Node{
 			TypeMeta: v1.TypeMeta{
 				Kind:       "Node",
@@ -160,16 +255,25 @@ func handleDraining(input *go_hook.HookInput, dc dependency.Container) error {
 			continue
 		}
 
+		// every node has its own helper to record the eviction progress separately
+		nodeDrainHelper := *drainHelper
+		nodeDrainHelper.Progress = drain.NewProgress()
+		nodeDrainHelper.Timeout = drainAttemptTimeoutLeft(startedAt, drainTimeout, now)
+
 		wg.Add(1)
-		go func(node drainingNode) {
-			err = drain.RunNodeDrain(drainHelper, node.Name)
+		go func(node drainingNode, helper *drain.Helper, startedAt time.Time, drainTimeout time.Duration) {
+			err := drain.RunNodeDrain(helper, node.Name)
 			drainingNodesC <- drainedNodeRes{
 				NodeName:       node.Name,
 				DrainingSource: node.DrainingSource,
+				Node:           node,
+				StartedAt:      startedAt,
+				DrainTimeout:   drainTimeout,
+				Progress:       helper.Progress,
 				Err:            err,
 			}
 			wg.Done()
-		}(dNode)
+		}(dNode, &nodeDrainHelper, startedAt, drainTimeout)
 	}
 
 	go func() {
@@ -182,14 +286,100 @@ func handleDraining(input *go_hook.HookInput, dc dependency.Container) error {
 			input.LogEntry.Errorf("node %q drain failed: %s", drainedNode.NodeName, drainedNode.Err)
 			event := drainedNode.buildEvent()
 			input.PatchCollector.Create(event, object_patch.UpdateIfExists())
+
+			finishedAt := time.Now()
+			reason, message := drainedNode.conditionReasonAndMessage(finishedAt)
+			setNodeDrainCondition(input.PatchCollector, drainedNode.NodeName, corev1.ConditionTrue, reason, message, drainedNode.StartedAt)
+			setDrainMetrics(input, drainedNode.Node, drainedNode.StartedAt, finishedAt, reason == conditions.DrainReasonStuck)
+
+			for _, pod := range drainedNode.Progress.BlockedPods() {
+				for _, pdb := range pod.BlockingPDBs {
+					input.MetricsCollector.Set("d8_node_drain_blocked_pod_eviction_attempts", float64(pod.Attempts), map[string]string{
+						"node":       drainedNode.NodeName,
+						"node_group": drainedNode.Node.NodeGroup,
+						"namespace":  pod.Namespace,
+						"pod":        pod.Name,
+						"pdb":        pdb,
+					}, metrics.WithGroup("node_drain"))
+				}
+			}
 			continue
 		}
 		input.PatchCollector.MergePatch(newDrainedAnnotationPatch(drainedNode.DrainingSource), "v1", "Node", "", drainedNode.NodeName)
+		setNodeDrainCondition(input.PatchCollector, drainedNode.NodeName, corev1.ConditionFalse, conditions.DrainReasonDrained, "", time.Now())
 	}
 
 	return nil
 }
 
+// drainAttemptTimeoutLeft returns the timeout of the drain attempt, it does not exceed the time left until the drainTimeout of the NodeGroup.
+func drainAttemptTimeoutLeft(startedAt time.Time, drainTimeout time.Duration, now time.Time) time.Duration {
+	if drainTimeout > 0 && drainTimeout-now.Sub(startedAt) < drainAttemptTimeout {
+		return drainTimeout - now.Sub(startedAt)
+	}
+
+	return drainAttemptTimeout
+}
+
+func setDrainMetrics(input *go_hook.HookInput, node drainingNode, startedAt, now time.Time, stuck bool) {
+	labels := map[string]string{
+		"node":       node.Name,
+		"node_group": node.NodeGroup,
+	}
+
+	var stuckValue float64
+	if stuck {
+		stuckValue = 1
+	}
+
+	input.MetricsCollector.Set("d8_node_drain_duration_seconds", now.Sub(startedAt).Seconds(), labels, metrics.WithGroup("node_drain"))
+	input.MetricsCollector.Set("d8_node_drain_stuck", stuckValue, labels, metrics.WithGroup("node_drain"))
+}
+
+// setNodeDrainCondition sets the Draining condition of the node. The last transition time is changed only if the status is changed.
+func setNodeDrainCondition(patchCollector *object_patch.PatchCollector, nodeName string, status corev1.ConditionStatus, reason, message string, transitionTime time.Time) {
+	if len(message) > drainConditionMessageLimit {
+		message = message[:drainConditionMessageLimit]
+	}
+
+	patchCollector.Filter(func(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+		var node corev1.Node
+		err := sdk.FromUnstructured(obj, &node)
+		if err != nil {
+			return nil, err
+		}
+
+		newCondition := corev1.NodeCondition{
+			Type:               conditions.NodeConditionTypeDraining,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+			LastHeartbeatTime:  v1.NewTime(time.Now()),
+			LastTransitionTime: v1.NewTime(transitionTime),
+		}
+
+		found := false
+		for i, c := range node.Status.Conditions {
+			if c.Type != conditions.NodeConditionTypeDraining {
+				continue
+			}
+
+			if c.Status == status {
+				newCondition.LastTransitionTime = c.LastTransitionTime
+			}
+			node.Status.Conditions[i] = newCondition
+			found = true
+			break
+		}
+
+		if !found {
+			node.Status.Conditions = append(node.Status.Conditions, newCondition)
+		}
+
+		return sdk.ToUnstructured(&node)
+	}, "v1", "Node", "", nodeName, object_patch.WithSubresource("/status"), object_patch.IgnoreMissingObject())
+}
+
 func newDrainedAnnotationPatch(source string) map[string]interface{} {
 	return map[string]interface{}{
 		"metadata": map[string]interface{}{
@@ -213,9 +403,11 @@ var (
 
 type drainingNode struct {
 	Name           string
+	NodeGroup      string
 	DrainingSource string
 	DrainedSource  string
 	Unschedulable  bool
+	DrainCondition *corev1.NodeCondition
 }
 
 func (dn drainingNode) isDraining() bool {
@@ -226,10 +418,58 @@ func (dn drainingNode) isDrained() bool {
 	return dn.DrainedSource != ""
 }
 
+// drainStartedAt returns the start time of the current drain or nil if the node is not being drained.
+func (dn drainingNode) drainStartedAt() *time.Time {
+	if dn.DrainCondition == nil || dn.DrainCondition.Status != corev1.ConditionTrue {
+		return nil
+	}
+
+	return &dn.DrainCondition.LastTransitionTime.Time
+}
+
+func (dn drainingNode) isStuck() bool {
+	return dn.drainStartedAt() != nil && dn.DrainCondition.Reason == conditions.DrainReasonStuck
+}
+
+type drainNodeGroup struct {
+	Name         string
+	DrainTimeout time.Duration
+}
+
 type drainedNodeRes struct {
 	NodeName       string
 	DrainingSource string
 	Err            error
+
+	Node         drainingNode
+	StartedAt    time.Time
+	DrainTimeout time.Duration
+	Progress     *drain.Progress
+}
+
+// conditionReasonAndMessage describes the failed drain attempt for the Draining condition of the node.
+func (dr drainedNodeRes) conditionReasonAndMessage(now time.Time) (string, string) {
+	reason := conditions.DrainReasonInProgress
+	message := fmt.Sprintf("Drain attempt failed: %s", dr.Err)
+
+	if dr.Progress != nil {
+		if blocked := dr.Progress.BlockedPods(); len(blocked) > 0 {
+			reason = conditions.DrainReasonEvictionBlocked
+
+			pods := make([]string, 0, len(blocked))
+			for _, pod := range blocked {
+				pods = append(pods, fmt.Sprintf("%s/%s (%s)", pod.Namespace, pod.Name, strings.Join(pod.BlockingPDBs, ", ")))
+			}
+			message = fmt.Sprintf("Eviction of pods is blocked by PodDisruptionBudgets: %s.", strings.Join(pods, "; "))
+		}
+	}
+
+	if dr.DrainTimeout > 0 && now.Sub(dr.StartedAt) >= dr.DrainTimeout {
+		reason = conditions.DrainReasonStuck
+		message = fmt.Sprintf("The node was not drained within %s. %s", dr.DrainTimeout, message)
+	}
+
+	return reason, message
 }
 
 func (dr drainedNodeRes) buildEvent() *eventsv1.Event {
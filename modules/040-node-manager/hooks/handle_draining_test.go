@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/flant/addon-operator/sdk"
 	. "github.com/onsi/ginkgo"
@@ -29,6 +30,7 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
+	"github.com/deckhouse/deckhouse/go_lib/dependency/k8s/drain"
 	. "github.com/deckhouse/deckhouse/testing/hooks"
 )
 
@@ -86,6 +88,99 @@ metadata:
 			node2 := f.KubernetesGlobalResource("Node", "wor-ker-2")
 			Expect(node2.Field("metadata.annotations.update\\.node\\.deckhouse\\.io/drained").String()).To(Equal("user"))
 			Expect(node2.Field("metadata.annotations.update\\.node\\.deckhouse\\.io/draining").Exists()).To(BeFalse())
+
+			drainCondition := node2.Field(`status.conditions.#(type=="Draining")`)
+			Expect(drainCondition.Get("status").String()).To(Equal("False"))
+			Expect(drainCondition.Get("reason").String()).To(Equal("Drained"))
+		})
+	})
+
+	Context("Node drain is not completed within the drainTimeout", func() {
+		BeforeEach(func() {
+			st := f.KubeStateSet(fmt.Sprintf(`
+---
+apiVersion: deckhouse.io/v1
+kind: NodeGroup
+metadata:
+  name: worker
+spec:
+  nodeType: Static
+  disruptions:
+    drainTimeout: 30m
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: worker-1
+  labels:
+    node.deckhouse.io/group: worker
+  annotations:
+    update.node.deckhouse.io/draining: bashible
+spec:
+  unschedulable: true
+status:
+  conditions:
+  - type: Draining
+    status: "True"
+    reason: EvictionBlocked
+    message: "Eviction of pods is blocked by PodDisruptionBudgets: default/app-1 (app)."
+    lastHeartbeatTime: %[1]q
+    lastTransitionTime: %[1]q
+`, time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)))
+			f.BindingContexts.Set(st)
+			testMoveNodesToStaticClient(f)
+			f.RunHook()
+		})
+
+		It("Node must be marked as stuck and must not be drained", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			node := f.KubernetesGlobalResource("Node", "worker-1")
+			Expect(node.Field(`metadata.annotations.update\.node\.deckhouse\.io/draining`).String()).To(Equal("bashible"))
+			Expect(node.Field(`metadata.annotations.update\.node\.deckhouse\.io/drained`).Exists()).To(BeFalse())
+
+			drainCondition := node.Field(`status.conditions.#(type=="Draining")`)
+			Expect(drainCondition.Get("status").String()).To(Equal("True"))
+			Expect(drainCondition.Get("reason").String()).To(Equal("Stuck"))
+			Expect(drainCondition.Get("message").String()).To(Equal("The node was not drained within 30m0s. Eviction of pods is blocked by PodDisruptionBudgets: default/app-1 (app)."))
+
+			m := f.MetricsCollector.CollectedMetrics()
+			Expect(m).To(HaveLen(3))
+			Expect(m[0].Group).To(Equal("node_drain"))
+			Expect(m[2].Name).To(Equal("d8_node_drain_stuck"))
+			Expect(m[2].Labels).To(Equal(map[string]string{"node": "worker-1", "node_group": "worker"}))
+			Expect(*m[2].Value).To(Equal(1.0))
+		})
+	})
+
+	Context("Draining annotation is removed before the drain completion", func() {
+		BeforeEach(func() {
+			st := f.KubeStateSet(`
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: worker-1
+  labels:
+    node.deckhouse.io/group: worker
+spec:
+  unschedulable: true
+status:
+  conditions:
+  - type: Draining
+    status: "True"
+    reason: InProgress
+    lastHeartbeatTime: "2024-01-01T10:00:00Z"
+    lastTransitionTime: "2024-01-01T10:00:00Z"
+`)
+			f.BindingContexts.Set(st)
+			f.RunHook()
+		})
+
+		It("Drain must be canceled", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			drainCondition := f.KubernetesGlobalResource("Node", "worker-1").Field(`status.conditions.#(type=="Draining")`)
+			Expect(drainCondition.Get("status").String()).To(Equal("False"))
+			Expect(drainCondition.Get("reason").String()).To(Equal("Canceled"))
 		})
 	})
 
@@ -170,6 +265,62 @@ data:
 		}
 	})
 
+	Context("Cordon of the draining node failed", func() {
+		BeforeEach(func() {
+			// the node is not copied to the static client, so the cordon fails
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: worker-1
+  labels:
+    node.deckhouse.io/group: worker
+  annotations:
+    update.node.deckhouse.io/draining: "user"
+`))
+			f.RunHook()
+		})
+
+		It("Drain must be reported as in progress", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			drainCondition := f.KubernetesGlobalResource("Node", "worker-1").Field(`status.conditions.#(type=="Draining")`)
+			Expect(drainCondition.Get("status").String()).To(Equal("True"))
+			Expect(drainCondition.Get("reason").String()).To(Equal("InProgress"))
+			Expect(drainCondition.Get("message").String()).To(Equal("The node is being drained."))
+		})
+	})
+
+	Context("Drain attempt timeout", func() {
+		It("Must not exceed the time left until the drainTimeout", func() {
+			now := time.Now()
+			Expect(drainAttemptTimeoutLeft(now, 0, now)).To(Equal(5 * time.Minute))
+			Expect(drainAttemptTimeoutLeft(now.Add(-10*time.Minute), time.Hour, now)).To(Equal(5 * time.Minute))
+			Expect(drainAttemptTimeoutLeft(now.Add(-58*time.Minute), time.Hour, now)).To(Equal(2 * time.Minute))
+		})
+	})
+
+	Context("Failed drain attempt", func() {
+		It("Should be described in the Draining condition", func() {
+			dnode := drainedNodeRes{
+				NodeName:     "foo-1",
+				StartedAt:    time.Now().Add(-10 * time.Minute),
+				DrainTimeout: time.Hour,
+				Progress:     drain.NewProgress(),
+				Err:          errors.New("global timeout reached"),
+			}
+
+			reason, message := dnode.conditionReasonAndMessage(time.Now())
+			Expect(reason).To(Equal("InProgress"))
+			Expect(message).To(Equal("Drain attempt failed: global timeout reached"))
+
+			dnode.StartedAt = time.Now().Add(-2 * time.Hour)
+			reason, message = dnode.conditionReasonAndMessage(time.Now())
+			Expect(reason).To(Equal("Stuck"))
+			Expect(message).To(Equal("The node was not drained within 1h0m0s. Drain attempt failed: global timeout reached"))
+		})
+	})
+
 	Context("simulate error", func() {
 		var event *eventsv1.Event
 
@@ -0,0 +1,361 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook/metrics"
+	"github.com/flant/addon-operator/sdk"
+	"github.com/flant/shell-operator/pkg/kube_events_manager/types"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/deckhouse/deckhouse/modules/040-node-manager/hooks/internal/mcm/v1alpha1"
+	ngv1 "github.com/deckhouse/deckhouse/modules/040-node-manager/hooks/internal/v1"
+)
+
+// This hook tracks failed instances of NodeGroups with the fallback settings (usually spot or preemptible ones).
+// Both instances that could not be provisioned (failed machines of the MachineDeployment) and instances that were lost
+// after the Node had joined the cluster (reclaimed or preempted by the cloud provider, the Machine turns Unknown or Failed)
+// are counted. If instances fail repeatedly within a window, the fallback is activated and the set_ng_priorities hook
+// raises the priority of the fallback NodeGroup. The fallback is deactivated after a cooldown without failures.
+// The state is stored in the NodeGroup status to survive restarts of Deckhouse.
+
+const (
+	defaultFallbackFailureThreshold = 3
+	defaultFallbackWindow           = 30 * time.Minute
+	defaultFallbackCooldown         = time.Hour
+)
+
+var _ = sdk.RegisterFunc(&go_hook.HookConfig{
+	// this hook stores the state used by update_node_group_status hook
+	Queue: "/modules/node-manager/update_ngs_statuses",
+	Schedule: []go_hook.ScheduleConfig{
+		{
+			Name:    "fallback_cooldown",
+			Crontab: "* * * * *",
+		},
+	},
+	Kubernetes: []go_hook.KubernetesConfig{
+		{
+			Name:       "ngs",
+			ApiVersion: "deckhouse.io/v1",
+			Kind:       "NodeGroup",
+			FilterFunc: fallbackFilterNodeGroup,
+		},
+		{
+			Name:       "mds",
+			ApiVersion: "machine.sapcloud.io/v1alpha1",
+			Kind:       "MachineDeployment",
+			NamespaceSelector: &types.NamespaceSelector{
+				NameSelector: &types.NameSelector{
+					MatchNames: []string{"d8-cloud-instance-manager"},
+				},
+			},
+			FilterFunc: fallbackFilterMachineDeployment,
+		},
+		{
+			Name:       "machines",
+			ApiVersion: "machine.sapcloud.io/v1alpha1",
+			Kind:       "Machine",
+			NamespaceSelector: &types.NamespaceSelector{
+				NameSelector: &types.NameSelector{
+					MatchNames: []string{"d8-cloud-instance-manager"},
+				},
+			},
+			FilterFunc: fallbackFilterMachine,
+		},
+	},
+}, handleNodeGroupFallback)
+
+type fallbackNodeGroup struct {
+	Name string
+	// Spec is nil if the fallback is not configured or was removed from the NodeGroup, but the status is left.
+	Spec   *fallbackSpec
+	Status *ngv1.FallbackStatus
+}
+
+type fallbackSpec struct {
+	NodeGroup        string
+	FailureThreshold int
+	Window           time.Duration
+	Cooldown         time.Duration
+}
+
+type fallbackMachineDeployment struct {
+	NodeGroup string
+	Failures  []ngv1.FallbackFailure
+}
+
+type fallbackMachine struct {
+	NodeGroup string
+	Failure   ngv1.FallbackFailure
+}
+
+func fallbackFilterNodeGroup(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	var ng ngv1.NodeGroup
+
+	err := sdk.FromUnstructured(obj, &ng)
+	if err != nil {
+		return nil, err
+	}
+
+	fallback := ng.Spec.CloudInstances.Fallback
+
+	// NodeGroups without the fallback are kept in the snapshot to check that the fallback NodeGroup exists.
+	res := fallbackNodeGroup{
+		Name:   ng.Name,
+		Status: ng.Status.Fallback,
+	}
+
+	if fallback == nil {
+		return res, nil
+	}
+
+	spec := &fallbackSpec{
+		NodeGroup:        fallback.NodeGroup,
+		FailureThreshold: defaultFallbackFailureThreshold,
+		Window:           defaultFallbackWindow,
+		Cooldown:         defaultFallbackCooldown,
+	}
+
+	if fallback.FailureThreshold != nil {
+		spec.FailureThreshold = int(*fallback.FailureThreshold)
+	}
+
+	if fallback.Window != "" {
+		spec.Window, err = time.ParseDuration(fallback.Window)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse spec.cloudInstances.fallback.window of NodeGroup %s: %v", ng.Name, err)
+		}
+	}
+
+	if fallback.Cooldown != "" {
+		spec.Cooldown, err = time.ParseDuration(fallback.Cooldown)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse spec.cloudInstances.fallback.cooldown of NodeGroup %s: %v", ng.Name, err)
+		}
+	}
+
+	res.Spec = spec
+
+	return res, nil
+}
+
+func fallbackFilterMachineDeployment(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	var md v1alpha1.MachineDeployment
+
+	err := sdk.FromUnstructured(obj, &md)
+	if err != nil {
+		return nil, err
+	}
+
+	failures := make([]ngv1.FallbackFailure, 0, len(md.Status.FailedMachines))
+	for _, m := range md.Status.FailedMachines {
+		if m == nil || m.LastOperation.State != v1alpha1.MachineStateFailed {
+			continue
+		}
+
+		failures = append(failures, ngv1.FallbackFailure{
+			Name: m.Name,
+			Time: m.LastOperation.LastUpdateTime,
+		})
+	}
+
+	if len(failures) == 0 {
+		return nil, nil
+	}
+
+	return fallbackMachineDeployment{
+		NodeGroup: md.Labels["node-group"],
+		Failures:  failures,
+	}, nil
+}
+
+// fallbackFilterMachine returns the Machine whose Node was lost after it had joined the cluster,
+// it is the way machine-controller-manager reflects the instance reclaimed or preempted by the cloud provider.
+func fallbackFilterMachine(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	var machine v1alpha1.Machine
+
+	err := sdk.FromUnstructured(obj, &machine)
+	if err != nil {
+		return nil, err
+	}
+
+	if machine.Status.Node == "" {
+		return nil, nil
+	}
+
+	phase := machine.Status.CurrentStatus.Phase
+	if phase != v1alpha1.MachineUnknown && phase != v1alpha1.MachineFailed {
+		return nil, nil
+	}
+
+	return fallbackMachine{
+		NodeGroup: machine.Spec.NodeTemplateSpec.Labels["node.deckhouse.io/group"],
+		Failure: ngv1.FallbackFailure{
+			Name: machine.Name,
+			Time: machine.Status.CurrentStatus.LastUpdateTime,
+		},
+	}, nil
+}
+
+func handleNodeGroupFallback(input *go_hook.HookInput) error {
+	input.MetricsCollector.Expire("node_group_fallback")
+
+	failuresByNodeGroup := make(map[string][]ngv1.FallbackFailure)
+	for _, sn := range input.Snapshots["mds"] {
+		if sn == nil {
+			continue
+		}
+		md := sn.(fallbackMachineDeployment)
+		failuresByNodeGroup[md.NodeGroup] = append(failuresByNodeGroup[md.NodeGroup], md.Failures...)
+	}
+	for _, sn := range input.Snapshots["machines"] {
+		if sn == nil {
+			continue
+		}
+		m := sn.(fallbackMachine)
+		failuresByNodeGroup[m.NodeGroup] = append(failuresByNodeGroup[m.NodeGroup], m.Failure)
+	}
+
+	nodeGroups := make(map[string]struct{}, len(input.Snapshots["ngs"]))
+	for _, sn := range input.Snapshots["ngs"] {
+		nodeGroups[sn.(fallbackNodeGroup).Name] = struct{}{}
+	}
+
+	now := time.Now().UTC()
+
+	for _, sn := range input.Snapshots["ngs"] {
+		ng := sn.(fallbackNodeGroup)
+
+		if ng.Spec == nil {
+			if ng.Status != nil {
+				setNodeGroupStatus(input.PatchCollector, ng.Name, "fallback", nil)
+			}
+			continue
+		}
+
+		state := calculateFallbackState(ng, failuresByNodeGroup[ng.Name], now)
+		if _, ok := nodeGroups[ng.Spec.NodeGroup]; !ok {
+			state.Message = fmt.Sprintf("NodeGroup %s is not found", ng.Spec.NodeGroup)
+		}
+
+		// the hook runs every minute, do not patch the NodeGroup if nothing is changed
+		if !equality.Semantic.DeepEqual(ng.Status, state) {
+			setNodeGroupStatus(input.PatchCollector, ng.Name, "fallback", state)
+		}
+
+		var active float64
+		if state.Active {
+			active = 1
+		}
+
+		input.MetricsCollector.Set("d8_node_group_fallback_active", active,
+			map[string]string{"node_group": ng.Name, "fallback_node_group": ng.Spec.NodeGroup},
+			metrics.WithGroup("node_group_fallback"),
+		)
+		input.MetricsCollector.Set("d8_node_group_fallback_failed_instances", float64(countFailuresSince(state.RecentFailures, now.Add(-ng.Spec.Window))),
+			map[string]string{"node_group": ng.Name},
+			metrics.WithGroup("node_group_fallback"),
+		)
+	}
+
+	return nil
+}
+
+// calculateFallbackState merges previously seen failures with the current ones and decides if the fallback is active.
+// Only the latest failureThreshold failures are kept, it is enough both to check the threshold
+// and to find the time of the last failure for the cooldown.
+func calculateFallbackState(ng fallbackNodeGroup, failures []ngv1.FallbackFailure, now time.Time) *ngv1.FallbackStatus {
+	prev := ng.Status
+	if prev == nil {
+		prev = &ngv1.FallbackStatus{}
+	}
+
+	retention := ng.Spec.Window
+	if ng.Spec.Cooldown > retention {
+		retention = ng.Spec.Cooldown
+	}
+	retainSince := now.Add(-retention)
+
+	seen := make(map[string]struct{})
+	recent := make([]ngv1.FallbackFailure, 0, len(prev.RecentFailures)+len(failures))
+	for _, list := range [][]ngv1.FallbackFailure{prev.RecentFailures, failures} {
+		for _, f := range list {
+			if f.Time.Time.Before(retainSince) {
+				continue
+			}
+
+			// the Machine is counted once, even if it is both a failed machine of the MachineDeployment and a lost one
+			if _, ok := seen[f.Name]; ok {
+				continue
+			}
+			seen[f.Name] = struct{}{}
+
+			recent = append(recent, ngv1.FallbackFailure{Name: f.Name, Time: metav1.NewTime(f.Time.UTC())})
+		}
+	}
+
+	sort.Slice(recent, func(i, j int) bool {
+		return recent[i].Time.Before(&recent[j].Time)
+	})
+
+	if len(recent) > ng.Spec.FailureThreshold {
+		recent = recent[len(recent)-ng.Spec.FailureThreshold:]
+	}
+
+	active := prev.Active
+	switch {
+	case countFailuresSince(recent, now.Add(-ng.Spec.Window)) >= ng.Spec.FailureThreshold:
+		active = true
+
+	case prev.Active:
+		if len(recent) == 0 || now.Sub(recent[len(recent)-1].Time.Time) >= ng.Spec.Cooldown {
+			active = false
+		}
+	}
+
+	state := &ngv1.FallbackStatus{
+		Active:             active,
+		NodeGroup:          ng.Spec.NodeGroup,
+		LastTransitionTime: prev.LastTransitionTime,
+		RecentFailures:     recent,
+	}
+
+	if active != prev.Active {
+		state.LastTransitionTime = metav1.NewTime(now)
+	}
+
+	return state
+}
+
+func countFailuresSince(failures []ngv1.FallbackFailure, since time.Time) int {
+	var count int
+	for _, f := range failures {
+		if !f.Time.Time.Before(since) {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,297 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/deckhouse/deckhouse/testing/hooks"
+)
+
+var _ = Describe("Modules :: node-manager :: hooks :: handle_node_group_fallback ::", func() {
+	const stateSpotNG = `
+---
+apiVersion: deckhouse.io/v1
+kind: NodeGroup
+metadata:
+  name: spot
+spec:
+  nodeType: CloudEphemeral
+  cloudInstances:
+    maxPerZone: 4
+    minPerZone: 1
+    priority: 50
+    fallback:
+      nodeGroup: on-demand
+      failureThreshold: 2
+      window: 30m
+      cooldown: 1h
+`
+
+	machineDeployment := func(failedAt ...time.Time) string {
+		failed := ""
+		for i, t := range failedAt {
+			failed += fmt.Sprintf(`
+    - name: machine-spot-%d
+      ownerRef: md-spot
+      lastOperation:
+        description: "Cloud provider message - preemptible instance was reclaimed"
+        lastUpdateTime: %q
+        state: Failed
+        type: HealthCheck`, i, t.UTC().Format(time.RFC3339))
+		}
+
+		return fmt.Sprintf(`
+---
+apiVersion: machine.sapcloud.io/v1alpha1
+kind: MachineDeployment
+metadata:
+  name: md-spot
+  namespace: d8-cloud-instance-manager
+  labels:
+    node-group: spot
+spec:
+  replicas: 1
+status:
+  failedMachines:%s
+`, failed)
+	}
+
+	const stateOnDemandNG = `
+---
+apiVersion: deckhouse.io/v1
+kind: NodeGroup
+metadata:
+  name: on-demand
+spec:
+  nodeType: CloudEphemeral
+  cloudInstances:
+    maxPerZone: 4
+    minPerZone: 0
+`
+
+	lostMachine := func(name, phase string, lostAt time.Time) string {
+		return fmt.Sprintf(`
+---
+apiVersion: machine.sapcloud.io/v1alpha1
+kind: Machine
+metadata:
+  name: %s
+  namespace: d8-cloud-instance-manager
+spec:
+  nodeTemplate:
+    metadata:
+      labels:
+        node.deckhouse.io/group: spot
+status:
+  node: %s
+  currentStatus:
+    phase: %s
+    lastUpdateTime: %q
+`, name, name, phase, lostAt.UTC().Format(time.RFC3339))
+	}
+
+	f := HookExecutionConfigInit(`{"nodeManager":{"internal":{}}}`, `{}`)
+	f.RegisterCRD("deckhouse.io", "v1", "NodeGroup", false)
+	f.RegisterCRD("machine.sapcloud.io", "v1alpha1", "MachineDeployment", true)
+	f.RegisterCRD("machine.sapcloud.io", "v1alpha1", "Machine", true)
+
+	Context("Empty cluster", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(``))
+			f.RunHook()
+		})
+
+		It("Hook must not fail", func() {
+			Expect(f).To(ExecuteSuccessfully())
+		})
+	})
+
+	Context("Failures below the threshold", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(stateSpotNG + stateOnDemandNG + machineDeployment(time.Now().Add(-5*time.Minute))))
+			f.RunHook()
+		})
+
+		It("Fallback must not be active", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			ng := f.KubernetesGlobalResource("NodeGroup", "spot")
+			Expect(ng.Field("status.fallback.active").Bool()).To(BeFalse())
+			Expect(ng.Field("status.fallback.nodeGroup").String()).To(Equal("on-demand"))
+			Expect(ng.Field("status.fallback.recentFailures").Array()).To(HaveLen(1))
+
+			metrics := f.MetricsCollector.CollectedMetrics()
+			Expect(metrics).To(HaveLen(3))
+			Expect(metrics[1].Name).To(Equal("d8_node_group_fallback_active"))
+			Expect(*metrics[1].Value).To(Equal(0.0))
+			Expect(metrics[2].Name).To(Equal("d8_node_group_fallback_failed_instances"))
+			Expect(*metrics[2].Value).To(Equal(1.0))
+		})
+	})
+
+	Context("Failures reach the threshold within the window", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(stateSpotNG + stateOnDemandNG + machineDeployment(time.Now().Add(-10*time.Minute), time.Now().Add(-5*time.Minute))))
+			f.RunHook()
+		})
+
+		It("Fallback must be activated", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			ng := f.KubernetesGlobalResource("NodeGroup", "spot")
+			Expect(ng.Field("status.fallback.active").Bool()).To(BeTrue())
+			Expect(ng.Field("status.fallback.lastTransitionTime").Exists()).To(BeTrue())
+			Expect(ng.Field("status.fallback.recentFailures").Array()).To(HaveLen(2))
+
+			metrics := f.MetricsCollector.CollectedMetrics()
+			Expect(metrics[1].Labels).To(Equal(map[string]string{"node_group": "spot", "fallback_node_group": "on-demand"}))
+			Expect(*metrics[1].Value).To(Equal(1.0))
+		})
+	})
+
+	Context("Instances are reclaimed by the cloud provider", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(stateSpotNG + stateOnDemandNG +
+				lostMachine("spot-a", "Unknown", time.Now().Add(-10*time.Minute)) +
+				lostMachine("spot-b", "Failed", time.Now().Add(-5*time.Minute)) +
+				lostMachine("spot-c", "Running", time.Now().Add(-5*time.Minute)) +
+				machineDeployment(time.Now().Add(-20*time.Minute))))
+			f.RunHook()
+		})
+
+		It("Lost machines must be counted as failures", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			ng := f.KubernetesGlobalResource("NodeGroup", "spot")
+			Expect(ng.Field("status.fallback.active").Bool()).To(BeTrue())
+			Expect(ng.Field("status.fallback.recentFailures.#.name").String()).To(MatchJSON(`["spot-a","spot-b"]`))
+		})
+	})
+
+	Context("Lost machine is also a failed machine of the MachineDeployment", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(stateSpotNG + stateOnDemandNG +
+				lostMachine("machine-spot-0", "Failed", time.Now().Add(-5*time.Minute)) +
+				machineDeployment(time.Now().Add(-10*time.Minute))))
+			f.RunHook()
+		})
+
+		It("Machine must be counted once", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			ng := f.KubernetesGlobalResource("NodeGroup", "spot")
+			Expect(ng.Field("status.fallback.active").Bool()).To(BeFalse())
+			Expect(ng.Field("status.fallback.recentFailures").Array()).To(HaveLen(1))
+		})
+	})
+
+	Context("Fallback NodeGroup is not found", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(stateSpotNG))
+			f.RunHook()
+		})
+
+		It("Problem must be reported in the status", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.KubernetesGlobalResource("NodeGroup", "spot").Field("status.fallback.message").String()).To(Equal("NodeGroup on-demand is not found"))
+		})
+	})
+
+	Context("Failures are outside of the window", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(stateSpotNG + stateOnDemandNG + machineDeployment(time.Now().Add(-3*time.Hour), time.Now().Add(-2*time.Hour))))
+			f.RunHook()
+		})
+
+		It("Fallback must not be active and old failures must be forgotten", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			ng := f.KubernetesGlobalResource("NodeGroup", "spot")
+			Expect(ng.Field("status.fallback.active").Bool()).To(BeFalse())
+			Expect(ng.Field("status.fallback.recentFailures").Array()).To(HaveLen(0))
+		})
+	})
+
+	Context("Active fallback", func() {
+		activeState := func(lastFailure time.Time) string {
+			return stateSpotNG + fmt.Sprintf(`
+status:
+  fallback:
+    active: true
+    nodeGroup: on-demand
+    lastTransitionTime: %q
+    recentFailures:
+    - name: machine-spot-0
+      time: %q
+`, lastFailure.UTC().Format(time.RFC3339), lastFailure.UTC().Format(time.RFC3339))
+		}
+
+		Context("Cooldown is not passed", func() {
+			BeforeEach(func() {
+				f.BindingContexts.Set(f.KubeStateSet(stateOnDemandNG + activeState(time.Now().Add(-45*time.Minute))))
+				f.RunHook()
+			})
+
+			It("Fallback must stay active and the NodeGroup must not be patched", func() {
+				Expect(f).To(ExecuteSuccessfully())
+				Expect(f.KubernetesGlobalResource("NodeGroup", "spot").Field("status.fallback.active").Bool()).To(BeTrue())
+				Expect(f.PatchCollector.Operations()).To(BeEmpty())
+			})
+		})
+
+		Context("Cooldown is passed", func() {
+			BeforeEach(func() {
+				f.BindingContexts.Set(f.KubeStateSet(stateOnDemandNG + activeState(time.Now().Add(-90*time.Minute))))
+				f.RunHook()
+			})
+
+			It("Fallback must be deactivated", func() {
+				Expect(f).To(ExecuteSuccessfully())
+				ng := f.KubernetesGlobalResource("NodeGroup", "spot")
+				Expect(ng.Field("status.fallback.active").Bool()).To(BeFalse())
+				Expect(ng.Field("status.fallback.recentFailures").Array()).To(HaveLen(0))
+			})
+		})
+	})
+
+	Context("Fallback is removed from the NodeGroup spec", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: deckhouse.io/v1
+kind: NodeGroup
+metadata:
+  name: spot
+spec:
+  nodeType: CloudEphemeral
+  cloudInstances:
+    maxPerZone: 4
+    minPerZone: 1
+status:
+  fallback:
+    active: true
+    nodeGroup: on-demand
+`))
+			f.RunHook()
+		})
+
+		It("Fallback status must be removed", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.KubernetesGlobalResource("NodeGroup", "spot").Field("status.fallback").Exists()).To(BeFalse())
+		})
+	})
+})
@@ -17,7 +17,9 @@ limitations under the License.
 package conditions
 
 import (
+	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -33,32 +35,54 @@ const (
 	machineGeneralError     = "Started Machine creation process"
 )
 
+const (
+	// NodeConditionTypeDraining is the type of the Node condition describing the drain progress.
+	NodeConditionTypeDraining corev1.NodeConditionType = "Draining"
+
+	DrainReasonInProgress      = "InProgress"
+	DrainReasonEvictionBlocked = "EvictionBlocked"
+	DrainReasonStuck           = "Stuck"
+	DrainReasonDrained         = "Drained"
+	DrainReasonCanceled        = "Canceled"
+)
+
 type NodeGroup struct {
 	Type      ngv1.NodeType
 	Instances int32
 	Desired   int32
 
 	HasFrozenMachineDeployment bool
+
+	// Fallback is the fallback state of the NodeGroup, nil if the fallback is not configured.
+	Fallback *ngv1.FallbackStatus
 }
 
 type Node struct {
+	Name                      string
 	Ready                     bool
 	ShouldDeleted             bool
 	Unschedulable             bool
 	Updating                  bool
 	CreationTimestamp         time.Time
 	WaitingDisruptiveApproval bool
+	// DrainBlocked is true if the node drain is blocked by PodDisruptionBudgets or is stuck.
+	DrainBlocked bool
 }
 
 func NodeToConditionsNode(node *corev1.Node) *Node {
-	res := &Node{}
+	res := &Node{Name: node.Name}
 
 	for _, c := range node.Status.Conditions {
-		if c.Type == corev1.NodeReady {
+		switch c.Type {
+		case corev1.NodeReady:
 			if c.Status == corev1.ConditionTrue {
 				res.Ready = true
 			}
-			break
+
+		case NodeConditionTypeDraining:
+			if c.Status == corev1.ConditionTrue && (c.Reason == DrainReasonEvictionBlocked || c.Reason == DrainReasonStuck) {
+				res.DrainBlocked = true
+			}
 		}
 	}
 
@@ -180,6 +204,20 @@ func calcErrorCondition(ng *NodeGroup, currentConditions []ngv1.NodeGroupConditi
 	return curError
 }
 
+func calcDrainBlockedCondition(nodes []string) ngv1.NodeGroupCondition {
+	condition := ngv1.NodeGroupCondition{
+		Type:   ngv1.NodeGroupConditionTypeDrainBlocked,
+		Status: boolToConditionStatus(len(nodes) > 0),
+	}
+
+	if len(nodes) > 0 {
+		sort.Strings(nodes)
+		condition.Message = fmt.Sprintf("Drain of nodes is blocked or stuck: %s. Check the Draining condition of the nodes for details.", strings.Join(nodes, ", "))
+	}
+
+	return condition
+}
+
 func CalculateNodeGroupConditions(
 	ng NodeGroup,
 	nodes []*Node,
@@ -188,6 +226,7 @@ func CalculateNodeGroupConditions(
 	minPerAllZone int,
 ) []ngv1.NodeGroupCondition {
 	var inDownScale, isWaitingDisruptiveApproval, isUpdating bool
+	var drainBlockedNodes []string
 
 	schedulableNodes := 0
 	readySchedulableNodes := 0
@@ -218,6 +257,10 @@ func CalculateNodeGroupConditions(
 		if node.WaitingDisruptiveApproval {
 			isWaitingDisruptiveApproval = true
 		}
+
+		if node.DrainBlocked {
+			drainBlockedNodes = append(drainBlockedNodes, node.Name)
+		}
 	}
 
 	isReady := readySchedulableNodes >= minPerAllZone
@@ -241,6 +284,8 @@ func CalculateNodeGroupConditions(
 		},
 
 		*errorCondition,
+
+		calcDrainBlockedCondition(drainBlockedNodes),
 	}
 
 	if ng.Type == ngv1.NodeTypeCloudEphemeral {
@@ -255,5 +300,20 @@ func CalculateNodeGroupConditions(
 		})
 	}
 
+	if ng.Fallback != nil {
+		fallbackCondition := ngv1.NodeGroupCondition{
+			Type:   ngv1.NodeGroupConditionTypeFallbackActive,
+			Status: boolToConditionStatus(ng.Fallback.Active),
+		}
+		switch {
+		case ng.Fallback.Message != "":
+			fallbackCondition.Message = ng.Fallback.Message
+		case ng.Fallback.Active:
+			fallbackCondition.Message = fmt.Sprintf("Priority of the %s NodeGroup is raised due to failed instances.", ng.Fallback.NodeGroup)
+		}
+
+		newConditions = append(newConditions, fallbackCondition)
+	}
+
 	return fillTransitionTime(currentConditions, newConditions, curTime)
 }
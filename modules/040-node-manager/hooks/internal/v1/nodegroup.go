@@ -183,6 +183,27 @@ type CloudInstances struct {
 
 	// Priority setting for autoscaler expander
 	Priority *int32 `json:"priority,omitempty"`
+
+	// Fallback to another NodeGroup when instances can not be provisioned. Optional.
+	Fallback *CloudInstancesFallback `json:"fallback,omitempty"`
+}
+
+// CloudInstancesFallback describes a NodeGroup whose priority is raised when instances of the group fail repeatedly.
+type CloudInstancesFallback struct {
+	// Name of the fallback NodeGroup. Required.
+	NodeGroup string `json:"nodeGroup"`
+
+	// Number of failed instances within the window to activate the fallback.
+	// Default: 3
+	FailureThreshold *int32 `json:"failureThreshold,omitempty"`
+
+	// Period in which failed instances are counted.
+	// Default: '30m'
+	Window string `json:"window,omitempty"`
+
+	// Period without failed instances after which the fallback is deactivated.
+	// Default: '1h'
+	Cooldown string `json:"cooldown,omitempty"`
 }
 
 func (c CloudInstances) IsEmpty() bool {
@@ -193,7 +214,8 @@ func (c CloudInstances) IsEmpty() bool {
 		c.MaxSurgePerZone == nil &&
 		c.Standby == nil &&
 		c.StandbyHolder.IsEmpty() &&
-		c.ClassReference.IsEmpty()
+		c.ClassReference.IsEmpty() &&
+		c.Fallback == nil
 }
 
 type StandbyHolder struct {
@@ -263,10 +285,13 @@ type Disruptions struct {
 	Automatic AutomaticDisruptions `json:"automatic,omitempty"`
 	// Extra settings for RolloutRestart mode.
 	RollingUpdate RollingUpdateDisruptions `json:"rollingUpdate,omitempty"`
+
+	// Time after which the node drain is considered stuck and is not retried anymore, e.g. 30m.
+	DrainTimeout string `json:"drainTimeout,omitempty"`
 }
 
 func (d Disruptions) IsEmpty() bool {
-	return d.ApprovalMode == "" && d.Automatic.IsEmpty()
+	return d.ApprovalMode == "" && d.Automatic.IsEmpty() && d.DrainTimeout == ""
 }
 
 type Update struct {
@@ -310,6 +335,73 @@ type Kubelet struct {
 	ContainerLogMaxFiles int `json:"containerLogMaxFiles,omitempty"`
 
 	ResourceReservation KubeletResourceReservation `json:"resourceReservation"`
+
+	// Eviction thresholds and grace periods. Optional.
+	Eviction *KubeletEviction `json:"eviction,omitempty"`
+
+	// Image garbage collection thresholds. Optional.
+	ImageGC *KubeletImageGC `json:"imageGC,omitempty"`
+
+	// CPU manager policy: None or Static.
+	// Default: 'None'
+	CPUManagerPolicy KubeletCPUManagerPolicy `json:"cpuManagerPolicy,omitempty"`
+
+	// Topology manager settings. Optional.
+	TopologyManager *KubeletTopologyManager `json:"topologyManager,omitempty"`
+
+	// Kubelet feature gates, only an allowed subset is accepted. Optional.
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+}
+
+type KubeletEviction struct {
+	// Hard eviction thresholds.
+	Hard *KubeletEvictionSignals `json:"hard,omitempty"`
+
+	// Soft eviction thresholds.
+	Soft *KubeletEvictionSignals `json:"soft,omitempty"`
+
+	// Grace periods for soft eviction thresholds.
+	SoftGracePeriod *KubeletEvictionSignals `json:"softGracePeriod,omitempty"`
+
+	// Maximum allowed grace period (in seconds) to use when terminating pods
+	// in response to a soft eviction threshold being met.
+	// Default: 90
+	MaxPodGracePeriod *int32 `json:"maxPodGracePeriod,omitempty"`
+}
+
+type KubeletEvictionSignals struct {
+	MemoryAvailable   string `json:"memoryAvailable,omitempty"`
+	NodefsAvailable   string `json:"nodefsAvailable,omitempty"`
+	NodefsInodesFree  string `json:"nodefsInodesFree,omitempty"`
+	ImagefsAvailable  string `json:"imagefsAvailable,omitempty"`
+	ImagefsInodesFree string `json:"imagefsInodesFree,omitempty"`
+}
+
+type KubeletImageGC struct {
+	// The percent of disk usage after which image garbage collection is always run.
+	// Default: 70
+	HighThresholdPercent *int32 `json:"highThresholdPercent,omitempty"`
+
+	// The percent of disk usage before which image garbage collection is never run.
+	// Default: 65
+	LowThresholdPercent *int32 `json:"lowThresholdPercent,omitempty"`
+}
+
+type KubeletCPUManagerPolicy string
+
+const (
+	KubeletCPUManagerPolicyNone   KubeletCPUManagerPolicy = "None"
+	KubeletCPUManagerPolicyStatic KubeletCPUManagerPolicy = "Static"
+)
+
+type KubeletTopologyManager struct {
+	// Topology manager policy: None, BestEffort, Restricted or SingleNUMANode.
+	// Default: 'None'
+	Policy string `json:"policy,omitempty"`
+
+	// Scope of topology hints: Container or Pod.
+	// Default: 'Container'
+	Scope string `json:"scope,omitempty"`
 }
 
 type KubeletResourceReservation struct {
@@ -334,7 +426,9 @@ const (
 
 func (k Kubelet) IsEmpty() bool {
 	return k.MaxPods == nil && k.RootDir == "" && k.ContainerLogMaxSize == "" && k.ContainerLogMaxFiles == 0 &&
-		k.ResourceReservation.Mode == "" && k.ResourceReservation.Static == nil
+		k.ResourceReservation.Mode == "" && k.ResourceReservation.Static == nil &&
+		k.Eviction == nil && k.ImageGC == nil && k.CPUManagerPolicy == "" && k.TopologyManager == nil &&
+		len(k.FeatureGates) == 0
 }
 
 type NodeGroupConditionType string
@@ -345,6 +439,8 @@ const (
 	NodeGroupConditionTypeWaitingForDisruptiveApproval = "WaitingForDisruptiveApproval"
 	NodeGroupConditionTypeScaling                      = "Scaling"
 	NodeGroupConditionTypeError                        = "Error"
+	NodeGroupConditionTypeFallbackActive               = "FallbackActive"
+	NodeGroupConditionTypeDrainBlocked                 = "DrainBlocked"
 )
 
 type ConditionStatus string
@@ -424,6 +520,34 @@ type NodeGroupStatus struct {
 
 	// Current nodegroup conditions
 	Conditions []NodeGroupCondition `json:"conditions,omitempty"`
+
+	// State of the fallback to another NodeGroup.
+	Fallback *FallbackStatus `json:"fallback,omitempty"`
+}
+
+type FallbackStatus struct {
+	// True if the priority of the fallback NodeGroup is raised.
+	Active bool `json:"active"`
+
+	// Name of the fallback NodeGroup.
+	NodeGroup string `json:"nodeGroup,omitempty"`
+
+	// Last time the fallback was activated or deactivated.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// The problem with the fallback settings, for example, the fallback NodeGroup is not found.
+	Message string `json:"message,omitempty"`
+
+	// Failed instances within the window.
+	RecentFailures []FallbackFailure `json:"recentFailures"`
+}
+
+type FallbackFailure struct {
+	// Machine's name.
+	Name string `json:"name"`
+
+	// Time of the failure.
+	Time metav1.Time `json:"time"`
 }
 
 type MachineFailure struct {
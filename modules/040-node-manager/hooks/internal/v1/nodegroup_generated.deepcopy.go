@@ -22,6 +22,7 @@ limitations under the License.
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	intstr "k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -155,6 +156,11 @@ func (in *CloudInstances) DeepCopyInto(out *CloudInstances) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.Fallback != nil {
+		in, out := &in.Fallback, &out.Fallback
+		*out = new(CloudInstancesFallback)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -168,6 +174,27 @@ func (in *CloudInstances) DeepCopy() *CloudInstances {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudInstancesFallback) DeepCopyInto(out *CloudInstancesFallback) {
+	*out = *in
+	if in.FailureThreshold != nil {
+		in, out := &in.FailureThreshold, &out.FailureThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudInstancesFallback.
+func (in *CloudInstancesFallback) DeepCopy() *CloudInstancesFallback {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudInstancesFallback)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConditionSummary) DeepCopyInto(out *ConditionSummary) {
 	*out = *in
@@ -249,6 +276,63 @@ func (in *Docker) DeepCopy() *Docker {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FallbackFailure) DeepCopyInto(out *FallbackFailure) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FallbackFailure.
+func (in *FallbackFailure) DeepCopy() *FallbackFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(FallbackFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FallbackStatus) DeepCopyInto(out *FallbackStatus) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	if in.RecentFailures != nil {
+		in, out := &in.RecentFailures, &out.RecentFailures
+		*out = make([]FallbackFailure, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FallbackStatus.
+func (in *FallbackStatus) DeepCopy() *FallbackStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FallbackStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InfrastructureTemplateReference) DeepCopyInto(out *InfrastructureTemplateReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InfrastructureTemplateReference.
+func (in *InfrastructureTemplateReference) DeepCopy() *InfrastructureTemplateReference {
+	if in == nil {
+		return nil
+	}
+	out := new(InfrastructureTemplateReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Kubelet) DeepCopyInto(out *Kubelet) {
 	*out = *in
@@ -258,6 +342,28 @@ func (in *Kubelet) DeepCopyInto(out *Kubelet) {
 		**out = **in
 	}
 	in.ResourceReservation.DeepCopyInto(&out.ResourceReservation)
+	if in.Eviction != nil {
+		in, out := &in.Eviction, &out.Eviction
+		*out = new(KubeletEviction)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageGC != nil {
+		in, out := &in.ImageGC, &out.ImageGC
+		*out = new(KubeletImageGC)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TopologyManager != nil {
+		in, out := &in.TopologyManager, &out.TopologyManager
+		*out = new(KubeletTopologyManager)
+		**out = **in
+	}
+	if in.FeatureGates != nil {
+		in, out := &in.FeatureGates, &out.FeatureGates
+		*out = make(map[string]bool, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -271,6 +377,84 @@ func (in *Kubelet) DeepCopy() *Kubelet {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeletEviction) DeepCopyInto(out *KubeletEviction) {
+	*out = *in
+	if in.Hard != nil {
+		in, out := &in.Hard, &out.Hard
+		*out = new(KubeletEvictionSignals)
+		**out = **in
+	}
+	if in.Soft != nil {
+		in, out := &in.Soft, &out.Soft
+		*out = new(KubeletEvictionSignals)
+		**out = **in
+	}
+	if in.SoftGracePeriod != nil {
+		in, out := &in.SoftGracePeriod, &out.SoftGracePeriod
+		*out = new(KubeletEvictionSignals)
+		**out = **in
+	}
+	if in.MaxPodGracePeriod != nil {
+		in, out := &in.MaxPodGracePeriod, &out.MaxPodGracePeriod
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeletEviction.
+func (in *KubeletEviction) DeepCopy() *KubeletEviction {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeletEviction)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeletEvictionSignals) DeepCopyInto(out *KubeletEvictionSignals) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeletEvictionSignals.
+func (in *KubeletEvictionSignals) DeepCopy() *KubeletEvictionSignals {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeletEvictionSignals)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeletImageGC) DeepCopyInto(out *KubeletImageGC) {
+	*out = *in
+	if in.HighThresholdPercent != nil {
+		in, out := &in.HighThresholdPercent, &out.HighThresholdPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.LowThresholdPercent != nil {
+		in, out := &in.LowThresholdPercent, &out.LowThresholdPercent
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeletImageGC.
+func (in *KubeletImageGC) DeepCopy() *KubeletImageGC {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeletImageGC)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubeletResourceReservation) DeepCopyInto(out *KubeletResourceReservation) {
 	*out = *in
@@ -311,6 +495,22 @@ func (in *KubeletStaticResourceReservation) DeepCopy() *KubeletStaticResourceRes
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeletTopologyManager) DeepCopyInto(out *KubeletTopologyManager) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeletTopologyManager.
+func (in *KubeletTopologyManager) DeepCopy() *KubeletTopologyManager {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeletTopologyManager)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MachineFailure) DeepCopyInto(out *MachineFailure) {
 	*out = *in
@@ -385,6 +585,11 @@ func (in *NodeGroupCondition) DeepCopy() *NodeGroupCondition {
 func (in *NodeGroupSpec) DeepCopyInto(out *NodeGroupSpec) {
 	*out = *in
 	in.CRI.DeepCopyInto(&out.CRI)
+	if in.StaticInstances != nil {
+		in, out := &in.StaticInstances, &out.StaticInstances
+		*out = new(StaticInstances)
+		(*in).DeepCopyInto(*out)
+	}
 	in.CloudInstances.DeepCopyInto(&out.CloudInstances)
 	in.NodeTemplate.DeepCopyInto(&out.NodeTemplate)
 	out.Chaos = in.Chaos
@@ -421,6 +626,11 @@ func (in *NodeGroupStatus) DeepCopyInto(out *NodeGroupStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Fallback != nil {
+		in, out := &in.Fallback, &out.Fallback
+		*out = new(FallbackStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -533,6 +743,27 @@ func (in *StandbyHolder) DeepCopy() *StandbyHolder {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticInstances) DeepCopyInto(out *StaticInstances) {
+	*out = *in
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticInstances.
+func (in *StaticInstances) DeepCopy() *StaticInstances {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticInstances)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Update) DeepCopyInto(out *Update) {
 	*out = *in
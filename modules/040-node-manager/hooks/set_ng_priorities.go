@@ -41,6 +41,8 @@ var _ = sdk.RegisterFunc(&go_hook.HookConfig{
 type setPriorityNodeGroup struct {
 	Name     string
 	Priority *int32
+	// Name of the fallback NodeGroup if the fallback is active.
+	ActiveFallback string
 }
 
 func setPriorityFilterNG(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
@@ -51,14 +53,17 @@ func setPriorityFilterNG(obj *unstructured.Unstructured) (go_hook.FilterResult,
 		return nil, err
 	}
 
-	if ng.Spec.CloudInstances.Priority == nil {
-		return nil, nil
-	}
-
-	return setPriorityNodeGroup{
+	res := setPriorityNodeGroup{
 		Name:     ng.Name,
 		Priority: ng.Spec.CloudInstances.Priority,
-	}, nil
+	}
+
+	fallback := ng.Spec.CloudInstances.Fallback
+	if fallback != nil && ng.Status.Fallback != nil && ng.Status.Fallback.Active {
+		res.ActiveFallback = fallback.NodeGroup
+	}
+
+	return res, nil
 }
 
 func handleSetPriorities(input *go_hook.HookInput) error {
@@ -69,14 +74,42 @@ func handleSetPriorities(input *go_hook.HookInput) error {
 	}
 
 	snap := input.Snapshots["ngs"]
+	ngPriorities := make(map[string]int32, len(snap))
 	for _, sn := range snap {
 		if sn == nil {
 			continue
 		}
 		ng := sn.(setPriorityNodeGroup)
 		if ng.Priority != nil {
+			ngPriorities[ng.Name] = *ng.Priority
+		}
+	}
+
+	// Raise the priority of fallback NodeGroups above the priority of NodeGroups with failing instances.
+	for _, sn := range snap {
+		if sn == nil {
+			continue
+		}
+		ng := sn.(setPriorityNodeGroup)
+		// without the priority of the source NodeGroup there is nothing to raise the fallback above
+		if ng.ActiveFallback == "" || ng.Priority == nil {
+			continue
+		}
+
+		sourcePriority := *ng.Priority
+		if p, ok := ngPriorities[ng.ActiveFallback]; !ok || p <= sourcePriority {
+			ngPriorities[ng.ActiveFallback] = sourcePriority + 1
+		}
+	}
+
+	for _, sn := range snap {
+		if sn == nil {
+			continue
+		}
+		ng := sn.(setPriorityNodeGroup)
+		if p, ok := ngPriorities[ng.Name]; ok {
 			key := fmt.Sprintf("^%s-%s-[0-9a-zA-Z]+$", prefix, ng.Name)
-			priorities[*ng.Priority] = append(priorities[*ng.Priority], key)
+			priorities[p] = append(priorities[p], key)
 		}
 	}
 
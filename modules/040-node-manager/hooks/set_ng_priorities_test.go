@@ -55,6 +55,55 @@ spec:
   cloudInstances:
     maxPerZone: 10
     minPerZone: 6
+`
+		stateFallbackNGs = `
+---
+apiVersion: deckhouse.io/v1
+kind: NodeGroup
+metadata:
+  name: spot
+spec:
+  cloudInstances:
+    maxPerZone: 4
+    minPerZone: 1
+    priority: 50
+    fallback:
+      nodeGroup: ng3
+status:
+  fallback:
+    active: true
+    nodeGroup: ng3
+---
+apiVersion: deckhouse.io/v1
+kind: NodeGroup
+metadata:
+  name: spot-inactive
+spec:
+  cloudInstances:
+    maxPerZone: 4
+    minPerZone: 1
+    priority: 30
+    fallback:
+      nodeGroup: ng1
+status:
+  fallback:
+    active: false
+    nodeGroup: ng1
+---
+apiVersion: deckhouse.io/v1
+kind: NodeGroup
+metadata:
+  name: spot-without-priority
+spec:
+  cloudInstances:
+    maxPerZone: 4
+    minPerZone: 1
+    fallback:
+      nodeGroup: ng1
+status:
+  fallback:
+    active: true
+    nodeGroup: ng1
 `
 	)
 	f := HookExecutionConfigInit(`{"nodeManager":{"internal":{}, "instancePrefix": "test"}}`, `{}`)
@@ -73,4 +122,17 @@ spec:
 		})
 	})
 
+	Context("NodeGroups with fallback", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(stateNGs + stateFallbackNGs))
+			f.RunHook()
+		})
+
+		It("Must raise priority of the fallback NodeGroup only for the active fallback of the NodeGroup with the priority", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			m := f.ValuesGet("nodeManager.internal.clusterAutoscalerPriorities").String()
+			Expect(m).To(Equal(`{"20":["^test-ng1-[0-9a-zA-Z]+$"],"30":["^test-spot-inactive-[0-9a-zA-Z]+$"],"50":["^test-ng2-[0-9a-zA-Z]+$","^test-spot-[0-9a-zA-Z]+$"],"51":["^test-ng3-[0-9a-zA-Z]+$"]}`))
+		})
+	})
+
 })
@@ -194,6 +194,15 @@ func updStatusFilterNodeGroup(obj *unstructured.Unstructured) (go_hook.FilterRes
 
 	zonesNum := len(ng.Spec.CloudInstances.Zones)
 
+	var fallback *ngv1.FallbackStatus
+	if ng.Spec.CloudInstances.Fallback != nil {
+		fallback = &ngv1.FallbackStatus{NodeGroup: ng.Spec.CloudInstances.Fallback.NodeGroup}
+		if ng.Status.Fallback != nil {
+			fallback.Active = ng.Status.Fallback.Active
+			fallback.Message = ng.Status.Fallback.Message
+		}
+	}
+
 	return statusNodeGroup{
 		Name:       ng.Name,
 		NodeType:   ng.Spec.NodeType,
@@ -204,6 +213,7 @@ func updStatusFilterNodeGroup(obj *unstructured.Unstructured) (go_hook.FilterRes
 
 		UID:        ng.UID,
 		Conditions: ng.Status.Conditions,
+		Fallback:   fallback,
 	}, nil
 }
 
@@ -393,6 +403,8 @@ func handleUpdateNGStatus(input *go_hook.HookInput) error {
 			Instances: instancesCount,
 
 			HasFrozenMachineDeployment: hasFrozenMd,
+
+			Fallback: nodeGroup.Fallback,
 		}
 		errors := make([]string, 0, 2)
 		if len(nodeGroup.Error) > 0 {
@@ -480,6 +492,9 @@ type statusNodeGroup struct {
 
 	Conditions []ngv1.NodeGroupCondition
 
+	// nil if the fallback is not configured
+	Fallback *ngv1.FallbackStatus
+
 	// for event generation
 	UID apimtypes.UID
 }
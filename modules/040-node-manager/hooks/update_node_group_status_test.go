@@ -332,6 +332,11 @@ status:
 						"status": "False",
 						"type": "Error"
 					},
+					{
+						"lastTransitionTime": "2023-03-03T16:49:52Z",
+						"status": "False",
+						"type": "DrainBlocked"
+					},
 					{
 						"lastTransitionTime": "2023-03-03T16:49:52Z",
 						"status": "True",
@@ -395,6 +400,11 @@ status:
 							"status": "False",
 							"type": "Error"
 						},
+						{
+							"lastTransitionTime": "2023-03-03T16:49:52Z",
+							"status": "False",
+							"type": "DrainBlocked"
+						},
 						{
 							"lastTransitionTime": "2023-03-03T16:49:52Z",
 							"status": "False",
@@ -448,6 +458,11 @@ status:
 							"type": "Error",
 							"message": "Wrong classReference: Kind ImproperInstanceClass is not allowed, the only allowed kind is D8TestInstanceClass."
 						},
+						{
+							"lastTransitionTime": "2023-03-03T16:49:52Z",
+							"status": "False",
+							"type": "DrainBlocked"
+						},
 						{
 							"lastTransitionTime": "2023-03-03T16:49:52Z",
 							"status": "True",
@@ -506,6 +521,11 @@ status:
 							"lastTransitionTime": "2023-03-03T16:49:52Z",
 							"status": "False",
 							"type": "Error"
+						},
+						{
+							"lastTransitionTime": "2023-03-03T16:49:52Z",
+							"status": "False",
+							"type": "DrainBlocked"
 						}
 					],
 					"deckhouse": {
@@ -548,6 +568,11 @@ status:
 							"status": "True",
 							"type": "Error",
 							"message": "Wrong classReference: Kind ImproperInstanceClass is not allowed, the only allowed kind is D8TestInstanceClass."
+						},
+						{
+							"lastTransitionTime": "2023-03-03T16:49:52Z",
+							"status": "False",
+							"type": "DrainBlocked"
 						}
 					],
 					"deckhouse": {
@@ -634,6 +659,11 @@ status:
 							"type": "Error",
 							"message": "Wrong classReference: Kind ImproperInstanceClass is not allowed, the only allowed kind is D8TestInstanceClass.|Cloud provider message - rpc error: code = FailedPrecondition desc = Image not found #2."
 						},
+						{
+							"lastTransitionTime": "2023-03-03T16:49:52Z",
+							"status": "False",
+							"type": "DrainBlocked"
+						},
 						{
 							"lastTransitionTime": "2023-03-03T16:49:52Z",
 							"status": "True",
@@ -729,6 +759,11 @@ status:
 							"type": "Error",
 							"message": "Wrong classReference: Kind ImproperInstanceClass is not allowed, the only allowed kind is D8TestInstanceClass.|Cloud provider message - rpc error: code = FailedPrecondition desc = Image not found #3."
 						},
+						{
+							"lastTransitionTime": "2023-03-03T16:49:52Z",
+							"status": "False",
+							"type": "DrainBlocked"
+						},
 						{
 							"lastTransitionTime": "2023-03-03T16:49:52Z",
 							"status": "True",
@@ -27,6 +27,7 @@ import (
 
 	"github.com/mohae/deepcopy"
 	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
 	"sigs.k8s.io/yaml"
 )
 
@@ -354,19 +355,74 @@ func (rid *registryInputData) FromMap(m map[string][]byte) {
 	if v, ok := m[".dockerconfigjson"]; ok {
 		rid.DockerConfig = v
 	}
+
+	if v, ok := m["mirrors"]; ok && len(v) > 0 {
+		err := json.Unmarshal(v, &rid.Mirrors)
+		if err != nil {
+			klog.Errorf("unmarshal registry mirrors failed: %s", err)
+		}
+	}
 }
 
 func (rid registryInputData) toRegistry() registry {
+	mirrors := make([]registryMirror, 0, len(rid.Mirrors))
+	for _, mirror := range rid.Mirrors {
+		scheme := mirror.Scheme
+		if scheme == "" {
+			scheme = "https"
+		}
+
+		var mirrorAuth string
+		if mirror.DockerCfg != "" {
+			dockerConfig, err := base64.StdEncoding.DecodeString(mirror.DockerCfg)
+			if err != nil {
+				klog.Errorf("decode docker config of the %q registry mirror failed, skipping the mirror: %s", mirror.Address, err)
+				continue
+			}
+
+			mirrorAuth, err = registryAuth(dockerConfig, mirror.Address)
+			if err != nil {
+				klog.Errorf("parse docker config of the %q registry mirror failed, skipping the mirror: %s", mirror.Address, err)
+				continue
+			}
+		}
+
+		mirrors = append(mirrors, registryMirror{
+			Address: mirror.Address,
+			Scheme:  scheme,
+			CA:      mirror.CA,
+			Auth:    mirrorAuth,
+		})
+	}
+
+	auth, err := registryAuth(rid.DockerConfig, rid.Address)
+	if err != nil {
+		klog.Errorf("parse docker config of the %q registry failed: %s", rid.Address, err)
+	}
+
+	return registry{
+		Address:   rid.Address,
+		Path:      rid.Path,
+		Scheme:    rid.Scheme,
+		CA:        rid.CA,
+		DockerCFG: rid.DockerConfig,
+		Auth:      auth,
+		Mirrors:   mirrors,
+	}
+}
+
+// registryAuth returns the auth of the address from the docker config
+func registryAuth(dockerConfig []byte, address string) (string, error) {
 	var auth string
 
-	if len(rid.DockerConfig) > 0 {
+	if len(dockerConfig) > 0 {
 		var dcfg dockerCfg
-		err := json.Unmarshal(rid.DockerConfig, &dcfg)
+		err := json.Unmarshal(dockerConfig, &dcfg)
 		if err != nil {
-			panic(err)
+			return "", err
 		}
 
-		if registryObj, ok := dcfg.Auths[rid.Address]; ok {
+		if registryObj, ok := dcfg.Auths[address]; ok {
 			switch {
 			case registryObj.Auth != "":
 				auth = registryObj.Auth
@@ -377,14 +433,7 @@ func (rid registryInputData) toRegistry() registry {
 		}
 	}
 
-	return registry{
-		Address:   rid.Address,
-		Path:      rid.Path,
-		Scheme:    rid.Scheme,
-		CA:        rid.CA,
-		DockerCFG: rid.DockerConfig,
-		Auth:      auth,
-	}
+	return auth, nil
 }
 
 func versionMapFromMap(m map[string]interface{}) versionMapWrapper {
@@ -527,15 +576,32 @@ type registry struct {
 	CA        string `json:"ca,omitempty" yaml:"ca,omitempty"`
 	DockerCFG []byte `json:"dockerCfg" yaml:"dockerCfg"`
 	Auth      string `json:"auth" yaml:"auth"`
+	// Mirrors serve the same path as the registry
+	Mirrors []registryMirror `json:"mirrors,omitempty" yaml:"mirrors,omitempty"`
+}
+
+type registryMirror struct {
+	Address string `json:"address" yaml:"address"`
+	Scheme  string `json:"scheme" yaml:"scheme"`
+	CA      string `json:"ca,omitempty" yaml:"ca,omitempty"`
+	Auth    string `json:"auth" yaml:"auth"`
 }
 
 // input from secret
 type registryInputData struct {
-	Address      string `json:"address" yaml:"address"`
-	Path         string `json:"path" yaml:"path"`
-	Scheme       string `json:"scheme" yaml:"scheme"`
-	CA           string `json:"ca,omitempty" yaml:"ca,omitempty"`
-	DockerConfig []byte `json:".dockerconfigjson" yaml:".dockerconfigjson"`
+	Address      string                    `json:"address" yaml:"address"`
+	Path         string                    `json:"path" yaml:"path"`
+	Scheme       string                    `json:"scheme" yaml:"scheme"`
+	CA           string                    `json:"ca,omitempty" yaml:"ca,omitempty"`
+	DockerConfig []byte                    `json:".dockerconfigjson" yaml:".dockerconfigjson"`
+	Mirrors      []registryMirrorInputData `json:"mirrors,omitempty" yaml:"mirrors,omitempty"`
+}
+
+type registryMirrorInputData struct {
+	Address   string `json:"address" yaml:"address"`
+	Scheme    string `json:"scheme,omitempty" yaml:"scheme,omitempty"`
+	CA        string `json:"ca,omitempty" yaml:"ca,omitempty"`
+	DockerCfg string `json:"dockerCfg,omitempty" yaml:"dockerCfg,omitempty"`
 }
 
 type dockerCfg struct {
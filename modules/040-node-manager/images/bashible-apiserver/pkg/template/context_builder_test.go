@@ -134,3 +134,40 @@ updateEpoch: "1680009541"
 		}
 	})
 }
+
+func TestRegistryMirrors(t *testing.T) {
+	var input registryInputData
+	input.FromMap(map[string][]byte{
+		"address":           []byte("registry.example.com"),
+		"path":              []byte("/deckhouse"),
+		"scheme":            []byte("https"),
+		".dockerconfigjson": []byte(`{"auths":{"registry.example.com":{"auth":"YTpi"}}}`),
+		// dockerCfg is {"auths":{"mirror.example.com":{"username":"c","password":"d"}}}
+		"mirrors": []byte(`[
+{"address":"mirror.example.com","dockerCfg":"eyJhdXRocyI6eyJtaXJyb3IuZXhhbXBsZS5jb20iOnsidXNlcm5hbWUiOiJjIiwicGFzc3dvcmQiOiJkIn19fQ=="},
+{"address":"mirror2.example.com:5000","scheme":"http"},
+{"address":"broken-base64.example.com","dockerCfg":"!"},
+{"address":"broken-json.example.com","dockerCfg":"bm90IGpzb24="}
+]`),
+	})
+
+	reg := input.toRegistry()
+	if reg.Auth != "YTpi" {
+		t.Errorf("registry auth %q != %q", reg.Auth, "YTpi")
+	}
+
+	// mirrors with a broken docker config are skipped
+	expected := []registryMirror{
+		{Address: "mirror.example.com", Scheme: "https", Auth: "Yzpk"},
+		{Address: "mirror2.example.com:5000", Scheme: "http"},
+	}
+	if fmt.Sprint(reg.Mirrors) != fmt.Sprint(expected) {
+		t.Errorf("mirrors %v != %v", reg.Mirrors, expected)
+	}
+
+	// a broken docker config of the registry does not panic
+	input.DockerConfig = []byte("not json")
+	if reg = input.toRegistry(); reg.Auth != "" {
+		t.Errorf("registry auth %q is not empty", reg.Auth)
+	}
+}
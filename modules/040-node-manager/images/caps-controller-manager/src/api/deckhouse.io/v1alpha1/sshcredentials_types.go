@@ -37,7 +37,13 @@ type SSHCredentialsSpec struct {
 	//+kubebuilder:validation:Maximum=65535
 	SSHPort int `json:"sshPort,omitempty"`
 
+	// Deprecated: only the "-c" (ciphers) and "-m" (MACs) arguments are supported by the built-in SSH client.
 	SSHExtraArgs string `json:"sshExtraArgs,omitempty"`
+
+	// KnownHostKey is the public key in the authorized_keys format or the SHA256 fingerprint of the public key
+	// that the hosts must present. The StaticInstance spec.knownHostKey takes precedence over it.
+	// +optional
+	KnownHostKey string `json:"knownHostKey,omitempty"`
 }
 
 //+kubebuilder:object:root=true
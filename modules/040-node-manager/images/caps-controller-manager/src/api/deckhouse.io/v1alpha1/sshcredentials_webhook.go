@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	"encoding/base64"
+	"strings"
 
 	"golang.org/x/crypto/ssh"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -67,6 +68,11 @@ func (r *SSHCredentials) ValidateCreate() (admission.Warnings, error) {
 		return nil, field.Invalid(field.NewPath("spec", "privateSSHKey"), "******", "privateSSHKey must be a valid private key encoded as base64 string")
 	}
 
+	err = validateKnownHostKey(field.NewPath("spec", "knownHostKey"), r.Spec.KnownHostKey)
+	if err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
@@ -84,6 +90,11 @@ func (r *SSHCredentials) ValidateUpdate(old runtime.Object) (admission.Warnings,
 		return nil, field.Invalid(field.NewPath("spec", "privateSSHKey"), "******", "privateSSHKey must be a valid private key encoded as base64 string")
 	}
 
+	err = validateKnownHostKey(field.NewPath("spec", "knownHostKey"), r.Spec.KnownHostKey)
+	if err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
@@ -93,3 +104,28 @@ func (r *SSHCredentials) ValidateDelete() (admission.Warnings, error) {
 
 	return nil, nil
 }
+
+// validateKnownHostKey checks that the known host key is either a public key in the authorized_keys format
+// or a SHA256 fingerprint of the public key.
+func validateKnownHostKey(path *field.Path, knownHostKey string) error {
+	knownHostKey = strings.TrimSpace(knownHostKey)
+	if knownHostKey == "" {
+		return nil
+	}
+
+	if strings.HasPrefix(knownHostKey, "SHA256:") {
+		hash, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(knownHostKey, "SHA256:"))
+		if err != nil || len(hash) != 32 {
+			return field.Invalid(path, knownHostKey, "knownHostKey must be a valid SHA256 fingerprint, e.g., 'SHA256:nThbg6kXUpJWGl7E1IGOCspRomTxdCARLviKw6E5SY8'")
+		}
+
+		return nil
+	}
+
+	_, _, _, _, err := ssh.ParseAuthorizedKey([]byte(knownHostKey))
+	if err != nil {
+		return field.Invalid(path, knownHostKey, "knownHostKey must be a public key in the authorized_keys format, e.g., 'ssh-ed25519 AAAA...', or a SHA256 fingerprint")
+	}
+
+	return nil
+}
@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
@@ -32,6 +33,11 @@ type StaticInstanceSpec struct {
 
 	Address        string                  `json:"address"`
 	CredentialsRef *corev1.ObjectReference `json:"credentialsRef"`
+
+	// KnownHostKey is the public key in the authorized_keys format or the SHA256 fingerprint of the public key
+	// that the host must present.
+	// +optional
+	KnownHostKey string `json:"knownHostKey,omitempty"`
 }
 
 // StaticInstanceStatus defines the observed state of StaticInstance
@@ -48,6 +54,10 @@ type StaticInstanceStatus struct {
 	// +optional
 	CurrentStatus *StaticInstanceStatusCurrentStatus `json:"currentStatus,omitempty"`
 
+	// Inventory contains the hardware and OS information collected from the host over SSH.
+	// +optional
+	Inventory *StaticInstanceInventory `json:"inventory,omitempty"`
+
 	// Conditions defines current service state of the StaticInstance.
 	// +optional
 	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
@@ -62,6 +72,74 @@ type StaticInstanceStatusCurrentStatus struct {
 	Phase StaticInstanceStatusCurrentStatusPhase `json:"phase"`
 }
 
+type StaticInstanceInventory struct {
+	// +optional
+	LastProbeTime metav1.Time `json:"lastProbeTime,omitempty"`
+
+	// +optional
+	CPU StaticInstanceInventoryCPU `json:"cpu,omitempty"`
+
+	// +optional
+	Memory resource.Quantity `json:"memory,omitempty"`
+
+	// +optional
+	Disks []StaticInstanceInventoryDisk `json:"disks,omitempty"`
+
+	// +optional
+	OS StaticInstanceInventoryOS `json:"os,omitempty"`
+
+	// +optional
+	NetworkInterfaces []StaticInstanceInventoryNetworkInterface `json:"networkInterfaces,omitempty"`
+}
+
+type StaticInstanceInventoryCPU struct {
+	// +optional
+	Model string `json:"model,omitempty"`
+
+	// +optional
+	Cores int32 `json:"cores,omitempty"`
+
+	// +optional
+	Architecture string `json:"architecture,omitempty"`
+}
+
+type StaticInstanceInventoryDisk struct {
+	Name string `json:"name"`
+
+	// +optional
+	Size resource.Quantity `json:"size,omitempty"`
+
+	// +optional
+	Rotational bool `json:"rotational,omitempty"`
+}
+
+type StaticInstanceInventoryOS struct {
+	// +optional
+	ID string `json:"id,omitempty"`
+
+	// +optional
+	IDLike string `json:"idLike,omitempty"`
+
+	// +optional
+	VersionID string `json:"versionID,omitempty"`
+
+	// +optional
+	PrettyName string `json:"prettyName,omitempty"`
+
+	// +optional
+	KernelVersion string `json:"kernelVersion,omitempty"`
+}
+
+type StaticInstanceInventoryNetworkInterface struct {
+	Name string `json:"name"`
+
+	// +optional
+	MACAddress string `json:"macAddress,omitempty"`
+
+	// +optional
+	Addresses []string `json:"addresses,omitempty"`
+}
+
 type StaticInstanceStatusCurrentStatusPhase string
 
 const (
@@ -50,7 +50,7 @@ func (r *StaticInstance) Default() {
 }
 
 // TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
-//+kubebuilder:webhook:path=/validate-deckhouse-io-v1alpha1-staticinstance,mutating=false,failurePolicy=fail,sideEffects=None,groups=deckhouse.io,resources=staticinstances,verbs=update;delete,versions=v1alpha1,name=vstaticinstance.deckhouse.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-deckhouse-io-v1alpha1-staticinstance,mutating=false,failurePolicy=fail,sideEffects=None,groups=deckhouse.io,resources=staticinstances,verbs=create;update;delete,versions=v1alpha1,name=vstaticinstance.deckhouse.io,admissionReviewVersions=v1
 
 var _ webhook.Validator = &StaticInstance{}
 
@@ -58,6 +58,11 @@ var _ webhook.Validator = &StaticInstance{}
 func (r *StaticInstance) ValidateCreate() (admission.Warnings, error) {
 	staticinstancelog.Info("validate create", "name", r.Name)
 
+	err := validateKnownHostKey(field.NewPath("spec", "knownHostKey"), r.Spec.KnownHostKey)
+	if err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
@@ -70,6 +75,11 @@ func (r *StaticInstance) ValidateUpdate(old runtime.Object) (admission.Warnings,
 		return nil, field.Forbidden(field.NewPath("spec", "address"), "StaticInstance address is immutable")
 	}
 
+	err := validateKnownHostKey(field.NewPath("spec", "knownHostKey"), r.Spec.KnownHostKey)
+	if err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
@@ -127,6 +127,105 @@ func (in *StaticInstance) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticInstanceInventory) DeepCopyInto(out *StaticInstanceInventory) {
+	*out = *in
+	in.LastProbeTime.DeepCopyInto(&out.LastProbeTime)
+	out.CPU = in.CPU
+	out.Memory = in.Memory.DeepCopy()
+	if in.Disks != nil {
+		in, out := &in.Disks, &out.Disks
+		*out = make([]StaticInstanceInventoryDisk, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.OS = in.OS
+	if in.NetworkInterfaces != nil {
+		in, out := &in.NetworkInterfaces, &out.NetworkInterfaces
+		*out = make([]StaticInstanceInventoryNetworkInterface, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticInstanceInventory.
+func (in *StaticInstanceInventory) DeepCopy() *StaticInstanceInventory {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticInstanceInventory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticInstanceInventoryCPU) DeepCopyInto(out *StaticInstanceInventoryCPU) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticInstanceInventoryCPU.
+func (in *StaticInstanceInventoryCPU) DeepCopy() *StaticInstanceInventoryCPU {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticInstanceInventoryCPU)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticInstanceInventoryDisk) DeepCopyInto(out *StaticInstanceInventoryDisk) {
+	*out = *in
+	out.Size = in.Size.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticInstanceInventoryDisk.
+func (in *StaticInstanceInventoryDisk) DeepCopy() *StaticInstanceInventoryDisk {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticInstanceInventoryDisk)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticInstanceInventoryNetworkInterface) DeepCopyInto(out *StaticInstanceInventoryNetworkInterface) {
+	*out = *in
+	if in.Addresses != nil {
+		in, out := &in.Addresses, &out.Addresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticInstanceInventoryNetworkInterface.
+func (in *StaticInstanceInventoryNetworkInterface) DeepCopy() *StaticInstanceInventoryNetworkInterface {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticInstanceInventoryNetworkInterface)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticInstanceInventoryOS) DeepCopyInto(out *StaticInstanceInventoryOS) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticInstanceInventoryOS.
+func (in *StaticInstanceInventoryOS) DeepCopy() *StaticInstanceInventoryOS {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticInstanceInventoryOS)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StaticInstanceList) DeepCopyInto(out *StaticInstanceList) {
 	*out = *in
@@ -197,6 +296,11 @@ func (in *StaticInstanceStatus) DeepCopyInto(out *StaticInstanceStatus) {
 		*out = new(StaticInstanceStatusCurrentStatus)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Inventory != nil {
+		in, out := &in.Inventory, &out.Inventory
+		*out = new(StaticInstanceInventory)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make(v1beta1.Conditions, len(*in))
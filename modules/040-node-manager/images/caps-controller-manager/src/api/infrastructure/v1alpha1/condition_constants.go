@@ -36,6 +36,29 @@ const (
 	// StaticInstanceWaitingForNodeRefReason indicates when a StaticInstance is registered into a capacity pool and
 	// waiting for a StaticInstance.Status.NodeRef to be assigned.
 	StaticInstanceWaitingForNodeRefReason = "WaitingForNodeRefToBeAssigned"
+
+	// StaticInstanceInventoryCollectedCondition documents that the hardware and OS inventory is collected from the host.
+	StaticInstanceInventoryCollectedCondition clusterv1.ConditionType = "InventoryCollected"
+
+	// StaticInstanceInventoryCollectionFailedReason indicates that the inventory could not be collected over SSH.
+	StaticInstanceInventoryCollectionFailedReason = "InventoryCollectionFailed"
+
+	// StaticInstanceUnsupportedOSReason indicates that the OS of the host is not supported and the StaticInstance
+	// will not be bootstrapped.
+	StaticInstanceUnsupportedOSReason = "UnsupportedOS"
+
+	// StaticInstanceSSHConnectedCondition documents that CAPS is able to connect to the host over SSH.
+	StaticInstanceSSHConnectedCondition clusterv1.ConditionType = "SSHConnected"
+
+	// StaticInstanceSSHConnectionFailedReason indicates that the host is unreachable over SSH.
+	StaticInstanceSSHConnectionFailedReason = "SSHConnectionFailed"
+
+	// StaticInstanceSSHAuthenticationFailedReason indicates that the host rejected the SSH credentials.
+	StaticInstanceSSHAuthenticationFailedReason = "SSHAuthenticationFailed"
+
+	// StaticInstanceSSHHostKeyMismatchReason indicates that the host key presented by the host
+	// doesn't match the known host key from the StaticInstance or SSHCredentials.
+	StaticInstanceSSHHostKeyMismatchReason = "SSHHostKeyMismatch"
 )
 
 // Conditions and Reasons defined on StaticMachine.
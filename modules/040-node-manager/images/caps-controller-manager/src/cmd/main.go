@@ -116,11 +116,13 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "StaticCluster")
 		os.Exit(1)
 	}
+	hostClient := client.NewClient(recorder)
+
 	if err = (&infrastructurecontroller.StaticMachineReconciler{
 		Client:     mgr.GetClient(),
 		Scheme:     mgr.GetScheme(),
 		Config:     mgr.GetConfig(),
-		HostClient: client.NewClient(recorder),
+		HostClient: hostClient,
 		Recorder:   recorder,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "StaticMachine")
@@ -131,10 +133,11 @@ func main() {
 		os.Exit(1)
 	}
 	if err = (&deckhouseiocontroller.StaticInstanceReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Config:   mgr.GetConfig(),
-		Recorder: recorder,
+		Client:     mgr.GetClient(),
+		Scheme:     mgr.GetScheme(),
+		Config:     mgr.GetConfig(),
+		HostClient: hostClient,
+		Recorder:   recorder,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "StaticInstance")
 		os.Exit(1)
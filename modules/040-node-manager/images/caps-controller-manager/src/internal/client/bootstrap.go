@@ -33,7 +33,6 @@ import (
 	infrav1 "caps-controller-manager/api/infrastructure/v1alpha1"
 	"caps-controller-manager/internal/providerid"
 	"caps-controller-manager/internal/scope"
-	"caps-controller-manager/internal/ssh"
 )
 
 // Bootstrap runs the bootstrap script on StaticInstance.
@@ -137,8 +136,10 @@ func (c *Client) bootstrap(ctx context.Context, instanceScope *scope.InstanceSco
 		}
 	}
 
-	done := c.bootstrapTaskManager.spawn(instanceScope.MachineScope.StaticMachine.Spec.ProviderID, func() bool {
-		err := ssh.ExecSSHCommand(instanceScope, fmt.Sprintf("mkdir -p /var/lib/bashible && echo '%s' > /var/lib/bashible/node-spec-provider-id && echo '%s' > /var/lib/bashible/machine-name && echo '%s' | base64 -d | bash", instanceScope.MachineScope.StaticMachine.Spec.ProviderID, instanceScope.MachineScope.Machine.Name, base64.StdEncoding.EncodeToString(bootstrapScript)), nil)
+	providerID := instanceScope.MachineScope.StaticMachine.Spec.ProviderID
+
+	done := c.bootstrapTaskManager.spawn(providerID, func() bool {
+		err := c.execSSHCommand(providerID, instanceScope, fmt.Sprintf("mkdir -p /var/lib/bashible && echo '%s' > /var/lib/bashible/node-spec-provider-id && echo '%s' > /var/lib/bashible/machine-name && echo '%s' | base64 -d | bash", instanceScope.MachineScope.StaticMachine.Spec.ProviderID, instanceScope.MachineScope.Machine.Name, base64.StdEncoding.EncodeToString(bootstrapScript)))
 		if err != nil {
 			// If Node reboots, the ssh connection will close, and we will get an error.
 			instanceScope.Logger.Error(err, "Failed to bootstrap StaticInstance: failed to exec ssh command")
@@ -148,6 +149,14 @@ func (c *Client) bootstrap(ctx context.Context, instanceScope *scope.InstanceSco
 
 		return true
 	})
+
+	if c.updateSSHConnectedCondition(providerID, instanceScope) {
+		err := instanceScope.Patch(ctx)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to patch StaticInstance SSHConnected condition")
+		}
+	}
+
 	if done {
 		c.recorder.SendNormalEvent(instanceScope.Instance, instanceScope.MachineScope.StaticMachine.Labels["node-group"], "BootstrapScriptSucceeded", "Bootstrap script executed successfully")
 	} else {
@@ -23,7 +23,6 @@ import (
 
 	deckhousev1 "caps-controller-manager/api/deckhouse.io/v1alpha1"
 	"caps-controller-manager/internal/scope"
-	"caps-controller-manager/internal/ssh"
 )
 
 // Cleanup runs the cleanup script on StaticInstance.
@@ -56,19 +55,25 @@ func (c *Client) cleanupFromBootstrappingOrRunningPhase(ctx context.Context, ins
 		return errors.Wrap(err, "failed to patch StaticInstance phase")
 	}
 
-	c.cleanup(instanceScope)
+	_, err = c.cleanup(ctx, instanceScope)
+	if err != nil {
+		return errors.Wrap(err, "failed to clean up StaticInstance")
+	}
 
 	return nil
 }
 
 // cleanupFromCleaningPhase finishes the cleanup process by checking if the cleanup script was successfully executed and patching StaticInstance.
 func (c *Client) cleanupFromCleaningPhase(ctx context.Context, instanceScope *scope.InstanceScope) error {
-	done := c.cleanup(instanceScope)
+	done, err := c.cleanup(ctx, instanceScope)
+	if err != nil {
+		return errors.Wrap(err, "failed to clean up StaticInstance")
+	}
 	if !done {
 		return nil
 	}
 
-	err := instanceScope.ToPending(ctx)
+	err = instanceScope.ToPending(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to set StaticInstance to Pending phase")
 	}
@@ -76,9 +81,11 @@ func (c *Client) cleanupFromCleaningPhase(ctx context.Context, instanceScope *sc
 	return nil
 }
 
-func (c *Client) cleanup(instanceScope *scope.InstanceScope) bool {
-	done := c.cleanupTaskManager.spawn(instanceScope.MachineScope.StaticMachine.Spec.ProviderID, func() bool {
-		err := ssh.ExecSSHCommand(instanceScope, "test -f /var/lib/bashible/cleanup_static_node.sh || exit 0 && bash /var/lib/bashible/cleanup_static_node.sh --yes-i-am-sane-and-i-understand-what-i-am-doing", nil)
+func (c *Client) cleanup(ctx context.Context, instanceScope *scope.InstanceScope) (bool, error) {
+	providerID := instanceScope.MachineScope.StaticMachine.Spec.ProviderID
+
+	done := c.cleanupTaskManager.spawn(providerID, func() bool {
+		err := c.execSSHCommand(providerID, instanceScope, "test -f /var/lib/bashible/cleanup_static_node.sh || exit 0 && bash /var/lib/bashible/cleanup_static_node.sh --yes-i-am-sane-and-i-understand-what-i-am-doing")
 		if err != nil {
 			instanceScope.Logger.Error(err, "Failed to clean up StaticInstance: failed to exec ssh command")
 
@@ -87,11 +94,19 @@ func (c *Client) cleanup(instanceScope *scope.InstanceScope) bool {
 
 		return true
 	})
+
+	if c.updateSSHConnectedCondition(providerID, instanceScope) {
+		err := instanceScope.Patch(ctx)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to patch StaticInstance SSHConnected condition")
+		}
+	}
+
 	if done {
 		c.recorder.SendNormalEvent(instanceScope.Instance, instanceScope.MachineScope.StaticMachine.Labels["node-group"], "CleanupScriptSucceeded", "Cleanup script executed successfully")
 	} else {
 		instanceScope.Logger.Info("Cleaning is not finished yet, waiting...")
 	}
 
-	return done
+	return done, nil
 }
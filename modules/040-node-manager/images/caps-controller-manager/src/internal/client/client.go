@@ -17,14 +17,21 @@ limitations under the License.
 package client
 
 import (
+	"sync"
+
 	"caps-controller-manager/internal/event"
 )
 
-// Client is a client that executes commands on hosts using the OpenSSH client.
+// Client is a client that executes commands on hosts over SSH.
 // It spawns tasks and stores their results by providerID.
 type Client struct {
 	bootstrapTaskManager *taskManager
 	cleanupTaskManager   *taskManager
+	inventoryTaskManager *taskManager
+
+	inventoryResults sync.Map
+	// sshResults stores the errors of SSH commands executed by bootstrap and cleanup tasks by providerID.
+	sshResults sync.Map
 
 	recorder *event.Recorder
 }
@@ -34,6 +41,7 @@ func NewClient(recorder *event.Recorder) *Client {
 	return &Client{
 		bootstrapTaskManager: newTaskManager(),
 		cleanupTaskManager:   newTaskManager(),
+		inventoryTaskManager: newTaskManager(),
 		recorder:             recorder,
 	}
 }
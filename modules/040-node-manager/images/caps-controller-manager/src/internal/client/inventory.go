@@ -0,0 +1,57 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"github.com/pkg/errors"
+
+	deckhousev1 "caps-controller-manager/api/deckhouse.io/v1alpha1"
+	"caps-controller-manager/internal/inventory"
+	"caps-controller-manager/internal/providerid"
+	"caps-controller-manager/internal/scope"
+)
+
+type inventoryResult struct {
+	inventory *deckhousev1.StaticInstanceInventory
+	err       error
+}
+
+// CollectInventory collects the hardware and OS inventory of StaticInstance over SSH.
+// The inventory is collected in the background, it returns false until the collection is finished.
+func (c *Client) CollectInventory(instanceScope *scope.InstanceScope) (*deckhousev1.StaticInstanceInventory, bool, error) {
+	key := providerid.GenerateProviderID(instanceScope.Instance.Name)
+
+	done := c.inventoryTaskManager.spawn(key, func() bool {
+		result, err := inventory.Collect(instanceScope)
+
+		c.inventoryResults.Store(key, inventoryResult{inventory: result, err: err})
+
+		return true
+	})
+	if !done {
+		return nil, false, nil
+	}
+
+	result, ok := c.inventoryResults.LoadAndDelete(key)
+	if !ok {
+		return nil, false, errors.New("inventory collection result is lost")
+	}
+
+	c.setSSHConnectedCondition(instanceScope, result.(inventoryResult).err)
+
+	return result.(inventoryResult).inventory, true, result.(inventoryResult).err
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "caps-controller-manager/api/infrastructure/v1alpha1"
+	"caps-controller-manager/internal/providerid"
+	"caps-controller-manager/internal/scope"
+	"caps-controller-manager/internal/ssh"
+)
+
+// execSSHCommand executes a command on StaticInstance and stores the error to report it in the SSHConnected condition later.
+// Tasks are executed in the background, so they can't change StaticInstance themselves.
+func (c *Client) execSSHCommand(key providerid.ProviderID, instanceScope *scope.InstanceScope, command string) error {
+	err := ssh.ExecSSHCommand(instanceScope, command, nil)
+
+	c.sshResults.Store(key, err)
+
+	return err
+}
+
+// updateSSHConnectedCondition sets the SSHConnected condition according to the result of the last finished task.
+// It returns false if no task has finished since the last call.
+func (c *Client) updateSSHConnectedCondition(key providerid.ProviderID, instanceScope *scope.InstanceScope) bool {
+	result, ok := c.sshResults.LoadAndDelete(key)
+	if !ok {
+		return false
+	}
+
+	err, _ := result.(error)
+
+	c.setSSHConnectedCondition(instanceScope, err)
+
+	return true
+}
+
+// setSSHConnectedCondition sets the SSHConnected condition. The errors of the executed commands don't affect it.
+func (c *Client) setSSHConnectedCondition(instanceScope *scope.InstanceScope, err error) {
+	sshErr, ok := ssh.AsError(err)
+	if !ok {
+		conditions.MarkTrue(instanceScope.Instance, infrav1.StaticInstanceSSHConnectedCondition)
+
+		return
+	}
+
+	if conditions.GetReason(instanceScope.Instance, infrav1.StaticInstanceSSHConnectedCondition) != sshErr.Reason {
+		var nodeGroup string
+
+		if instanceScope.MachineScope != nil {
+			nodeGroup = instanceScope.MachineScope.StaticMachine.Labels["node-group"]
+		}
+
+		c.recorder.SendWarningEvent(instanceScope.Instance, nodeGroup, sshErr.Reason, sshErr.Err.Error())
+	}
+
+	severity := clusterv1.ConditionSeverityWarning
+	if sshErr.Reason == infrav1.StaticInstanceSSHHostKeyMismatchReason {
+		severity = clusterv1.ConditionSeverityError
+	}
+
+	conditions.MarkFalse(instanceScope.Instance, infrav1.StaticInstanceSSHConnectedCondition, sshErr.Reason, severity, "%s", sshErr.Err.Error())
+}
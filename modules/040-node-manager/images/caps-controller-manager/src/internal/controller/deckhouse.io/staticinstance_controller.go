@@ -19,12 +19,14 @@ package controller
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -33,19 +35,27 @@ import (
 
 	deckhousev1 "caps-controller-manager/api/deckhouse.io/v1alpha1"
 	infrav1 "caps-controller-manager/api/infrastructure/v1alpha1"
+	hostclient "caps-controller-manager/internal/client"
 	controller "caps-controller-manager/internal/controller/infrastructure"
 	"caps-controller-manager/internal/event"
+	"caps-controller-manager/internal/inventory"
 	"caps-controller-manager/internal/scope"
 )
 
 // StaticInstanceReconciler reconciles a StaticInstance object
 type StaticInstanceReconciler struct {
 	client.Client
-	Scheme   *runtime.Scheme
-	Config   *rest.Config
-	Recorder *event.Recorder
+	Scheme     *runtime.Scheme
+	Config     *rest.Config
+	HostClient *hostclient.Client
+	Recorder   *event.Recorder
 }
 
+const (
+	RequeueForInventoryCollecting = 10 * time.Second
+	RequeueForInventoryFailed     = 5 * time.Minute
+)
+
 //+kubebuilder:rbac:groups=deckhouse.io,resources=staticinstances,verbs=get;list;watch;update;patch
 //+kubebuilder:rbac:groups=deckhouse.io,resources=staticinstances/status,verbs=get;update;patch
 
@@ -144,6 +154,10 @@ func (r *StaticInstanceReconciler) reconcileNormal(
 		instanceScope.Logger.Info("StaticInstance is pending")
 	}
 
+	if instanceScope.GetPhase() == deckhousev1.StaticInstanceStatusCurrentStatusPhasePending && instanceScope.Instance.Status.Inventory == nil {
+		return r.collectInventory(instanceScope)
+	}
+
 	if instanceScope.MachineScope != nil {
 		instances := &deckhousev1.StaticInstanceList{}
 
@@ -181,6 +195,54 @@ func (r *StaticInstanceReconciler) reconcileNormal(
 	return ctrl.Result{}, nil
 }
 
+// collectInventory collects the hardware and OS inventory of the pending StaticInstance and sets labels derived from it.
+// StaticInstances with an unsupported OS are not picked for bootstrap.
+func (r *StaticInstanceReconciler) collectInventory(instanceScope *scope.InstanceScope) (ctrl.Result, error) {
+	instanceInventory, done, err := r.HostClient.CollectInventory(instanceScope)
+	if err != nil {
+		instanceScope.Logger.Error(err, "Failed to collect StaticInstance inventory")
+
+		if conditions.GetReason(instanceScope.Instance, infrav1.StaticInstanceInventoryCollectedCondition) != infrav1.StaticInstanceInventoryCollectionFailedReason {
+			r.Recorder.SendWarningEvent(instanceScope.Instance, "", "InventoryCollectionFailed", err.Error())
+		}
+
+		conditions.MarkFalse(instanceScope.Instance, infrav1.StaticInstanceInventoryCollectedCondition, infrav1.StaticInstanceInventoryCollectionFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+
+		return ctrl.Result{RequeueAfter: RequeueForInventoryFailed}, nil
+	}
+	if !done {
+		return ctrl.Result{RequeueAfter: RequeueForInventoryCollecting}, nil
+	}
+
+	instanceScope.Instance.Status.Inventory = instanceInventory
+
+	instanceLabels := instanceScope.Instance.GetLabels()
+	if instanceLabels == nil {
+		instanceLabels = make(map[string]string)
+	}
+	for key, value := range inventory.Labels(instanceInventory) {
+		instanceLabels[key] = value
+	}
+	instanceScope.Instance.SetLabels(instanceLabels)
+
+	err = inventory.CheckOS(instanceInventory.OS)
+	if err != nil {
+		instanceScope.Logger.Info("StaticInstance has unsupported OS and will not be bootstrapped", "reason", err.Error())
+
+		r.Recorder.SendWarningEvent(instanceScope.Instance, "", "UnsupportedOS", err.Error())
+
+		conditions.MarkFalse(instanceScope.Instance, infrav1.StaticInstanceInventoryCollectedCondition, infrav1.StaticInstanceUnsupportedOSReason, clusterv1.ConditionSeverityError, err.Error())
+
+		return ctrl.Result{}, nil
+	}
+
+	conditions.MarkTrue(instanceScope.Instance, infrav1.StaticInstanceInventoryCollectedCondition)
+
+	instanceScope.Logger.Info("StaticInstance inventory is collected", "os", instanceInventory.OS.PrettyName, "cpuCores", instanceInventory.CPU.Cores, "memory", instanceInventory.Memory.String())
+
+	return ctrl.Result{}, nil
+}
+
 func (r *StaticInstanceReconciler) getStaticMachine(
 	ctx context.Context,
 	staticInstance *deckhousev1.StaticInstance,
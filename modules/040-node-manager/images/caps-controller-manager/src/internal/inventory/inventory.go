@@ -0,0 +1,273 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	deckhousev1 "caps-controller-manager/api/deckhouse.io/v1alpha1"
+	"caps-controller-manager/internal/scope"
+	"caps-controller-manager/internal/ssh"
+)
+
+// Labels derived from the inventory. They can be used in the NodeGroup staticInstances.labelSelector.
+const (
+	LabelOSID          = "inventory.deckhouse.io/os-id"
+	LabelOSVersion     = "inventory.deckhouse.io/os-version"
+	LabelKernelVersion = "inventory.deckhouse.io/kernel-version"
+	LabelArchitecture  = "inventory.deckhouse.io/architecture"
+	LabelCPUCores      = "inventory.deckhouse.io/cpu-cores"
+	LabelMemoryGiB     = "inventory.deckhouse.io/memory-gib"
+)
+
+// probeScript prints the inventory of the host as "key=value" lines.
+const probeScript = `
+echo "cpu.cores=$(nproc --all 2>/dev/null || grep -c '^processor' /proc/cpuinfo)"
+echo "cpu.model=$(grep -m1 '^model name' /proc/cpuinfo | cut -d: -f2- | sed 's/^ *//')"
+echo "cpu.architecture=$(uname -m)"
+echo "memory.kb=$(awk '/^MemTotal:/ {print $2}' /proc/meminfo)"
+echo "os.kernelVersion=$(uname -r)"
+if [ -e /etc/os-release ]; then
+  (
+    . /etc/os-release
+    echo "os.id=${ID}"
+    echo "os.idLike=${ID_LIKE}"
+    echo "os.versionID=${VERSION_ID}"
+    echo "os.prettyName=${PRETTY_NAME}"
+  )
+fi
+for disk in /sys/block/*; do
+  name="$(basename "${disk}")"
+  case "${name}" in loop*|ram*|zram*|sr*|fd*) continue ;; esac
+  echo "disk=${name} $(( $(cat "${disk}/size") * 512 )) $(cat "${disk}/queue/rotational" 2>/dev/null || echo 0)"
+done
+for iface in /sys/class/net/*; do
+  name="$(basename "${iface}")"
+  [ "${name}" = "lo" ] && continue
+  mac="$(cat "${iface}/address" 2>/dev/null)"
+  echo "iface=${name} ${mac:--} $(ip -o addr show dev "${name}" 2>/dev/null | awk '{print $4}' | tr '\n' ' ')"
+done
+`
+
+// Collect probes the StaticInstance over SSH and returns its inventory.
+func Collect(instanceScope *scope.InstanceScope) (*deckhousev1.StaticInstanceInventory, error) {
+	output, err := ssh.ExecSSHCommandToString(instanceScope, fmt.Sprintf("echo '%s' | base64 -d | sh", base64.StdEncoding.EncodeToString([]byte(probeScript))))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to run inventory probe script")
+	}
+
+	inventory, err := Parse(output)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse inventory probe script output")
+	}
+
+	inventory.LastProbeTime = metav1.NewTime(time.Now().UTC())
+
+	return inventory, nil
+}
+
+// Parse parses the output of the probe script.
+func Parse(output string) (*deckhousev1.StaticInstanceInventory, error) {
+	inventory := &deckhousev1.StaticInstanceInventory{}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "cpu.cores":
+			cores, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse CPU cores '%s'", value)
+			}
+
+			inventory.CPU.Cores = int32(cores)
+		case "cpu.model":
+			inventory.CPU.Model = value
+		case "cpu.architecture":
+			inventory.CPU.Architecture = normalizeArchitecture(value)
+		case "memory.kb":
+			kb, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse memory size '%s'", value)
+			}
+
+			inventory.Memory = *resource.NewQuantity(kb*1024, resource.BinarySI)
+		case "os.kernelVersion":
+			inventory.OS.KernelVersion = value
+		case "os.id":
+			inventory.OS.ID = value
+		case "os.idLike":
+			inventory.OS.IDLike = value
+		case "os.versionID":
+			inventory.OS.VersionID = value
+		case "os.prettyName":
+			inventory.OS.PrettyName = value
+		case "disk":
+			fields := strings.Fields(value)
+			if len(fields) != 3 {
+				return nil, errors.Errorf("failed to parse disk '%s'", value)
+			}
+
+			size, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse disk '%s' size", fields[0])
+			}
+
+			inventory.Disks = append(inventory.Disks, deckhousev1.StaticInstanceInventoryDisk{
+				Name:       fields[0],
+				Size:       *resource.NewQuantity(size, resource.BinarySI),
+				Rotational: fields[2] == "1",
+			})
+		case "iface":
+			fields := strings.Fields(value)
+			if len(fields) < 2 {
+				return nil, errors.Errorf("failed to parse network interface '%s'", value)
+			}
+
+			iface := deckhousev1.StaticInstanceInventoryNetworkInterface{
+				Name:      fields[0],
+				Addresses: fields[2:],
+			}
+
+			// the MAC address is absent for some virtual interfaces, e.g., tun
+			if fields[1] != "-" {
+				iface.MACAddress = fields[1]
+			}
+
+			inventory.NetworkInterfaces = append(inventory.NetworkInterfaces, iface)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read probe script output")
+	}
+
+	if inventory.OS.KernelVersion == "" {
+		return nil, errors.New("probe script output doesn't contain kernel version")
+	}
+
+	return inventory, nil
+}
+
+// Labels returns the StaticInstance labels derived from the inventory.
+func Labels(inventory *deckhousev1.StaticInstanceInventory) map[string]string {
+	labels := map[string]string{
+		LabelOSID:          inventory.OS.ID,
+		LabelOSVersion:     inventory.OS.VersionID,
+		LabelKernelVersion: inventory.OS.KernelVersion,
+		LabelArchitecture:  inventory.CPU.Architecture,
+	}
+
+	if inventory.CPU.Cores > 0 {
+		labels[LabelCPUCores] = strconv.Itoa(int(inventory.CPU.Cores))
+	}
+
+	if gib := inventory.Memory.Value() / (1 << 30); gib > 0 {
+		labels[LabelMemoryGiB] = strconv.FormatInt(gib, 10)
+	}
+
+	for key, value := range labels {
+		value = sanitizeLabelValue(value)
+		if value == "" {
+			delete(labels, key)
+			continue
+		}
+
+		labels[key] = value
+	}
+
+	return labels
+}
+
+// CheckOS returns an error if the OS is not supported by bashible.
+// The rules are the same as in the candi/bashible/detect_bundle.sh script: the well-known distributions
+// are supported only in the listed versions, other distributions are bootstrapped with the best matching bundle.
+func CheckOS(os deckhousev1.StaticInstanceInventoryOS) error {
+	var (
+		supportedVersions []string
+		// minor versions are accepted, e.g., 8.6 for 8
+		allowMinorVersions bool
+	)
+
+	switch os.ID {
+	case "":
+		return errors.New("failed to determine OS: no ID in /etc/os-release")
+	case "centos", "rocky", "almalinux", "rhel":
+		supportedVersions = []string{"7", "8", "9"}
+		allowMinorVersions = true
+	case "ubuntu":
+		supportedVersions = []string{"18.04", "20.04", "22.04"}
+	case "debian":
+		supportedVersions = []string{"9", "10", "11"}
+	default:
+		return nil
+	}
+
+	for _, version := range supportedVersions {
+		if os.VersionID == version || (allowMinorVersions && strings.HasPrefix(os.VersionID, version+".")) {
+			return nil
+		}
+	}
+
+	return errors.Errorf("%s is not supported, supported versions: %s", osName(os), strings.Join(supportedVersions, ", "))
+}
+
+func osName(os deckhousev1.StaticInstanceInventoryOS) string {
+	if os.PrettyName != "" {
+		return os.PrettyName
+	}
+
+	return strings.TrimSpace(os.ID + " " + os.VersionID)
+}
+
+func normalizeArchitecture(arch string) string {
+	switch arch {
+	case "x86_64":
+		return "amd64"
+	case "aarch64":
+		return "arm64"
+	default:
+		return arch
+	}
+}
+
+var invalidLabelValueChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// sanitizeLabelValue makes the value a valid label value: at most 63 characters [A-Za-z0-9._-],
+// beginning and ending with an alphanumeric character.
+func sanitizeLabelValue(value string) string {
+	value = invalidLabelValueChars.ReplaceAllString(value, "_")
+	if len(value) > 63 {
+		value = value[:63]
+	}
+
+	return strings.Trim(value, "._-")
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"reflect"
+	"testing"
+
+	deckhousev1 "caps-controller-manager/api/deckhouse.io/v1alpha1"
+)
+
+func TestCheckOS(t *testing.T) {
+	tests := []struct {
+		id        string
+		versionID string
+		supported bool
+	}{
+		{id: "", versionID: "", supported: false},
+
+		{id: "centos", versionID: "7", supported: true},
+		{id: "centos", versionID: "7.9", supported: true},
+		{id: "rocky", versionID: "8.6", supported: true},
+		{id: "almalinux", versionID: "9", supported: true},
+		{id: "rhel", versionID: "9.2", supported: true},
+		{id: "centos", versionID: "6.10", supported: false},
+		{id: "rocky", versionID: "10.0", supported: false},
+		{id: "rhel", versionID: "70", supported: false},
+
+		{id: "ubuntu", versionID: "18.04", supported: true},
+		{id: "ubuntu", versionID: "20.04", supported: true},
+		{id: "ubuntu", versionID: "22.04", supported: true},
+		{id: "ubuntu", versionID: "18.04.6", supported: false},
+		{id: "ubuntu", versionID: "18.10", supported: false},
+		{id: "ubuntu", versionID: "24.04", supported: false},
+
+		{id: "debian", versionID: "9", supported: true},
+		{id: "debian", versionID: "11", supported: true},
+		{id: "debian", versionID: "11.7", supported: false},
+		{id: "debian", versionID: "12", supported: false},
+
+		// other distributions are bootstrapped with the best matching bundle
+		{id: "astra", versionID: "1.7_x86-64", supported: true},
+		{id: "altlinux", versionID: "10", supported: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id+" "+tt.versionID, func(t *testing.T) {
+			err := CheckOS(deckhousev1.StaticInstanceInventoryOS{ID: tt.id, VersionID: tt.versionID})
+			if tt.supported && err != nil {
+				t.Errorf("expected the OS to be supported, got: %v", err)
+			}
+			if !tt.supported && err == nil {
+				t.Error("expected the OS not to be supported")
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	inventory, err := Parse(`
+cpu.cores=4
+cpu.model=Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz
+cpu.architecture=x86_64
+memory.kb=8148040
+os.kernelVersion=5.15.0-91-generic
+os.id=ubuntu
+os.idLike=debian
+os.versionID=22.04
+os.prettyName=Ubuntu 22.04.3 LTS
+disk=sda 53687091200 1
+iface=eth0 52:54:00:12:34:56 192.168.1.10/24
+iface=tun0 -
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if inventory.CPU.Cores != 4 || inventory.CPU.Architecture != "amd64" {
+		t.Errorf("unexpected CPU: %+v", inventory.CPU)
+	}
+	if inventory.OS.ID != "ubuntu" || inventory.OS.VersionID != "22.04" || inventory.OS.PrettyName != "Ubuntu 22.04.3 LTS" {
+		t.Errorf("unexpected OS: %+v", inventory.OS)
+	}
+	if len(inventory.Disks) != 1 || inventory.Disks[0].Name != "sda" || !inventory.Disks[0].Rotational {
+		t.Errorf("unexpected disks: %+v", inventory.Disks)
+	}
+	if len(inventory.NetworkInterfaces) != 2 || inventory.NetworkInterfaces[1].MACAddress != "" {
+		t.Errorf("unexpected network interfaces: %+v", inventory.NetworkInterfaces)
+	}
+
+	expectedLabels := map[string]string{
+		LabelOSID:          "ubuntu",
+		LabelOSVersion:     "22.04",
+		LabelKernelVersion: "5.15.0-91-generic",
+		LabelArchitecture:  "amd64",
+		LabelCPUCores:      "4",
+		LabelMemoryGiB:     "7",
+	}
+	if labels := Labels(inventory); !reflect.DeepEqual(labels, expectedLabels) {
+		t.Errorf("expected labels %v, got %v", expectedLabels, labels)
+	}
+}
+
+func TestParseWithoutKernelVersion(t *testing.T) {
+	if _, err := Parse("cpu.cores=4\n"); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -22,10 +22,12 @@ import (
 
 	"github.com/pkg/errors"
 	"k8s.io/client-go/rest"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	deckhousev1 "caps-controller-manager/api/deckhouse.io/v1alpha1"
+	infrav1 "caps-controller-manager/api/infrastructure/v1alpha1"
 	"caps-controller-manager/internal/event"
 	"caps-controller-manager/internal/scope"
 )
@@ -113,6 +115,19 @@ func (p *StaticInstancePool) findStaticInstancesInPhase(
 			continue
 		}
 
+		// StaticInstances with an unsupported OS must not be bootstrapped, so wait for the inventory to be collected.
+		// If the inventory could not be collected, the bootstrap is tried anyway to report the error.
+		inventoryReason := conditions.GetReason(&staticInstance, infrav1.StaticInstanceInventoryCollectedCondition)
+		if inventoryReason == infrav1.StaticInstanceUnsupportedOSReason ||
+			(staticInstance.Status.Inventory == nil && inventoryReason != infrav1.StaticInstanceInventoryCollectionFailedReason) {
+			continue
+		}
+
+		// The host presents an unknown key, it could be a spoofed host, so it must not be bootstrapped.
+		if conditions.GetReason(&staticInstance, infrav1.StaticInstanceSSHConnectedCondition) == infrav1.StaticInstanceSSHHostKeyMismatchReason {
+			continue
+		}
+
 		staticInstancesInPhase = append(staticInstancesInPhase, staticInstance)
 	}
 
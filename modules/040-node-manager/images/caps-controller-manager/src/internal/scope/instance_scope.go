@@ -115,6 +115,8 @@ func (i *InstanceScope) Patch(ctx context.Context) error {
 	conditions.SetSummary(i.Instance,
 		conditions.WithConditions(
 			infrav1.StaticInstanceAddedToNodeGroupCondition,
+			infrav1.StaticInstanceSSHConnectedCondition,
+			infrav1.StaticInstanceInventoryCollectedCondition,
 			infrav1.StaticInstanceBootstrapSucceededCondition,
 		),
 		conditions.WithStepCounterIf(i.Instance.ObjectMeta.DeletionTimestamp.IsZero()),
@@ -127,6 +129,8 @@ func (i *InstanceScope) Patch(ctx context.Context) error {
 		patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{
 			clusterv1.ReadyCondition,
 			infrav1.StaticInstanceAddedToNodeGroupCondition,
+			infrav1.StaticInstanceSSHConnectedCondition,
+			infrav1.StaticInstanceInventoryCollectedCondition,
 			infrav1.StaticInstanceBootstrapSucceededCondition,
 		}})
 	if err != nil {
@@ -140,6 +144,8 @@ func (i *InstanceScope) ToPending(ctx context.Context) error {
 	i.Instance.Status.MachineRef = nil
 	i.Instance.Status.NodeRef = nil
 	i.Instance.Status.CurrentStatus = nil
+	// the host could be reinstalled after the cleanup, so the inventory is collected again
+	i.Instance.Status.Inventory = nil
 
 	conditions.MarkFalse(i.Instance, infrav1.StaticInstanceBootstrapSucceededCondition, infrav1.StaticInstanceWaitingForNodeRefReason, clusterv1.ConditionSeverityInfo, "")
 
@@ -0,0 +1,292 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+
+	infrav1 "caps-controller-manager/api/infrastructure/v1alpha1"
+	"caps-controller-manager/internal/scope"
+)
+
+const (
+	dialTimeout       = 30 * time.Second
+	keepAliveInterval = 30 * time.Second
+	// idleTimeout is the time after which an unused connection is closed.
+	idleTimeout = 10 * time.Minute
+)
+
+// connections are shared by bootstrap, cleanup and inventory tasks of all StaticInstances.
+var connections = &connectionPool{connections: make(map[string]*connection)}
+
+type connection struct {
+	client   *ssh.Client
+	sessions int
+	lastUsed time.Time
+}
+
+// connectionPool caches SSH connections by the address and credentials, so the consecutive commands
+// executed on the same host don't establish a new connection every time.
+type connectionPool struct {
+	mu          sync.Mutex
+	connections map[string]*connection
+}
+
+// acquire returns a cached connection or dials a new one. The connection must be released after use.
+func (p *connectionPool) acquire(key string, dial func() (*ssh.Client, error)) (*ssh.Client, error) {
+	p.mu.Lock()
+	p.closeIdle()
+	conn, ok := p.connections[key]
+	if ok {
+		conn.sessions++
+		p.mu.Unlock()
+
+		return conn.client, nil
+	}
+	p.mu.Unlock()
+
+	// dial without the lock, connecting to an unreachable host takes up to dialTimeout
+	client, err := dial()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conn, ok = p.connections[key]
+	if ok {
+		// another task has connected to the same host concurrently
+		_ = client.Close()
+	} else {
+		conn = &connection{client: client}
+		p.connections[key] = conn
+
+		go p.keepAlive(key, client)
+	}
+
+	conn.sessions++
+
+	return conn.client, nil
+}
+
+func (p *connectionPool) release(key string, client *ssh.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conn, ok := p.connections[key]
+	if !ok || conn.client != client {
+		return
+	}
+
+	conn.sessions--
+	conn.lastUsed = time.Now()
+}
+
+// drop closes the broken connection, e.g., after the host was rebooted.
+func (p *connectionPool) drop(key string, client *ssh.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conn, ok := p.connections[key]
+	if ok && conn.client == client {
+		delete(p.connections, key)
+	}
+
+	_ = client.Close()
+}
+
+func (p *connectionPool) closeIdle() {
+	for key, conn := range p.connections {
+		if conn.sessions > 0 || time.Since(conn.lastUsed) < idleTimeout {
+			continue
+		}
+
+		delete(p.connections, key)
+
+		_ = conn.client.Close()
+	}
+}
+
+// keepAlive detects dead connections, otherwise a command on a rebooted host would hang forever.
+func (p *connectionPool) keepAlive(key string, client *ssh.Client) {
+	closed := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(closed)
+	}()
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			if err != nil {
+				p.drop(key, client)
+
+				return
+			}
+		}
+	}
+}
+
+// dial returns the key of the StaticInstance connection in the pool and the function connecting to it using SSHCredentials.
+func dial(instanceScope *scope.InstanceScope) (string, func() (*ssh.Client, error), error) {
+	credentials := instanceScope.Credentials.Spec
+
+	privateSSHKey, err := base64.StdEncoding.DecodeString(credentials.PrivateSSHKey)
+	if err != nil {
+		return "", nil, &Error{Reason: infrav1.StaticInstanceSSHAuthenticationFailedReason, Err: errors.Wrap(err, "failed to decode private ssh key")}
+	}
+
+	signer, err := ssh.ParsePrivateKey(privateSSHKey)
+	if err != nil {
+		return "", nil, &Error{Reason: infrav1.StaticInstanceSSHAuthenticationFailedReason, Err: errors.Wrap(err, "failed to parse private ssh key")}
+	}
+
+	knownHostKey := strings.TrimSpace(instanceScope.Instance.Spec.KnownHostKey)
+	if knownHostKey == "" {
+		knownHostKey = strings.TrimSpace(credentials.KnownHostKey)
+	}
+
+	// the mismatch error is not wrapped by the ssh package, so it is passed through the variable
+	var hostKeyErr error
+
+	config := &ssh.ClientConfig{
+		User:    credentials.User,
+		Auth:    []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		Timeout: dialTimeout,
+		HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			hostKeyErr = checkHostKey(instanceScope.Logger, knownHostKey, key)
+
+			return hostKeyErr
+		},
+	}
+
+	if knownHostKey != "" && !strings.HasPrefix(knownHostKey, "SHA256:") {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(knownHostKey))
+		if err != nil {
+			return "", nil, &Error{Reason: infrav1.StaticInstanceSSHHostKeyMismatchReason, Err: errors.Wrap(err, "failed to parse known host key")}
+		}
+
+		// ask the host for the key of the known type, otherwise it can present a key of another type
+		config.HostKeyAlgorithms = hostKeyAlgorithms(key.Type())
+	}
+
+	applyExtraArgs(instanceScope.Logger, config, credentials.SSHExtraArgs)
+
+	address := net.JoinHostPort(instanceScope.Instance.Spec.Address, strconv.Itoa(credentials.SSHPort))
+
+	hash := sha256.New()
+	for _, value := range []string{address, credentials.User, credentials.PrivateSSHKey, knownHostKey, credentials.SSHExtraArgs} {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+	key := hex.EncodeToString(hash.Sum(nil))
+
+	return key, func() (*ssh.Client, error) {
+		instanceScope.Logger.Info("Connecting to StaticInstance over SSH", "address", address, "user", credentials.User)
+
+		client, err := ssh.Dial("tcp", address, config)
+		if err != nil {
+			switch {
+			case hostKeyErr != nil:
+				return nil, &Error{Reason: infrav1.StaticInstanceSSHHostKeyMismatchReason, Err: hostKeyErr}
+			case strings.Contains(err.Error(), "unable to authenticate"):
+				return nil, &Error{Reason: infrav1.StaticInstanceSSHAuthenticationFailedReason, Err: err}
+			default:
+				return nil, &Error{Reason: infrav1.StaticInstanceSSHConnectionFailedReason, Err: err}
+			}
+		}
+
+		return client, nil
+	}, nil
+}
+
+// checkHostKey checks the host key against the known host key. Any host key is accepted if the known host key is not set.
+func checkHostKey(logger logr.Logger, knownHostKey string, key ssh.PublicKey) error {
+	fingerprint := ssh.FingerprintSHA256(key)
+
+	if knownHostKey == "" {
+		logger.Info("Known host key is not set, accepting the host key", "fingerprint", fingerprint)
+
+		return nil
+	}
+
+	if strings.HasPrefix(knownHostKey, "SHA256:") {
+		if knownHostKey != fingerprint {
+			return &HostKeyMismatchError{Expected: knownHostKey, Actual: fingerprint}
+		}
+
+		return nil
+	}
+
+	known, _, _, _, err := ssh.ParseAuthorizedKey([]byte(knownHostKey))
+	if err != nil {
+		return errors.Wrap(err, "failed to parse known host key")
+	}
+
+	if !bytes.Equal(known.Marshal(), key.Marshal()) {
+		return &HostKeyMismatchError{Expected: ssh.FingerprintSHA256(known), Actual: fingerprint}
+	}
+
+	return nil
+}
+
+func hostKeyAlgorithms(keyType string) []string {
+	if keyType == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+
+	return []string{keyType}
+}
+
+// applyExtraArgs applies the OpenSSH client arguments that make sense for the built-in client.
+func applyExtraArgs(logger logr.Logger, config *ssh.ClientConfig, extraArgs string) {
+	args := strings.Fields(extraArgs)
+
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-c" && i+1 < len(args):
+			i++
+			config.Ciphers = strings.Split(args[i], ",")
+		case args[i] == "-m" && i+1 < len(args):
+			i++
+			config.MACs = strings.Split(args[i], ",")
+		case strings.HasPrefix(args[i], "-v"):
+			// the verbosity of the OpenSSH client
+		default:
+			logger.Info("SSH extra argument is not supported and ignored", "argument", args[i])
+		}
+	}
+}
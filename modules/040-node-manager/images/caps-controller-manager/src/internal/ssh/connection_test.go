@@ -0,0 +1,393 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/pem"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+
+	deckhousev1 "caps-controller-manager/api/deckhouse.io/v1alpha1"
+	infrav1 "caps-controller-manager/api/infrastructure/v1alpha1"
+	"caps-controller-manager/internal/scope"
+)
+
+func newSigner(t *testing.T) (ssh.Signer, ed25519.PrivateKey) {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer, key
+}
+
+func authorizedKey(key ssh.PublicKey) string {
+	return string(ssh.MarshalAuthorizedKey(key))
+}
+
+// startServer starts the SSH server accepting any client key and returns its address.
+func startServer(t *testing.T, hostKey ssh.Signer) string {
+	t.Helper()
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(ssh.ConnMetadata, ssh.PublicKey) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+
+				go ssh.DiscardRequests(reqs)
+				for ch := range chans {
+					_ = ch.Reject(ssh.Prohibited, "sessions are not supported")
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func newInstanceScope(t *testing.T, address, knownHostKey, extraArgs string) *scope.InstanceScope {
+	t.Helper()
+
+	_, clientKey := newSigner(t)
+	block, err := ssh.MarshalPrivateKey(clientKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPort, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &scope.InstanceScope{
+		Scope: &scope.Scope{Logger: logr.Discard()},
+		Instance: &deckhousev1.StaticInstance{
+			Spec: deckhousev1.StaticInstanceSpec{
+				Address:      host,
+				KnownHostKey: knownHostKey,
+			},
+		},
+		Credentials: &deckhousev1.SSHCredentials{
+			Spec: deckhousev1.SSHCredentialsSpec{
+				User:          "caps",
+				PrivateSSHKey: base64.StdEncoding.EncodeToString(pem.EncodeToMemory(block)),
+				SSHPort:       sshPort,
+				SSHExtraArgs:  extraArgs,
+			},
+		},
+	}
+}
+
+func TestCheckHostKey(t *testing.T) {
+	hostKey, _ := newSigner(t)
+	otherKey, _ := newSigner(t)
+
+	tests := []struct {
+		name         string
+		knownHostKey string
+		mismatch     bool
+		err          bool
+	}{
+		{name: "Known host key is not set", knownHostKey: ""},
+		{name: "Fingerprint matches", knownHostKey: ssh.FingerprintSHA256(hostKey.PublicKey())},
+		{name: "Fingerprint mismatches", knownHostKey: ssh.FingerprintSHA256(otherKey.PublicKey()), mismatch: true},
+		{name: "Public key matches", knownHostKey: authorizedKey(hostKey.PublicKey())},
+		{name: "Public key mismatches", knownHostKey: authorizedKey(otherKey.PublicKey()), mismatch: true},
+		{name: "Known host key is invalid", knownHostKey: "ssh-ed25519 invalid", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkHostKey(logr.Discard(), tt.knownHostKey, hostKey.PublicKey())
+
+			var mismatchErr *HostKeyMismatchError
+			switch {
+			case tt.mismatch:
+				if !errors.As(err, &mismatchErr) {
+					t.Fatalf("expected the host key mismatch error, got: %v", err)
+				}
+				if mismatchErr.Expected != ssh.FingerprintSHA256(otherKey.PublicKey()) || mismatchErr.Actual != ssh.FingerprintSHA256(hostKey.PublicKey()) {
+					t.Errorf("unexpected fingerprints in the error: %v", mismatchErr)
+				}
+			case tt.err:
+				if err == nil || errors.As(err, &mismatchErr) {
+					t.Errorf("expected the parse error, got: %v", err)
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHostKeyAlgorithms(t *testing.T) {
+	tests := []struct {
+		keyType  string
+		expected []string
+	}{
+		{keyType: ssh.KeyAlgoRSA, expected: []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}},
+		{keyType: ssh.KeyAlgoED25519, expected: []string{ssh.KeyAlgoED25519}},
+		{keyType: ssh.KeyAlgoECDSA256, expected: []string{ssh.KeyAlgoECDSA256}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.keyType, func(t *testing.T) {
+			if got := hostKeyAlgorithms(tt.keyType); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestApplyExtraArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		extraArgs string
+		ciphers   []string
+		macs      []string
+	}{
+		{name: "No arguments"},
+		{
+			name:      "Ciphers and MACs",
+			extraArgs: "-c aes256-ctr,aes128-ctr -m hmac-sha2-256",
+			ciphers:   []string{"aes256-ctr", "aes128-ctr"},
+			macs:      []string{"hmac-sha2-256"},
+		},
+		{
+			name:      "Unsupported arguments are ignored",
+			extraArgs: "-vvv -o StrictHostKeyChecking=no -c chacha20-poly1305@openssh.com",
+			ciphers:   []string{"chacha20-poly1305@openssh.com"},
+		},
+		{
+			name:      "Argument without the value is ignored",
+			extraArgs: "-m",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ssh.ClientConfig{}
+			applyExtraArgs(logr.Discard(), config, tt.extraArgs)
+
+			if !reflect.DeepEqual(config.Ciphers, tt.ciphers) {
+				t.Errorf("expected ciphers %v, got %v", tt.ciphers, config.Ciphers)
+			}
+			if !reflect.DeepEqual(config.MACs, tt.macs) {
+				t.Errorf("expected MACs %v, got %v", tt.macs, config.MACs)
+			}
+		})
+	}
+}
+
+func TestDial(t *testing.T) {
+	hostKey, _ := newSigner(t)
+	otherKey, _ := newSigner(t)
+	address := startServer(t, hostKey)
+
+	t.Run("Known host key matches", func(t *testing.T) {
+		_, dialFunc, err := dial(newInstanceScope(t, address, authorizedKey(hostKey.PublicKey()), ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		client, err := dialFunc()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_ = client.Close()
+	})
+
+	t.Run("Known host key mismatches", func(t *testing.T) {
+		_, dialFunc, err := dial(newInstanceScope(t, address, ssh.FingerprintSHA256(otherKey.PublicKey()), ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = dialFunc()
+
+		sshErr, ok := AsError(err)
+		if !ok || sshErr.Reason != infrav1.StaticInstanceSSHHostKeyMismatchReason {
+			t.Fatalf("expected the %s error, got: %v", infrav1.StaticInstanceSSHHostKeyMismatchReason, err)
+		}
+
+		var mismatchErr *HostKeyMismatchError
+		if !errors.As(err, &mismatchErr) {
+			t.Errorf("expected the host key mismatch error, got: %v", err)
+		}
+	})
+
+	t.Run("Connection key depends on the settings", func(t *testing.T) {
+		instanceScope := newInstanceScope(t, address, "", "")
+
+		key, _, err := dial(instanceScope)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		instanceScope.Credentials.Spec.SSHExtraArgs = "-c aes256-ctr"
+		keyWithArgs, _, err := dial(instanceScope)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if key == keyWithArgs {
+			t.Error("expected different connection keys")
+		}
+	})
+
+	t.Run("Private key is invalid", func(t *testing.T) {
+		instanceScope := newInstanceScope(t, address, "", "")
+		instanceScope.Credentials.Spec.PrivateSSHKey = base64.StdEncoding.EncodeToString([]byte("invalid"))
+
+		_, _, err := dial(instanceScope)
+
+		sshErr, ok := AsError(err)
+		if !ok || sshErr.Reason != infrav1.StaticInstanceSSHAuthenticationFailedReason {
+			t.Errorf("expected the %s error, got: %v", infrav1.StaticInstanceSSHAuthenticationFailedReason, err)
+		}
+	})
+}
+
+func TestConnectionPool(t *testing.T) {
+	hostKey, _ := newSigner(t)
+	address := startServer(t, hostKey)
+
+	key, dialFunc, err := dial(newInstanceScope(t, address, "", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dials int
+	countingDial := func() (*ssh.Client, error) {
+		dials++
+		return dialFunc()
+	}
+
+	pool := &connectionPool{connections: make(map[string]*connection)}
+
+	client, err := pool.acquire(key, countingDial)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Connection is reused", func(t *testing.T) {
+		reused, err := pool.acquire(key, countingDial)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if reused != client || dials != 1 {
+			t.Errorf("expected the cached connection, dials: %d", dials)
+		}
+		if sessions := pool.connections[key].sessions; sessions != 2 {
+			t.Errorf("expected 2 sessions, got %d", sessions)
+		}
+
+		pool.release(key, reused)
+		pool.release(key, client)
+	})
+
+	t.Run("Connection in use is not closed as idle", func(t *testing.T) {
+		_, err := pool.acquire(key, countingDial)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool.connections[key].lastUsed = time.Now().Add(-2 * idleTimeout)
+
+		pool.closeIdle()
+
+		if _, ok := pool.connections[key]; !ok {
+			t.Error("expected the connection to be kept")
+		}
+
+		pool.release(key, client)
+	})
+
+	t.Run("Idle connection is closed", func(t *testing.T) {
+		pool.connections[key].lastUsed = time.Now().Add(-2 * idleTimeout)
+
+		pool.closeIdle()
+
+		if _, ok := pool.connections[key]; ok {
+			t.Error("expected the connection to be closed")
+		}
+	})
+
+	t.Run("Broken connection is dropped", func(t *testing.T) {
+		client, err := pool.acquire(key, countingDial)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dials != 2 {
+			t.Errorf("expected a new connection, dials: %d", dials)
+		}
+
+		pool.drop(key, client)
+
+		if _, ok := pool.connections[key]; ok {
+			t.Error("expected the connection to be dropped")
+		}
+
+		// releasing the dropped connection must not panic or change the pool
+		pool.release(key, client)
+		if len(pool.connections) != 0 {
+			t.Errorf("expected the empty pool, got %d connections", len(pool.connections))
+		}
+	})
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Error is an error of the SSH connection to the host. Errors of the executed commands are not wrapped into it.
+type Error struct {
+	// Reason is one of the StaticInstanceSSHConnected condition reasons.
+	Reason string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", e.Reason, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// HostKeyMismatchError is returned when the host presents a key that doesn't match the known host key.
+type HostKeyMismatchError struct {
+	Expected string
+	Actual   string
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("host key mismatch: expected '%s', got '%s'", e.Expected, e.Actual)
+}
+
+// AsError returns the SSH connection error if err is caused by it.
+func AsError(err error) (*Error, bool) {
+	var sshErr *Error
+	if errors.As(err, &sshErr) {
+		return sshErr, true
+	}
+
+	return nil, false
+}
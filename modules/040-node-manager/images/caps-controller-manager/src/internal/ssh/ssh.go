@@ -18,88 +18,88 @@ package ssh
 
 import (
 	"bytes"
-	"encoding/base64"
 	"fmt"
 	"io"
-	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
 
 	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
 
+	infrav1 "caps-controller-manager/api/infrastructure/v1alpha1"
 	"caps-controller-manager/internal/scope"
 )
 
 // ExecSSHCommand executes a command on the StaticInstance.
+// The SSH connection to the host is reused by the consecutive commands.
 func ExecSSHCommand(instanceScope *scope.InstanceScope, command string, stdout io.Writer) error {
-	privateSSHKey, err := base64.StdEncoding.DecodeString(instanceScope.Credentials.Spec.PrivateSSHKey)
+	key, dialFunc, err := dial(instanceScope)
 	if err != nil {
-		return errors.Wrap(err, "failed to decode private ssh key")
+		return err
 	}
 
-	dir := os.TempDir()
-	sshKey := filepath.Join(dir, "ssh-key")
-
-	err = os.WriteFile(sshKey, privateSSHKey, 0600)
+	client, session, err := newSession(key, dialFunc)
 	if err != nil {
-		return errors.Wrap(err, "failed to write private ssh key to temporary file")
-	}
-
-	args := []string{
-		"-qv",
-		"-i",
-		sshKey,
-		"-o",
-		"StrictHostKeyChecking=no",
-		fmt.Sprintf("-p %d", instanceScope.Credentials.Spec.SSHPort),
-	}
-
-	for _, arg := range strings.Split(instanceScope.Credentials.Spec.SSHExtraArgs, " ") {
-		if arg == "" {
-			continue
-		}
-
-		args = append(args, arg)
+		return err
 	}
+	defer connections.release(key, client)
+	defer session.Close()
 
-	var stdin io.Reader
-
-	// If the sudo password is set, we need to pipe it to the ssh command.
+	// If the sudo password is set, we need to pipe it to the command.
 	if instanceScope.Credentials.Spec.SudoPassword != "" {
-		stdin = bytes.NewBufferString(instanceScope.Credentials.Spec.SudoPassword)
+		session.Stdin = bytes.NewBufferString(instanceScope.Credentials.Spec.SudoPassword + "\n")
 
 		command = fmt.Sprintf(`sudo -S sh -c "%s"`, command)
 	} else {
 		command = fmt.Sprintf(`sudo sh -c "%s"`, command)
 	}
 
-	args = append(args, []string{
-		fmt.Sprintf("%s@%s", instanceScope.Credentials.Spec.User, instanceScope.Instance.Spec.Address),
-		command,
-	}...)
-
-	cmd := exec.Command("ssh", args...)
-
-	cmd.Stdin = stdin
-
 	if stdout == nil {
 		stdout = NewLogger(instanceScope.Logger.WithName("stdout"))
 	}
 
-	cmd.Stdout = stdout
-	cmd.Stderr = NewLogger(instanceScope.Logger.WithName("stderr"))
+	session.Stdout = stdout
+	session.Stderr = NewLogger(instanceScope.Logger.WithName("stderr"))
 
-	instanceScope.Logger.Info("Exec ssh command", "command", cmd.String())
+	instanceScope.Logger.Info("Exec ssh command", "address", instanceScope.Instance.Spec.Address, "user", instanceScope.Credentials.Spec.User)
 
-	err = cmd.Run()
+	err = session.Run(command)
 	if err != nil {
-		return errors.Wrap(err, "failed to run ssh command")
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			return errors.Wrap(err, "failed to run ssh command")
+		}
+
+		// the connection is lost while the command is running, e.g., the host is rebooted by the command
+		connections.drop(key, client)
+
+		return &Error{Reason: infrav1.StaticInstanceSSHConnectionFailedReason, Err: errors.Wrap(err, "failed to run ssh command")}
 	}
 
 	return nil
 }
 
+// newSession opens a session on the cached connection. If the cached connection is broken, it reconnects once.
+func newSession(key string, dialFunc func() (*ssh.Client, error)) (*ssh.Client, *ssh.Session, error) {
+	for attempt := 0; ; attempt++ {
+		client, err := connections.acquire(key, dialFunc)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		session, err := client.NewSession()
+		if err == nil {
+			return client, session, nil
+		}
+
+		connections.release(key, client)
+		connections.drop(key, client)
+
+		if attempt > 0 {
+			return nil, nil, &Error{Reason: infrav1.StaticInstanceSSHConnectionFailedReason, Err: errors.Wrap(err, "failed to open ssh session")}
+		}
+	}
+}
+
 // ExecSSHCommandToString executes a command on the StaticInstance and returns the output as a string.
 func ExecSSHCommandToString(instanceScope *scope.InstanceScope, command string) (string, error) {
 	stdout := &bytes.Buffer{}
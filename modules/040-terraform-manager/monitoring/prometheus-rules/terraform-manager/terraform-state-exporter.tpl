@@ -234,3 +234,25 @@
         First, run the `dhctl terraform check` command to check what will change.
         Use `dhctl converge` command or manually adjust NodeGroup settings to fix the issue.
       summary: Terraform-state-exporter node template changed
+
+  - alert: D8ClusterConfigurationDrift
+    expr: |
+      max by(job, kind) (candi_converge_drift{job="terraform-state-exporter"} == 1)
+    for: 30m
+    labels:
+      severity_level: "8"
+      tier: cluster
+      d8_module: terraform-manager
+      d8_component: terraform-state-exporter
+    annotations:
+      plk_protocol_version: "1"
+      plk_markup_format: "markdown"
+      plk_create_group_if_not_exists__d8_terraform_state_exporter_malfunctioning: "D8TerraformStateExporterMalfunctioning,tier=cluster,prometheus=deckhouse,kubernetes=~kubernetes"
+      plk_grouped_by__d8_terraform_state_exporter_malfunctioning: "D8TerraformStateExporterMalfunctioning,tier=cluster,prometheus=deckhouse,kubernetes=~kubernetes"
+      description: |
+        Terraform-state-exporter found `{{`{{ $labels.kind }}`}}` difference between the desired cluster configuration and the actual cluster state.
+        Probably, the cluster was changed manually without `dhctl`.
+
+        To get the drifted objects, run `kubectl -n d8-system port-forward deploy/terraform-state-exporter 9101:9101` and open `http://127.0.0.1:9101/drift`.
+        Use `dhctl converge` command or revert the manual changes to fix the issue.
+      summary: Cluster state differs from the desired configuration
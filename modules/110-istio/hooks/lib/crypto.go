@@ -26,4 +26,13 @@ type IstioCA struct {
 type Keypair struct {
 	Pub  string `json:"pub"`
 	Priv string `json:"priv"`
+	// CreatedAt and ExpiresAt are RFC3339 timestamps, the keypair is rotated before the expiration.
+	CreatedAt string `json:"createdAt,omitempty"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
+	// NextPub is published along with Pub before the rotation, so the remote clusters know it when NextPriv starts signing.
+	NextPub  string `json:"nextPub,omitempty"`
+	NextPriv string `json:"nextPriv,omitempty"`
+	// PreviousPub is published along with Pub until PreviousPubExpiresAt to verify the requests signed before the rotation.
+	PreviousPub          string `json:"previousPub,omitempty"`
+	PreviousPubExpiresAt string `json:"previousPubExpiresAt,omitempty"`
 }
@@ -31,6 +31,10 @@ type publicMetadata struct {
 	ClusterUUID string `json:"clusterUUID,omitempty"`
 	AuthnKeyPub string `json:"authnKeyPub,omitempty"`
 	RootCA      string `json:"rootCA,omitempty"`
+
+	NextAuthnKeyPub              string `json:"nextAuthnKeyPub,omitempty"`
+	PreviousAuthnKeyPub          string `json:"previousAuthnKeyPub,omitempty"`
+	PreviousAuthnKeyPubExpiresAt string `json:"previousAuthnKeyPubExpiresAt,omitempty"`
 }
 
 // map[custerUUID]pubilcMetadata
@@ -93,20 +97,39 @@ func checkAuthn(header http.Header, scope string) error {
 		return fmt.Errorf("JWT token expired.")
 	}
 
-	if _, ok := remotePublicMetadataMap[payload.Sub]; !ok {
+	remoteMetadata, ok := remotePublicMetadataMap[payload.Sub]
+	if !ok {
 		return fmt.Errorf("JWT is signed for unknown source cluster.")
 	}
-	remoteAuthnKeyPubBlock, _ := pem.Decode([]byte(remotePublicMetadataMap[payload.Sub].AuthnKeyPub))
-	remoteAuthnKeyPub, err := x509.ParsePKIXPublicKey(remoteAuthnKeyPubBlock.Bytes)
-	if err != nil {
-		return err
+
+	// The next key is accepted in case the remote keypair is rotated before the metadata is fetched again,
+	// the previous key of the rotated remote keypair is accepted until the end of the grace period.
+	remoteAuthnKeyPubPems := []string{remoteMetadata.AuthnKeyPub}
+	if remoteMetadata.NextAuthnKeyPub != "" {
+		remoteAuthnKeyPubPems = append(remoteAuthnKeyPubPems, remoteMetadata.NextAuthnKeyPub)
+	}
+	if remoteMetadata.PreviousAuthnKeyPub != "" {
+		expiresAt, err := time.Parse(time.RFC3339, remoteMetadata.PreviousAuthnKeyPubExpiresAt)
+		if err == nil && time.Now().Before(expiresAt) {
+			remoteAuthnKeyPubPems = append(remoteAuthnKeyPubPems, remoteMetadata.PreviousAuthnKeyPub)
+		}
 	}
 
-	if _, err := reqToken.Verify(remoteAuthnKeyPub); err != nil {
-		return fmt.Errorf("Cannot verify JWT token with known public key.")
+	for _, remoteAuthnKeyPubPem := range remoteAuthnKeyPubPems {
+		remoteAuthnKeyPubBlock, _ := pem.Decode([]byte(remoteAuthnKeyPubPem))
+		if remoteAuthnKeyPubBlock == nil {
+			continue
+		}
+		remoteAuthnKeyPub, err := x509.ParsePKIXPublicKey(remoteAuthnKeyPubBlock.Bytes)
+		if err != nil {
+			continue
+		}
+		if _, err := reqToken.Verify(remoteAuthnKeyPub); err == nil {
+			return nil
+		}
 	}
 
-	return nil
+	return fmt.Errorf("Cannot verify JWT token with known public key.")
 }
 
 func initProxyTransport() {
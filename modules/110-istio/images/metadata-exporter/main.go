@@ -44,6 +44,11 @@ type AlliancePublicMetadata struct {
 	ClusterUUID string `json:"clusterUUID,omitempty"`
 	AuthnKeyPub string `json:"authnKeyPub,omitempty"`
 	RootCA      string `json:"rootCA,omitempty"`
+
+	AuthnKeyPubExpiresAt         string `json:"authnKeyPubExpiresAt,omitempty"`
+	NextAuthnKeyPub              string `json:"nextAuthnKeyPub,omitempty"`
+	PreviousAuthnKeyPub          string `json:"previousAuthnKeyPub,omitempty"`
+	PreviousAuthnKeyPubExpiresAt string `json:"previousAuthnKeyPubExpiresAt,omitempty"`
 }
 
 type FederationPrivateMetadata struct {
@@ -151,6 +156,24 @@ func renderPublicMetadataJSON() {
 		RootCA:      string(rootCAPem),
 	}
 
+	// the rotation details are absent if the keypair was generated by an older release
+	expiresAt, err := os.ReadFile("/keys/expires-at")
+	if err == nil {
+		pm.AuthnKeyPubExpiresAt = string(expiresAt)
+	}
+	nextAuthnKeyPubPem, err := os.ReadFile("/keys/next-pub.pem")
+	if err == nil && len(nextAuthnKeyPubPem) > 0 {
+		pm.NextAuthnKeyPub = string(nextAuthnKeyPubPem)
+	}
+	previousAuthnKeyPubPem, err := os.ReadFile("/keys/previous-pub.pem")
+	if err == nil && len(previousAuthnKeyPubPem) > 0 {
+		pm.PreviousAuthnKeyPub = string(previousAuthnKeyPubPem)
+		previousExpiresAt, err := os.ReadFile("/keys/previous-pub-expires-at")
+		if err == nil {
+			pm.PreviousAuthnKeyPubExpiresAt = string(previousExpiresAt)
+		}
+	}
+
 	jsonbuf, err := json.MarshalIndent(pm, "", "  ")
 	if err != nil {
 		panic("Error marshalling cluster public metadata to json: " + err.Error())
@@ -248,20 +271,39 @@ func checkAuthn(header http.Header, scope string) error {
 		return fmt.Errorf("JWT token expired.")
 	}
 
-	if _, ok := remotePublicMetadataMap[payload.Sub]; !ok {
+	remoteMetadata, ok := remotePublicMetadataMap[payload.Sub]
+	if !ok {
 		return fmt.Errorf("JWT is signed for unknown source cluster.")
 	}
-	remoteAuthnKeyPubBlock, _ := pem.Decode([]byte(remotePublicMetadataMap[payload.Sub].AuthnKeyPub))
-	remoteAuthnKeyPub, err := x509.ParsePKIXPublicKey(remoteAuthnKeyPubBlock.Bytes)
-	if err != nil {
-		return err
+
+	// The next key is accepted in case the remote keypair is rotated before the metadata is fetched again,
+	// the previous key of the rotated remote keypair is accepted until the end of the grace period.
+	remoteAuthnKeyPubPems := []string{remoteMetadata.AuthnKeyPub}
+	if remoteMetadata.NextAuthnKeyPub != "" {
+		remoteAuthnKeyPubPems = append(remoteAuthnKeyPubPems, remoteMetadata.NextAuthnKeyPub)
+	}
+	if remoteMetadata.PreviousAuthnKeyPub != "" {
+		expiresAt, err := time.Parse(time.RFC3339, remoteMetadata.PreviousAuthnKeyPubExpiresAt)
+		if err == nil && time.Now().Before(expiresAt) {
+			remoteAuthnKeyPubPems = append(remoteAuthnKeyPubPems, remoteMetadata.PreviousAuthnKeyPub)
+		}
 	}
 
-	if _, err := reqToken.Verify(remoteAuthnKeyPub); err != nil {
-		return fmt.Errorf("Cannot verify JWT token with known public key.")
+	for _, remoteAuthnKeyPubPem := range remoteAuthnKeyPubPems {
+		remoteAuthnKeyPubBlock, _ := pem.Decode([]byte(remoteAuthnKeyPubPem))
+		if remoteAuthnKeyPubBlock == nil {
+			continue
+		}
+		remoteAuthnKeyPub, err := x509.ParsePKIXPublicKey(remoteAuthnKeyPubBlock.Bytes)
+		if err != nil {
+			continue
+		}
+		if _, err := reqToken.Verify(remoteAuthnKeyPub); err == nil {
+			return nil
+		}
 	}
 
-	return nil
+	return fmt.Errorf("Cannot verify JWT token with known public key.")
 }
 
 func httpHandlerPubilcJSON(w http.ResponseWriter, r *http.Request) {
@@ -305,9 +347,11 @@ func httpHandlerHealthz(w http.ResponseWriter, r *http.Request) {
 }
 
 func renderScheduler() {
-	time.Sleep(1 * time.Minute)
-	renderSpiffeBundleJSON()
-	renderPublicMetadataJSON()
+	for {
+		time.Sleep(1 * time.Minute)
+		renderSpiffeBundleJSON()
+		renderPublicMetadataJSON()
+	}
 }
 
 func main() {
@@ -85,6 +85,8 @@ const istioValues = `
       remoteAuthnKeypair:
         priv: aaa
         pub: bbb
+        createdAt: "2024-05-01T12:00:00Z"
+        expiresAt: "2024-06-01T12:00:00Z"
       ca:
         cert: mycert
         key: mykey
@@ -366,6 +368,24 @@ neighbour-0:
 			Expect(f.KubernetesGlobalResource("ClusterRole", "d8:istio:alliance:metadata-exporter").Exists()).To(BeTrue())
 			Expect(f.KubernetesGlobalResource("ClusterRoleBinding", "d8:istio:alliance:metadata-exporter").Exists()).To(BeTrue())
 
+			keypairSecret := f.KubernetesResource("Secret", "d8-istio", "d8-remote-authn-keypair")
+			Expect(keypairSecret.Field(`data.pub\.pem`).String()).To(Equal("YmJi"))
+			Expect(keypairSecret.Field("data.created-at").String()).To(Equal("MjAyNC0wNS0wMVQxMjowMDowMFo="))
+			Expect(keypairSecret.Field(`data.next-pub\.pem`).String()).To(Equal(""))
+			Expect(keypairSecret.Field(`data.previous-pub\.pem`).String()).To(Equal(""))
+			Expect(f.KubernetesResource("Deployment", "d8-istio", "metadata-exporter").Field(`spec.template.spec.volumes.#(name=="authn-keypair").secret.items`).String()).To(MatchYAML(`
+- key: pub.pem
+  path: pub.pem
+- key: expires-at
+  path: expires-at
+- key: next-pub.pem
+  path: next-pub.pem
+- key: previous-pub.pem
+  path: previous-pub.pem
+- key: previous-pub-expires-at
+  path: previous-pub-expires-at
+`))
+
 			Expect(f.KubernetesResource("DaemonSet", "d8-istio", "ingressgateway").Exists()).To(BeTrue())
 			Expect(f.KubernetesResource("VerticalPodAutoscaler", "d8-istio", "ingressgateway").Exists()).To(BeTrue())
 			Expect(f.KubernetesResource("Gateway", "d8-istio", "ingressgateway").Exists()).To(BeTrue())
@@ -28,7 +28,8 @@ const (
 )
 
 var _ = sdk.RegisterFunc(&go_hook.HookConfig{
-	Queue: internal.Queue(authRuleSnapshot),
+	Queue:    internal.Queue(authRuleSnapshot),
+	Schedule: []go_hook.ScheduleConfig{internal.TemporaryGrantsSchedule},
 	Kubernetes: []go_hook.KubernetesConfig{
 		{
 			Name:       authRuleSnapshot,
@@ -37,4 +38,4 @@ var _ = sdk.RegisterFunc(&go_hook.HookConfig{
 			FilterFunc: internal.ApplyAuthorizationRuleFilter,
 		},
 	},
-}, internal.AuthorizationRulesHandler("userAuthz.internal.authRuleCrds", authRuleSnapshot))
+}, internal.AuthorizationRulesHandler("AuthorizationRule", "userAuthz.internal.authRuleCrds", authRuleSnapshot))
@@ -28,7 +28,8 @@ const (
 )
 
 var _ = sdk.RegisterFunc(&go_hook.HookConfig{
-	Queue: internal.Queue(clusterAuthRuleSnapshot),
+	Queue:    internal.Queue(clusterAuthRuleSnapshot),
+	Schedule: []go_hook.ScheduleConfig{internal.TemporaryGrantsSchedule},
 	Kubernetes: []go_hook.KubernetesConfig{
 		{
 			Name:       clusterAuthRuleSnapshot,
@@ -37,4 +38,4 @@ var _ = sdk.RegisterFunc(&go_hook.HookConfig{
 			FilterFunc: internal.ApplyAuthorizationRuleFilter,
 		},
 	},
-}, internal.AuthorizationRulesHandler("userAuthz.internal.clusterAuthRuleCrds", clusterAuthRuleSnapshot))
+}, internal.AuthorizationRulesHandler("ClusterAuthorizationRule", "userAuthz.internal.clusterAuthRuleCrds", clusterAuthRuleSnapshot))
@@ -17,9 +17,15 @@ limitations under the License.
 package hooks
 
 import (
+	"context"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
 	. "github.com/deckhouse/deckhouse/testing/hooks"
 )
 
@@ -45,6 +51,42 @@ spec:
   subjects:
   - kind: Group
     name: Everyone
+`
+	stateTemporaryClusterAuthRules = `
+---
+apiVersion: deckhouse.io/v1
+kind: ClusterAuthorizationRule
+metadata:
+  name: on-call
+spec:
+  accessLevel: ClusterAdmin
+  expiresAt: "2150-10-10T10:10:10Z"
+  subjects:
+  - kind: Group
+    name: on-call
+---
+apiVersion: deckhouse.io/v1
+kind: ClusterAuthorizationRule
+metadata:
+  name: expired
+spec:
+  accessLevel: ClusterAdmin
+  notBefore: "2020-10-10T10:10:10Z"
+  expiresAt: "2021-10-10T10:10:10Z"
+  subjects:
+  - kind: User
+    name: john
+---
+apiVersion: deckhouse.io/v1
+kind: ClusterAuthorizationRule
+metadata:
+  name: scheduled
+spec:
+  accessLevel: ClusterAdmin
+  notBefore: "2150-10-10T10:10:10Z"
+  subjects:
+  - kind: User
+    name: jane
 `
 )
 
@@ -75,4 +117,68 @@ var _ = Describe("User Authz hooks :: handle cluster authorization rules ::", fu
 			Expect(f.ValuesGet("userAuthz.internal.clusterAuthRuleCrds").String()).To(MatchJSON(`[{"name":"car0","spec":{"accessLevel":"ClusterEditor", "subjects":[{"kind":"Group", "name":"NotEveryone"}]}},{"name":"car1","spec":{"accessLevel":"ClusterAdmin", "subjects":[{"kind":"Group", "name":"Everyone"}]}}]`))
 		})
 	})
+
+	Context("Cluster with time-bound CARs", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(stateTemporaryClusterAuthRules))
+			f.RunHook()
+		})
+
+		It("Only the active CAR must be stored in values", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.ValuesGet("userAuthz.internal.clusterAuthRuleCrds").String()).To(MatchJSON(`[{"name":"on-call","spec":{"accessLevel":"ClusterAdmin","expiresAt":"2150-10-10T10:10:10Z","subjects":[{"kind":"Group","name":"on-call"}]}}]`))
+		})
+
+		It("Grant must be reported", func() {
+			metrics := f.MetricsCollector.CollectedMetrics()
+			Expect(metrics).To(HaveLen(2))
+			Expect(metrics[1].Name).To(Equal("d8_user_authz_temporary_grant_expiration_timestamp_seconds"))
+			Expect(metrics[1].Labels).To(Equal(map[string]string{"kind": "ClusterAuthorizationRule", "namespace": "", "name": "on-call"}))
+			Expect(*metrics[1].Value).To(Equal(float64(5704683010)))
+
+			events := listEvents(f)
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Object["reason"]).To(Equal("AccessGranted"))
+			Expect(events[0].Object["note"]).To(Equal("Temporary access is granted to Group on-call until 2150-10-10T10:10:10Z"))
+		})
+
+		Context("The grant expired", func() {
+			BeforeEach(func() {
+				f.BindingContexts.Set(f.KubeStateSet(`
+---
+apiVersion: deckhouse.io/v1
+kind: ClusterAuthorizationRule
+metadata:
+  name: on-call
+spec:
+  accessLevel: ClusterAdmin
+  expiresAt: "2022-10-10T10:10:10Z"
+  subjects:
+  - kind: Group
+    name: on-call
+`))
+				f.RunHook()
+			})
+
+			It("CAR must be removed from values", func() {
+				Expect(f).To(ExecuteSuccessfully())
+				Expect(f.ValuesGet("userAuthz.internal.clusterAuthRuleCrds").String()).To(MatchJSON(`[]`))
+				Expect(f.MetricsCollector.CollectedMetrics()).To(HaveLen(1))
+
+				events := listEvents(f)
+				Expect(events).To(HaveLen(2))
+				Expect(events[1].Object["reason"]).To(Equal("AccessRevoked"))
+				Expect(events[1].Object["note"]).To(Equal("Temporary access of Group on-call is revoked, the rule expired at 2022-10-10T10:10:10Z"))
+			})
+		})
+	})
 })
+
+func listEvents(f *HookExecutionConfig) []unstructured.Unstructured {
+	events, err := f.BindingContextController.FakeCluster().Client.Dynamic().
+		Resource(schema.GroupVersionResource{Group: "events.k8s.io", Version: "v1", Resource: "events"}).
+		Namespace("default").
+		List(context.Background(), metav1.ListOptions{})
+	Expect(err).ToNot(HaveOccurred())
+	return events.Items
+}
@@ -22,19 +22,11 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"github.com/deckhouse/deckhouse/go_lib/set"
+	"github.com/deckhouse/deckhouse/go_lib/userauthz"
 	"github.com/deckhouse/deckhouse/modules/140-user-authz/hooks/internal"
 )
 
-const (
-	customClusterRoleSnapshots = "custom_cluster_roles"
-
-	accessLevelUser           = "User"
-	accessLevelPrivilegedUser = "PrivilegedUser"
-	accessLevelEditor         = "Editor"
-	accessLevelAdmin          = "Admin"
-	accessLevelClusterEditor  = "ClusterEditor"
-	accessLevelClusterAdmin   = "ClusterAdmin"
-)
+const customClusterRoleSnapshots = "custom_cluster_roles"
 
 type customClusterRole struct {
 	Name string
@@ -48,7 +40,7 @@ func applyCustomRoleFilter(obj *unstructured.Unstructured) (go_hook.FilterResult
 
 	role := obj.GetAnnotations()["user-authz.deckhouse.io/access-level"]
 	switch role {
-	case accessLevelUser, accessLevelPrivilegedUser, accessLevelEditor, accessLevelAdmin, accessLevelClusterEditor, accessLevelClusterAdmin:
+	case userauthz.AccessLevelUser, userauthz.AccessLevelPrivilegedUser, userauthz.AccessLevelEditor, userauthz.AccessLevelAdmin, userauthz.AccessLevelClusterEditor, userauthz.AccessLevelClusterAdmin:
 		ccr.Role = role
 	default:
 		return nil, nil
@@ -98,23 +90,23 @@ func snapshotsToInternalValuesCustomClusterRoles(snapshots []go_hook.FilterResul
 		}
 		customRole := snapshot.(*customClusterRole)
 		switch customRole.Role {
-		case accessLevelUser:
+		case userauthz.AccessLevelUser:
 			userRoleNames.Add(customRole.Name)
 			fallthrough
-		case accessLevelPrivilegedUser:
+		case userauthz.AccessLevelPrivilegedUser:
 			privilegedUserRoleNames.Add(customRole.Name)
 			fallthrough
-		case accessLevelEditor:
+		case userauthz.AccessLevelEditor:
 			editorRoleNames.Add(customRole.Name)
 			fallthrough
-		case accessLevelAdmin:
+		case userauthz.AccessLevelAdmin:
 			adminRoleNames.Add(customRole.Name)
 			fallthrough
-		case accessLevelClusterEditor:
+		case userauthz.AccessLevelClusterEditor:
 
 			clusterEditorRoleNames.Add(customRole.Name)
 			fallthrough
-		case accessLevelClusterAdmin:
+		case userauthz.AccessLevelClusterAdmin:
 			clusterAdminRoleNames.Add(customRole.Name)
 		}
 	}
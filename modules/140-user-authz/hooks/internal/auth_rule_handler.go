@@ -17,13 +17,32 @@ limitations under the License.
 package internal
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook/metrics"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
+// TemporaryGrantsSchedule rechecks the rules every minute to create and to remove the bindings of the time-bound rules on time
+var TemporaryGrantsSchedule = go_hook.ScheduleConfig{
+	Name:    "temporary_grants",
+	Crontab: "* * * * *",
+}
+
+var ruleAPIVersions = map[string]string{
+	"ClusterAuthorizationRule": "deckhouse.io/v1",
+	"AuthorizationRule":        "deckhouse.io/v1alpha1",
+}
+
 type authorizationRule struct {
 	Name      string                 `json:"name"`
 	Spec      map[string]interface{} `json:"spec"`
@@ -48,13 +67,215 @@ func ApplyAuthorizationRuleFilter(obj *unstructured.Unstructured) (go_hook.Filte
 	return car, nil
 }
 
-func AuthorizationRulesHandler(valuesPath, snapshotKey string) func(input *go_hook.HookInput) error {
+// AuthorizationRulesHandler stores the rules that are active at the moment to the values,
+// the bindings of the rules outside their notBefore/expiresAt window are not rendered.
+func AuthorizationRulesHandler(kind, valuesPath, snapshotKey string) func(input *go_hook.HookInput) error {
 	return func(input *go_hook.HookInput) error {
-		input.Values.Set(valuesPath, snapshotsToAuthorizationRulesSlice(input.Snapshots[snapshotKey]))
+		now := time.Now()
+
+		rules := snapshotsToAuthorizationRulesSlice(input.Snapshots[snapshotKey])
+
+		active := make([]authorizationRule, 0)
+		for _, rule := range rules {
+			ok, err := rule.activeAt(now)
+			if err != nil {
+				input.LogEntry.Warnf("%s %s is skipped: %v", kind, rule.key(), err)
+				continue
+			}
+			if ok {
+				active = append(active, rule)
+			}
+		}
+
+		previous := make([]authorizationRule, 0)
+		for _, raw := range input.Values.Get(valuesPath).Array() {
+			var rule authorizationRule
+			if err := json.Unmarshal([]byte(raw.Raw), &rule); err != nil {
+				return fmt.Errorf("unmarshal %s from values: %w", kind, err)
+			}
+			previous = append(previous, rule)
+		}
+
+		reportTemporaryGrants(input, kind, rules, previous, active, now)
+
+		input.Values.Set(valuesPath, active)
 		return nil
 	}
 }
 
+// reportTemporaryGrants creates the audit events for the time-bound rules that are applied or stopped being applied,
+// and exports the expiration time of the active ones.
+// The values are empty after the restart, so the grant events of the active rules are repeated.
+func reportTemporaryGrants(input *go_hook.HookInput, kind string, rules, previous, active []authorizationRule, now time.Time) {
+	metricGroup := "d8_user_authz_temporary_grants_" + strings.ToLower(kind)
+	input.MetricsCollector.Expire(metricGroup)
+
+	previousRules := make(map[string]authorizationRule, len(previous))
+	for _, rule := range previous {
+		previousRules[rule.key()] = rule
+	}
+
+	activeRules := make(map[string]struct{}, len(active))
+	for _, rule := range active {
+		activeRules[rule.key()] = struct{}{}
+
+		if !rule.temporary() {
+			continue
+		}
+
+		if _, ok := previousRules[rule.key()]; !ok {
+			note := fmt.Sprintf("Temporary access is granted to %s", rule.subjects())
+			if expiresAt, ok := rule.timeField("expiresAt"); ok {
+				note += " until " + expiresAt
+			}
+			input.PatchCollector.Create(rule.event(kind, "AccessGranted", note, now))
+		}
+
+		if expiresAt, err := rule.parseTimeField("expiresAt"); err == nil && expiresAt != nil {
+			input.MetricsCollector.Set(
+				"d8_user_authz_temporary_grant_expiration_timestamp_seconds",
+				float64(expiresAt.Unix()),
+				map[string]string{"kind": kind, "namespace": rule.Namespace, "name": rule.Name},
+				metrics.WithGroup(metricGroup),
+			)
+		}
+	}
+
+	currentRules := make(map[string]authorizationRule, len(rules))
+	for _, rule := range rules {
+		currentRules[rule.key()] = rule
+	}
+
+	keys := make([]string, 0, len(previousRules))
+	for key := range previousRules {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		rule := previousRules[key]
+		if _, ok := activeRules[key]; ok || !rule.temporary() {
+			continue
+		}
+
+		note := fmt.Sprintf("Temporary access of %s is revoked", rule.subjects())
+		current, ok := currentRules[key]
+		switch {
+		case !ok:
+			note += ", the rule is deleted"
+		case current.expiredAt(now) != "":
+			note += ", the rule expired at " + current.expiredAt(now)
+		default:
+			notBefore, _ := current.timeField("notBefore")
+			note += ", the rule is not applied until " + notBefore
+		}
+		input.PatchCollector.Create(rule.event(kind, "AccessRevoked", note, now))
+	}
+}
+
+func (r *authorizationRule) key() string {
+	if r.Namespace == "" {
+		return r.Name
+	}
+	return r.Namespace + "/" + r.Name
+}
+
+func (r *authorizationRule) temporary() bool {
+	_, notBefore := r.timeField("notBefore")
+	_, expiresAt := r.timeField("expiresAt")
+	return notBefore || expiresAt
+}
+
+func (r *authorizationRule) activeAt(now time.Time) (bool, error) {
+	notBefore, err := r.parseTimeField("notBefore")
+	if err != nil {
+		return false, err
+	}
+	expiresAt, err := r.parseTimeField("expiresAt")
+	if err != nil {
+		return false, err
+	}
+
+	if notBefore != nil && now.Before(*notBefore) {
+		return false, nil
+	}
+	if expiresAt != nil && !now.Before(*expiresAt) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// expiredAt returns the expiration time if the rule is expired at the moment
+func (r *authorizationRule) expiredAt(now time.Time) string {
+	expiresAt, err := r.parseTimeField("expiresAt")
+	if err != nil || expiresAt == nil || now.Before(*expiresAt) {
+		return ""
+	}
+	return expiresAt.Format(time.RFC3339)
+}
+
+func (r *authorizationRule) timeField(name string) (string, bool) {
+	value, ok := r.Spec[name].(string)
+	return value, ok && value != ""
+}
+
+func (r *authorizationRule) parseTimeField(name string) (*time.Time, error) {
+	value, ok := r.timeField(name)
+	if !ok {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return &t, nil
+}
+
+func (r *authorizationRule) subjects() string {
+	subjects, _ := r.Spec["subjects"].([]interface{})
+
+	result := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		subject, _ := s.(map[string]interface{})
+		result = append(result, fmt.Sprintf("%v %v", subject["kind"], subject["name"]))
+	}
+	return strings.Join(result, ", ")
+}
+
+func (r *authorizationRule) event(kind, reason, note string, now time.Time) *eventsv1.Event {
+	// Namespace field has to be filled, the events of the cluster-wide objects are created in the 'default' namespace
+	namespace := r.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	return &eventsv1.Event{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Event",
+			APIVersion: "events.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			// the same naming as the one of the client-go event recorder
+			Name:      fmt.Sprintf("%s.%x", r.Name, now.UnixNano()),
+			Namespace: namespace,
+		},
+		Regarding: corev1.ObjectReference{
+			Kind:       kind,
+			Name:       r.Name,
+			Namespace:  r.Namespace,
+			APIVersion: ruleAPIVersions[kind],
+		},
+		Reason:              reason,
+		Note:                note,
+		Type:                corev1.EventTypeNormal,
+		EventTime:           metav1.MicroTime{Time: now},
+		Action:              "Binding",
+		ReportingInstance:   "deckhouse",
+		ReportingController: "deckhouse",
+	}
+}
+
 func snapshotsToAuthorizationRulesSlice(snapshots []go_hook.FilterResult) []authorizationRule {
 	ars := make([]authorizationRule, 0, len(snapshots))
 	for _, snapshot := range snapshots {
@@ -0,0 +1,393 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook/metrics"
+	"github.com/flant/addon-operator/sdk"
+	"github.com/flant/shell-operator/pkg/kube/object_patch"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/deckhouse/deckhouse/go_lib/userauthz"
+	"github.com/deckhouse/deckhouse/modules/140-user-authz/hooks/internal"
+)
+
+// The conditions of the ClusterAuthorizationRule and AuthorizationRule status
+const (
+	conditionReady             = "Ready"
+	conditionRolesFound        = "RolesFound"
+	conditionNamespacesMatched = "NamespacesMatched"
+	conditionNoConflicts       = "NoConflicts"
+
+	ruleProblemMetric      = "d8_user_authz_rule_problem"
+	ruleProblemMetricGroup = "d8_user_authz_rule_problems"
+)
+
+var _ = sdk.RegisterFunc(&go_hook.HookConfig{
+	Queue: internal.Queue("authorization_rules_status"),
+	Kubernetes: []go_hook.KubernetesConfig{
+		{
+			Name:       "cluster_authorization_rules",
+			ApiVersion: "deckhouse.io/v1",
+			Kind:       "ClusterAuthorizationRule",
+			FilterFunc: filterRuleForStatus,
+		},
+		{
+			Name:       "authorization_rules",
+			ApiVersion: "deckhouse.io/v1alpha1",
+			Kind:       "AuthorizationRule",
+			FilterFunc: filterRuleForStatus,
+		},
+		{
+			Name:       "cluster_roles",
+			ApiVersion: "rbac.authorization.k8s.io/v1",
+			Kind:       "ClusterRole",
+			FilterFunc: filterClusterRoleName,
+		},
+		{
+			Name:       "namespaces",
+			ApiVersion: "v1",
+			Kind:       "Namespace",
+			FilterFunc: filterNamespaceLabels,
+		},
+	},
+}, updateAuthorizationRulesStatus)
+
+type ruleForStatus struct {
+	Kind      string
+	Name      string
+	Namespace string
+	Spec      ruleForStatusSpec
+	Status    struct {
+		MatchedNamespaces int                `json:"matchedNamespaces"`
+		Conditions        []metav1.Condition `json:"conditions"`
+	}
+}
+
+type ruleForStatusSpec struct {
+	AllowAccessToSystemNamespaces bool     `json:"allowAccessToSystemNamespaces"`
+	LimitNamespaces               []string `json:"limitNamespaces"`
+	NamespaceSelector             *struct {
+		LabelSelector *metav1.LabelSelector `json:"labelSelector"`
+		MatchAny      bool                  `json:"matchAny"`
+	} `json:"namespaceSelector"`
+	AdditionalRoles []struct {
+		Name string `json:"name"`
+	} `json:"additionalRoles"`
+	Subjects []struct {
+		Kind      string `json:"kind"`
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"subjects"`
+}
+
+type namespaceLabels struct {
+	Name   string
+	Labels map[string]string
+}
+
+func filterRuleForStatus(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	rule := &ruleForStatus{
+		Kind:      obj.GetKind(),
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+	}
+
+	spec, _, err := unstructured.NestedMap(obj.Object, "spec")
+	if err != nil {
+		return nil, err
+	}
+	if err := sdk.FromUnstructured(&unstructured.Unstructured{Object: spec}, &rule.Spec); err != nil {
+		return nil, err
+	}
+
+	status, _, err := unstructured.NestedMap(obj.Object, "status")
+	if err != nil {
+		return nil, err
+	}
+	if err := sdk.FromUnstructured(&unstructured.Unstructured{Object: status}, &rule.Status); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func filterClusterRoleName(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	return obj.GetName(), nil
+}
+
+func filterNamespaceLabels(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	return namespaceLabels{Name: obj.GetName(), Labels: obj.GetLabels()}, nil
+}
+
+// updateAuthorizationRulesStatus validates the rules against the ClusterRoles and the namespaces of the cluster,
+// the problems are written to the status conditions of the rules and exported as the metrics for the alerts.
+func updateAuthorizationRulesStatus(input *go_hook.HookInput) error {
+	input.MetricsCollector.Expire(ruleProblemMetricGroup)
+
+	clusterRoles := make(map[string]struct{}, len(input.Snapshots["cluster_roles"]))
+	for _, snap := range input.Snapshots["cluster_roles"] {
+		clusterRoles[snap.(string)] = struct{}{}
+	}
+
+	namespaces := make([]namespaceLabels, 0, len(input.Snapshots["namespaces"]))
+	for _, snap := range input.Snapshots["namespaces"] {
+		namespaces = append(namespaces, snap.(namespaceLabels))
+	}
+
+	clusterRules := snapshotsToRulesForStatus(input.Snapshots["cluster_authorization_rules"])
+	rules := append(clusterRules, snapshotsToRulesForStatus(input.Snapshots["authorization_rules"])...)
+
+	now := metav1.NewTime(time.Now().UTC().Truncate(time.Second))
+
+	for _, rule := range rules {
+		matched, namespacesCondition := rule.matchNamespaces(namespaces)
+
+		conditions := []metav1.Condition{rule.rolesCondition(clusterRoles), namespacesCondition}
+		if rule.Kind == "ClusterAuthorizationRule" {
+			conditions = append(conditions, rule.conflictsCondition(clusterRules))
+		}
+
+		ready := metav1.Condition{Type: conditionReady, Status: metav1.ConditionTrue, Reason: "Valid", Message: "The rule is valid"}
+		for _, condition := range conditions {
+			if condition.Status == metav1.ConditionTrue {
+				continue
+			}
+			ready = metav1.Condition{Type: conditionReady, Status: metav1.ConditionFalse, Reason: condition.Reason, Message: condition.Message}
+
+			input.MetricsCollector.Set(ruleProblemMetric, 1, map[string]string{
+				"kind":      rule.Kind,
+				"namespace": rule.Namespace,
+				"name":      rule.Name,
+				"condition": condition.Type,
+				"reason":    condition.Reason,
+			}, metrics.WithGroup(ruleProblemMetricGroup))
+		}
+		conditions = append([]metav1.Condition{ready}, conditions...)
+
+		for i := range conditions {
+			conditions[i].LastTransitionTime = rule.lastTransitionTime(conditions[i], now)
+		}
+
+		if matched == rule.Status.MatchedNamespaces && reflect.DeepEqual(conditions, rule.Status.Conditions) {
+			continue
+		}
+
+		patch := map[string]interface{}{
+			"status": map[string]interface{}{
+				"matchedNamespaces": matched,
+				"conditions":        conditions,
+			},
+		}
+		input.PatchCollector.MergePatch(patch, ruleAPIVersion(rule.Kind), rule.Kind, rule.Namespace, rule.Name,
+			object_patch.WithSubresource("/status"), object_patch.IgnoreMissingObject())
+	}
+
+	return nil
+}
+
+func ruleAPIVersion(kind string) string {
+	if kind == "ClusterAuthorizationRule" {
+		return "deckhouse.io/v1"
+	}
+	return "deckhouse.io/v1alpha1"
+}
+
+func snapshotsToRulesForStatus(snapshots []go_hook.FilterResult) []*ruleForStatus {
+	result := make([]*ruleForStatus, 0, len(snapshots))
+	for _, snap := range snapshots {
+		result = append(result, snap.(*ruleForStatus))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// lastTransitionTime keeps the time of the previous condition if the status is not changed
+func (r *ruleForStatus) lastTransitionTime(condition metav1.Condition, now metav1.Time) metav1.Time {
+	for _, previous := range r.Status.Conditions {
+		if previous.Type == condition.Type && previous.Status == condition.Status {
+			return previous.LastTransitionTime
+		}
+	}
+	return now
+}
+
+func (r *ruleForStatus) rolesCondition(clusterRoles map[string]struct{}) metav1.Condition {
+	missing := make([]string, 0)
+	for _, role := range r.Spec.AdditionalRoles {
+		if _, ok := clusterRoles[role.Name]; !ok {
+			missing = append(missing, role.Name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return metav1.Condition{
+			Type:    conditionRolesFound,
+			Status:  metav1.ConditionFalse,
+			Reason:  "RoleNotFound",
+			Message: fmt.Sprintf("The additional ClusterRoles are not found: %s", strings.Join(missing, ", ")),
+		}
+	}
+
+	return metav1.Condition{Type: conditionRolesFound, Status: metav1.ConditionTrue, Reason: "RolesFound", Message: "All the roles of the rule exist"}
+}
+
+// matchNamespaces returns the number of the namespaces accessible by the rule.
+// The AuthorizationRule grants access in its own namespace only.
+func (r *ruleForStatus) matchNamespaces(namespaces []namespaceLabels) (int, metav1.Condition) {
+	if r.Kind == "AuthorizationRule" {
+		return 1, metav1.Condition{Type: conditionNamespacesMatched, Status: metav1.ConditionTrue, Reason: "NamespacesMatched", Message: "The rule grants access in its namespace"}
+	}
+
+	match, err := r.namespaceMatcher()
+	if err != nil {
+		return 0, metav1.Condition{Type: conditionNamespacesMatched, Status: metav1.ConditionFalse, Reason: "InvalidNamespaceFilter", Message: err.Error()}
+	}
+
+	matched := 0
+	for _, ns := range namespaces {
+		if match(ns) {
+			matched++
+		}
+	}
+
+	if matched == 0 {
+		return 0, metav1.Condition{
+			Type:    conditionNamespacesMatched,
+			Status:  metav1.ConditionFalse,
+			Reason:  "NoMatchingNamespaces",
+			Message: "The namespaceSelector or limitNamespaces of the rule match no namespaces",
+		}
+	}
+
+	return matched, metav1.Condition{
+		Type:    conditionNamespacesMatched,
+		Status:  metav1.ConditionTrue,
+		Reason:  "NamespacesMatched",
+		Message: fmt.Sprintf("The rule grants access in %d namespaces", matched),
+	}
+}
+
+// namespaceMatcher mirrors the user-authz webhook, the namespaceSelector takes precedence over the limitNamespaces
+func (r *ruleForStatus) namespaceMatcher() (func(ns namespaceLabels) bool, error) {
+	if selector := r.Spec.NamespaceSelector; selector != nil {
+		if selector.MatchAny {
+			return func(namespaceLabels) bool { return true }, nil
+		}
+		if selector.LabelSelector != nil {
+			s, err := metav1.LabelSelectorAsSelector(selector.LabelSelector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid namespaceSelector: %v", err)
+			}
+			return func(ns namespaceLabels) bool { return s.Matches(labels.Set(ns.Labels)) }, nil
+		}
+	}
+
+	limits := make([]*regexp.Regexp, 0, len(r.Spec.LimitNamespaces))
+	for _, pattern := range r.Spec.LimitNamespaces {
+		re, err := regexp.Compile(userauthz.WrapRegex(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid limitNamespaces pattern %q: %v", pattern, err)
+		}
+		limits = append(limits, re)
+	}
+
+	return func(ns namespaceLabels) bool {
+		if !r.Spec.AllowAccessToSystemNamespaces && userauthz.IsSystemNamespace(ns.Name) {
+			return false
+		}
+		return len(limits) == 0 || matchesAny(limits, ns.Name)
+	}, nil
+}
+
+// conflictsCondition finds the ClusterAuthorizationRules of the same subjects with the other namespace restrictions.
+// The user-authz webhook combines the restrictions of all the rules of the subject,
+// so each rule grants its access level in the namespaces of the other rules too.
+func (r *ruleForStatus) conflictsCondition(clusterRules []*ruleForStatus) metav1.Condition {
+	conflicts := make([]string, 0)
+	for _, other := range clusterRules {
+		if other.Name == r.Name || !r.sharesSubject(other) {
+			continue
+		}
+		if !reflect.DeepEqual(r.namespaceRestrictions(), other.namespaceRestrictions()) {
+			conflicts = append(conflicts, other.Name)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return metav1.Condition{
+			Type:   conditionNoConflicts,
+			Status: metav1.ConditionFalse,
+			Reason: "ConflictingNamespaceRestrictions",
+			Message: fmt.Sprintf("The rules %s have the same subjects and the other namespace restrictions, "+
+				"the restrictions of the rules are combined and the access level of every rule is granted in the namespaces of all of them",
+				strings.Join(conflicts, ", ")),
+		}
+	}
+
+	return metav1.Condition{Type: conditionNoConflicts, Status: metav1.ConditionTrue, Reason: "NoConflicts", Message: "The rule does not conflict with the other rules"}
+}
+
+func (r *ruleForStatus) sharesSubject(other *ruleForStatus) bool {
+	for _, subject := range r.Spec.Subjects {
+		for _, otherSubject := range other.Spec.Subjects {
+			if subject == otherSubject {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// namespaceRestrictions returns the namespace options of the rule in the comparable form
+func (r *ruleForStatus) namespaceRestrictions() string {
+	limits := append([]string{}, r.Spec.LimitNamespaces...)
+	sort.Strings(limits)
+
+	data, _ := json.Marshal(struct {
+		AllowAccessToSystemNamespaces bool
+		LimitNamespaces               []string
+		NamespaceSelector             interface{}
+	}{r.Spec.AllowAccessToSystemNamespaces, limits, r.Spec.NamespaceSelector})
+
+	return string(data)
+}
+
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/deckhouse/deckhouse/testing/hooks"
+	"github.com/deckhouse/deckhouse/testing/library/object_store"
+)
+
+const stateAuthorizationRulesStatus = `
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: default
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: kube-system
+  labels:
+    team: platform
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: dev
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod
+  labels:
+    team: backend
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: cert-manager:view
+---
+apiVersion: deckhouse.io/v1
+kind: ClusterAuthorizationRule
+metadata:
+  name: valid
+spec:
+  accessLevel: User
+  additionalRoles:
+  - apiGroup: rbac.authorization.k8s.io
+    kind: ClusterRole
+    name: cert-manager:view
+  subjects:
+  - kind: Group
+    name: everyone
+---
+apiVersion: deckhouse.io/v1
+kind: ClusterAuthorizationRule
+metadata:
+  name: missing-role
+spec:
+  accessLevel: User
+  additionalRoles:
+  - apiGroup: rbac.authorization.k8s.io
+    kind: ClusterRole
+    name: missing
+  subjects:
+  - kind: Group
+    name: auditors
+---
+apiVersion: deckhouse.io/v1
+kind: ClusterAuthorizationRule
+metadata:
+  name: no-namespaces
+spec:
+  accessLevel: Editor
+  namespaceSelector:
+    labelSelector:
+      matchLabels:
+        team: frontend
+  subjects:
+  - kind: Group
+    name: frontend
+---
+apiVersion: deckhouse.io/v1
+kind: ClusterAuthorizationRule
+metadata:
+  name: system
+spec:
+  accessLevel: User
+  namespaceSelector:
+    labelSelector:
+      matchLabels:
+        team: platform
+  subjects:
+  - kind: Group
+    name: platform
+---
+apiVersion: deckhouse.io/v1
+kind: ClusterAuthorizationRule
+metadata:
+  name: jane-dev
+spec:
+  accessLevel: Editor
+  limitNamespaces:
+  - dev
+  subjects:
+  - kind: User
+    name: jane@example.com
+---
+apiVersion: deckhouse.io/v1
+kind: ClusterAuthorizationRule
+metadata:
+  name: jane-prod
+spec:
+  accessLevel: User
+  limitNamespaces:
+  - prod
+  subjects:
+  - kind: User
+    name: jane@example.com
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: AuthorizationRule
+metadata:
+  name: dev-editors
+  namespace: dev
+spec:
+  accessLevel: Editor
+  subjects:
+  - kind: Group
+    name: developers
+`
+
+var _ = Describe("User Authz hooks :: update authorization rules status ::", func() {
+	f := HookExecutionConfigInit(`{"userAuthz":{"internal":{}}}`, `{}`)
+	f.RegisterCRD("deckhouse.io", "v1", "ClusterAuthorizationRule", false)
+	f.RegisterCRD("deckhouse.io", "v1alpha1", "AuthorizationRule", true)
+
+	condition := func(obj object_store.KubeObject, conditionType, field string) string {
+		return obj.Field(`status.conditions.#(type=="` + conditionType + `").` + field).String()
+	}
+
+	Context("Cluster with the authorization rules", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(stateAuthorizationRulesStatus))
+			f.RunHook()
+		})
+
+		It("Must write the conditions of the valid rules", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			car := f.KubernetesGlobalResource("ClusterAuthorizationRule", "valid")
+			Expect(car.Field("status.matchedNamespaces").Int()).To(Equal(int64(2)))
+			Expect(condition(car, "Ready", "status")).To(Equal("True"))
+			Expect(condition(car, "RolesFound", "status")).To(Equal("True"))
+			Expect(condition(car, "NamespacesMatched", "status")).To(Equal("True"))
+			Expect(condition(car, "NoConflicts", "status")).To(Equal("True"))
+			Expect(condition(car, "Ready", "lastTransitionTime")).NotTo(BeEmpty())
+
+			system := f.KubernetesGlobalResource("ClusterAuthorizationRule", "system")
+			Expect(system.Field("status.matchedNamespaces").Int()).To(Equal(int64(1)))
+			Expect(condition(system, "Ready", "status")).To(Equal("True"))
+
+			ar := f.KubernetesResource("AuthorizationRule", "dev", "dev-editors")
+			Expect(ar.Field("status.matchedNamespaces").Int()).To(Equal(int64(1)))
+			Expect(condition(ar, "Ready", "status")).To(Equal("True"))
+			Expect(ar.Field(`status.conditions.#(type=="NoConflicts")`).Exists()).To(BeFalse())
+		})
+
+		It("Must report the missing additional roles", func() {
+			car := f.KubernetesGlobalResource("ClusterAuthorizationRule", "missing-role")
+			Expect(condition(car, "RolesFound", "status")).To(Equal("False"))
+			Expect(condition(car, "RolesFound", "reason")).To(Equal("RoleNotFound"))
+			Expect(condition(car, "RolesFound", "message")).To(ContainSubstring("missing"))
+			Expect(condition(car, "Ready", "status")).To(Equal("False"))
+			Expect(condition(car, "Ready", "reason")).To(Equal("RoleNotFound"))
+		})
+
+		It("Must report the namespace selectors matching nothing", func() {
+			car := f.KubernetesGlobalResource("ClusterAuthorizationRule", "no-namespaces")
+			Expect(car.Field("status.matchedNamespaces").Int()).To(Equal(int64(0)))
+			Expect(condition(car, "NamespacesMatched", "status")).To(Equal("False"))
+			Expect(condition(car, "NamespacesMatched", "reason")).To(Equal("NoMatchingNamespaces"))
+		})
+
+		It("Must report the rules of the same subject with the different namespace restrictions", func() {
+			for _, name := range []string{"jane-dev", "jane-prod"} {
+				car := f.KubernetesGlobalResource("ClusterAuthorizationRule", name)
+				Expect(car.Field("status.matchedNamespaces").Int()).To(Equal(int64(1)))
+				Expect(condition(car, "NoConflicts", "status")).To(Equal("False"))
+				Expect(condition(car, "NoConflicts", "reason")).To(Equal("ConflictingNamespaceRestrictions"))
+			}
+			Expect(condition(f.KubernetesGlobalResource("ClusterAuthorizationRule", "jane-dev"), "NoConflicts", "message")).To(ContainSubstring("jane-prod"))
+		})
+
+		It("Must export the problems as metrics", func() {
+			m := f.MetricsCollector.CollectedMetrics()
+			Expect(m[0].Action).To(Equal("expire"))
+			Expect(m[0].Group).To(Equal("d8_user_authz_rule_problems"))
+
+			problems := make([]string, 0)
+			for _, metric := range m[1:] {
+				Expect(metric.Name).To(Equal("d8_user_authz_rule_problem"))
+				problems = append(problems, metric.Labels["name"]+"/"+metric.Labels["reason"])
+			}
+			Expect(problems).To(ConsistOf(
+				"missing-role/RoleNotFound",
+				"no-namespaces/NoMatchingNamespaces",
+				"jane-dev/ConflictingNamespaceRestrictions",
+				"jane-prod/ConflictingNamespaceRestrictions",
+			))
+		})
+
+		Context("The missing role is created", func() {
+			BeforeEach(func() {
+				f.BindingContexts.Set(f.KubeStateSet(stateAuthorizationRulesStatus + `
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: missing
+`))
+				f.RunHook()
+			})
+
+			It("Must make the rule ready", func() {
+				Expect(f).To(ExecuteSuccessfully())
+				car := f.KubernetesGlobalResource("ClusterAuthorizationRule", "missing-role")
+				Expect(condition(car, "RolesFound", "status")).To(Equal("True"))
+				Expect(condition(car, "Ready", "status")).To(Equal("True"))
+			})
+		})
+	})
+})
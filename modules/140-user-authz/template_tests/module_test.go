@@ -87,6 +87,10 @@ crds:
       - apiGroup: rbac.authorization.k8s.io
         kind: ClusterRole
         name: cluster-write-all
+systemNamespaces:
+  - kube-.*
+  - d8-.*
+  - default
 `
 
 	testRoleCRDs = `---
@@ -126,6 +130,7 @@ var _ = Describe("Module :: user-authz :: helm template ::", func() {
 			f.ValuesSetFromYaml("userAuthz.internal.clusterAuthRuleCrds", testCLusterRoleCRDsWithLimitNamespaces)
 			f.ValuesSetFromYaml("userAuthz.internal.authRuleCrds", testRoleCRDs)
 			f.ValuesSetFromYaml("userAuthz.internal.customClusterRoles", customClusterRolesFlat)
+			f.ValuesSetFromYaml("userAuthz.internal.systemNamespaces", `["kube-.*", "d8-.*", "default"]`)
 
 			f.ValuesSet("userAuthz.enableMultiTenancy", true)
 			f.ValuesSet("userAuthz.controlPlaneConfigurator.enabled", true)
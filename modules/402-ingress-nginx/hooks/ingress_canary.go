@@ -0,0 +1,508 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook"
+	"github.com/flant/addon-operator/pkg/module_manager/go_hook/metrics"
+	"github.com/flant/addon-operator/sdk"
+	"github.com/flant/shell-operator/pkg/kube/object_patch"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+
+	"github.com/deckhouse/deckhouse/go_lib/dependency"
+	d8http "github.com/deckhouse/deckhouse/go_lib/dependency/http"
+	"github.com/deckhouse/deckhouse/go_lib/set"
+)
+
+const (
+	canaryLabel          = "ingress-nginx.deckhouse.io/canary"
+	canaryIngressPrefix  = "d8-canary-"
+	canaryMetricsGroup   = "d8_ingress_nginx_canary"
+	canaryPrometheusURL  = "https://prometheus.d8-monitoring:9090/api/v1/query"
+	defaultCanaryStepInt = 5 * time.Minute
+
+	canaryPhaseStatic      = "Static"
+	canaryPhaseProgressing = "Progressing"
+	canaryPhasePromoted    = "Promoted"
+	canaryPhaseRolledBack  = "RolledBack"
+	canaryPhasePending     = "Pending"
+)
+
+type ingressCanary struct {
+	Name       string
+	Namespace  string
+	UID        types.UID
+	Generation int64
+	Spec       ingressCanarySpec
+	Status     ingressCanaryStatus
+}
+
+type ingressCanarySpec struct {
+	IngressName        string                             `json:"ingressName"`
+	PrimaryServiceName string                             `json:"primaryServiceName,omitempty"`
+	CanaryService      networkingv1.IngressServiceBackend `json:"canaryService"`
+	Weight             int                                `json:"weight,omitempty"`
+	Header             *struct {
+		Name    string `json:"name"`
+		Value   string `json:"value,omitempty"`
+		Pattern string `json:"pattern,omitempty"`
+	} `json:"header,omitempty"`
+	Cookie *struct {
+		Name string `json:"name"`
+	} `json:"cookie,omitempty"`
+	Progression *canaryProgression `json:"progression,omitempty"`
+}
+
+type canaryProgression struct {
+	Steps             []int    `json:"steps"`
+	StepInterval      string   `json:"stepInterval,omitempty"`
+	MaxErrorRate      *float64 `json:"maxErrorRate,omitempty"`
+	MaxLatencySeconds *float64 `json:"maxLatencySeconds,omitempty"`
+}
+
+type ingressCanaryStatus struct {
+	Phase              string   `json:"phase,omitempty"`
+	Weight             int      `json:"weight"`
+	Step               int      `json:"step"`
+	StepStartTime      string   `json:"stepStartTime,omitempty"`
+	ErrorRate          *float64 `json:"errorRate,omitempty"`
+	LatencySeconds     *float64 `json:"latencySeconds,omitempty"`
+	ObservedGeneration int64    `json:"observedGeneration,omitempty"`
+	Message            string   `json:"message,omitempty"`
+}
+
+type canaryIngressInfo struct {
+	Name      string
+	Namespace string
+	// Canary is the name of the IngressCanary the Ingress is generated for
+	Canary           string
+	IngressClassName *string
+	ClassAnnotation  string
+	Rules            []networkingv1.IngressRule
+}
+
+func applyIngressCanaryFilter(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	var canary struct {
+		metav1.ObjectMeta `json:"metadata"`
+		Spec              ingressCanarySpec   `json:"spec"`
+		Status            ingressCanaryStatus `json:"status"`
+	}
+	if err := sdk.FromUnstructured(obj, &canary); err != nil {
+		return nil, fmt.Errorf("cannot convert IngressCanary %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+	}
+
+	return ingressCanary{
+		Name:       canary.Name,
+		Namespace:  canary.Namespace,
+		UID:        canary.UID,
+		Generation: canary.Generation,
+		Spec:       canary.Spec,
+		Status:     canary.Status,
+	}, nil
+}
+
+func applyCanaryIngressFilter(obj *unstructured.Unstructured) (go_hook.FilterResult, error) {
+	var ingress networkingv1.Ingress
+	if err := sdk.FromUnstructured(obj, &ingress); err != nil {
+		return nil, fmt.Errorf("cannot convert Ingress %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+	}
+
+	info := canaryIngressInfo{Name: ingress.Name, Namespace: ingress.Namespace, Canary: ingress.Labels[canaryLabel]}
+	if info.Canary == "" {
+		info.IngressClassName = ingress.Spec.IngressClassName
+		info.ClassAnnotation = ingress.Annotations["kubernetes.io/ingress.class"]
+		info.Rules = ingress.Spec.Rules
+	}
+	return info, nil
+}
+
+var _ = sdk.RegisterFunc(&go_hook.HookConfig{
+	Queue: "/modules/ingress-nginx/canary",
+	Kubernetes: []go_hook.KubernetesConfig{
+		{
+			Name:       "canaries",
+			ApiVersion: "deckhouse.io/v1alpha1",
+			Kind:       "IngressCanary",
+			FilterFunc: applyIngressCanaryFilter,
+		},
+		{
+			Name:       "ingresses",
+			ApiVersion: "networking.k8s.io/v1",
+			Kind:       "Ingress",
+			FilterFunc: applyCanaryIngressFilter,
+			// the changes of the primary Ingresses are applied by the schedule not to run the hook on every Ingress change in the cluster
+			ExecuteHookOnEvents: pointer.Bool(false),
+		},
+	},
+	Schedule: []go_hook.ScheduleConfig{
+		{Name: "progression", Crontab: "* * * * *"},
+	},
+}, dependency.WithExternalDependencies(handleIngressCanaries))
+
+func handleIngressCanaries(input *go_hook.HookInput, dc dependency.Container) error {
+	input.MetricsCollector.Expire(canaryMetricsGroup)
+
+	primaries := make(map[string]canaryIngressInfo)
+	generated := make(map[string]canaryIngressInfo)
+	for _, obj := range input.Snapshots["ingresses"] {
+		ingress := obj.(canaryIngressInfo)
+		if ingress.Canary != "" {
+			generated[ingress.Namespace+"/"+ingress.Canary] = ingress
+			continue
+		}
+		primaries[ingress.Namespace+"/"+ingress.Name] = ingress
+	}
+
+	analyzer := &canaryAnalyzer{
+		client:            dc.GetHTTPClient(d8http.WithInsecureSkipVerify()),
+		prometheusEnabled: set.NewFromValues(input.Values, "global.enabledModules").Has("prometheus"),
+	}
+	now := time.Now().UTC()
+
+	for _, obj := range input.Snapshots["canaries"] {
+		canary := obj.(ingressCanary)
+		key := canary.Namespace + "/" + canary.Name
+
+		primary, ok := primaries[canary.Namespace+"/"+canary.Spec.IngressName]
+		var primaryRules []networkingv1.IngressRule
+		if ok {
+			primaryRules = canaryRules(primary.Rules, canary.Spec)
+		}
+
+		status := reconcileCanaryStatus(canary, primaryRules, analyzer, now)
+		if !reflect.DeepEqual(status, canary.Status) {
+			input.PatchCollector.MergePatch(map[string]interface{}{"status": canaryStatusPatch(status)},
+				"deckhouse.io/v1alpha1", "IngressCanary", canary.Namespace, canary.Name,
+				object_patch.WithSubresource("/status"), object_patch.IgnoreMissingObject())
+		}
+
+		input.MetricsCollector.Set("d8_ingress_nginx_canary_weight", float64(status.Weight), map[string]string{
+			"namespace": canary.Namespace,
+			"name":      canary.Name,
+			"ingress":   canary.Spec.IngressName,
+			"phase":     status.Phase,
+		}, metrics.WithGroup(canaryMetricsGroup))
+
+		if status.Phase == canaryPhasePending || status.Phase == canaryPhaseRolledBack {
+			continue
+		}
+
+		input.PatchCollector.Create(canaryIngress(canary, primary, primaryRules, status.Weight), object_patch.UpdateIfExists())
+		delete(generated, key)
+	}
+
+	for _, ingress := range generated {
+		input.PatchCollector.Delete("networking.k8s.io/v1", "Ingress", ingress.Namespace, ingress.Name)
+	}
+
+	return nil
+}
+
+// canaryStatusPatch sets the missing fields to null to remove them from the status by the merge patch
+func canaryStatusPatch(status ingressCanaryStatus) map[string]interface{} {
+	patch := map[string]interface{}{
+		"phase":              status.Phase,
+		"weight":             status.Weight,
+		"step":               status.Step,
+		"observedGeneration": status.ObservedGeneration,
+		"stepStartTime":      nil,
+		"errorRate":          nil,
+		"latencySeconds":     nil,
+		"message":            nil,
+	}
+	if status.StepStartTime != "" {
+		patch["stepStartTime"] = status.StepStartTime
+	}
+	if status.ErrorRate != nil {
+		patch["errorRate"] = *status.ErrorRate
+	}
+	if status.LatencySeconds != nil {
+		patch["latencySeconds"] = *status.LatencySeconds
+	}
+	if status.Message != "" {
+		patch["message"] = status.Message
+	}
+	return patch
+}
+
+// canaryRules copies the rules of the primary Ingress leading to the primary Service with the canary Service as the backend
+func canaryRules(rules []networkingv1.IngressRule, spec ingressCanarySpec) []networkingv1.IngressRule {
+	result := make([]networkingv1.IngressRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.HTTP == nil {
+			continue
+		}
+
+		paths := make([]networkingv1.HTTPIngressPath, 0, len(rule.HTTP.Paths))
+		for _, path := range rule.HTTP.Paths {
+			if spec.PrimaryServiceName != "" && (path.Backend.Service == nil || path.Backend.Service.Name != spec.PrimaryServiceName) {
+				continue
+			}
+			service := spec.CanaryService
+			paths = append(paths, networkingv1.HTTPIngressPath{
+				Path:     path.Path,
+				PathType: path.PathType,
+				Backend:  networkingv1.IngressBackend{Service: &service},
+			})
+		}
+		if len(paths) == 0 {
+			continue
+		}
+
+		result = append(result, networkingv1.IngressRule{
+			Host:             rule.Host,
+			IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{Paths: paths}},
+		})
+	}
+	return result
+}
+
+func canaryIngress(canary ingressCanary, primary canaryIngressInfo, rules []networkingv1.IngressRule, weight int) *networkingv1.Ingress {
+	annotations := map[string]string{
+		"nginx.ingress.kubernetes.io/canary":        "true",
+		"nginx.ingress.kubernetes.io/canary-weight": strconv.Itoa(weight),
+	}
+	if primary.ClassAnnotation != "" {
+		annotations["kubernetes.io/ingress.class"] = primary.ClassAnnotation
+	}
+	if header := canary.Spec.Header; header != nil {
+		annotations["nginx.ingress.kubernetes.io/canary-by-header"] = header.Name
+		if header.Value != "" {
+			annotations["nginx.ingress.kubernetes.io/canary-by-header-value"] = header.Value
+		}
+		if header.Pattern != "" {
+			annotations["nginx.ingress.kubernetes.io/canary-by-header-pattern"] = header.Pattern
+		}
+	}
+	if cookie := canary.Spec.Cookie; cookie != nil {
+		annotations["nginx.ingress.kubernetes.io/canary-by-cookie"] = cookie.Name
+	}
+
+	return &networkingv1.Ingress{
+		TypeMeta: metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "Ingress"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      canaryIngressPrefix + canary.Name,
+			Namespace: canary.Namespace,
+			Labels: map[string]string{
+				"heritage":  "deckhouse",
+				"module":    "ingress-nginx",
+				canaryLabel: canary.Name,
+			},
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "deckhouse.io/v1alpha1",
+				Kind:       "IngressCanary",
+				Name:       canary.Name,
+				UID:        canary.UID,
+				Controller: pointer.Bool(true),
+			}},
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: primary.IngressClassName,
+			Rules:            rules,
+		},
+	}
+}
+
+func reconcileCanaryStatus(canary ingressCanary, rules []networkingv1.IngressRule, analyzer *canaryAnalyzer, now time.Time) ingressCanaryStatus {
+	status := canary.Status
+	restart := status.ObservedGeneration != canary.Generation
+	status.ObservedGeneration = canary.Generation
+
+	if len(rules) == 0 {
+		message := fmt.Sprintf("Ingress %s is not found", canary.Spec.IngressName)
+		if canary.Spec.PrimaryServiceName != "" {
+			message = fmt.Sprintf("Ingress %s is not found or has no paths leading to the Service %s", canary.Spec.IngressName, canary.Spec.PrimaryServiceName)
+		}
+		return ingressCanaryStatus{Phase: canaryPhasePending, ObservedGeneration: canary.Generation, Message: message}
+	}
+
+	progression := canary.Spec.Progression
+	if progression == nil {
+		return ingressCanaryStatus{Phase: canaryPhaseStatic, Weight: canary.Spec.Weight, ObservedGeneration: canary.Generation}
+	}
+
+	interval, err := time.ParseDuration(progression.StepInterval)
+	if err != nil || interval <= 0 {
+		interval = defaultCanaryStepInt
+	}
+
+	if restart || (status.Phase != canaryPhaseProgressing && status.Phase != canaryPhasePromoted && status.Phase != canaryPhaseRolledBack) {
+		return ingressCanaryStatus{
+			Phase:              canaryPhaseProgressing,
+			Weight:             progression.Steps[0],
+			StepStartTime:      now.Format(time.RFC3339),
+			ObservedGeneration: canary.Generation,
+			Message:            fmt.Sprintf("Step 1 of %d", len(progression.Steps)),
+		}
+	}
+
+	if status.Phase != canaryPhaseProgressing {
+		return status
+	}
+
+	if progression.MaxErrorRate != nil || progression.MaxLatencySeconds != nil {
+		breach, err := analyzer.analyze(canary, &status, interval)
+		if err != nil {
+			status.Message = fmt.Sprintf("The progression is paused: %v", err)
+			return status
+		}
+		if breach != "" {
+			status.Phase = canaryPhaseRolledBack
+			status.Weight = 0
+			status.Message = breach
+			return status
+		}
+	}
+
+	stepStart, err := time.Parse(time.RFC3339, status.StepStartTime)
+	if err != nil || status.Step >= len(progression.Steps) {
+		stepStart, status.Step = now, 0
+		status.StepStartTime = now.Format(time.RFC3339)
+	}
+	if now.Sub(stepStart) < interval {
+		status.Weight = progression.Steps[status.Step]
+		status.Message = fmt.Sprintf("Step %d of %d", status.Step+1, len(progression.Steps))
+		return status
+	}
+
+	if status.Step == len(progression.Steps)-1 {
+		status.Phase = canaryPhasePromoted
+		status.Weight = progression.Steps[status.Step]
+		status.Message = "All the progression steps are passed"
+		return status
+	}
+
+	status.Step++
+	status.Weight = progression.Steps[status.Step]
+	status.StepStartTime = now.Format(time.RFC3339)
+	status.Message = fmt.Sprintf("Step %d of %d", status.Step+1, len(progression.Steps))
+	return status
+}
+
+type canaryAnalyzer struct {
+	client            d8http.Client
+	prometheusEnabled bool
+}
+
+// analyze measures the error rate and the request duration of the canary Service over the interval and
+// returns the description of the breached limit
+func (a *canaryAnalyzer) analyze(canary ingressCanary, status *ingressCanaryStatus, interval time.Duration) (string, error) {
+	if !a.prometheusEnabled {
+		return "", fmt.Errorf("the prometheus module is disabled")
+	}
+
+	progression := canary.Spec.Progression
+	selector := fmt.Sprintf(`namespace=%q, service=%q`, canary.Namespace, canary.Spec.CanaryService.Name)
+	window := fmt.Sprintf("%ds", int(interval.Seconds()))
+
+	if progression.MaxErrorRate != nil {
+		errorRate, ok, err := a.query(fmt.Sprintf(
+			`sum(increase(ingress_nginx_detail_responses_total{%[1]s, status=~"5.."}[%[2]s])) / sum(increase(ingress_nginx_detail_responses_total{%[1]s}[%[2]s]))`,
+			selector, window))
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			status.ErrorRate = &errorRate
+			if errorRate > *progression.MaxErrorRate {
+				return fmt.Sprintf("The error rate %.4f exceeds the limit %.4f", errorRate, *progression.MaxErrorRate), nil
+			}
+		}
+	}
+
+	if progression.MaxLatencySeconds != nil {
+		latency, ok, err := a.query(fmt.Sprintf(
+			`sum(increase(ingress_nginx_detail_request_seconds_sum{%[1]s}[%[2]s])) / sum(increase(ingress_nginx_detail_request_seconds_count{%[1]s}[%[2]s]))`,
+			selector, window))
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			status.LatencySeconds = &latency
+			if latency > *progression.MaxLatencySeconds {
+				return fmt.Sprintf("The average request duration %.3fs exceeds the limit %.3fs", latency, *progression.MaxLatencySeconds), nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// query returns the value of the single-sample instant vector, the result is not ok if there is no data
+func (a *canaryAnalyzer) query(query string) (float64, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, canaryPrometheusURL+"?query="+url.QueryEscape(query), nil)
+	if err != nil {
+		return 0, false, err
+	}
+	if err := d8http.SetKubeAuthToken(req); err != nil {
+		return 0, false, err
+	}
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return 0, false, fmt.Errorf("query Prometheus: %v", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, false, fmt.Errorf("read Prometheus response: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return 0, false, fmt.Errorf("query Prometheus: HTTP %d: %s", res.StatusCode, body)
+	}
+
+	var response struct {
+		Data struct {
+			Result []struct {
+				Value []interface{} `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0, false, fmt.Errorf("unmarshal Prometheus response: %v", err)
+	}
+	if len(response.Data.Result) == 0 || len(response.Data.Result[0].Value) != 2 {
+		return 0, false, nil
+	}
+
+	raw, _ := response.Data.Result[0].Value[1].(string)
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("parse Prometheus value %q: %v", raw, err)
+	}
+	// there were no requests to the canary backend during the interval
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false, nil
+	}
+	return value, true, nil
+}
@@ -0,0 +1,325 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/deckhouse/deckhouse/go_lib/dependency"
+	. "github.com/deckhouse/deckhouse/testing/hooks"
+)
+
+const primaryIngress = `
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: shop
+  namespace: shop
+spec:
+  ingressClassName: nginx
+  rules:
+  - host: shop.example.com
+    http:
+      paths:
+      - path: /
+        pathType: Prefix
+        backend:
+          service:
+            name: shop
+            port:
+              number: 80
+      - path: /static
+        pathType: Prefix
+        backend:
+          service:
+            name: static
+            port:
+              number: 80
+`
+
+const generatedCanaryIngress = `
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: d8-canary-shop
+  namespace: shop
+  labels:
+    heritage: deckhouse
+    ingress-nginx.deckhouse.io/canary: shop
+spec:
+  ingressClassName: nginx
+`
+
+func ingressCanaryYAML(spec, status string) string {
+	return `
+---
+apiVersion: deckhouse.io/v1alpha1
+kind: IngressCanary
+metadata:
+  name: shop
+  namespace: shop
+  generation: 1
+spec:
+  ingressName: shop
+  primaryServiceName: shop
+  canaryService:
+    name: shop-v2
+    port:
+      number: 8080
+` + spec + status
+}
+
+const progressionSpec = `
+  progression:
+    steps: [10, 50, 100]
+    stepInterval: 5m
+    maxErrorRate: 0.05
+    maxLatencySeconds: 0.5
+`
+
+func prometheusResponse(value string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewBufferString(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1714564800,"` + value + `"]}]}}`)),
+	}
+}
+
+var _ = Describe("Modules :: ingress-nginx :: hooks :: ingress_canary ::", func() {
+	f := HookExecutionConfigInit(`{"global":{"enabledModules":["prometheus"]},"ingressNginx":{"internal":{}}}`, `{}`)
+	f.RegisterCRD("deckhouse.io", "v1alpha1", "IngressCanary", true)
+
+	var queries []string
+	mockPrometheus := func(errorRate, latency string) {
+		queries = nil
+		dependency.TestDC.HTTPClient.DoMock.Set(func(req *http.Request) (*http.Response, error) {
+			query := req.URL.Query().Get("query")
+			queries = append(queries, query)
+			if strings.Contains(query, "ingress_nginx_detail_responses_total") {
+				return prometheusResponse(errorRate), nil
+			}
+			return prometheusResponse(latency), nil
+		})
+	}
+
+	Context("Canary with the static weight and the header", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(primaryIngress + ingressCanaryYAML(`
+  weight: 20
+  header:
+    name: X-Canary
+    value: beta
+  cookie:
+    name: canary
+`, "")))
+			f.RunHook()
+		})
+
+		It("Must generate the canary Ingress", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			ingress := f.KubernetesResource("Ingress", "shop", "d8-canary-shop")
+			Expect(ingress.Exists()).To(BeTrue())
+			Expect(ingress.Field("metadata.labels").String()).To(MatchJSON(`{"heritage":"deckhouse","module":"ingress-nginx","ingress-nginx.deckhouse.io/canary":"shop"}`))
+			Expect(ingress.Field("metadata.annotations").String()).To(MatchJSON(`{
+				"nginx.ingress.kubernetes.io/canary": "true",
+				"nginx.ingress.kubernetes.io/canary-weight": "20",
+				"nginx.ingress.kubernetes.io/canary-by-header": "X-Canary",
+				"nginx.ingress.kubernetes.io/canary-by-header-value": "beta",
+				"nginx.ingress.kubernetes.io/canary-by-cookie": "canary"
+			}`))
+			Expect(ingress.Field("metadata.ownerReferences.0.kind").String()).To(Equal("IngressCanary"))
+			Expect(ingress.Field("spec").String()).To(MatchYAML(`
+ingressClassName: nginx
+rules:
+- host: shop.example.com
+  http:
+    paths:
+    - path: /
+      pathType: Prefix
+      backend:
+        service:
+          name: shop-v2
+          port:
+            number: 8080
+`))
+
+			canary := f.KubernetesResource("IngressCanary", "shop", "shop")
+			Expect(canary.Field("status.phase").String()).To(Equal("Static"))
+			Expect(canary.Field("status.weight").Int()).To(Equal(int64(20)))
+			Expect(canary.Field("status.observedGeneration").Int()).To(Equal(int64(1)))
+		})
+
+		It("Must export the weight", func() {
+			m := f.MetricsCollector.CollectedMetrics()
+			Expect(m).To(HaveLen(2))
+			Expect(m[1].Name).To(Equal("d8_ingress_nginx_canary_weight"))
+			Expect(*m[1].Value).To(Equal(20.0))
+			Expect(m[1].Labels).To(Equal(map[string]string{"namespace": "shop", "name": "shop", "ingress": "shop", "phase": "Static"}))
+		})
+	})
+
+	Context("Primary Ingress is not found", func() {
+		BeforeEach(func() {
+			f.BindingContexts.Set(f.KubeStateSet(generatedCanaryIngress + ingressCanaryYAML("", "")))
+			f.RunHook()
+		})
+
+		It("Must delete the canary Ingress", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.KubernetesResource("Ingress", "shop", "d8-canary-shop").Exists()).To(BeFalse())
+
+			canary := f.KubernetesResource("IngressCanary", "shop", "shop")
+			Expect(canary.Field("status.phase").String()).To(Equal("Pending"))
+			Expect(canary.Field("status.message").String()).To(ContainSubstring("Ingress shop is not found"))
+		})
+	})
+
+	Context("Canary with the progression is created", func() {
+		BeforeEach(func() {
+			mockPrometheus("0", "0.1")
+			f.BindingContexts.Set(f.KubeStateSet(primaryIngress + ingressCanaryYAML(progressionSpec, "")))
+			f.RunHook()
+		})
+
+		It("Must start the first step", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(queries).To(BeEmpty())
+
+			canary := f.KubernetesResource("IngressCanary", "shop", "shop")
+			Expect(canary.Field("status.phase").String()).To(Equal("Progressing"))
+			Expect(canary.Field("status.weight").Int()).To(Equal(int64(10)))
+			Expect(canary.Field("status.stepStartTime").String()).NotTo(BeEmpty())
+			Expect(f.KubernetesResource("Ingress", "shop", "d8-canary-shop").Field(`metadata.annotations.nginx\.ingress\.kubernetes\.io/canary-weight`).String()).To(Equal("10"))
+		})
+	})
+
+	Context("Step of the healthy canary is passed", func() {
+		BeforeEach(func() {
+			mockPrometheus("0.01", "0.1")
+			f.BindingContexts.Set(f.KubeStateSet(primaryIngress + ingressCanaryYAML(progressionSpec, `
+status:
+  phase: Progressing
+  weight: 10
+  step: 0
+  stepStartTime: "2024-05-01T12:00:00Z"
+  observedGeneration: 1
+`)))
+			f.RunHook()
+		})
+
+		It("Must go to the next step", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(queries).To(HaveLen(2))
+			Expect(queries[0]).To(ContainSubstring(`namespace="shop", service="shop-v2"`))
+			Expect(queries[0]).To(ContainSubstring(`[300s]`))
+
+			canary := f.KubernetesResource("IngressCanary", "shop", "shop")
+			Expect(canary.Field("status.phase").String()).To(Equal("Progressing"))
+			Expect(canary.Field("status.step").Int()).To(Equal(int64(1)))
+			Expect(canary.Field("status.weight").Int()).To(Equal(int64(50)))
+			Expect(canary.Field("status.errorRate").Float()).To(Equal(0.01))
+			Expect(canary.Field("status.latencySeconds").Float()).To(Equal(0.1))
+			Expect(canary.Field("status.stepStartTime").String()).NotTo(Equal("2024-05-01T12:00:00Z"))
+			Expect(f.KubernetesResource("Ingress", "shop", "d8-canary-shop").Field(`metadata.annotations.nginx\.ingress\.kubernetes\.io/canary-weight`).String()).To(Equal("50"))
+		})
+	})
+
+	Context("Last step of the healthy canary is passed", func() {
+		BeforeEach(func() {
+			mockPrometheus("NaN", "NaN")
+			f.BindingContexts.Set(f.KubeStateSet(primaryIngress + ingressCanaryYAML(progressionSpec, `
+status:
+  phase: Progressing
+  weight: 100
+  step: 2
+  stepStartTime: "2024-05-01T12:00:00Z"
+  observedGeneration: 1
+`)))
+			f.RunHook()
+		})
+
+		It("Must promote the canary", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			canary := f.KubernetesResource("IngressCanary", "shop", "shop")
+			Expect(canary.Field("status.phase").String()).To(Equal("Promoted"))
+			Expect(canary.Field("status.weight").Int()).To(Equal(int64(100)))
+			Expect(canary.Field("status.errorRate").Exists()).To(BeFalse())
+		})
+	})
+
+	Context("Canary breaches the error rate", func() {
+		BeforeEach(func() {
+			mockPrometheus("0.2", "0.1")
+			f.BindingContexts.Set(f.KubeStateSet(primaryIngress + generatedCanaryIngress + ingressCanaryYAML(progressionSpec, `
+status:
+  phase: Progressing
+  weight: 50
+  step: 1
+  stepStartTime: "2099-05-01T12:00:00Z"
+  observedGeneration: 1
+`)))
+			f.RunHook()
+		})
+
+		It("Must roll back the canary", func() {
+			Expect(f).To(ExecuteSuccessfully())
+			Expect(f.KubernetesResource("Ingress", "shop", "d8-canary-shop").Exists()).To(BeFalse())
+
+			canary := f.KubernetesResource("IngressCanary", "shop", "shop")
+			Expect(canary.Field("status.phase").String()).To(Equal("RolledBack"))
+			Expect(canary.Field("status.weight").Int()).To(Equal(int64(0)))
+			Expect(canary.Field("status.message").String()).To(ContainSubstring("error rate 0.2000 exceeds the limit 0.0500"))
+
+			m := f.MetricsCollector.CollectedMetrics()
+			Expect(m[1].Labels["phase"]).To(Equal("RolledBack"))
+		})
+	})
+
+	Context("Specification of the rolled back canary is changed", func() {
+		BeforeEach(func() {
+			mockPrometheus("0", "0.1")
+			f.BindingContexts.Set(f.KubeStateSet(primaryIngress + strings.Replace(ingressCanaryYAML(progressionSpec, `
+status:
+  phase: RolledBack
+  weight: 0
+  step: 1
+  observedGeneration: 1
+`), "generation: 1", "generation: 2", 1)))
+			f.RunHook()
+		})
+
+		It("Must restart the progression", func() {
+			Expect(f).To(ExecuteSuccessfully())
+
+			canary := f.KubernetesResource("IngressCanary", "shop", "shop")
+			Expect(canary.Field("status.phase").String()).To(Equal("Progressing"))
+			Expect(canary.Field("status.step").Int()).To(Equal(int64(0)))
+			Expect(canary.Field("status.weight").Int()).To(Equal(int64(10)))
+			Expect(canary.Field("status.observedGeneration").Int()).To(Equal(int64(2)))
+			Expect(f.KubernetesResource("Ingress", "shop", "d8-canary-shop").Exists()).To(BeTrue())
+		})
+	})
+})
@@ -264,6 +264,8 @@ local function fill_buffer()
 
   local var_namespace = ngx.var.namespace == "" and "-" or ngx.var.namespace
   local var_ingress_name = ngx.var.ingress_name == "" and "-" or ngx.var.ingress_name
+  -- the balancer sets the service of the backend the request is routed to, so the requests routed to the canary backend
+  -- are accounted to the canary service of the canary Ingress (see patches/balancer-lua.patch)
   local var_service_name = ngx.var.service_name == "" and "-" or ngx.var.service_name
   local var_service_port = ngx.var.service_port == "" and "-" or ngx.var.service_port
   local var_location_path = ngx.var.location_path == "" and "-" or ngx.var.location_path
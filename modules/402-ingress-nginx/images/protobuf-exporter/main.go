@@ -23,8 +23,10 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
 
+	"github.com/flant/protobuf_exporter/pkg/routes"
 	"github.com/flant/protobuf_exporter/pkg/server"
 	"github.com/flant/protobuf_exporter/pkg/vault"
 )
@@ -34,11 +36,13 @@ func main() {
 	exporterAddress := ":8081"
 	mappingsPath := "./mappings.yaml"
 	logLevel := "info"
+	controllerName := ""
 
 	flag.StringVar(&telemetryAddress, "server.telemetry-address", telemetryAddress, "Address to listen telemetry messages")
 	flag.StringVar(&exporterAddress, "server.exporter-address", exporterAddress, "Address to export prometheus metrics")
 	flag.StringVar(&mappingsPath, "mappings", mappingsPath, "Path to mappings")
 	flag.StringVar(&logLevel, "log-level", logLevel, "Log level")
+	flag.StringVar(&controllerName, "controller-name", controllerName, "Name of the controller to apply the route metrics config of")
 	flag.Parse()
 
 	if err := log.Base().SetLevel(logLevel); err != nil {
@@ -56,9 +60,16 @@ func main() {
 		log.Fatalf("Mappings registration from %q failed: %v", mappingsPath, err)
 	}
 
+	routeHistograms := routes.NewHistograms()
+	if err := prometheus.Register(routeHistograms); err != nil {
+		log.Fatalf("Route histograms registration failed: %v", err)
+	}
+	topRoutes := routes.NewTop(routes.DefaultTopWindow)
+	routeProcessor := server.NewRouteProcessor(controllerName, mappings, routeHistograms, topRoutes)
+
 	errorCh := make(chan error)
-	metricsServer := server.NewMetricsServer()
-	tcpServer := server.NewTelemetryServer(metricsVault)
+	metricsServer := server.NewMetricsServer(topRoutes)
+	tcpServer := server.NewTelemetryServer(metricsVault, routeProcessor)
 
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
@@ -72,6 +83,7 @@ func main() {
 		// TODO: Think about deleting stale metrics on Collect instead of using scheduled job
 		case <-tick.C:
 			metricsVault.RemoveStaleMetrics()
+			routeHistograms.Clear(time.Now())
 		case s := <-signalChan:
 			log.Warnf("Signal received: %v. Exiting...", s)
 			tcpServer.Close()
@@ -0,0 +1,193 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routes
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+
+	"github.com/flant/protobuf_exporter/pkg/stats"
+)
+
+const (
+	histogramName = "ingress_nginx_route_request_seconds"
+	histogramHelp = "Request duration of the routes selected in the route metrics config."
+	histogramTTL  = time.Hour
+)
+
+// Route selects the locations of the Ingress to collect the latency histogram for
+type Route struct {
+	Namespace string `yaml:"namespace"`
+	Ingress   string `yaml:"ingress"`
+	// Locations of the Ingress, all locations are selected if empty
+	Locations []string `yaml:"locations,omitempty"`
+	BucketSet string   `yaml:"bucketSet"`
+}
+
+// Config is the per-controller route metrics config
+type Config struct {
+	BucketSets map[string][]float64 `yaml:"bucketSets,omitempty"`
+	Routes     []Route              `yaml:"routes,omitempty"`
+	TopWindow  time.Duration        `yaml:"topWindow,omitempty"`
+}
+
+type routeHistogram struct {
+	Count       uint64
+	Sum         float64
+	Buckets     map[float64]uint64
+	LabelValues []string
+	LastUpdate  time.Time
+}
+
+// Histograms collects the latency histograms of the selected routes with the bucket sets of the routes.
+// Unlike the vault histograms, the received buckets are re-binned, so the bucket sets do not have to match the source buckets.
+type Histograms struct {
+	mtx sync.Mutex
+
+	config     Config
+	desc       *prometheus.Desc
+	collection map[RouteKey]*routeHistogram
+}
+
+func NewHistograms() *Histograms {
+	return &Histograms{
+		desc:       prometheus.NewDesc(histogramName, histogramHelp, []string{"namespace", "ingress", "vhost", "location"}, nil),
+		collection: make(map[RouteKey]*routeHistogram),
+	}
+}
+
+// SetConfig replaces the routes and the bucket sets, the collected histograms are dropped if the config is changed
+func (h *Histograms) SetConfig(config Config) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	bucketSets := make(map[string][]float64, len(config.BucketSets))
+	for name, buckets := range config.BucketSets {
+		sorted := append([]float64(nil), buckets...)
+		sort.Float64s(sorted)
+		bucketSets[name] = sorted
+	}
+	config.BucketSets = bucketSets
+
+	if reflect.DeepEqual(h.config.BucketSets, config.BucketSets) && reflect.DeepEqual(h.config.Routes, config.Routes) {
+		return
+	}
+
+	h.config = config
+	h.collection = make(map[RouteKey]*routeHistogram)
+	log.Infof("Collect latency histograms for %d routes", len(config.Routes))
+}
+
+// Observe stores the pre-aggregated observations if the route is selected.
+// The buckets contain the number of observations which got into the bucket first, as in the HistogramMessage.
+func (h *Histograms) Observe(key RouteKey, count uint64, sum float64, buckets map[float64]uint64, now time.Time) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	bucketSet, ok := h.bucketSet(key)
+	if !ok {
+		return
+	}
+
+	stored, ok := h.collection[key]
+	if !ok {
+		stored = &routeHistogram{
+			Buckets:     make(map[float64]uint64, len(bucketSet)),
+			LabelValues: []string{key.Namespace, key.Ingress, key.Vhost, key.Location},
+		}
+		for _, bucket := range bucketSet {
+			stored.Buckets[bucket] = 0
+		}
+		h.collection[key] = stored
+	}
+
+	stored.Count += count
+	stored.Sum += sum
+	stored.LastUpdate = now
+
+	// every received bucket is counted in all the buckets of the set which are not less than it
+	for received, value := range buckets {
+		first := sort.SearchFloat64s(bucketSet, received)
+		for _, bucket := range bucketSet[first:] {
+			stored.Buckets[bucket] += value
+		}
+	}
+}
+
+func (h *Histograms) bucketSet(key RouteKey) ([]float64, bool) {
+	for _, route := range h.config.Routes {
+		if route.Namespace != key.Namespace || route.Ingress != key.Ingress {
+			continue
+		}
+		if len(route.Locations) > 0 && !contains(route.Locations, key.Location) {
+			continue
+		}
+		buckets, ok := h.config.BucketSets[route.BucketSet]
+		return buckets, ok
+	}
+	return nil, false
+}
+
+func (h *Histograms) Describe(ch chan<- *prometheus.Desc) {
+	ch <- h.desc
+}
+
+func (h *Histograms) Collect(ch chan<- prometheus.Metric) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	for _, s := range h.collection {
+		buckets := make(map[float64]uint64, len(s.Buckets))
+		for bucket, value := range s.Buckets {
+			buckets[bucket] = value
+		}
+
+		metric, err := prometheus.NewConstHistogram(h.desc, s.Count, s.Sum, buckets, s.LabelValues...)
+		if err != nil {
+			log.Warnf("prepare route histogram: %v", err)
+			stats.Errors.WithLabelValues("prepare-route-histogram").Inc()
+			continue
+		}
+		ch <- metric
+	}
+}
+
+// Clear removes the histograms of the routes without requests during the TTL
+func (h *Histograms) Clear(now time.Time) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	for key, s := range h.collection {
+		if s.LastUpdate.Add(histogramTTL).Before(now) {
+			delete(h.collection, key)
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
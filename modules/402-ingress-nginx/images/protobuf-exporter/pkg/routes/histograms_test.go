@@ -0,0 +1,99 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routes
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistograms(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	h := NewHistograms()
+	h.SetConfig(Config{
+		BucketSets: map[string][]float64{"api": {1, 0.1, 0.5}},
+		Routes: []Route{
+			{Namespace: "shop", Ingress: "api", Locations: []string{"/orders"}, BucketSet: "api"},
+			{Namespace: "shop", Ingress: "web", BucketSet: "api"},
+			{Namespace: "shop", Ingress: "broken", BucketSet: "missing"},
+		},
+	})
+
+	orders := RouteKey{Namespace: "shop", Ingress: "api", Vhost: "shop.example.com", Location: "/orders"}
+	h.Observe(orders, 5, 7.3, map[float64]uint64{0.05: 1, 0.1: 1, 0.45: 1, 1.5: 1}, now)
+	h.Observe(orders, 1, 0.2, map[float64]uint64{0.25: 1}, now)
+
+	for _, key := range []RouteKey{
+		{Namespace: "shop", Ingress: "api", Vhost: "shop.example.com", Location: "/cart"},
+		{Namespace: "shop", Ingress: "broken", Vhost: "shop.example.com", Location: "/"},
+		{Namespace: "other", Ingress: "api", Vhost: "shop.example.com", Location: "/orders"},
+	} {
+		h.Observe(key, 1, 1, map[float64]uint64{1: 1}, now)
+	}
+	h.Observe(RouteKey{Namespace: "shop", Ingress: "web", Vhost: "shop.example.com", Location: "/"}, 1, 1, map[float64]uint64{1: 1}, now)
+
+	if len(h.collection) != 2 {
+		t.Fatalf("expected histograms of 2 routes, got %d", len(h.collection))
+	}
+
+	stored := h.collection[orders]
+	if stored.Count != 6 || stored.Sum != 7.5 {
+		t.Errorf("unexpected count %d and sum %v", stored.Count, stored.Sum)
+	}
+	expected := map[float64]uint64{0.1: 2, 0.5: 4, 1: 4}
+	if !reflect.DeepEqual(stored.Buckets, expected) {
+		t.Errorf("expected buckets %v, got %v", expected, stored.Buckets)
+	}
+
+	h.Clear(now.Add(30 * time.Minute))
+	if len(h.collection) != 2 {
+		t.Errorf("histograms must be kept during the TTL")
+	}
+	h.Clear(now.Add(2 * time.Hour))
+	if len(h.collection) != 0 {
+		t.Errorf("stale histograms must be removed")
+	}
+}
+
+func TestHistogramsSetConfig(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	config := func() Config {
+		return Config{
+			BucketSets: map[string][]float64{"api": {1, 0.1}},
+			Routes:     []Route{{Namespace: "shop", Ingress: "api", BucketSet: "api"}},
+		}
+	}
+	key := RouteKey{Namespace: "shop", Ingress: "api", Vhost: "shop.example.com", Location: "/"}
+
+	h := NewHistograms()
+	h.SetConfig(config())
+	h.Observe(key, 1, 0.1, map[float64]uint64{0.1: 1}, now)
+
+	h.SetConfig(config())
+	if len(h.collection) != 1 {
+		t.Errorf("histograms must be kept if the config is not changed")
+	}
+
+	changed := config()
+	changed.BucketSets["api"] = []float64{0.1, 0.2, 1}
+	h.SetConfig(changed)
+	if len(h.collection) != 0 {
+		t.Errorf("histograms must be dropped if the config is changed")
+	}
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routes
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTopWindow = 5 * time.Minute
+
+	// the window slides by 1/topSlots of its length
+	topSlots = 10
+)
+
+type TopOrder string
+
+const (
+	ByLatency TopOrder = "latency"
+	ByErrors  TopOrder = "errors"
+)
+
+func ParseTopOrder(order string) (TopOrder, error) {
+	switch TopOrder(order) {
+	case "", ByLatency:
+		return ByLatency, nil
+	case ByErrors:
+		return ByErrors, nil
+	default:
+		return "", fmt.Errorf("unknown order %q, %q or %q is expected", order, ByLatency, ByErrors)
+	}
+}
+
+type RouteKey struct {
+	Namespace string
+	Ingress   string
+	Vhost     string
+	Location  string
+}
+
+type routeStats struct {
+	Requests uint64
+	Errors   uint64
+	Seconds  float64
+}
+
+type topSlot struct {
+	start  time.Time
+	routes map[RouteKey]*routeStats
+}
+
+// TopEntry is the route statistics over the window
+type TopEntry struct {
+	Namespace      string  `json:"namespace"`
+	Ingress        string  `json:"ingress"`
+	Vhost          string  `json:"vhost"`
+	Location       string  `json:"location"`
+	Requests       uint64  `json:"requests"`
+	Errors         uint64  `json:"errors"`
+	ErrorRatio     float64 `json:"errorRatio"`
+	AverageSeconds float64 `json:"averageSeconds"`
+}
+
+// Top tracks the requests, the 5xx responses and the request duration of all the routes over the sliding window
+// to report the slowest and the most erroring routes without exporting them as metrics.
+type Top struct {
+	mtx sync.Mutex
+
+	window time.Duration
+	slots  []topSlot
+}
+
+func NewTop(window time.Duration) *Top {
+	t := &Top{}
+	t.SetWindow(window)
+	return t
+}
+
+func (t *Top) Window() time.Duration {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	return t.window
+}
+
+// SetWindow changes the window length, the collected statistics are dropped if the length is changed
+func (t *Top) SetWindow(window time.Duration) {
+	if window <= 0 {
+		window = DefaultTopWindow
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.window == window {
+		return
+	}
+	t.window = window
+	t.slots = make([]topSlot, topSlots)
+}
+
+func (t *Top) ObserveRequests(key RouteKey, count uint64, seconds float64, now time.Time) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	stats := t.routeStats(key, now)
+	stats.Requests += count
+	stats.Seconds += seconds
+}
+
+func (t *Top) ObserveErrors(key RouteKey, count uint64, now time.Time) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.routeStats(key, now).Errors += count
+}
+
+func (t *Top) routeStats(key RouteKey, now time.Time) *routeStats {
+	slotLength := t.window / topSlots
+	start := now.Truncate(slotLength)
+	slot := &t.slots[(start.UnixNano()/int64(slotLength))%topSlots]
+
+	if !slot.start.Equal(start) {
+		slot.start = start
+		slot.routes = make(map[RouteKey]*routeStats)
+	}
+
+	stats, ok := slot.routes[key]
+	if !ok {
+		stats = &routeStats{}
+		slot.routes[key] = stats
+	}
+	return stats
+}
+
+// List returns up to n routes with the highest average request duration or the highest number of errors over the window
+func (t *Top) List(n int, order TopOrder, now time.Time) []TopEntry {
+	t.mtx.Lock()
+	total := make(map[RouteKey]*routeStats)
+	since := now.Add(-t.window)
+	for _, slot := range t.slots {
+		if slot.routes == nil || !slot.start.After(since) {
+			continue
+		}
+		for key, stats := range slot.routes {
+			sum, ok := total[key]
+			if !ok {
+				sum = &routeStats{}
+				total[key] = sum
+			}
+			sum.Requests += stats.Requests
+			sum.Errors += stats.Errors
+			sum.Seconds += stats.Seconds
+		}
+	}
+	t.mtx.Unlock()
+
+	entries := make([]TopEntry, 0, len(total))
+	for key, stats := range total {
+		if order == ByErrors && stats.Errors == 0 {
+			continue
+		}
+		entry := TopEntry{
+			Namespace: key.Namespace,
+			Ingress:   key.Ingress,
+			Vhost:     key.Vhost,
+			Location:  key.Location,
+			Requests:  stats.Requests,
+			Errors:    stats.Errors,
+		}
+		if stats.Requests > 0 {
+			entry.ErrorRatio = float64(stats.Errors) / float64(stats.Requests)
+			entry.AverageSeconds = stats.Seconds / float64(stats.Requests)
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case order == ByErrors && a.Errors != b.Errors:
+			return a.Errors > b.Errors
+		case order == ByLatency && a.AverageSeconds != b.AverageSeconds:
+			return a.AverageSeconds > b.AverageSeconds
+		case a.Requests != b.Requests:
+			return a.Requests > b.Requests
+		case a.Namespace != b.Namespace:
+			return a.Namespace < b.Namespace
+		case a.Ingress != b.Ingress:
+			return a.Ingress < b.Ingress
+		case a.Vhost != b.Vhost:
+			return a.Vhost < b.Vhost
+		}
+		return a.Location < b.Location
+	})
+
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
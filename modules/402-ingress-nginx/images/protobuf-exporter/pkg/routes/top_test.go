@@ -0,0 +1,109 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package routes
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func locations(entries []TopEntry) []string {
+	result := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry.Location)
+	}
+	return result
+}
+
+func TestTop(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	route := func(location string) RouteKey {
+		return RouteKey{Namespace: "shop", Ingress: "api", Vhost: "shop.example.com", Location: location}
+	}
+
+	top := NewTop(0)
+	if top.Window() != DefaultTopWindow {
+		t.Fatalf("expected the default window, got %v", top.Window())
+	}
+
+	top.ObserveRequests(route("/fast"), 10, 0.5, now)
+	top.ObserveRequests(route("/slow"), 2, 6, now)
+	top.ObserveRequests(route("/medium"), 4, 2, now.Add(time.Minute))
+	top.ObserveErrors(route("/medium"), 2, now.Add(time.Minute))
+	top.ObserveErrors(route("/fast"), 1, now.Add(time.Minute))
+
+	tests := []struct {
+		name     string
+		n        int
+		order    TopOrder
+		now      time.Time
+		expected []string
+	}{
+		{name: "By latency", n: 10, order: ByLatency, now: now.Add(2 * time.Minute), expected: []string{"/slow", "/medium", "/fast"}},
+		{name: "Limited", n: 1, order: ByLatency, now: now.Add(2 * time.Minute), expected: []string{"/slow"}},
+		{name: "By errors", n: 10, order: ByErrors, now: now.Add(2 * time.Minute), expected: []string{"/medium", "/fast"}},
+		{name: "Old requests are out of the window", n: 10, order: ByLatency, now: now.Add(5*time.Minute + time.Second), expected: []string{"/medium", "/fast"}},
+		{name: "All requests are out of the window", n: 10, order: ByErrors, now: now.Add(time.Hour), expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := locations(top.List(tt.n, tt.order, tt.now))
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	medium := top.List(1, ByErrors, now.Add(2*time.Minute))[0]
+	if medium.Requests != 4 || medium.Errors != 2 || medium.ErrorRatio != 0.5 || medium.AverageSeconds != 0.5 {
+		t.Errorf("unexpected route statistics %+v", medium)
+	}
+}
+
+func TestTopSlotReuse(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	key := RouteKey{Namespace: "shop", Ingress: "api", Vhost: "shop.example.com", Location: "/"}
+
+	top := NewTop(time.Minute)
+	top.ObserveRequests(key, 1, 1, now)
+	// the same slot of the ring is used a window later
+	top.ObserveRequests(key, 1, 3, now.Add(time.Minute))
+
+	entries := top.List(10, ByLatency, now.Add(time.Minute))
+	if len(entries) != 1 || entries[0].Requests != 1 || entries[0].AverageSeconds != 3 {
+		t.Errorf("the statistics of the previous window must be dropped, got %+v", entries)
+	}
+
+	top.SetWindow(2 * time.Minute)
+	if len(top.List(10, ByLatency, now.Add(time.Minute))) != 0 {
+		t.Errorf("the statistics must be dropped if the window is changed")
+	}
+}
+
+func TestParseTopOrder(t *testing.T) {
+	for value, expected := range map[string]TopOrder{"": ByLatency, "latency": ByLatency, "errors": ByErrors} {
+		order, err := ParseTopOrder(value)
+		if err != nil || order != expected {
+			t.Errorf("ParseTopOrder(%q) = %q, %v", value, order, err)
+		}
+	}
+	if _, err := ParseTopOrder("bytes"); err == nil {
+		t.Errorf("an error is expected for the unknown order")
+	}
+}
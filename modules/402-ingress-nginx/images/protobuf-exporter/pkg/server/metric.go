@@ -17,31 +17,42 @@ limitations under the License.
 package server
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/log"
+
+	"github.com/flant/protobuf_exporter/pkg/routes"
 )
 
+const defaultTopSize = 10
+
 type MetricsServer struct {
 	srv *http.Server
+	top *routes.Top
 }
 
-func NewMetricsServer() *MetricsServer {
-	return &MetricsServer{srv: &http.Server{}}
+func NewMetricsServer(top *routes.Top) *MetricsServer {
+	return &MetricsServer{srv: &http.Server{}, top: top}
 }
 
 func (m *MetricsServer) Start(address string, errorCh chan error) {
 	http.Handle("/metrics", promhttp.Handler())
+	http.HandleFunc("/top", m.handleTop)
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		_, err := fmt.Fprintf(w, `<!DOCTYPE html>
 			<title>Protobuf Exporter</title>
 			<h1>Protobuf Exporter</h1>
-			<p><a href=%q>Metrics</a></p>`,
-			"/metrics")
+			<p><a href=%q>Metrics</a></p>
+			<p><a href=%q>Slowest routes</a></p>
+			<p><a href=%q>Most erroring routes</a></p>`,
+			"/metrics", "/top?by=latency", "/top?by=errors")
 		if err != nil {
 			log.Warnf("Error while sending a response for the '/' path: %v", err)
 			return
@@ -58,6 +69,39 @@ func (m *MetricsServer) Start(address string, errorCh chan error) {
 	errorCh <- m.srv.Serve(listener)
 }
 
+// handleTop responds with the slowest or the most erroring routes over the sliding window
+func (m *MetricsServer) handleTop(w http.ResponseWriter, r *http.Request) {
+	order, err := routes.ParseTopOrder(r.URL.Query().Get("by"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n := defaultTopSize
+	if value := r.URL.Query().Get("n"); value != "" {
+		n, err = strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, fmt.Sprintf("invalid n %q, a positive number is expected", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	response := struct {
+		Window string            `json:"window"`
+		By     routes.TopOrder   `json:"by"`
+		Routes []routes.TopEntry `json:"routes"`
+	}{
+		Window: m.top.Window().String(),
+		By:     order,
+		Routes: m.top.List(n, order, time.Now()),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Warnf("Error while sending a response for the '/top' path: %v", err)
+	}
+}
+
 func (m *MetricsServer) Close() {
 	_ = m.srv.Close()
 }
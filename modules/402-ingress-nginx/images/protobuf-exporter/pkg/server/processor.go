@@ -24,6 +24,8 @@ import (
 	"github.com/fsnotify/fsnotify"
 	"github.com/prometheus/common/log"
 	"gopkg.in/yaml.v3"
+
+	"github.com/flant/protobuf_exporter/pkg/routes"
 )
 
 const (
@@ -32,10 +34,11 @@ const (
 
 type telemetryMessageProcessor struct {
 	discardProcessor *discardProcessor
+	routeProcessor   *RouteProcessor
 }
 
-func newTelemetryMessageProcessor() *telemetryMessageProcessor {
-	return &telemetryMessageProcessor{discardProcessor: newDiscardProcessor(nil)}
+func newTelemetryMessageProcessor(routeProcessor *RouteProcessor) *telemetryMessageProcessor {
+	return &telemetryMessageProcessor{discardProcessor: newDiscardProcessor(nil), routeProcessor: routeProcessor}
 }
 
 func (tmp *telemetryMessageProcessor) LoadConfig(ctx context.Context) error {
@@ -69,6 +72,8 @@ func (tmp *telemetryMessageProcessor) parseConfig() error {
 		tmp.discardProcessor = dp
 	}
 
+	tmp.routeProcessor.Configure(config.RouteMetrics)
+
 	return nil
 }
 
@@ -115,4 +120,6 @@ func (tmp *telemetryMessageProcessor) runConfigWatcher(ctx context.Context) {
 
 type telemetryConfig struct {
 	Discard *discardConfig `yaml:"discard,omitempty"`
+	// RouteMetrics are the route metrics configs by the controller names
+	RouteMetrics map[string]routes.Config `yaml:"routeMetrics,omitempty"`
 }
@@ -0,0 +1,145 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/log"
+
+	"github.com/flant/protobuf_exporter/pkg/routes"
+	"github.com/flant/protobuf_exporter/pkg/vault"
+)
+
+// the route statistics are taken from these mappings
+const (
+	routeLatencyMapping   = "ingress_nginx_detail_request_seconds"
+	routeResponsesMapping = "ingress_nginx_detail_responses_total"
+)
+
+type routeLabels struct {
+	mappingIndex int
+	namespace    int
+	ingress      int
+	vhost        int
+	location     int
+	status       int
+}
+
+func newRouteLabels(mappings []vault.Mapping, name string) *routeLabels {
+	for index, mapping := range mappings {
+		if mapping.Name != name {
+			continue
+		}
+
+		labels := &routeLabels{mappingIndex: index, namespace: -1, ingress: -1, vhost: -1, location: -1, status: -1}
+		for i, label := range mapping.LabelNames {
+			switch label {
+			case "namespace":
+				labels.namespace = i
+			case "ingress":
+				labels.ingress = i
+			case "vhost":
+				labels.vhost = i
+			case "location":
+				labels.location = i
+			case "status":
+				labels.status = i
+			}
+		}
+		if labels.namespace < 0 || labels.ingress < 0 || labels.vhost < 0 || labels.location < 0 {
+			break
+		}
+		return labels
+	}
+
+	log.Warnf("Mapping %q with the route labels is not found, the route metrics are not collected from it", name)
+	return nil
+}
+
+func (rl *routeLabels) key(values []string) (routes.RouteKey, bool) {
+	if rl.namespace >= len(values) || rl.ingress >= len(values) || rl.vhost >= len(values) || rl.location >= len(values) {
+		return routes.RouteKey{}, false
+	}
+	return routes.RouteKey{
+		Namespace: values[rl.namespace],
+		Ingress:   values[rl.ingress],
+		Vhost:     values[rl.vhost],
+		Location:  values[rl.location],
+	}, true
+}
+
+// RouteProcessor feeds the route histograms and the top routes from the received messages
+type RouteProcessor struct {
+	controllerName string
+	histograms     *routes.Histograms
+	top            *routes.Top
+
+	latency   *routeLabels
+	responses *routeLabels
+}
+
+func NewRouteProcessor(controllerName string, mappings []vault.Mapping, histograms *routes.Histograms, top *routes.Top) *RouteProcessor {
+	rp := &RouteProcessor{
+		controllerName: controllerName,
+		histograms:     histograms,
+		top:            top,
+		latency:        newRouteLabels(mappings, routeLatencyMapping),
+		responses:      newRouteLabels(mappings, routeResponsesMapping),
+	}
+	if rp.responses != nil && rp.responses.status < 0 {
+		rp.responses = nil
+	}
+	return rp
+}
+
+// Configure applies the route metrics config of the controller
+func (rp *RouteProcessor) Configure(configs map[string]routes.Config) {
+	config := configs[rp.controllerName]
+	rp.histograms.SetConfig(config)
+	rp.top.SetWindow(config.TopWindow)
+}
+
+func (rp *RouteProcessor) ObserveHistogram(index int, labels []string, count uint64, sum float64, buckets map[float64]uint64) {
+	if rp.latency == nil || index != rp.latency.mappingIndex {
+		return
+	}
+	key, ok := rp.latency.key(labels)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	rp.histograms.Observe(key, count, sum, buckets, now)
+	rp.top.ObserveRequests(key, count, sum, now)
+}
+
+func (rp *RouteProcessor) ObserveCounter(index int, labels []string, value uint64) {
+	if rp.responses == nil || index != rp.responses.mappingIndex || rp.responses.status >= len(labels) {
+		return
+	}
+	if !strings.HasPrefix(labels[rp.responses.status], "5") {
+		return
+	}
+	key, ok := rp.responses.key(labels)
+	if !ok {
+		return
+	}
+
+	rp.top.ObserveErrors(key, value, time.Now())
+}
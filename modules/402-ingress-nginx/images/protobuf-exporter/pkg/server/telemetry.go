@@ -48,13 +48,13 @@ type TelemetryServer struct {
 	messageProcessor *telemetryMessageProcessor
 }
 
-func NewTelemetryServer(vault *vault.MetricsVault) *TelemetryServer {
+func NewTelemetryServer(vault *vault.MetricsVault, routeProcessor *RouteProcessor) *TelemetryServer {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &TelemetryServer{
 		ctx:              ctx,
 		stopFunc:         cancel,
 		vault:            vault,
-		messageProcessor: newTelemetryMessageProcessor(),
+		messageProcessor: newTelemetryMessageProcessor(routeProcessor),
 	}
 }
 
@@ -123,6 +123,7 @@ func (s *TelemetryServer) handleConn(c *net.TCPConn) {
 				stats.Errors.WithLabelValues("wrong-mapping").Inc()
 			} else {
 				stats.Messages.WithLabelValues("counter").Inc()
+				s.messageProcessor.routeProcessor.ObserveCounter(int(message.MappingIndex), message.Labels, message.Value)
 			}
 		case GaugeMarker:
 			var message mproto.GaugeMessage
@@ -162,6 +163,7 @@ func (s *TelemetryServer) handleConn(c *net.TCPConn) {
 				stats.Errors.WithLabelValues("wrong-mapping").Inc()
 			} else {
 				stats.Messages.WithLabelValues("histogram").Inc()
+				s.messageProcessor.routeProcessor.ObserveHistogram(int(message.MappingIndex), message.Labels, message.Count, message.Sum, buckets)
 			}
 		default:
 			log.Warnf("protocol error: unknown metric marker: %v", marker)
@@ -95,6 +95,15 @@ var _ = Describe("Module :: ingress-nginx :: helm template :: controllers ", fun
       - 2.2.2.2
     maxReplicas: 6
     minReplicas: 2
+    routeMetrics:
+      bucketSets:
+        api: [0.05, 0.1, 0.5]
+      routes:
+      - namespace: shop
+        ingress: api
+        locations: ["/orders"]
+        bucketSet: api
+      topWindow: 10m
 - name: test-lbwpp
   spec:
     config:
@@ -184,6 +193,26 @@ memory: 200Mi`))
 			Expect(hec.KubernetesResource("ConfigMap", "d8-ingress-nginx", "test-custom-headers").Exists()).To(BeTrue())
 			Expect(hec.KubernetesResource("Secret", "d8-ingress-nginx", "ingress-nginx-test-auth-tls").Exists()).To(BeTrue())
 
+			Expect(testD.Field("spec.template.spec.containers.1.name").String()).To(Equal("protobuf-exporter"))
+			Expect(testD.Field("spec.template.spec.containers.1.args").AsStringSlice()).To(Equal([]string{"-controller-name=test"}))
+
+			telemetryConfig := hec.KubernetesResource("ConfigMap", "d8-ingress-nginx", "d8-ingress-telemetry-config").Field(`data.telemetry_config\.yml`).String()
+			Expect(telemetryConfig).To(MatchYAML(`
+discard:
+  namespaces: []
+  ingresses: []
+routeMetrics:
+  test:
+    bucketSets:
+      api: [0.05, 0.1, 0.5]
+    routes:
+    - namespace: shop
+      ingress: api
+      locations: ["/orders"]
+      bucketSet: api
+    topWindow: 10m
+`))
+
 			fakeIng := hec.KubernetesResource("Ingress", "d8-ingress-nginx", "test-custom-headers-reload")
 			Expect(fakeIng.Field("spec.rules.0.http.paths.0.path").String()).To(Equal("/e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"))
 
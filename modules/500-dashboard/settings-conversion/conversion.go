@@ -22,9 +22,14 @@ import (
 
 const moduleName = "dashboard"
 
-var _ = conversion.RegisterFunc(moduleName, 1, 2, convertV1ToV2)
+var _ = conversion.RegisterReversibleFunc(moduleName, 1, 2, convertV1ToV2, convertV2ToV1)
 
 // convertV1ToV2 removes deprecated fields.
 func convertV1ToV2(settings *conversion.Settings) error {
 	return settings.DeleteAndClean("auth.password")
 }
+
+// convertV2ToV1 keeps settings as is: the removed auth.password field is optional in version 1.
+func convertV2ToV1(_ *conversion.Settings) error {
+	return nil
+}
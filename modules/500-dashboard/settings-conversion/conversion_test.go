@@ -81,6 +81,22 @@ auth:
 				`
 auth:
   allowScale: true
+`,
+			))
+	})
+
+	Context("giving settings in version 2", func() {
+		table.DescribeTable("should convert from 2 to 1",
+			ct.TestConversionToPreviousVersion(2, 1),
+			table.Entry("giving empty settings", ``, ``),
+			table.Entry("giving settings with auth",
+				`
+auth:
+  allowScale: true
+`,
+				`
+auth:
+  allowScale: true
 `,
 			))
 	})
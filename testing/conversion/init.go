@@ -110,6 +110,27 @@ func (c *ConversionTester) TestConversionToValidLatest(fromVersion int) func(inp
 	}
 }
 
+func (c *ConversionTester) TestConversionToPreviousVersion(fromVersion int, targetVersion int) func(input string, expect string) {
+	return func(input string, expect string) {
+		res := c.ConvertTo(fromVersion, targetVersion, input)
+		Expect(res.Error).ShouldNot(HaveOccurred())
+
+		expectSettings, err := conversion.SettingsFromYAML(expect)
+		Expect(err).ShouldNot(HaveOccurred(), "should convert expected to Settings")
+
+		expectMap, err := expectSettings.Map()
+		Expect(err).ShouldNot(HaveOccurred(), "should convert expected Settings to map")
+
+		// A guard for BeComparableTo: it is an error for actual and expected to be nil.
+		if len(res.SettingsMap) == 0 && len(expectMap) == 0 {
+			return
+		}
+
+		Expect(res.Version).Should(Equal(targetVersion), "should convert to previous version")
+		Expect(res.SettingsMap).To(BeComparableTo(expectMap), "expected settings should not differ from the conversion result")
+	}
+}
+
 func (c *ConversionTester) ConvertToNext(fromVersion int, input string) ConvTestResult {
 	res := ConvTestResult{}
 
@@ -143,6 +164,38 @@ func (c *ConversionTester) ConvertToNext(fromVersion int, input string) ConvTest
 	return res
 }
 
+func (c *ConversionTester) ConvertTo(fromVersion int, targetVersion int, input string) ConvTestResult {
+	res := ConvTestResult{}
+
+	inSettings, err := conversion.SettingsFromYAML(input)
+	if err != nil {
+		res.Error = fmt.Errorf("input YAML to Settings: %v", err)
+		return res
+	}
+
+	inSettingsMap, err := inSettings.Map()
+	if err != nil {
+		res.Error = fmt.Errorf("input Settings to map: %v", err)
+		return res
+	}
+
+	chain := conversion.Registry().Chain(c.moduleName)
+	res.SettingsMap, err = chain.ConvertTo(fromVersion, targetVersion, inSettingsMap)
+	if err != nil {
+		res.Error = fmt.Errorf("convert input Settings: %v", err)
+		return res
+	}
+
+	res.Settings, err = conversion.SettingsFromMap(res.SettingsMap)
+	if err != nil {
+		res.Error = fmt.Errorf("converted result map to Settings: %v", err)
+		return res
+	}
+
+	res.Version = targetVersion
+	return res
+}
+
 func (c *ConversionTester) ConvertToLatest(fromVersion int, input string) ConvTestResult {
 	res := ConvTestResult{}
 
@@ -4,9 +4,10 @@ package library
 
 var DefaultImagesDigests = map[string]interface{}{
 	"admissionPolicyEngine": map[string]interface{}{
-		"constraintExporter": "imageHash-admissionPolicyEngine-constraintExporter",
-		"gatekeeper":         "imageHash-admissionPolicyEngine-gatekeeper",
-		"trivyProvider":      "imageHash-admissionPolicyEngine-trivyProvider",
+		"constraintExporter":     "imageHash-admissionPolicyEngine-constraintExporter",
+		"gatekeeper":             "imageHash-admissionPolicyEngine-gatekeeper",
+		"imageSignatureProvider": "imageHash-admissionPolicyEngine-imageSignatureProvider",
+		"trivyProvider":          "imageHash-admissionPolicyEngine-trivyProvider",
 	},
 	"basicAuth": map[string]interface{}{
 		"nginx": "imageHash-basicAuth-nginx",
@@ -0,0 +1,45 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policyexceptions
+
+import (
+	"flag"
+)
+
+type PolicyExceptions struct {
+	fs *flag.FlagSet
+}
+
+func NewPolicyExceptions() *PolicyExceptions {
+	pe := &PolicyExceptions{
+		fs: flag.NewFlagSet("policy-exceptions", flag.ContinueOnError),
+	}
+
+	return pe
+}
+
+func (pe *PolicyExceptions) Name() string {
+	return pe.fs.Name()
+}
+
+func (pe *PolicyExceptions) Init(args []string) error {
+	return pe.fs.Parse(args)
+}
+
+func (pe *PolicyExceptions) Run() error {
+	return run()
+}
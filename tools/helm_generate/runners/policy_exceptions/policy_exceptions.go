@@ -0,0 +1,130 @@
+/*
+Copyright 2024 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+This binary is used for copying the PolicyException support into the ConstraintTemplates of
+../modules/015-admission-policy-engine/charts/constraint-templates/templates.
+Gatekeeper can't share the rego libs and the parameters schema between the ConstraintTemplates,
+so the single copy is kept in the lib directory of the chart and is inserted between the lines
+"# start policy exceptions <part> placeholder" and "# end policy exceptions <part> placeholder":
+  - the "schema" part is lib/exceptions-schema.yaml;
+  - the "lib" part is lib/exceptions.rego.
+Steps to use:
+  - make generate
+  - check diff for ./modules/015-admission-policy-engine/charts/constraint-templates/templates
+*/
+
+package policyexceptions
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"tools/helm_generate/helper"
+)
+
+const chartPath = "modules/015-admission-policy-engine/charts/constraint-templates"
+
+// parts are the placeholder names and the files with their content
+var parts = map[string]string{
+	"schema": "lib/exceptions-schema.yaml",
+	"lib":    "lib/exceptions.rego",
+}
+
+func run() error {
+	deckhouseRoot, err := helper.DeckhouseRoot()
+	if err != nil {
+		return err
+	}
+	chartDir := filepath.Join(deckhouseRoot, chartPath)
+
+	snippets := make(map[string]string, len(parts))
+	for part, file := range parts {
+		content, err := os.ReadFile(filepath.Join(chartDir, file))
+		if err != nil {
+			return err
+		}
+		snippets[part] = string(content)
+	}
+
+	return filepath.WalkDir(filepath.Join(chartDir, "templates"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".yaml" {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		updated := string(content)
+		for part, snippet := range snippets {
+			updated, err = fillPlaceholder(updated, part, snippet)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+
+		if updated == string(content) {
+			return nil
+		}
+
+		log.Printf("Update %s", path)
+		return os.WriteFile(path, []byte(updated), d.Type().Perm()|0o644)
+	})
+}
+
+// fillPlaceholder replaces the lines between the placeholder comments with the snippet
+// indented as the start comment, the content without the placeholder is returned as is
+func fillPlaceholder(content, part, snippet string) (string, error) {
+	startMarker := fmt.Sprintf("# start policy exceptions %s placeholder", part)
+	endMarker := fmt.Sprintf("# end policy exceptions %s placeholder", part)
+
+	lines := strings.Split(content, "\n")
+	start, end := -1, -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case startMarker:
+			start = i
+		case endMarker:
+			end = i
+		}
+	}
+
+	switch {
+	case start == -1 && end == -1:
+		return content, nil
+	case start == -1 || end < start:
+		return "", fmt.Errorf("the %q placeholder is broken", part)
+	}
+
+	indent := lines[start][:len(lines[start])-len(strings.TrimLeft(lines[start], " "))]
+	filled := make([]string, 0, len(lines))
+	filled = append(filled, lines[:start+1]...)
+	for _, line := range strings.Split(strings.TrimRight(snippet, "\n"), "\n") {
+		if line == "" {
+			filled = append(filled, "")
+			continue
+		}
+		filled = append(filled, indent+line)
+	}
+	filled = append(filled, lines[end:]...)
+
+	return strings.Join(filled, "\n"), nil
+}